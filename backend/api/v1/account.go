@@ -0,0 +1,247 @@
+package v1
+
+import (
+	"easy-password-backend/internal/apierror"
+	"easy-password-backend/internal/audit"
+	"easy-password-backend/internal/auth"
+	"easy-password-backend/internal/core"
+	"easy-password-backend/pkg/apitypes"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// AccountHandler 处理与当前账户相关的 API 请求。
+type AccountHandler struct {
+	authService  *auth.AuthService
+	auditService *audit.AuditService
+}
+
+// NewAccountHandler 创建一个新的 AccountHandler。
+func NewAccountHandler(authService *auth.AuthService, auditService *audit.AuditService) *AccountHandler {
+	return &AccountHandler{authService: authService, auditService: auditService}
+}
+
+// RegisterRoutes 注册账户路由。
+func (h *AccountHandler) RegisterRoutes(router *gin.RouterGroup) {
+	account := router.Group("/account")
+	{
+		account.DELETE("", h.deleteAccount)
+		account.GET("/events", h.listEvents)
+		account.PUT("/login-approval", h.setLoginApproval)
+		account.POST("/email", h.requestEmailChange)
+		account.POST("/email/confirm", h.confirmEmailChange)
+		account.POST("/username", h.changeUsername)
+	}
+}
+
+type requestEmailChangeRequest struct {
+	NewEmail      string `json:"new_email" binding:"required,email"`
+	MasterKeyHash string `json:"master_key_hash" binding:"required"`
+}
+
+type confirmEmailChangeRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	Code     string `json:"code" binding:"required,len=6"`
+}
+
+type changeUsernameRequest struct {
+	Username      string `json:"username" binding:"required,min=1,max=255,excludes=@"`
+	MasterKeyHash string `json:"master_key_hash" binding:"required"`
+}
+
+type deleteAccountRequest struct {
+	MasterKeyHash string `json:"master_key_hash" binding:"required"`
+}
+
+type setLoginApprovalRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+type loginApprovalResponse struct {
+	RequireDeviceApproval bool `json:"require_device_approval"`
+}
+
+type listEventsQuery struct {
+	Page     int `form:"page" binding:"omitempty,min=1"`
+	PageSize int `form:"page_size" binding:"omitempty,min=1"`
+}
+
+type auditEventResponse struct {
+	ID        uuid.UUID         `json:"id"`
+	Type      string            `json:"type"`
+	IPAddress string            `json:"ip_address"`
+	UserAgent string            `json:"user_agent"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+type listEventsResponse struct {
+	Events   []auditEventResponse `json:"events"`
+	Total    int64                `json:"total"`
+	Page     int                  `json:"page"`
+	PageSize int                  `json:"page_size"`
+}
+
+func (h *AccountHandler) listEvents(c *gin.Context) {
+	var query listEventsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		handleError(c, invalidRequest(err))
+		return
+	}
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = defaultPageSize
+	}
+	if query.PageSize > maxPageSize {
+		query.PageSize = maxPageSize
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		handleError(c, apierror.ErrUnauthorized)
+		return
+	}
+
+	events, total, err := h.auditService.ListUserEvents(c.Request.Context(), userID.(uuid.UUID), query.Page, query.PageSize)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	resp := listEventsResponse{
+		Events:   make([]auditEventResponse, 0, len(events)),
+		Total:    total,
+		Page:     query.Page,
+		PageSize: query.PageSize,
+	}
+	for _, event := range events {
+		resp.Events = append(resp.Events, newAuditEventResponse(event))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func newAuditEventResponse(event core.AuditEvent) auditEventResponse {
+	return auditEventResponse{
+		ID:        event.ID,
+		Type:      string(event.Type),
+		IPAddress: event.IPAddress,
+		UserAgent: event.UserAgent,
+		Metadata:  event.Metadata,
+		CreatedAt: event.CreatedAt,
+	}
+}
+
+func (h *AccountHandler) setLoginApproval(c *gin.Context) {
+	var req setLoginApprovalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, invalidRequest(err))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		handleError(c, apierror.ErrUnauthorized)
+		return
+	}
+
+	if err := h.authService.SetLoginApprovalPolicy(c.Request.Context(), userID.(uuid.UUID), *req.Enabled); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, loginApprovalResponse{RequireDeviceApproval: *req.Enabled})
+}
+
+func (h *AccountHandler) deleteAccount(c *gin.Context) {
+	var req deleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, invalidRequest(err))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		handleError(c, apierror.ErrUnauthorized)
+		return
+	}
+
+	if err := h.authService.DeleteAccount(c.Request.Context(), userID.(uuid.UUID), req.MasterKeyHash); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apitypes.MessageResponse{Message: "Account deleted successfully"})
+}
+
+func (h *AccountHandler) requestEmailChange(c *gin.Context) {
+	var req requestEmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, invalidRequest(err))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		handleError(c, apierror.ErrUnauthorized)
+		return
+	}
+
+	if err := h.authService.RequestEmailChange(c.Request.Context(), userID.(uuid.UUID), req.NewEmail, req.MasterKeyHash); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apitypes.MessageResponse{Message: "Verification code sent to the new email address"})
+}
+
+func (h *AccountHandler) confirmEmailChange(c *gin.Context) {
+	var req confirmEmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, invalidRequest(err))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		handleError(c, apierror.ErrUnauthorized)
+		return
+	}
+
+	if err := h.authService.ConfirmEmailChange(c.Request.Context(), userID.(uuid.UUID), req.NewEmail, req.Code); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apitypes.MessageResponse{Message: "Email changed successfully"})
+}
+
+func (h *AccountHandler) changeUsername(c *gin.Context) {
+	var req changeUsernameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, invalidRequest(err))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		handleError(c, apierror.ErrUnauthorized)
+		return
+	}
+
+	if err := h.authService.ChangeUsername(c.Request.Context(), userID.(uuid.UUID), req.Username, req.MasterKeyHash); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apitypes.MessageResponse{Message: "Username changed successfully"})
+}
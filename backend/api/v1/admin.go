@@ -0,0 +1,283 @@
+package v1
+
+import (
+	"easy-password-backend/internal/apierror"
+	"easy-password-backend/internal/auth"
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/service"
+	"easy-password-backend/pkg/apitypes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AdminHandler 处理管理员 API 请求。路由组必须依次使用 AuthMiddleware 和 RequireRole(core.UserRoleAdmin)。
+type AdminHandler struct {
+	adminService *service.AdminService
+	authService  *auth.AuthService
+}
+
+// NewAdminHandler 创建一个新的 AdminHandler。
+func NewAdminHandler(adminService *service.AdminService, authService *auth.AuthService) *AdminHandler {
+	return &AdminHandler{adminService: adminService, authService: authService}
+}
+
+// RegisterRoutes 注册管理员路由。
+func (h *AdminHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/health", h.health)
+	router.GET("/stats", h.stats)
+	router.GET("/backup", h.backup)
+	router.POST("/encryption/rotate", h.rotateEncryptionKey)
+
+	users := router.Group("/users")
+	{
+		users.GET("", h.listUsers)
+		users.GET("/:id", h.getUser)
+		users.PUT("/:id/status", h.setUserStatus)
+		users.POST("/:id/password-reset", h.sendPasswordReset)
+	}
+}
+
+// adminUserResponse 只包含账户元数据，不包含认证材料或保险库内容。
+type adminUserResponse struct {
+	ID                    uuid.UUID       `json:"id"`
+	Username              string          `json:"username"`
+	Email                 string          `json:"email"`
+	Status                core.UserStatus `json:"status"`
+	Role                  core.UserRole   `json:"role"`
+	RequireDeviceApproval bool            `json:"require_device_approval"`
+	Items                 int64           `json:"items"`
+	Devices               *int            `json:"devices,omitempty"`
+	CreatedAt             time.Time       `json:"created_at"`
+	UpdatedAt             time.Time       `json:"updated_at"`
+}
+
+func newAdminUserResponse(summary *service.UserSummary) adminUserResponse {
+	user := summary.User
+	return adminUserResponse{
+		ID:                    user.ID,
+		Username:              user.Username,
+		Email:                 user.Email,
+		Status:                user.AccountStatus(),
+		Role:                  user.AccountRole(),
+		RequireDeviceApproval: user.RequireDeviceApproval,
+		Items:                 summary.Items,
+		CreatedAt:             user.CreatedAt,
+		UpdatedAt:             user.UpdatedAt,
+	}
+}
+
+type listUsersQuery struct {
+	Query    string `form:"q" binding:"max=255"`
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1"`
+}
+
+type listUsersResponse struct {
+	Users    []adminUserResponse `json:"users"`
+	Total    int64               `json:"total"`
+	Page     int                 `json:"page"`
+	PageSize int                 `json:"page_size"`
+}
+
+type setUserStatusRequest struct {
+	Status core.UserStatus `json:"status" binding:"required"`
+	Reason string          `json:"reason" binding:"max=500"`
+}
+
+type healthResponse struct {
+	Status            string    `json:"status"`
+	StartedAt         time.Time `json:"started_at"`
+	UptimeSeconds     int64     `json:"uptime_seconds"`
+	DatabaseOK        bool      `json:"database_ok"`
+	DatabaseLatencyMS int64     `json:"database_latency_ms"`
+}
+
+type statsResponse struct {
+	Backend    string                    `json:"backend"`
+	SizeBytes  int64                     `json:"size_bytes"`
+	Records    map[string]int64          `json:"records"`
+	Encryption *encryptionStatusResponse `json:"encryption,omitempty"`
+}
+
+type encryptionStatusResponse struct {
+	ActiveKey uint32    `json:"active_key"`
+	DataKeys  int       `json:"data_keys"`
+	Pending   bool      `json:"reencryption_pending"`
+	RotatedAt time.Time `json:"rotated_at"`
+}
+
+func newEncryptionStatusResponse(status *core.EncryptionStatus) *encryptionStatusResponse {
+	if status == nil {
+		return nil
+	}
+	return &encryptionStatusResponse{
+		ActiveKey: status.ActiveKey,
+		DataKeys:  status.DataKeys,
+		Pending:   status.Pending,
+		RotatedAt: status.RotatedAt,
+	}
+}
+
+func (h *AdminHandler) listUsers(c *gin.Context) {
+	var query listUsersQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		handleError(c, invalidRequest(err))
+		return
+	}
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = defaultPageSize
+	}
+	if query.PageSize > maxPageSize {
+		query.PageSize = maxPageSize
+	}
+
+	summaries, total, err := h.adminService.ListUsers(c.Request.Context(), query.Query, query.Page, query.PageSize)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	resp := listUsersResponse{
+		Users:    make([]adminUserResponse, 0, len(summaries)),
+		Total:    total,
+		Page:     query.Page,
+		PageSize: query.PageSize,
+	}
+	for i := range summaries {
+		resp.Users = append(resp.Users, newAdminUserResponse(&summaries[i]))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *AdminHandler) getUser(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		handleError(c, apierror.ErrNotFound)
+		return
+	}
+
+	summary, err := h.adminService.GetUserSummary(c.Request.Context(), id)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	resp := newAdminUserResponse(summary)
+	resp.Devices = &summary.Devices
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *AdminHandler) setUserStatus(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		handleError(c, apierror.ErrNotFound)
+		return
+	}
+
+	var req setUserStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, invalidRequest(err))
+		return
+	}
+
+	// 管理员不能修改自己的状态，以免把自己锁在外面。
+	adminID, _ := c.Get("userID")
+	if adminID == id {
+		handleError(c, apierror.ErrForbidden)
+		return
+	}
+
+	user, err := h.adminService.SetUserStatus(c.Request.Context(), id, req.Status, "admin:"+adminID.(uuid.UUID).String(), req.Reason)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newAdminUserResponse(&service.UserSummary{User: user}))
+}
+
+func (h *AdminHandler) sendPasswordReset(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		handleError(c, apierror.ErrNotFound)
+		return
+	}
+
+	user, err := h.adminService.GetUser(c.Request.Context(), id)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	if user.AccountStatus() != core.UserStatusActive {
+		handleError(c, apierror.ErrPasswordResetInactive)
+		return
+	}
+
+	if err := h.authService.RequestPasswordReset(c.Request.Context(), user.Email); err != nil {
+		handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, apitypes.MessageResponse{Message: "Password reset email sent"})
+}
+
+func (h *AdminHandler) health(c *gin.Context) {
+	health := h.adminService.Health(c.Request.Context())
+	status := http.StatusOK
+	if !health.DatabaseOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, healthResponse{
+		Status:            health.Status,
+		StartedAt:         health.StartedAt,
+		UptimeSeconds:     int64(health.Uptime.Seconds()),
+		DatabaseOK:        health.DatabaseOK,
+		DatabaseLatencyMS: health.DatabaseLatency.Milliseconds(),
+	})
+}
+
+func (h *AdminHandler) stats(c *gin.Context) {
+	stats, err := h.adminService.StorageStats(c.Request.Context())
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, statsResponse{
+		Backend:    stats.Backend,
+		SizeBytes:  stats.SizeBytes,
+		Records:    stats.Records,
+		Encryption: newEncryptionStatusResponse(stats.Encryption),
+	})
+}
+
+// rotateEncryptionKey 生成新的数据密钥并立即返回，已有记录在后台重新加密，进度可通过 /stats 查看。
+func (h *AdminHandler) rotateEncryptionKey(c *gin.Context) {
+	adminID, _ := c.Get("userID")
+	status, err := h.adminService.RotateEncryptionKey(c.Request.Context(), adminID.(uuid.UUID))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, newEncryptionStatusResponse(status))
+}
+
+func (h *AdminHandler) backup(c *gin.Context) {
+	adminID, _ := c.Get("userID")
+
+	filename := fmt.Sprintf("easypassword-%s.db", time.Now().UTC().Format("20060102T150405Z"))
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	if err := h.adminService.Backup(c.Request.Context(), adminID.(uuid.UUID), c.Writer); err != nil {
+		// 已经开始写出快照时无法再返回错误响应，客户端会收到被截断的文件。
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			handleError(c, err)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package v1
+
+import (
+	"context"
+	"easy-password-backend/config"
+	"easy-password-backend/internal/apierror"
+	"easy-password-backend/internal/audit"
+	"easy-password-backend/internal/auth"
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/email"
+	"easy-password-backend/internal/kms"
+	"easy-password-backend/internal/repository/memory"
+	"easy-password-backend/internal/service"
+	"easy-password-backend/pkg/apitypes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// discardEmailService 丢弃所有邮件。
+type discardEmailService struct{}
+
+func (discardEmailService) SendEmail(to, subject, body string) error          { return nil }
+func (discardEmailService) SendPasswordResetEmail(to, resetLink string) error { return nil }
+func (discardEmailService) SendVerificationCodeEmail(to, code string) error   { return nil }
+func (discardEmailService) SendNewDeviceLoginEmail(to string, data email.NewDeviceLoginTemplateData) error {
+	return nil
+}
+func (discardEmailService) SendLoginApprovalEmail(to string, data email.LoginApprovalTemplateData) error {
+	return nil
+}
+func (discardEmailService) SendAccountDeletedEmail(to, username string) error     { return nil }
+func (discardEmailService) SendEmailChangeCodeEmail(to, code string) error        { return nil }
+func (discardEmailService) SendEmailChangedNoticeEmail(to, newEmail string) error { return nil }
+
+// adminTestEnv 是内存存储上注册了全部路由的 API，admin 和 user 是两个已登录的账户。
+type adminTestEnv struct {
+	router    *gin.Engine
+	storage   *memory.Storage
+	auth      *auth.AuthService
+	admin     *service.AdminService
+	adminUser *core.User
+	adminTok  string
+	user      *core.User
+	userTok   string
+}
+
+func newAdminTestEnv(t *testing.T) *adminTestEnv {
+	t.Helper()
+	storage := memory.NewMemoryStorage()
+	auditService := audit.NewAuditService(storage.Audit())
+	keys := kms.NewStaticProvider(map[kms.Purpose][]kms.Key{
+		kms.PurposeJWT: {kms.NewKey(kms.AlgHS256, []byte("test jwt secret"))},
+	})
+	cfg := &config.Config{JWTExpiration: time.Hour, FrontendURL: "http://localhost"}
+	services := Services{
+		Auth:   auth.NewAuthService(storage.User(), storage.VerificationCode(), storage.Device(), storage.LoginApproval(), storage.APIKey(), discardEmailService{}, auditService, cfg, keys),
+		Vault:  service.NewVaultService(storage.Vault(), storage.User(), auditService),
+		Device: service.NewDeviceService(storage.Device(), auditService),
+		Admin:  service.NewAdminService(storage.User(), storage.Vault(), storage.Device(), storage, auditService),
+		Audit:  auditService,
+	}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestIDMiddleware(), ClientInfoMiddleware())
+	RegisterRoutes(router, services)
+
+	env := &adminTestEnv{router: router, storage: storage, auth: services.Auth, admin: services.Admin}
+	env.adminUser, env.adminTok = env.createUser(t, "root", core.UserRoleAdmin)
+	env.user, env.userTok = env.createUser(t, "alice", core.UserRoleUser)
+	return env
+}
+
+// createUser 直接在存储中创建一个用户并登录，返回用户和访问令牌。
+func (e *adminTestEnv) createUser(t *testing.T, name string, role core.UserRole) (*core.User, string) {
+	t.Helper()
+	ctx := context.Background()
+	user := &core.User{Username: name, Email: name + "@example.com", AuthHash: "hash-" + name, MasterSalt: []byte("salt"), Role: role}
+	if err := e.storage.User().Create(ctx, user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	result, err := e.auth.Login(ctx, name, "hash-"+name, auth.DeviceInfo{ID: "laptop"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	return user, result.Token
+}
+
+// do 以 token 的身份发送请求，返回响应。
+func (e *adminTestEnv) do(t *testing.T, method, path, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	e.router.ServeHTTP(w, req)
+	return w
+}
+
+// expectStatus 确认响应的状态码；want 是错误时同时确认错误码。
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, status int, want *apierror.APIError) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d; body: %s", w.Code, status, w.Body)
+	}
+	if want == nil {
+		return
+	}
+	var resp apitypes.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode error response: %v", err)
+	}
+	if resp.Code != want.Code {
+		t.Errorf("error code = %s, want %s", resp.Code, want.Code)
+	}
+}
+
+// storedUser 从存储中重新读取用户。
+func (e *adminTestEnv) storedUser(t *testing.T, id uuid.UUID) *core.User {
+	t.Helper()
+	user, err := e.storage.User().FindByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("find user: %v", err)
+	}
+	return user
+}
+
+func TestAdminRoutesRequireAdmin(t *testing.T) {
+	env := newAdminTestEnv(t)
+	userPath := "/api/v1/admin/users/" + env.user.ID.String()
+	adminPath := "/api/v1/admin/users/" + env.adminUser.ID.String()
+	routes := []struct {
+		method, path, body string
+	}{
+		{"GET", "/api/v1/admin/health", ""},
+		{"GET", "/api/v1/admin/stats", ""},
+		{"GET", "/api/v1/admin/backup", ""},
+		{"POST", "/api/v1/admin/encryption/rotate", ""},
+		{"GET", "/api/v1/admin/users", ""},
+		{"GET", userPath, ""},
+		{"PUT", adminPath + "/status", `{"status":"suspended"}`},
+		{"POST", adminPath + "/password-reset", ""},
+	}
+	for _, route := range routes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			expectStatus(t, env.do(t, route.method, route.path, "", route.body), http.StatusUnauthorized, apierror.ErrUnauthorized)
+			expectStatus(t, env.do(t, route.method, route.path, env.userTok, route.body), http.StatusForbidden, apierror.ErrForbidden)
+		})
+	}
+	// 被拒绝的请求没有修改管理员账户。
+	if status := env.storedUser(t, env.adminUser.ID).AccountStatus(); status != core.UserStatusActive {
+		t.Errorf("admin status after rejected requests = %s, want active", status)
+	}
+}
+
+func TestAdminCannotChangeOwnStatus(t *testing.T) {
+	env := newAdminTestEnv(t)
+	for _, status := range []core.UserStatus{core.UserStatusSuspended, core.UserStatusLocked, core.UserStatusActive} {
+		w := env.do(t, "PUT", "/api/v1/admin/users/"+env.adminUser.ID.String()+"/status", env.adminTok, `{"status":"`+string(status)+`"}`)
+		expectStatus(t, w, http.StatusForbidden, apierror.ErrForbidden)
+	}
+	if status := env.storedUser(t, env.adminUser.ID).AccountStatus(); status != core.UserStatusActive {
+		t.Errorf("admin status = %s, want active", status)
+	}
+
+	// 修改其他用户的状态仍然可以。
+	w := env.do(t, "PUT", "/api/v1/admin/users/"+env.user.ID.String()+"/status", env.adminTok, `{"status":"suspended","reason":"test"}`)
+	expectStatus(t, w, http.StatusOK, nil)
+	if status := env.storedUser(t, env.user.ID).AccountStatus(); status != core.UserStatusSuspended {
+		t.Errorf("user status = %s, want suspended", status)
+	}
+}
+
+func TestRoleCannotBeChangedThroughAPI(t *testing.T) {
+	env := newAdminTestEnv(t)
+	userPath := "/api/v1/admin/users/" + env.user.ID.String()
+	requests := []struct {
+		name, method, path, token, body string
+	}{
+		{"role route as admin", "PUT", userPath + "/role", env.adminTok, `{"role":"admin"}`},
+		{"role route as user", "PUT", userPath + "/role", env.userTok, `{"role":"admin"}`},
+		{"role in status body", "PUT", userPath + "/status", env.adminTok, `{"status":"active","role":"admin"}`},
+		{"role in username body", "POST", "/api/v1/account/username", env.userTok, `{"username":"alice2","master_key_hash":"hash-alice","role":"admin"}`},
+		{"role in register body", "POST", "/api/v1/register", "", `{"username":"mallory","email":"m@example.com","master_key_hash":"h","master_salt":"s","code":"000000","role":"admin"}`},
+	}
+	for _, req := range requests {
+		t.Run(req.name, func(t *testing.T) {
+			env.do(t, req.method, req.path, req.token, req.body)
+			if role := env.storedUser(t, env.user.ID).AccountRole(); role != core.UserRoleUser {
+				t.Fatalf("user role = %s, want user", role)
+			}
+		})
+	}
+	if _, err := env.storage.User().FindByUsername(context.Background(), "mallory"); err == nil {
+		t.Error("register without a verification code created an account")
+	}
+}
+
+func TestRoleChangeAppliesToIssuedTokens(t *testing.T) {
+	ctx := context.Background()
+	env := newAdminTestEnv(t)
+
+	// 角色取自存储的用户记录，已签发的令牌在角色变化后立即按新角色授权。
+	if _, err := env.admin.SetUserRole(ctx, env.user.ID, core.UserRoleAdmin, "test"); err != nil {
+		t.Fatalf("promote user: %v", err)
+	}
+	expectStatus(t, env.do(t, "GET", "/api/v1/admin/users", env.userTok, ""), http.StatusOK, nil)
+
+	if _, err := env.admin.SetUserRole(ctx, env.adminUser.ID, core.UserRoleUser, "test"); err != nil {
+		t.Fatalf("demote admin: %v", err)
+	}
+	expectStatus(t, env.do(t, "GET", "/api/v1/admin/users", env.adminTok, ""), http.StatusForbidden, apierror.ErrForbidden)
+}
@@ -0,0 +1,152 @@
+package v1
+
+import (
+	"easy-password-backend/internal/apierror"
+	"easy-password-backend/internal/auth"
+	"easy-password-backend/internal/core"
+	"easy-password-backend/pkg/apitypes"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// APIKeyHandler 处理个人 API 密钥管理相关的 API 请求。
+type APIKeyHandler struct {
+	authService *auth.AuthService
+}
+
+// NewAPIKeyHandler 创建一个新的 APIKeyHandler。
+func NewAPIKeyHandler(authService *auth.AuthService) *APIKeyHandler {
+	return &APIKeyHandler{authService: authService}
+}
+
+// RegisterRoutes 注册 API 密钥管理路由。这些路由只接受用户登录签发的令牌，
+// API 密钥不能用来创建新的密钥。
+func (h *APIKeyHandler) RegisterRoutes(router *gin.RouterGroup) {
+	keys := router.Group("/account/api-keys")
+	{
+		keys.GET("", h.listAPIKeys)
+		keys.POST("", h.createAPIKey)
+		keys.DELETE("/:id", h.revokeAPIKey)
+	}
+}
+
+type createAPIKeyRequest struct {
+	Name       string             `json:"name" binding:"required,max=255"`
+	Scopes     []core.APIKeyScope `json:"scopes" binding:"required,min=1"`
+	ItemIDs    []uuid.UUID        `json:"item_ids"`
+	Categories []string           `json:"categories"`
+	ExpiresAt  *time.Time         `json:"expires_at"`
+}
+
+type apiKeyResponse struct {
+	ClientID   uuid.UUID          `json:"client_id"`
+	Name       string             `json:"name"`
+	Scopes     []core.APIKeyScope `json:"scopes"`
+	ItemIDs    []uuid.UUID        `json:"item_ids"`
+	Categories []string           `json:"categories"`
+	ExpiresAt  *time.Time         `json:"expires_at"`
+	LastUsedAt *time.Time         `json:"last_used_at"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+// createAPIKeyResponse 附带明文密钥，它只在创建时返回这一次。
+type createAPIKeyResponse struct {
+	apiKeyResponse
+	ClientSecret string `json:"client_secret"`
+}
+
+func newAPIKeyResponse(key *core.APIKey) apiKeyResponse {
+	resp := apiKeyResponse{
+		ClientID:   key.ID,
+		Name:       key.Name,
+		Scopes:     key.Scopes,
+		ItemIDs:    key.ItemIDs,
+		Categories: key.Categories,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+	if resp.ItemIDs == nil {
+		resp.ItemIDs = []uuid.UUID{}
+	}
+	if resp.Categories == nil {
+		resp.Categories = []string{}
+	}
+	return resp
+}
+
+func (h *APIKeyHandler) listAPIKeys(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		handleError(c, apierror.ErrUnauthorized)
+		return
+	}
+
+	keys, err := h.authService.ListAPIKeys(c.Request.Context(), userID.(uuid.UUID))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	resp := make([]apiKeyResponse, 0, len(keys))
+	for i := range keys {
+		resp = append(resp, newAPIKeyResponse(&keys[i]))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *APIKeyHandler) createAPIKey(c *gin.Context) {
+	var req createAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, invalidRequest(err))
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		handleError(c, apierror.ErrUnauthorized)
+		return
+	}
+
+	key, secret, err := h.authService.CreateAPIKey(c.Request.Context(), userID.(uuid.UUID), auth.APIKeyRequest{
+		Name:       req.Name,
+		Scopes:     req.Scopes,
+		ItemIDs:    req.ItemIDs,
+		Categories: req.Categories,
+		ExpiresAt:  req.ExpiresAt,
+	})
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, createAPIKeyResponse{
+		apiKeyResponse: newAPIKeyResponse(key),
+		ClientSecret:   secret,
+	})
+}
+
+func (h *APIKeyHandler) revokeAPIKey(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		handleError(c, apierror.ErrInvalidAPIKeyID)
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		handleError(c, apierror.ErrUnauthorized)
+		return
+	}
+
+	if err := h.authService.RevokeAPIKey(c.Request.Context(), userID.(uuid.UUID), id); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apitypes.MessageResponse{Message: "API key revoked successfully"})
+}
@@ -3,9 +3,13 @@ package v1
 import (
 	"easy-password-backend/internal/apierror"
 	"easy-password-backend/internal/auth"
+	"easy-password-backend/pkg/apitypes"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // AuthHandler 处理与身份验证相关的 API 请求。
@@ -28,89 +32,126 @@ func (h *AuthHandler) RegisterRoutes(router *gin.Engine) {
 		v1.POST("/send-verification-code", h.sendVerificationCode)
 		v1.POST("/request-password-reset", h.requestPasswordReset)
 		v1.POST("/reset-password", h.resetPassword)
+		v1.POST("/login-approvals/approve", h.approveLogin)
+		v1.GET("/login-approvals/:id", h.pollLoginApproval)
+		v1.POST("/login-approvals/:id/verify", h.verifyLoginApproval)
+		v1.POST("/api-keys/token", h.exchangeAPIKey)
 	}
+	// 其他服务从约定的位置获取验证令牌所用的公钥。
+	router.GET("/.well-known/jwks.json", h.jwks)
 }
 
-type registerRequest struct {
-	Username      string `json:"username" binding:"required,min=1"`
-	Email         string `json:"email" binding:"required,email"`
-	MasterKeyHash string `json:"master_key_hash" binding:"required"`
-	MasterSalt    string `json:"master_salt" binding:"required"`
-	Code          string `json:"code" binding:"required,len=6"`
-}
+func (h *AuthHandler) register(c *gin.Context) {
+	var req apitypes.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, invalidRequest(err))
+		return
+	}
 
-type loginRequest struct {
-	Identifier    string `json:"identifier" binding:"required"`
-	MasterKeyHash string `json:"master_key_hash" binding:"required"`
-}
+	user, err := h.authService.Register(c.Request.Context(), req.Username, req.Email, req.MasterKeyHash, req.MasterSalt, req.Code)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
 
-type loginResponse struct {
-	Username   string `json:"username"`
-	Token      string `json:"token"`
-	MasterSalt string `json:"master_salt"`
+	c.JSON(http.StatusCreated, apitypes.RegisterResponse{
+		Message: "User registered successfully",
+		UserID:  user.ID,
+	})
 }
 
-type getSaltRequest struct {
-	Identifier string `json:"identifier" binding:"required"`
-}
+func (h *AuthHandler) login(c *gin.Context) {
+	var req apitypes.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, invalidRequest(err))
+		return
+	}
 
-type sendVerificationCodeRequest struct {
-	Email string `json:"email" binding:"required,email"`
-}
+	device := auth.DeviceInfo{ID: req.DeviceID, Name: req.DeviceName, Type: req.DeviceType}
+	result, err := h.authService.Login(c.Request.Context(), req.Identifier, req.MasterKeyHash, device)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
 
-type requestPasswordResetRequest struct {
-	Email string `json:"email" binding:"required,email"`
+	writeLoginResult(c, result)
 }
 
-type resetPasswordRequest struct {
-	Token            string `json:"token" binding:"required"`
-	NewMasterKeyHash string `json:"new_master_key_hash" binding:"required"`
-	NewMasterSalt    string `json:"new_master_salt" binding:"required"`
+// writeLoginResult 写出登录结果；登录仍在等待批准时返回 202。
+func writeLoginResult(c *gin.Context, result *auth.LoginResult) {
+	if result.PendingApprovalID != uuid.Nil {
+		c.JSON(http.StatusAccepted, apitypes.PendingLoginResponse{
+			Status:     apitypes.LoginStatusPendingApproval,
+			ApprovalID: result.PendingApprovalID,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, apitypes.LoginResponse{
+		Username:   result.Username,
+		Token:      result.Token,
+		MasterSalt: result.MasterSalt,
+		DeviceID:   result.DeviceID,
+	})
 }
 
-func (h *AuthHandler) register(c *gin.Context) {
-	var req registerRequest
+func (h *AuthHandler) approveLogin(c *gin.Context) {
+	var req apitypes.ApproveLoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		handleError(c, apierror.ErrInvalidRequest)
+		handleError(c, invalidRequest(err))
 		return
 	}
 
-	user, err := h.authService.Register(c.Request.Context(), req.Username, req.Email, req.MasterKeyHash, req.MasterSalt, req.Code)
+	if err := h.authService.ApproveLogin(c.Request.Context(), req.Token); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apitypes.MessageResponse{Message: "Login approved successfully"})
+}
+
+func (h *AuthHandler) pollLoginApproval(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		handleError(c, apierror.ErrLoginApprovalNotFound)
+		return
+	}
+
+	result, err := h.authService.PollLoginApproval(c.Request.Context(), id)
 	if err != nil {
 		handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "User registered successfully",
-		"user_id": user.ID,
-	})
+	writeLoginResult(c, result)
 }
 
-func (h *AuthHandler) login(c *gin.Context) {
-	var req loginRequest
+func (h *AuthHandler) verifyLoginApproval(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		handleError(c, apierror.ErrLoginApprovalNotFound)
+		return
+	}
+
+	var req apitypes.VerifyLoginApprovalRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		handleError(c, apierror.ErrInvalidRequest)
+		handleError(c, invalidRequest(err))
 		return
 	}
 
-	token, masterSalt, username, err := h.authService.Login(c.Request.Context(), req.Identifier, req.MasterKeyHash)
+	result, err := h.authService.VerifyLoginApprovalCode(c.Request.Context(), id, req.Code)
 	if err != nil {
 		handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, loginResponse{
-		Username:   username,
-		Token:      token,
-		MasterSalt: masterSalt,
-	})
+	writeLoginResult(c, result)
 }
 
 func (h *AuthHandler) getSalt(c *gin.Context) {
-	var req getSaltRequest
+	var req apitypes.SaltRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		handleError(c, apierror.ErrInvalidRequest)
+		handleError(c, invalidRequest(err))
 		return
 	}
 	masterSalt, err := h.authService.GetMasterSalt(c.Request.Context(), req.Identifier)
@@ -118,13 +159,13 @@ func (h *AuthHandler) getSalt(c *gin.Context) {
 		handleError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"master_salt": masterSalt})
+	c.JSON(http.StatusOK, apitypes.SaltResponse{MasterSalt: masterSalt})
 }
 
 func (h *AuthHandler) sendVerificationCode(c *gin.Context) {
-	var req sendVerificationCodeRequest
+	var req apitypes.SendVerificationCodeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		handleError(c, apierror.ErrInvalidRequest)
+		handleError(c, invalidRequest(err))
 		return
 	}
 
@@ -134,13 +175,13 @@ func (h *AuthHandler) sendVerificationCode(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Verification code sent successfully"})
+	c.JSON(http.StatusOK, apitypes.MessageResponse{Message: "Verification code sent successfully"})
 }
 
 func (h *AuthHandler) requestPasswordReset(c *gin.Context) {
-	var req requestPasswordResetRequest
+	var req apitypes.RequestPasswordResetRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		handleError(c, apierror.ErrInvalidRequest)
+		handleError(c, invalidRequest(err))
 		return
 	}
 
@@ -151,13 +192,13 @@ func (h *AuthHandler) requestPasswordReset(c *gin.Context) {
 	}
 
 	// 出于安全考虑，即使找不到电子邮件，也始终返回成功的响应，以防止用户枚举攻击。
-	c.JSON(http.StatusOK, gin.H{"message": "If an account with that email exists, a password reset link has been sent."})
+	c.JSON(http.StatusOK, apitypes.MessageResponse{Message: "If an account with that email exists, a password reset link has been sent."})
 }
 
 func (h *AuthHandler) resetPassword(c *gin.Context) {
-	var req resetPasswordRequest
+	var req apitypes.ResetPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		handleError(c, apierror.ErrInvalidRequest)
+		handleError(c, invalidRequest(err))
 		return
 	}
 
@@ -167,5 +208,43 @@ func (h *AuthHandler) resetPassword(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset successfully."})
+	c.JSON(http.StatusOK, apitypes.MessageResponse{Message: "Password has been reset successfully."})
+}
+
+func (h *AuthHandler) jwks(c *gin.Context) {
+	set, err := h.authService.PublicKeys(c.Request.Context())
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	// 允许短时间缓存；轮换时新密钥在成为活动密钥之前应先发布足够长的时间。
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, set)
+}
+
+func (h *AuthHandler) exchangeAPIKey(c *gin.Context) {
+	var req apitypes.APIKeyTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleError(c, invalidRequest(err))
+		return
+	}
+
+	token, err := h.authService.ExchangeAPIKey(c.Request.Context(), req.ClientID, req.ClientSecret)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	scopes := make([]string, len(token.Scopes))
+	for i, scope := range token.Scopes {
+		scopes[i] = string(scope)
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, apitypes.APIKeyTokenResponse{
+		AccessToken: token.Token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(time.Until(token.ExpiresAt).Seconds()),
+		Scope:       strings.Join(scopes, " "),
+		MasterSalt:  token.MasterSalt,
+	})
 }
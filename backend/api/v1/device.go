@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"easy-password-backend/internal/apierror"
+	"easy-password-backend/internal/service"
+	"easy-password-backend/pkg/apitypes"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DeviceHandler 处理与设备管理相关的 API 请求。
+type DeviceHandler struct {
+	deviceService *service.DeviceService
+}
+
+// NewDeviceHandler 创建一个新的 DeviceHandler。
+func NewDeviceHandler(deviceService *service.DeviceService) *DeviceHandler {
+	return &DeviceHandler{deviceService: deviceService}
+}
+
+// RegisterRoutes 注册设备路由。
+func (h *DeviceHandler) RegisterRoutes(router *gin.RouterGroup) {
+	devices := router.Group("/devices")
+	{
+		devices.GET("", h.getDevices)
+		devices.DELETE("/:id", h.revokeDevice)
+	}
+}
+
+type deviceResponse struct {
+	ID          uuid.UUID `json:"id"`
+	DeviceID    string    `json:"device_id"`
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`
+	IPAddress   string    `json:"ip_address"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+	Current     bool      `json:"current"`
+}
+
+func (h *DeviceHandler) getDevices(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		handleError(c, apierror.ErrUnauthorized)
+		return
+	}
+	currentDeviceID, _ := c.Get("deviceID")
+
+	devices, err := h.deviceService.GetDevices(c.Request.Context(), userID.(uuid.UUID))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	resp := make([]deviceResponse, 0, len(devices))
+	for _, device := range devices {
+		resp = append(resp, deviceResponse{
+			ID:          device.ID,
+			DeviceID:    device.DeviceID,
+			Name:        device.Name,
+			Type:        device.Type,
+			IPAddress:   device.IPAddress,
+			FirstSeenAt: device.FirstSeenAt,
+			LastSeenAt:  device.LastSeenAt,
+			Current:     device.ID == currentDeviceID,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *DeviceHandler) revokeDevice(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		handleError(c, apierror.ErrInvalidDeviceID)
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		handleError(c, apierror.ErrUnauthorized)
+		return
+	}
+
+	if err := h.deviceService.RevokeDevice(c.Request.Context(), id, userID.(uuid.UUID)); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, apitypes.MessageResponse{Message: "Device revoked successfully"})
+}
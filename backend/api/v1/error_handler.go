@@ -2,19 +2,83 @@ package v1
 
 import (
 	"easy-password-backend/internal/apierror"
+	"easy-password-backend/pkg/apitypes"
+	"encoding/json"
 	"errors"
+	"io"
+	"reflect"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 )
 
+func init() {
+	// 让校验错误使用 JSON 或查询参数中的字段名，而不是 Go 结构体字段名。
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" && name != "-" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	}
+}
+
 // handleError 集中处理所有 API 处理程序的错误。
 func handleError(c *gin.Context, err error) {
 	var apiErr *apierror.APIError
-	if errors.As(err, &apiErr) {
-		c.JSON(apiErr.Code, gin.H{"error": apiErr.Message})
-		return
+	if !errors.As(err, &apiErr) {
+		// 对于任何其他错误，返回一个通用的 500 内部服务器错误。
+		apiErr = apierror.ErrInternalServer
+	}
+	c.JSON(apiErr.Status, apitypes.ErrorResponse{
+		Error:     apiErr.Message,
+		Code:      apiErr.Code,
+		Details:   apiErr.Details,
+		RequestID: c.GetString(requestIDKey),
+	})
+}
+
+// invalidRequest 将 gin 绑定请求体或查询参数时的错误转换为 ErrInvalidRequest，
+// 校验失败的字段以 "字段名: 规则" 的形式写入 details，例如 {"email": "email", "code": "len=6"}。
+func invalidRequest(err error) error {
+	details := map[string]string{}
+
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &validationErrs):
+		for _, fieldErr := range validationErrs {
+			rule := fieldErr.Tag()
+			if fieldErr.Param() != "" {
+				rule += "=" + fieldErr.Param()
+			}
+			details[fieldPath(fieldErr.Namespace())] = rule
+		}
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		details[typeErr.Field] = "type=" + typeErr.Type.Kind().String()
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		details["body"] = "json"
+	case errors.Is(err, io.EOF):
+		details["body"] = "required"
+	}
+
+	if len(details) == 0 {
+		return apierror.ErrInvalidRequest
 	}
+	return apierror.ErrInvalidRequest.WithDetails(details)
+}
 
-	// 对于任何其他错误，返回一个通用的 500 内部服务器错误。
-	c.JSON(apierror.ErrInternalServer.Code, gin.H{"error": apierror.ErrInternalServer.Message})
-}
\ No newline at end of file
+// fieldPath 去掉校验错误命名空间开头的结构体名，例如 RegisterRequest.email 变为 email。
+func fieldPath(namespace string) string {
+	_, path, found := strings.Cut(namespace, ".")
+	if !found {
+		return namespace
+	}
+	return path
+}
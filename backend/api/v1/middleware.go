@@ -1,18 +1,33 @@
 package v1
 
 import (
-	"easy-password-backend/config"
 	"easy-password-backend/internal/apierror"
-	"easy-password-backend/internal/crypto"
+	"easy-password-backend/internal/audit"
+	"easy-password-backend/internal/auth"
+	"easy-password-backend/internal/core"
+	"io"
 	"log/slog"
+	"runtime/debug"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // AuthMiddleware 创建一个用于 JWT 身份验证的 Gin 中间件。
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+// 用 API 密钥换取的令牌会被拒绝；接受这类令牌的路由组应使用 ScopedAuthMiddleware。
+func AuthMiddleware(authService *auth.AuthService) gin.HandlerFunc {
+	return authenticate(authService, false)
+}
+
+// ScopedAuthMiddleware 与 AuthMiddleware 相同，但也接受用 API 密钥换取的令牌。
+// 路由组中的每个路由都必须用 RequireScope 声明所需的作用域。
+func ScopedAuthMiddleware(authService *auth.AuthService) gin.HandlerFunc {
+	return authenticate(authService, true)
+}
+
+func authenticate(authService *auth.AuthService, allowAPIKeys bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -23,22 +38,80 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			handleError(c, apierror.New(401, "Authorization header format must be Bearer {token}"))
+			handleError(c, apierror.ErrInvalidAuthHeader)
 			c.Abort()
 			return
 		}
 
 		tokenString := parts[1]
-		claims, err := crypto.ValidateJWT(tokenString, cfg.JWTSecret)
+		claims, user, apiKey, err := authService.ValidateToken(c.Request.Context(), tokenString)
 		if err != nil {
-			handleError(c, apierror.ErrInvalidToken)
+			handleError(c, err)
+			c.Abort()
+			return
+		}
+		if apiKey != nil && !allowAPIKeys {
+			slog.Warn("Request rejected: API key token on session-only route", "path", c.Request.URL.Path, "api_key_id", apiKey.ID)
+			handleError(c, apierror.ErrAPIKeyNotAllowed)
 			c.Abort()
 			return
 		}
 
-		// 在上下文中设置用户 ID，以供下游处理程序使用
+		// 在上下文中设置用户 ID、设备记录 ID 和角色，以供下游处理程序使用。
+		// 角色取自存储的用户记录而不是令牌，降级立即生效。
 		c.Set("userID", claims.UserID)
+		c.Set("deviceID", claims.DeviceID)
+		c.Set("userRole", user.AccountRole())
+		if apiKey != nil {
+			c.Set("apiKey", apiKey)
+		}
+
+		c.Next()
+	}
+}
+
+// RequireScope 创建一个要求 API 密钥令牌具有指定作用域的 Gin 中间件，必须在 ScopedAuthMiddleware 之后使用。
+// 用户登录签发的令牌不受作用域限制。
+func RequireScope(scope core.APIKeyScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := apiKeyFromContext(c); key != nil && !key.HasScope(scope) {
+			slog.Warn("Request rejected: missing scope", "path", c.Request.URL.Path, "api_key_id", key.ID, "required_scope", scope)
+			handleError(c, apierror.ErrInsufficientScope)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// apiKeyFromContext 返回当前请求所用令牌对应的 API 密钥；用户登录签发的令牌返回 nil。
+func apiKeyFromContext(c *gin.Context) *core.APIKey {
+	key, _ := c.Get("apiKey")
+	apiKey, _ := key.(*core.APIKey)
+	return apiKey
+}
+
+// RequireRole 创建一个要求当前用户具有指定角色的 Gin 中间件，必须在 AuthMiddleware 之后使用。
+func RequireRole(role core.UserRole) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if current, _ := c.Get("userRole"); current != role {
+			slog.Warn("Request rejected: missing role", "path", c.Request.URL.Path, "user_id", c.Value("userID"), "required_role", role)
+			handleError(c, apierror.ErrForbidden)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
 
+// ClientInfoMiddleware 将客户端 IP 和 User-Agent 写入请求上下文，供审计记录使用。
+func ClientInfoMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := audit.WithClientInfo(c.Request.Context(), audit.ClientInfo{
+			IPAddress: c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		})
+		c.Request = c.Request.WithContext(ctx)
 		c.Next()
 	}
 }
@@ -60,6 +133,47 @@ func LoggingMiddleware() gin.HandlerFunc {
 			"status", status,
 			"latency", latency.String(),
 			"client_ip", c.ClientIP(),
+			"request_id", c.GetString(requestIDKey),
 		)
 	}
-}
\ No newline at end of file
+}
+
+// requestIDKey 是请求 ID 在 gin 上下文中的键。
+const requestIDKey = "requestID"
+
+// RequestIDMiddleware 为每个请求分配一个请求 ID，写入 X-Request-ID 响应头、
+// 错误响应和请求日志，便于用户报告的错误与服务器日志对应。
+// 反向代理已经设置的 X-Request-ID 会被沿用，只要它足够短且只含安全字符。
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader("X-Request-ID")
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		c.Set(requestIDKey, requestID)
+		c.Header("X-Request-ID", requestID)
+		c.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// RecoveryMiddleware 在处理程序 panic 时记录日志并返回与其他错误相同格式的 500 响应。
+func RecoveryMiddleware() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		slog.Error("Panic while handling request", "method", c.Request.Method, "path", c.Request.URL.Path,
+			"request_id", c.GetString(requestIDKey), "panic", recovered, "stack", string(debug.Stack()))
+		handleError(c, apierror.ErrInternalServer)
+		c.Abort()
+	})
+}
@@ -0,0 +1,455 @@
+package v1
+
+import (
+	"easy-password-backend/internal/apierror"
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/crypto"
+	"easy-password-backend/pkg/apitypes"
+	"easy-password-backend/pkg/vaultexport"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPIPath 是 OpenAPI 文档的路径，它本身不需要身份验证。
+const openAPIPath = "/api/v1/openapi.json"
+
+// access 表示路由所在路由组的身份验证方式。
+type access int
+
+const (
+	accessPublic access = iota
+	// accessUser 对应 AuthMiddleware：只接受用户登录签发的令牌。
+	accessUser
+	// accessScoped 对应 ScopedAuthMiddleware 和 RequireScope：也接受具有所需作用域的 API 密钥令牌。
+	accessScoped
+	// accessAdmin 对应 AuthMiddleware 和 RequireRole(core.UserRoleAdmin)。
+	accessAdmin
+)
+
+// binaryBody 表示响应体是二进制文件，例如 BoltDB 快照。
+type binaryBody struct{}
+
+type apiResponse struct {
+	status int
+	body   any
+}
+
+// apiRoute 描述一个路由的请求和响应，是生成 OpenAPI 文档的唯一来源。
+// 在 RegisterRoutes 中增加路由时必须同时在 apiRoutes 中登记，CheckOpenAPI 会报告遗漏。
+type apiRoute struct {
+	method  string
+	path    string
+	tag     string
+	summary string
+	access  access
+	scope   core.APIKeyScope
+	query   any
+	body    any
+	// responses 是成功响应；errors 是处理程序和服务可能返回的错误，
+	// 身份验证中间件的错误和 500 由 access 自动补充。
+	responses []apiResponse
+	errors    []*apierror.APIError
+}
+
+func ok(body any) []apiResponse { return []apiResponse{{http.StatusOK, body}} }
+
+func created(body any) []apiResponse { return []apiResponse{{http.StatusCreated, body}} }
+
+func accepted(body any) []apiResponse { return []apiResponse{{http.StatusAccepted, body}} }
+
+// loginResponses 是登录和登录批准接口的响应：成功时 200，仍在等待批准时 202。
+var loginResponses = []apiResponse{
+	{http.StatusOK, apitypes.LoginResponse{}},
+	{http.StatusAccepted, apitypes.PendingLoginResponse{}},
+}
+
+var message = apitypes.MessageResponse{}
+
+var apiRoutes = []apiRoute{
+	// 身份验证
+	{method: "POST", path: "/api/v1/auth/register", tag: "auth", summary: "Create an account with an emailed verification code",
+		body: apitypes.RegisterRequest{}, responses: created(apitypes.RegisterResponse{}),
+		errors: []*apierror.APIError{apierror.ErrInvalidRequest, apierror.ErrInvalidVerificationCode, apierror.ErrVerificationCodeExpired, apierror.ErrUserOrEmailExists}},
+	{method: "POST", path: "/api/v1/auth/login", tag: "auth", summary: "Sign in with the master key hash",
+		body: apitypes.LoginRequest{}, responses: loginResponses,
+		errors: []*apierror.APIError{apierror.ErrInvalidRequest, apierror.ErrInvalidCredentials, apierror.ErrAccountSuspended, apierror.ErrAccountLocked, apierror.ErrAccountPendingDeletion}},
+	{method: "POST", path: "/api/v1/auth/salt", tag: "auth", summary: "Look up the master salt of an account",
+		body: apitypes.SaltRequest{}, responses: ok(apitypes.SaltResponse{}),
+		errors: []*apierror.APIError{apierror.ErrInvalidRequest, apierror.ErrInvalidCredentials}},
+	{method: "POST", path: "/api/v1/auth/send-verification-code", tag: "auth", summary: "Email a registration code",
+		body: apitypes.SendVerificationCodeRequest{}, responses: ok(message),
+		errors: []*apierror.APIError{apierror.ErrInvalidRequest, apierror.ErrEmailExists}},
+	{method: "POST", path: "/api/v1/auth/request-password-reset", tag: "auth", summary: "Email a password reset link",
+		body: apitypes.RequestPasswordResetRequest{}, responses: ok(message),
+		errors: []*apierror.APIError{apierror.ErrInvalidRequest}},
+	{method: "POST", path: "/api/v1/auth/reset-password", tag: "auth", summary: "Set a new master key hash and salt with a reset token",
+		body: apitypes.ResetPasswordRequest{}, responses: ok(message),
+		errors: []*apierror.APIError{apierror.ErrInvalidRequest, apierror.ErrInvalidResetToken, apierror.ErrResetTokenExpired, apierror.ErrAccountSuspended, apierror.ErrAccountLocked, apierror.ErrAccountPendingDeletion}},
+	{method: "POST", path: "/api/v1/auth/login-approvals/approve", tag: "auth", summary: "Approve a pending login with the emailed token",
+		body: apitypes.ApproveLoginRequest{}, responses: ok(message),
+		errors: []*apierror.APIError{apierror.ErrInvalidRequest, apierror.ErrLoginApprovalNotFound, apierror.ErrLoginApprovalExpired}},
+	{method: "GET", path: "/api/v1/auth/login-approvals/:id", tag: "auth", summary: "Poll a pending login",
+		responses: loginResponses,
+		errors:    []*apierror.APIError{apierror.ErrLoginApprovalNotFound, apierror.ErrLoginApprovalExpired, apierror.ErrAccountSuspended, apierror.ErrAccountLocked, apierror.ErrAccountPendingDeletion}},
+	{method: "POST", path: "/api/v1/auth/login-approvals/:id/verify", tag: "auth", summary: "Approve a pending login with the emailed code",
+		body: apitypes.VerifyLoginApprovalRequest{}, responses: loginResponses,
+		errors: []*apierror.APIError{apierror.ErrInvalidRequest, apierror.ErrLoginApprovalNotFound, apierror.ErrLoginApprovalExpired, apierror.ErrInvalidApprovalCode, apierror.ErrTooManyAttempts}},
+	{method: "POST", path: "/api/v1/auth/api-keys/token", tag: "auth", summary: "Exchange API key credentials for an access token",
+		body: apitypes.APIKeyTokenRequest{}, responses: ok(apitypes.APIKeyTokenResponse{}),
+		errors: []*apierror.APIError{apierror.ErrInvalidRequest, apierror.ErrInvalidAPIKey, apierror.ErrAPIKeyExpired, apierror.ErrAccountSuspended, apierror.ErrAccountLocked, apierror.ErrAccountPendingDeletion}},
+	{method: "GET", path: "/.well-known/jwks.json", tag: "auth", summary: "Public keys for verifying access tokens",
+		responses: ok(crypto.JWKS{})},
+	{method: "GET", path: openAPIPath, tag: "meta", summary: "This OpenAPI document",
+		responses: ok(map[string]any{})},
+
+	// 保险库
+	{method: "POST", path: "/api/v1/vault/items", tag: "vault", summary: "Add an encrypted item",
+		access: accessScoped, scope: core.APIKeyScopeVaultWrite,
+		body: apitypes.CreateItemRequest{}, responses: created(apitypes.VaultItem{}),
+		errors: []*apierror.APIError{apierror.ErrInvalidRequest, apierror.ErrAPIKeyRestricted}},
+	{method: "GET", path: "/api/v1/vault/items", tag: "vault", summary: "List encrypted items",
+		access: accessScoped, scope: core.APIKeyScopeVaultRead,
+		responses: ok([]apitypes.VaultItem{})},
+	{method: "PUT", path: "/api/v1/vault/items/:id", tag: "vault", summary: "Replace the encrypted data of an item",
+		access: accessScoped, scope: core.APIKeyScopeVaultWrite,
+		body: apitypes.UpdateItemRequest{}, responses: ok(apitypes.VaultItem{}),
+		errors: []*apierror.APIError{apierror.ErrInvalidItemID, apierror.ErrInvalidRequest, apierror.ErrNotFound, apierror.ErrForbidden, apierror.ErrAPIKeyRestricted}},
+	{method: "DELETE", path: "/api/v1/vault/items/:id", tag: "vault", summary: "Delete an item",
+		access: accessScoped, scope: core.APIKeyScopeVaultWrite,
+		responses: ok(message),
+		errors:    []*apierror.APIError{apierror.ErrInvalidItemID, apierror.ErrNotFound, apierror.ErrForbidden, apierror.ErrAPIKeyRestricted}},
+	{method: "GET", path: "/api/v1/vault/export", tag: "vault", summary: "Download the vault, still encrypted",
+		access: accessScoped, scope: core.APIKeyScopeVaultRead,
+		responses: ok(vaultexport.Envelope{}),
+		errors:    []*apierror.APIError{apierror.ErrExportTooLarge, apierror.ErrAPIKeyRestricted}},
+	{method: "POST", path: "/api/v1/vault/import", tag: "vault", summary: "Add the items of an export to the vault",
+		access: accessScoped, scope: core.APIKeyScopeVaultWrite,
+		body: vaultexport.Envelope{}, responses: created(apitypes.ImportResponse{}),
+		errors: []*apierror.APIError{apierror.ErrInvalidImportFile, apierror.ErrImportSaltMismatch, apierror.ErrAPIKeyRestricted}},
+
+	// 账户
+	{method: "DELETE", path: "/api/v1/account", tag: "account", summary: "Delete the account and all its data except its audit events",
+		access: accessUser, body: deleteAccountRequest{}, responses: ok(message),
+		errors: []*apierror.APIError{apierror.ErrInvalidRequest, apierror.ErrInvalidCredentials, apierror.ErrNotFound}},
+	{method: "GET", path: "/api/v1/account/events", tag: "account", summary: "List the account's audit events",
+		access: accessUser, query: listEventsQuery{}, responses: ok(listEventsResponse{}),
+		errors: []*apierror.APIError{apierror.ErrInvalidRequest}},
+	{method: "PUT", path: "/api/v1/account/login-approval", tag: "account", summary: "Require email approval for logins from new devices",
+		access: accessUser, body: setLoginApprovalRequest{}, responses: ok(loginApprovalResponse{}),
+		errors: []*apierror.APIError{apierror.ErrInvalidRequest, apierror.ErrNotFound}},
+	{method: "POST", path: "/api/v1/account/email", tag: "account", summary: "Send a code to confirm a new email address",
+		access: accessUser, body: requestEmailChangeRequest{}, responses: ok(message),
+		errors: []*apierror.APIError{apierror.ErrInvalidRequest, apierror.ErrInvalidCredentials, apierror.ErrEmailExists, apierror.ErrNotFound}},
+	{method: "POST", path: "/api/v1/account/email/confirm", tag: "account", summary: "Change the email address with the code",
+		access: accessUser, body: confirmEmailChangeRequest{}, responses: ok(message),
+		errors: []*apierror.APIError{apierror.ErrInvalidRequest, apierror.ErrInvalidVerificationCode, apierror.ErrVerificationCodeExpired, apierror.ErrEmailExists, apierror.ErrNotFound}},
+	{method: "POST", path: "/api/v1/account/username", tag: "account", summary: "Change the username",
+		access: accessUser, body: changeUsernameRequest{}, responses: ok(message),
+		errors: []*apierror.APIError{apierror.ErrInvalidRequest, apierror.ErrInvalidCredentials, apierror.ErrUsernameExists, apierror.ErrNotFound}},
+	{method: "GET", path: "/api/v1/devices", tag: "devices", summary: "List devices that have signed in",
+		access: accessUser, responses: ok([]deviceResponse{})},
+	{method: "DELETE", path: "/api/v1/devices/:id", tag: "devices", summary: "Revoke a device and its tokens",
+		access: accessUser, responses: ok(message),
+		errors: []*apierror.APIError{apierror.ErrInvalidDeviceID, apierror.ErrNotFound}},
+	{method: "GET", path: "/api/v1/account/api-keys", tag: "api-keys", summary: "List API keys",
+		access: accessUser, responses: ok([]apiKeyResponse{})},
+	{method: "POST", path: "/api/v1/account/api-keys", tag: "api-keys", summary: "Create an API key; the secret is returned only once",
+		access: accessUser, body: createAPIKeyRequest{}, responses: created(createAPIKeyResponse{}),
+		errors: []*apierror.APIError{apierror.ErrInvalidRequest, apierror.ErrInvalidAPIKeyScope, apierror.ErrInvalidAPIKeyExpiry}},
+	{method: "DELETE", path: "/api/v1/account/api-keys/:id", tag: "api-keys", summary: "Revoke an API key",
+		access: accessUser, responses: ok(message),
+		errors: []*apierror.APIError{apierror.ErrInvalidAPIKeyID, apierror.ErrAPIKeyNotFound}},
+
+	// 管理员
+	{method: "GET", path: "/api/v1/admin/health", tag: "admin", summary: "Server and database health; 503 when the database is unreachable",
+		access: accessAdmin, responses: []apiResponse{{http.StatusOK, healthResponse{}}, {http.StatusServiceUnavailable, healthResponse{}}}},
+	{method: "GET", path: "/api/v1/admin/stats", tag: "admin", summary: "Record counts, storage size and encryption status",
+		access: accessAdmin, responses: ok(statsResponse{})},
+	{method: "GET", path: "/api/v1/admin/backup", tag: "admin", summary: "Download a consistent BoltDB snapshot",
+		access: accessAdmin, responses: ok(binaryBody{}),
+		errors: []*apierror.APIError{apierror.ErrBackupNotSupported}},
+	{method: "POST", path: "/api/v1/admin/encryption/rotate", tag: "admin", summary: "Rotate the at-rest data key and re-encrypt in the background",
+		access: accessAdmin, responses: accepted(encryptionStatusResponse{}),
+		errors: []*apierror.APIError{apierror.ErrEncryptionNotEnabled}},
+	{method: "GET", path: "/api/v1/admin/users", tag: "admin", summary: "Search users",
+		access: accessAdmin, query: listUsersQuery{}, responses: ok(listUsersResponse{}),
+		errors: []*apierror.APIError{apierror.ErrInvalidRequest}},
+	{method: "GET", path: "/api/v1/admin/users/:id", tag: "admin", summary: "Show a user",
+		access: accessAdmin, responses: ok(adminUserResponse{}),
+		errors: []*apierror.APIError{apierror.ErrNotFound}},
+	{method: "PUT", path: "/api/v1/admin/users/:id/status", tag: "admin", summary: "Set a user's account status",
+		access: accessAdmin, body: setUserStatusRequest{}, responses: ok(adminUserResponse{}),
+		errors: []*apierror.APIError{apierror.ErrNotFound, apierror.ErrInvalidRequest, apierror.ErrInvalidUserStatus}},
+	{method: "POST", path: "/api/v1/admin/users/:id/password-reset", tag: "admin", summary: "Email a user a password reset link",
+		access: accessAdmin, responses: accepted(message),
+		errors: []*apierror.APIError{apierror.ErrNotFound, apierror.ErrPasswordResetInactive}},
+}
+
+// accessErrors 是各身份验证方式下中间件可能返回的错误。
+var accessErrors = map[access][]*apierror.APIError{
+	accessUser: {apierror.ErrUnauthorized, apierror.ErrInvalidAuthHeader, apierror.ErrInvalidToken,
+		apierror.ErrAccountSuspended, apierror.ErrAccountLocked, apierror.ErrAccountPendingDeletion, apierror.ErrAPIKeyNotAllowed},
+	accessScoped: {apierror.ErrUnauthorized, apierror.ErrInvalidAuthHeader, apierror.ErrInvalidToken,
+		apierror.ErrAccountSuspended, apierror.ErrAccountLocked, apierror.ErrAccountPendingDeletion, apierror.ErrInsufficientScope},
+	accessAdmin: {apierror.ErrUnauthorized, apierror.ErrInvalidAuthHeader, apierror.ErrInvalidToken,
+		apierror.ErrAccountSuspended, apierror.ErrAccountLocked, apierror.ErrAccountPendingDeletion, apierror.ErrAPIKeyNotAllowed, apierror.ErrForbidden},
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Tags       []openAPITag                            `json:"tags"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+type openAPITag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags"`
+	Security    []map[string][]string       `json:"security,omitempty"`
+	Parameters  []parameter                 `json:"parameters,omitempty"`
+	RequestBody *requestBody                `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Schema   *schema            `json:"schema"`
+	Examples map[string]example `json:"examples,omitempty"`
+}
+
+type example struct {
+	Summary string `json:"summary,omitempty"`
+	Value   any    `json:"value"`
+}
+
+type openAPIResponse struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*schema        `json:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat"`
+	Description  string `json:"description"`
+}
+
+var pathParamPattern = regexp.MustCompile(`:([A-Za-z_]+)`)
+
+// buildOpenAPI 由 apiRoutes 和请求、响应类型的反射生成 OpenAPI 3.0 文档。
+func buildOpenAPI() *openAPIDocument {
+	registry := newSchemaRegistry()
+	errorSchema := registry.ref(apitypes.ErrorResponse{})
+	codes := []string{apierror.ErrRouteNotFound.Code, apierror.ErrMethodNotAllowed.Code}
+	doc := &openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:   "EasyPassword API",
+			Version: "1",
+			Description: "Vault items are encrypted by the client with a key derived from the master password " +
+				"(PBKDF2-SHA256, 100000 iterations, AES-256-GCM); the server only stores ciphertexts and the key hash. " +
+				"Every error response has the ErrorResponse body; branch on its code, not on the English message. " +
+				"Unknown paths return 404 ROUTE_NOT_FOUND and unsupported methods 405 METHOD_NOT_ALLOWED in the same format.",
+		},
+		Tags: []openAPITag{
+			{"auth", "Registration, login and token exchange"},
+			{"vault", "Encrypted vault items; also open to API key tokens with the listed scope"},
+			{"account", "The signed-in account"},
+			{"devices", "Devices that have signed in to the account"},
+			{"api-keys", "API keys for programmatic vault access"},
+			{"admin", "Administration; requires the admin role"},
+			{"meta", "The API itself"},
+		},
+		Paths: map[string]map[string]*openAPIOperation{},
+		Components: openAPIComponents{
+			SecuritySchemes: map[string]securityScheme{
+				"bearerAuth": {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT",
+					Description:  "A token from /api/v1/auth/login, or from /api/v1/auth/api-keys/token on vault routes.",
+				},
+			},
+		},
+	}
+
+	for _, route := range apiRoutes {
+		path := pathParamPattern.ReplaceAllString(route.path, "{$1}")
+		op := &openAPIOperation{
+			OperationID: operationID(route),
+			Summary:     route.summary,
+			Tags:        []string{route.tag},
+			Responses:   map[string]*openAPIResponse{},
+		}
+		switch route.access {
+		case accessUser:
+			op.Description = "Requires a user token; API key tokens are rejected."
+		case accessScoped:
+			op.Description = fmt.Sprintf("Accepts user tokens and API key tokens with the %s scope.", route.scope)
+		case accessAdmin:
+			op.Description = "Requires a user token of an account with the admin role."
+		}
+		if route.access != accessPublic {
+			op.Security = []map[string][]string{{"bearerAuth": {}}}
+		}
+
+		for _, match := range pathParamPattern.FindAllStringSubmatch(route.path, -1) {
+			op.Parameters = append(op.Parameters, parameter{Name: match[1], In: "path", Required: true, Schema: &schema{Type: "string", Format: "uuid"}})
+		}
+		if route.query != nil {
+			op.Parameters = append(op.Parameters, registry.queryParameters(route.query)...)
+		}
+		if route.body != nil {
+			op.RequestBody = &requestBody{Required: true, Content: map[string]mediaType{"application/json": {Schema: registry.ref(route.body)}}}
+		}
+
+		for _, resp := range route.responses {
+			r := &openAPIResponse{Description: http.StatusText(resp.status)}
+			if _, binary := resp.body.(binaryBody); binary {
+				r.Content = map[string]mediaType{"application/octet-stream": {Schema: &schema{Type: "string", Format: "binary"}}}
+			} else {
+				r.Content = map[string]mediaType{"application/json": {Schema: registry.ref(resp.body)}}
+			}
+			op.Responses[strconv.Itoa(resp.status)] = r
+		}
+
+		// 同一状态码的错误合并为一个响应，每个错误作为一个示例。
+		errs := slices.Concat(route.errors, accessErrors[route.access], []*apierror.APIError{apierror.ErrInternalServer})
+		for _, apiErr := range errs {
+			codes = append(codes, apiErr.Code)
+			status := strconv.Itoa(apiErr.Status)
+			r, exists := op.Responses[status]
+			if !exists {
+				r = &openAPIResponse{
+					Description: http.StatusText(apiErr.Status),
+					Content:     map[string]mediaType{"application/json": {Schema: errorSchema, Examples: map[string]example{}}},
+				}
+				op.Responses[status] = r
+			}
+			media, isError := r.Content["application/json"]
+			if !isError || media.Examples == nil {
+				continue
+			}
+			value := apitypes.ErrorResponse{Error: apiErr.Message, Code: apiErr.Code}
+			if apiErr == apierror.ErrInvalidRequest {
+				value.Details = map[string]string{"email": "required"}
+			}
+			media.Examples[apiErr.Code] = example{Summary: apiErr.Message, Value: value}
+		}
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*openAPIOperation{}
+		}
+		doc.Paths[path][strings.ToLower(route.method)] = op
+	}
+	slices.Sort(codes)
+	registry.schemas["ErrorResponse"].Properties["code"].Enum = slices.Compact(codes)
+	registry.schemas["ErrorResponse"].Properties["details"].Description = "Request fields and the validation rule each failed."
+	doc.Components.Schemas = registry.schemas
+	return doc
+}
+
+// operationID 由方法和路径生成，例如 PUT /api/v1/vault/items/:id 生成 putVaultItemsById。
+func operationID(route apiRoute) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(route.method))
+	path := strings.TrimPrefix(strings.TrimPrefix(route.path, "/api/v1"), "/.well-known")
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == '.' }) {
+		if name, isParam := strings.CutPrefix(part, ":"); isParam {
+			b.WriteString("By")
+			part = name
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// OpenAPIDocument 返回 JSON 编码的 OpenAPI 文档。
+var OpenAPIDocument = sync.OnceValues(func() ([]byte, error) {
+	return json.MarshalIndent(buildOpenAPI(), "", "  ")
+})
+
+func serveOpenAPI(c *gin.Context) {
+	data, err := OpenAPIDocument()
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
+
+// CheckOpenAPI 比较已注册的路由和 OpenAPI 文档中的路由，报告没有写进文档的路由，
+// 以及文档中有但没有注册的路由。routes 通常是调用 RegisterRoutes 之后的 router.Routes()。
+func CheckOpenAPI(routes gin.RoutesInfo) error {
+	documented := map[string]bool{}
+	for _, route := range apiRoutes {
+		documented[route.method+" "+route.path] = true
+	}
+
+	var missing, stale []string
+	registered := map[string]bool{}
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		registered[key] = true
+		if !documented[key] {
+			missing = append(missing, key)
+		}
+	}
+	for key := range documented {
+		if !registered[key] {
+			stale = append(stale, key)
+		}
+	}
+	slices.Sort(missing)
+	slices.Sort(stale)
+
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, "routes missing from the OpenAPI document: "+strings.Join(missing, ", "))
+	}
+	if len(stale) > 0 {
+		problems = append(problems, "documented routes that are not registered: "+strings.Join(stale, ", "))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package v1
+
+import (
+	"easy-password-backend/internal/core"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// schema 是 OpenAPI 3.0 Schema Object 中本项目用到的部分。
+type schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Maximum              *int               `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *schema            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	uuidType       = reflect.TypeFor[uuid.UUID]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+)
+
+// enumValues 列出以字符串类型定义的枚举的取值，生成的 schema 中带有 enum。
+var enumValues = map[reflect.Type][]string{
+	reflect.TypeFor[core.APIKeyScope](): {string(core.APIKeyScopeVaultRead), string(core.APIKeyScopeVaultWrite)},
+	reflect.TypeFor[core.UserStatus]():  {string(core.UserStatusActive), string(core.UserStatusSuspended), string(core.UserStatusLocked), string(core.UserStatusPendingDeletion)},
+	reflect.TypeFor[core.UserRole]():    {string(core.UserRoleUser), string(core.UserRoleAdmin)},
+}
+
+// schemaRegistry 把 Go 结构体类型转换为 components/schemas 中的命名 schema。
+type schemaRegistry struct {
+	schemas map[string]*schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{schemas: map[string]*schema{}, names: map[reflect.Type]string{}}
+}
+
+// ref 返回 v 的类型的 schema；结构体注册为命名 schema 并返回对它的引用。
+func (r *schemaRegistry) ref(v any) *schema {
+	return r.schemaFor(reflect.TypeOf(v))
+}
+
+func (r *schemaRegistry) schemaFor(t reflect.Type) *schema {
+	switch t {
+	case timeType:
+		return &schema{Type: "string", Format: "date-time"}
+	case uuidType:
+		return &schema{Type: "string", Format: "uuid"}
+	case rawMessageType:
+		return &schema{Description: "Any JSON value."}
+	}
+	if values, ok := enumValues[t]; ok {
+		return &schema{Type: "string", Enum: values}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := r.schemaFor(t.Elem())
+		// OpenAPI 3.0 忽略 $ref 旁边的其他属性，指向结构体的指针不标记 nullable。
+		if s.Ref == "" {
+			s.Nullable = true
+		}
+		return s
+	case reflect.String:
+		return &schema{Type: "string"}
+	case reflect.Bool:
+		return &schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s := &schema{Type: "integer"}
+		if t.Size() == 8 {
+			s.Format = "int64"
+		} else {
+			s.Format = "int32"
+		}
+		return s
+	case reflect.Float32, reflect.Float64:
+		return &schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &schema{Type: "string", Format: "byte"}
+		}
+		return &schema{Type: "array", Items: r.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &schema{Type: "object", AdditionalProperties: r.schemaFor(t.Elem())}
+	case reflect.Interface:
+		return &schema{}
+	case reflect.Struct:
+		return &schema{Ref: "#/components/schemas/" + r.register(t)}
+	}
+	panic(fmt.Sprintf("openapi: unsupported type %s", t))
+}
+
+// register 注册结构体 t 并返回 schema 名称。名称取自 Go 类型名，首字母大写；
+// 不同包中的同名类型加上包名前缀。
+func (r *schemaRegistry) register(t reflect.Type) string {
+	if name, ok := r.names[t]; ok {
+		return name
+	}
+	name := exportedName(t.Name())
+	if _, taken := r.schemas[name]; taken {
+		name = exportedName(t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]) + name
+	}
+	r.names[t] = name
+
+	s := &schema{Type: "object", Properties: map[string]*schema{}}
+	r.schemas[name] = s
+	r.addFields(s, t)
+	return name
+}
+
+// addFields 按 encoding/json 的规则把 t 的字段加入 s：匿名嵌入的结构体字段被展开。
+func (r *schemaRegistry) addFields(s *schema, t reflect.Type) {
+	for field := range fieldsOf(t) {
+		if field.Anonymous && field.Tag.Get("json") == "" && field.Type.Kind() == reflect.Struct {
+			r.addFields(s, field.Type)
+			continue
+		}
+		name, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+		fs := r.schemaFor(field.Type)
+		if required := applyBinding(fs, field.Tag.Get("binding")); required {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = fs
+	}
+}
+
+// queryParameters 把带 form 标签的查询结构体转换为查询参数。
+func (r *schemaRegistry) queryParameters(v any) []parameter {
+	var params []parameter
+	for field := range fieldsOf(reflect.TypeOf(v)) {
+		name := strings.Split(field.Tag.Get("form"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		s := r.schemaFor(field.Type)
+		required := applyBinding(s, field.Tag.Get("binding"))
+		params = append(params, parameter{Name: name, In: "query", Required: required, Schema: s})
+	}
+	return params
+}
+
+func fieldsOf(t reflect.Type) func(yield func(reflect.StructField) bool) {
+	return func(yield func(reflect.StructField) bool) {
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if !field.IsExported() && !field.Anonymous {
+				continue
+			}
+			if !yield(field) {
+				return
+			}
+		}
+	}
+}
+
+// jsonName 返回字段在 JSON 中的名称；被 "-" 忽略的字段 ok 为 false。
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" || !field.IsExported() {
+		return "", false
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, true
+	}
+	return field.Name, true
+}
+
+// applyBinding 把 gin 的 binding 校验规则转换为 schema 约束，返回字段是否必填。
+func applyBinding(s *schema, binding string) bool {
+	required := false
+	for rule := range strings.SplitSeq(binding, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		n, err := strconv.Atoi(value)
+		hasNumber := err == nil
+		switch {
+		case key == "required":
+			required = true
+		case key == "email":
+			s.Format = "email"
+		case key == "excludes":
+			s.Pattern = "^[^" + value + "]*$"
+		case key == "len" && hasNumber:
+			s.MinLength, s.MaxLength = &n, &n
+		case key == "min" && hasNumber:
+			switch s.Type {
+			case "string":
+				s.MinLength = &n
+			case "array":
+				s.MinItems = &n
+			default:
+				s.Minimum = &n
+			}
+		case key == "max" && hasNumber:
+			if s.Type == "string" {
+				s.MaxLength = &n
+			} else {
+				s.Maximum = &n
+			}
+		}
+	}
+	return required
+}
+
+func exportedName(name string) string {
+	if name == "" {
+		return name
+	}
+	runes := []rune(name)
+	// apiKeyResponse 这样以缩写开头的名称转换为 APIKeyResponse。
+	for _, initialism := range []string{"api"} {
+		if strings.HasPrefix(name, initialism) && len(name) > len(initialism) && unicode.IsUpper(runes[len(initialism)]) {
+			return strings.ToUpper(initialism) + name[len(initialism):]
+		}
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
@@ -0,0 +1,43 @@
+package v1
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestOpenAPICoversRoutes 确认每个注册的路由都写进了 OpenAPI 文档，文档中也没有已删除的路由。
+func TestOpenAPICoversRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// 只需要路由表，处理程序不会被调用，所以服务可以为空。
+	RegisterRoutes(router, Services{})
+	if err := CheckOpenAPI(router.Routes()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	data, err := OpenAPIDocument()
+	if err != nil {
+		t.Fatalf("build document: %v", err)
+	}
+	var doc struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("document is not valid JSON: %v", err)
+	}
+	if doc.OpenAPI == "" {
+		t.Error("document has no openapi version")
+	}
+	operations := 0
+	for _, methods := range doc.Paths {
+		operations += len(methods)
+	}
+	if operations != len(apiRoutes) {
+		t.Errorf("document has %d operations, want one for each of the %d routes", operations, len(apiRoutes))
+	}
+}
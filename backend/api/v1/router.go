@@ -0,0 +1,61 @@
+package v1
+
+import (
+	"easy-password-backend/internal/apierror"
+	"easy-password-backend/internal/audit"
+	"easy-password-backend/internal/auth"
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Services 汇集 API 处理程序所需的服务。
+type Services struct {
+	Auth   *auth.AuthService
+	Vault  *service.VaultService
+	Device *service.DeviceService
+	Admin  *service.AdminService
+	Audit  *audit.AuditService
+}
+
+// RegisterRoutes 在 router 上注册全部 API 路由及其身份验证中间件。
+// 日志和客户端信息等全局中间件由调用方添加。新增路由需要同时登记到 openapi.go 的 apiRoutes 中。
+func RegisterRoutes(router *gin.Engine, s Services) {
+	// gin 自身返回的 404 和 405 也使用统一的错误响应格式
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(func(c *gin.Context) { handleError(c, apierror.ErrRouteNotFound) })
+	router.NoMethod(func(c *gin.Context) { handleError(c, apierror.ErrMethodNotAllowed) })
+
+	authHandler := NewAuthHandler(s.Auth)
+	authHandler.RegisterRoutes(router)
+	router.GET(openAPIPath, serveOpenAPI)
+
+	// 保险库路由同时接受用 API 密钥换取的令牌，各路由自行声明所需的作用域
+	vaultAPI := router.Group("/api/v1")
+	vaultAPI.Use(ScopedAuthMiddleware(s.Auth))
+	{
+		vaultHandler := NewVaultHandler(s.Vault)
+		vaultHandler.RegisterRoutes(vaultAPI)
+	}
+
+	// 其他受保护的路由只接受用户登录签发的令牌
+	accountAPI := router.Group("/api/v1")
+	accountAPI.Use(AuthMiddleware(s.Auth))
+	{
+		accountHandler := NewAccountHandler(s.Auth, s.Audit)
+		accountHandler.RegisterRoutes(accountAPI)
+		deviceHandler := NewDeviceHandler(s.Device)
+		deviceHandler.RegisterRoutes(accountAPI)
+		apiKeyHandler := NewAPIKeyHandler(s.Auth)
+		apiKeyHandler.RegisterRoutes(accountAPI)
+	}
+
+	// 管理员路由，要求 admin 角色
+	adminAPI := router.Group("/api/v1/admin")
+	adminAPI.Use(AuthMiddleware(s.Auth), RequireRole(core.UserRoleAdmin))
+	{
+		adminHandler := NewAdminHandler(s.Admin, s.Auth)
+		adminHandler.RegisterRoutes(adminAPI)
+	}
+}
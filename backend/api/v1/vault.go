@@ -4,8 +4,13 @@ import (
 	"easy-password-backend/internal/apierror"
 	"easy-password-backend/internal/core"
 	"easy-password-backend/internal/service"
-	"encoding/json"
+	"easy-password-backend/pkg/apitypes"
+	"easy-password-backend/pkg/vaultexport"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"slices"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -21,31 +26,28 @@ func NewVaultHandler(vaultService *service.VaultService) *VaultHandler {
 	return &VaultHandler{vaultService: vaultService}
 }
 
-// RegisterRoutes 注册保险库路由。
+// RegisterRoutes 注册保险库路由。每个路由都声明了 API 密钥令牌访问它所需的作用域。
 func (h *VaultHandler) RegisterRoutes(router *gin.RouterGroup) {
+	read := RequireScope(core.APIKeyScopeVaultRead)
+	write := RequireScope(core.APIKeyScopeVaultWrite)
 	vault := router.Group("/vault")
 	{
-		vault.POST("/items", h.createItem)
-		vault.GET("/items", h.getItems)
-		vault.PUT("/items/:id", h.updateItem)
-		vault.DELETE("/items/:id", h.deleteItem)
+		vault.POST("/items", write, h.createItem)
+		vault.GET("/items", read, h.getItems)
+		vault.PUT("/items/:id", write, h.updateItem)
+		vault.DELETE("/items/:id", write, h.deleteItem)
+		vault.GET("/export", read, h.exportVault)
+		vault.POST("/import", write, h.importVault)
 	}
 }
 
-type createItemRequest struct {
-	EncryptedData json.RawMessage `json:"encrypted_data" binding:"required"`
-	Category      string          `json:"category"`
-}
-
-type updateItemRequest struct {
-	EncryptedData json.RawMessage `json:"encrypted_data" binding:"required"`
-	Category      *string         `json:"category"`
-}
+// maxImportSize 限制导入请求体的大小。
+const maxImportSize = 32 << 20
 
 func (h *VaultHandler) createItem(c *gin.Context) {
-	var req createItemRequest
+	var req apitypes.CreateItemRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		handleError(c, apierror.ErrInvalidRequest)
+		handleError(c, invalidRequest(err))
 		return
 	}
 
@@ -55,6 +57,11 @@ func (h *VaultHandler) createItem(c *gin.Context) {
 		return
 	}
 
+	if key := apiKeyFromContext(c); key != nil && !key.AllowsCategory(req.Category) {
+		handleError(c, apierror.ErrAPIKeyRestricted)
+		return
+	}
+
 	newItem := &core.VaultItem{
 		UserID:        userID.(uuid.UUID),
 		EncryptedData: req.EncryptedData,
@@ -67,7 +74,7 @@ func (h *VaultHandler) createItem(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, createdItem)
+	c.JSON(http.StatusCreated, toVaultItem(createdItem))
 }
 
 func (h *VaultHandler) getItems(c *gin.Context) {
@@ -82,21 +89,30 @@ func (h *VaultHandler) getItems(c *gin.Context) {
 		handleError(c, err)
 		return
 	}
+	if key := apiKeyFromContext(c); key != nil && key.Restricted() {
+		items = slices.DeleteFunc(items, func(item core.VaultItem) bool {
+			return !key.AllowsItem(&item)
+		})
+	}
 
-	c.JSON(http.StatusOK, items)
+	response := make([]apitypes.VaultItem, len(items))
+	for i := range items {
+		response[i] = toVaultItem(&items[i])
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 func (h *VaultHandler) updateItem(c *gin.Context) {
 	idParam := c.Param("id")
 	itemID, err := uuid.Parse(idParam)
 	if err != nil {
-		handleError(c, apierror.New(http.StatusBadRequest, "Invalid item ID"))
+		handleError(c, apierror.ErrInvalidItemID)
 		return
 	}
 
-	var req updateItemRequest
+	var req apitypes.UpdateItemRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		handleError(c, apierror.ErrInvalidRequest)
+		handleError(c, invalidRequest(err))
 		return
 	}
 
@@ -113,6 +129,10 @@ func (h *VaultHandler) updateItem(c *gin.Context) {
 	if req.Category != nil {
 		itemToUpdate.Category = *req.Category
 	}
+	if err := h.checkItemAccess(c, itemID, userID.(uuid.UUID), req.Category); err != nil {
+		handleError(c, err)
+		return
+	}
 
 	updatedItem, err := h.vaultService.UpdateVaultItem(c.Request.Context(), itemToUpdate, userID.(uuid.UUID))
 	if err != nil {
@@ -120,14 +140,14 @@ func (h *VaultHandler) updateItem(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, updatedItem)
+	c.JSON(http.StatusOK, toVaultItem(updatedItem))
 }
 
 func (h *VaultHandler) deleteItem(c *gin.Context) {
 	idParam := c.Param("id")
 	itemID, err := uuid.Parse(idParam)
 	if err != nil {
-		handleError(c, apierror.New(http.StatusBadRequest, "Invalid item ID"))
+		handleError(c, apierror.ErrInvalidItemID)
 		return
 	}
 
@@ -137,11 +157,122 @@ func (h *VaultHandler) deleteItem(c *gin.Context) {
 		return
 	}
 
+	if err := h.checkItemAccess(c, itemID, userID.(uuid.UUID), nil); err != nil {
+		handleError(c, err)
+		return
+	}
+
 	err = h.vaultService.DeleteVaultItem(c.Request.Context(), itemID, userID.(uuid.UUID))
 	if err != nil {
 		handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Item deleted successfully"})
-}
\ No newline at end of file
+	c.JSON(http.StatusOK, apitypes.MessageResponse{Message: "Item deleted successfully"})
+}
+
+func (h *VaultHandler) exportVault(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		handleError(c, apierror.ErrUnauthorized)
+		return
+	}
+
+	// 导出和导入涉及整个保险库，限制了项目的 API 密钥不能使用。
+	if key := apiKeyFromContext(c); key != nil && key.Restricted() {
+		handleError(c, apierror.ErrAPIKeyRestricted)
+		return
+	}
+
+	w := &attachmentWriter{c: c, filename: fmt.Sprintf("easypassword-export-%s.json", time.Now().Format("20060102"))}
+	if err := h.vaultService.ExportVault(c.Request.Context(), userID.(uuid.UUID), w); err != nil {
+		// 响应头可能已经发送，此时只能中止连接。
+		if !c.Writer.Written() {
+			handleError(c, err)
+			return
+		}
+		slog.Error("Vault export aborted", "user_id", userID, "error", err)
+		c.Abort()
+	}
+}
+
+// attachmentWriter 在第一次写入时才设置下载文件的响应头。导出在写出任何内容之前失败时，
+// 错误响应是普通的 JSON 错误，不带 Content-Disposition。
+type attachmentWriter struct {
+	c        *gin.Context
+	filename string
+	started  bool
+}
+
+func (w *attachmentWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.c.Header("Content-Type", "application/json")
+		w.c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, w.filename))
+		w.c.Status(http.StatusOK)
+	}
+	return w.c.Writer.Write(p)
+}
+
+func (h *VaultHandler) importVault(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		handleError(c, apierror.ErrUnauthorized)
+		return
+	}
+
+	if key := apiKeyFromContext(c); key != nil && key.Restricted() {
+		handleError(c, apierror.ErrAPIKeyRestricted)
+		return
+	}
+
+	env, err := vaultexport.Decode(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize))
+	if err != nil {
+		handleError(c, apierror.ErrInvalidImportFile.WithDetails(map[string]string{"reason": err.Error()}))
+		return
+	}
+
+	count, err := h.vaultService.ImportVault(c.Request.Context(), userID.(uuid.UUID), env)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, apitypes.ImportResponse{Message: "Vault imported successfully", Imported: count})
+}
+
+// checkItemAccess 确认当前请求的 API 密钥可以修改或删除项目 itemID；category 不为 nil 时，
+// 项目移入的新类别也必须被允许。用户登录签发的令牌不受限制。
+func (h *VaultHandler) checkItemAccess(c *gin.Context, itemID, userID uuid.UUID, category *string) error {
+	key := apiKeyFromContext(c)
+	if key == nil || !key.Restricted() {
+		return nil
+	}
+	item, err := h.vaultService.GetVaultItemByID(c.Request.Context(), itemID, userID)
+	if err != nil {
+		return err
+	}
+	if !key.AllowsItem(item) {
+		return apierror.ErrAPIKeyRestricted
+	}
+	if category != nil {
+		moved := *item
+		moved.Category = *category
+		if !key.AllowsItem(&moved) {
+			return apierror.ErrAPIKeyRestricted
+		}
+	}
+	return nil
+}
+
+// toVaultItem 将存储中的项目转换为响应体。
+func toVaultItem(item *core.VaultItem) apitypes.VaultItem {
+	return apitypes.VaultItem{
+		ID:            item.ID,
+		UserID:        item.UserID,
+		EncryptedData: item.EncryptedData,
+		Category:      item.Category,
+		CreatedAt:     item.CreatedAt,
+		UpdatedAt:     item.UpdatedAt,
+	}
+}
@@ -0,0 +1,47 @@
+package v1
+
+import (
+	"context"
+	"easy-password-backend/internal/apierror"
+	"easy-password-backend/internal/core"
+	"easy-password-backend/pkg/vaultexport"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestExportVault 检查导出只在写出内容时才带下载响应头，以及项目过多时在写出任何内容之前拒绝导出。
+func TestExportVault(t *testing.T) {
+	env := newAdminTestEnv(t)
+	addItems := func(n int) {
+		t.Helper()
+		for range n {
+			item := &core.VaultItem{UserID: env.user.ID, EncryptedData: json.RawMessage(`"data"`), Category: "login", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+			if err := env.storage.Vault().Create(context.Background(), item); err != nil {
+				t.Fatalf("create item: %v", err)
+			}
+		}
+	}
+
+	addItems(1)
+	w := env.do(t, http.MethodGet, "/api/v1/vault/export", env.userTok, "")
+	expectStatus(t, w, http.StatusOK, nil)
+	if w.Header().Get("Content-Disposition") == "" {
+		t.Fatal("export has no Content-Disposition header")
+	}
+	var exported vaultexport.Envelope
+	if err := json.Unmarshal(w.Body.Bytes(), &exported); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	if err := exported.Validate(); err != nil || len(exported.Items) != 1 {
+		t.Fatalf("export has %d items, Validate() = %v; want 1 valid item", len(exported.Items), err)
+	}
+
+	addItems(vaultexport.MaxItems)
+	w = env.do(t, http.MethodGet, "/api/v1/vault/export", env.userTok, "")
+	expectStatus(t, w, http.StatusUnprocessableEntity, apierror.ErrExportTooLarge)
+	if cd := w.Header().Get("Content-Disposition"); cd != "" {
+		t.Fatalf("error response has Content-Disposition %q", cd)
+	}
+}
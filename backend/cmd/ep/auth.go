@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"easy-password-backend/pkg/client"
+	"easy-password-backend/pkg/vaultcrypto"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const defaultServer = "http://localhost:8080"
+
+const loginUsage = `Usage: ep login [-server URL] [-password-file FILE] <email|username>
+
+Signs in, saves the session and prints the line that exports EP_SESSION.
+The first login from this machine may need approval by email.
+`
+
+const unlockUsage = `Usage: ep unlock [-password-file FILE]
+
+Unlocks the saved session with the master password and prints the line that exports EP_SESSION.
+`
+
+// runLogin 与扩展相同地派生主密钥：先取盐，再用 PBKDF2 派生，服务端只收到密钥的哈希。
+func (c *cli) runLogin(args []string) error {
+	fs := c.flagSet("login", loginUsage)
+	server := fs.String("server", c.serverDefault(), "server base URL")
+	passwordFile := fs.String("password-file", "", "read the master password from this file")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		fs.Usage()
+		return errors.New("login needs exactly one email or username")
+	}
+	identifier := rest[0]
+
+	// 同一账号重新登录时沿用设备 ID，避免每次登录都被当作新设备要求批准。
+	deviceID := uuid.NewString()
+	if old, err := c.loadSession(); err == nil && old.Server == *server && old.Identifier == identifier && old.DeviceID != "" {
+		deviceID = old.DeviceID
+	}
+
+	password, err := c.readMasterPassword(*passwordFile)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	api := c.newClient(*server)
+	salt, err := api.Salt(ctx, identifier)
+	if err != nil {
+		return err
+	}
+	key, err := vaultcrypto.DeriveKey(password, salt)
+	if err != nil {
+		return err
+	}
+	if err := api.Login(ctx, identifier, key.AuthHash(), epDevice(deviceID)); err != nil {
+		return err
+	}
+
+	s := &session{
+		Server:     *server,
+		Identifier: identifier,
+		MasterSalt: salt,
+		DeviceID:   deviceID,
+	}
+	if s.Token, err = key.Encrypt([]byte(api.Token())); err != nil {
+		return err
+	}
+	sessionKey, err := c.unlockSession(s, key)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "Logged in to %s as %s.\n", s.Server, s.Identifier)
+	c.printExport(sessionKey)
+	return nil
+}
+
+func (c *cli) runUnlock(args []string) error {
+	fs := c.flagSet("unlock", unlockUsage)
+	passwordFile := fs.String("password-file", "", "read the master password from this file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	s, err := c.loadSession()
+	if err != nil {
+		return err
+	}
+	key, err := c.keyFromPassword(s, *passwordFile)
+	if err != nil {
+		return err
+	}
+	sessionKey, err := c.unlockSession(s, key)
+	if err != nil {
+		return err
+	}
+	c.printExport(sessionKey)
+	return nil
+}
+
+func (c *cli) runLock(args []string) error {
+	fs := c.flagSet("lock", "Usage: ep lock\n\nForgets the unlocked session key; later commands ask for the master password.\n")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	s, err := c.loadSession()
+	if err != nil {
+		return err
+	}
+	s.Unlocked = ""
+	if err := c.saveSession(s); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stderr, "Session locked. Run: unset EP_SESSION")
+	return nil
+}
+
+func (c *cli) runLogout(args []string) error {
+	fs := c.flagSet("logout", "Usage: ep logout\n\nDeletes the saved session.\n")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := c.removeSession(); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stderr, "Logged out. Run: unset EP_SESSION")
+	return nil
+}
+
+// keyFromPassword 读取主密码并用会话中的盐派生保险库密钥。访问令牌是用该密钥加密的，
+// 能解开它就说明主密码正确。
+func (c *cli) keyFromPassword(s *session, passwordFile string) (vaultcrypto.Key, error) {
+	password, err := c.readMasterPassword(passwordFile)
+	if err != nil {
+		return vaultcrypto.Key{}, err
+	}
+	key, err := vaultcrypto.DeriveKey(password, s.MasterSalt)
+	if err != nil {
+		return vaultcrypto.Key{}, err
+	}
+	if _, err := key.Decrypt(s.Token); err != nil {
+		return vaultcrypto.Key{}, errors.New("wrong master password")
+	}
+	return key, nil
+}
+
+// readMasterPassword 依次从 -password-file、EP_MASTER_PASSWORD 和标准输入读取主密码。
+func (c *cli) readMasterPassword(passwordFile string) (string, error) {
+	if passwordFile != "" {
+		data, err := os.ReadFile(passwordFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	if password := c.getenv("EP_MASTER_PASSWORD"); password != "" {
+		return password, nil
+	}
+
+	fmt.Fprint(c.stderr, "Master password: ")
+	line, err := c.stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("no master password given")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (c *cli) serverDefault() string {
+	if server := c.getenv("EP_SERVER"); server != "" {
+		return server
+	}
+	return defaultServer
+}
+
+// newClient 创建访问 server 的客户端，登录等待新设备批准时在 c.stderr 上提示。
+func (c *cli) newClient(server string, opts ...client.Option) *client.Client {
+	notify := client.WithApprovalHandler(func(client.PendingApproval) {
+		fmt.Fprintln(c.stderr, "Login from this device needs approval; follow the link in the email sent to your account.")
+	})
+	return client.New(server, append([]client.Option{notify}, opts...)...)
+}
+
+// epDevice 是 ep 登录时上报的设备信息。
+func epDevice(id string) client.Device {
+	return client.Device{ID: id, Name: "ep", Type: "cli"}
+}
+
+// flagSet 创建输出到 c.stderr 的子命令 FlagSet。
+func (c *cli) flagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprint(c.stderr, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseInterspersed 解析参数，允许位置参数出现在选项之前，如 "ep get github -reveal"。
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
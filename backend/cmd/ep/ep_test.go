@@ -0,0 +1,419 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	v1 "easy-password-backend/api/v1"
+	"easy-password-backend/config"
+	"easy-password-backend/internal/audit"
+	"easy-password-backend/internal/auth"
+	"easy-password-backend/internal/email"
+	"easy-password-backend/internal/kms"
+	"easy-password-backend/internal/repository/memory"
+	"easy-password-backend/internal/service"
+	"easy-password-backend/pkg/client"
+	"easy-password-backend/pkg/vaultcrypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	testEmail    = "ep-test@example.com"
+	testPassword = "correct horse battery staple"
+)
+
+// discardEmailService 丢弃所有邮件；验证码直接从存储中读取。
+type discardEmailService struct{}
+
+func (discardEmailService) SendEmail(to, subject, body string) error          { return nil }
+func (discardEmailService) SendPasswordResetEmail(to, resetLink string) error { return nil }
+func (discardEmailService) SendVerificationCodeEmail(to, code string) error   { return nil }
+func (discardEmailService) SendNewDeviceLoginEmail(to string, data email.NewDeviceLoginTemplateData) error {
+	return nil
+}
+func (discardEmailService) SendLoginApprovalEmail(to string, data email.LoginApprovalTemplateData) error {
+	return nil
+}
+func (discardEmailService) SendAccountDeletedEmail(to, username string) error     { return nil }
+func (discardEmailService) SendEmailChangeCodeEmail(to, code string) error        { return nil }
+func (discardEmailService) SendEmailChangedNoticeEmail(to, newEmail string) error { return nil }
+
+// e2e 保存端到端测试在各步骤之间传递的状态。
+type e2e struct {
+	storage *memory.Storage
+	server  *httptest.Server
+	env     map[string]string
+	stdout  *bytes.Buffer
+	stderr  *bytes.Buffer
+	// githubItemID 是 create 步骤创建的 GitHub 保险库项目的 ID。
+	githubItemID string
+}
+
+// TestEndToEnd 是 ep 与服务端处理程序的互操作检查：它不模拟 HTTP，而是通过
+// v1.RegisterRoutes 启动与服务器相同的路由，再以子命令的方式像用户一样驱动
+// 登录、解锁、增删改查、锁定、令牌续期和登出的整个流程。步骤依次执行，第一个失败的步骤终止测试。
+func TestEndToEnd(t *testing.T) {
+	e := newE2E(t)
+	steps := []struct {
+		name string
+		fn   func() error
+	}{
+		{"register", e.register},
+		{"login", e.login},
+		{"create", e.create},
+		{"list", e.list},
+		{"search", e.search},
+		{"get", e.get},
+		{"field", e.field},
+		{"edit", e.edit},
+		{"extension-format", e.extensionFormat},
+		{"lock", e.lock},
+		{"unlock", e.unlock},
+		{"token-renewal", e.tokenRenewal},
+		{"delete", e.delete},
+		{"logout", e.logout},
+	}
+	for _, step := range steps {
+		ok := t.Run(step.name, func(t *testing.T) {
+			if err := step.fn(); err != nil {
+				t.Fatal(err)
+			}
+		})
+		if !ok {
+			t.FailNow()
+		}
+	}
+}
+
+func newE2E(t *testing.T) *e2e {
+	t.Helper()
+	keys, err := kms.WithEphemeralKey(kms.NewStaticProvider(nil), kms.PurposeJWT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{JWTExpiration: time.Hour, FrontendURL: "http://localhost"}
+	storage := memory.NewMemoryStorage()
+
+	auditService := audit.NewAuditService(storage.Audit())
+	services := v1.Services{
+		Auth:   auth.NewAuthService(storage.User(), storage.VerificationCode(), storage.Device(), storage.LoginApproval(), storage.APIKey(), discardEmailService{}, auditService, cfg, keys),
+		Vault:  service.NewVaultService(storage.Vault(), storage.User(), auditService),
+		Device: service.NewDeviceService(storage.Device(), auditService),
+		Admin:  service.NewAdminService(storage.User(), storage.Vault(), storage.Device(), storage, auditService),
+		Audit:  auditService,
+	}
+
+	// 服务端的日志与测试结果无关
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(v1.RequestIDMiddleware(), v1.ClientInfoMiddleware())
+	v1.RegisterRoutes(router, services)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return &e2e{
+		storage: storage,
+		server:  server,
+		env: map[string]string{
+			"EP_SERVER":          server.URL,
+			"EP_SESSION_FILE":    filepath.Join(t.TempDir(), "session.json"),
+			"EP_MASTER_PASSWORD": testPassword,
+		},
+		stdout: &bytes.Buffer{},
+		stderr: &bytes.Buffer{},
+	}
+}
+
+// ep 以 stdin 为标准输入运行一个子命令，返回其标准输出。
+func (e *e2e) ep(stdin string, args ...string) (string, error) {
+	e.stdout.Reset()
+	e.stderr.Reset()
+	c := &cli{
+		stdin:  bufio.NewReader(strings.NewReader(stdin)),
+		stdout: e.stdout,
+		stderr: e.stderr,
+		getenv: func(name string) string { return e.env[name] },
+	}
+	if err := c.run(args[0], args[1:]); err != nil {
+		return e.stdout.String(), fmt.Errorf("ep %s: %w", strings.Join(args, " "), err)
+	}
+	return e.stdout.String(), nil
+}
+
+// exportSession 从 login 或 unlock 的输出中取出 EP_SESSION 并设置到环境中。
+func (e *e2e) exportSession(out string) error {
+	line := strings.TrimSpace(out)
+	value, ok := strings.CutPrefix(line, "export EP_SESSION=")
+	if !ok {
+		return fmt.Errorf("unexpected output %q", line)
+	}
+	value = strings.Trim(value, `"`)
+	if value == "" {
+		return errors.New("empty EP_SESSION")
+	}
+	e.env["EP_SESSION"] = value
+	return nil
+}
+
+// register 按扩展的注册流程创建账户：生成盐、派生密钥，只向服务端发送密钥哈希。
+func (e *e2e) register() error {
+	ctx := context.Background()
+	api := client.New(e.server.URL)
+	if err := api.SendVerificationCode(ctx, testEmail); err != nil {
+		return err
+	}
+	vc, err := e.storage.VerificationCode().Find(ctx, testEmail)
+	if err != nil {
+		return fmt.Errorf("read verification code: %w", err)
+	}
+	_, _, err = api.Register(ctx, "eptest", testEmail, testPassword, vc.Code)
+	return err
+}
+
+func (e *e2e) login() error {
+	out, err := e.ep("", "login", testEmail)
+	if err != nil {
+		return err
+	}
+	if err := e.exportSession(out); err != nil {
+		return err
+	}
+	// 之后的命令只能靠 EP_SESSION 解锁
+	delete(e.env, "EP_MASTER_PASSWORD")
+	return nil
+}
+
+func (e *e2e) create() error {
+	out, err := e.ep("", "create", "-name", "GitHub", "-account", "octocat", "-website", "https://github.com", "-generate", "-length", "24")
+	if err != nil {
+		return err
+	}
+	e.githubItemID = strings.TrimSpace(out)
+	if _, err := uuid.Parse(e.githubItemID); err != nil {
+		return fmt.Errorf("create printed %q, want an id", e.githubItemID)
+	}
+	_, err = e.ep("hunter2\n", "create", "-name", "Mail", "-account", testEmail, "-category", "personal", "-password-stdin")
+	return err
+}
+
+func (e *e2e) list() error {
+	out, err := e.ep("", "list", "-json")
+	if err != nil {
+		return err
+	}
+	var items []client.Item
+	if err := json.Unmarshal([]byte(out), &items); err != nil {
+		return err
+	}
+	if len(items) != 2 || items[0].Name != "GitHub" || items[1].Name != "Mail" {
+		return fmt.Errorf("list returned %d items, want GitHub and Mail", len(items))
+	}
+	if items[1].Password != "hunter2" || items[1].Category != "personal" {
+		return errors.New("Mail item does not have the password and category it was created with")
+	}
+
+	out, err = e.ep("", "list", "-category", "personal")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(out, "Mail") || strings.Contains(out, "GitHub") {
+		return fmt.Errorf("list -category personal printed:\n%s", out)
+	}
+	return nil
+}
+
+func (e *e2e) search() error {
+	out, err := e.ep("", "search", "GITHUB.COM")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(out, e.githubItemID) || strings.Contains(out, "Mail") {
+		return fmt.Errorf("search printed:\n%s", out)
+	}
+	return nil
+}
+
+func (e *e2e) get() error {
+	out, err := e.ep("", "get", "github")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(out, "octocat") || !strings.Contains(out, "********") {
+		return fmt.Errorf("get did not mask the password:\n%s", out)
+	}
+	if _, err := e.ep("", "get", "nope"); err == nil {
+		return errors.New("get of a missing item succeeded")
+	}
+	return nil
+}
+
+func (e *e2e) field() error {
+	out, err := e.ep("", "field", "-n", e.githubItemID, "password")
+	if err != nil {
+		return err
+	}
+	if len(out) != 24 {
+		return fmt.Errorf("generated password %q has length %d, want 24", out, len(out))
+	}
+	return nil
+}
+
+func (e *e2e) edit() error {
+	before, err := e.ep("", "field", "GitHub", "password")
+	if err != nil {
+		return err
+	}
+	if _, err := e.ep("", "edit", "GitHub", "-notes", "2FA on", "-category", "work"); err != nil {
+		return err
+	}
+	out, err := e.ep("", "get", "GitHub", "-json")
+	if err != nil {
+		return err
+	}
+	var item client.Item
+	if err := json.Unmarshal([]byte(out), &item); err != nil {
+		return err
+	}
+	if item.Notes != "2FA on" || item.Category != "work" || item.Account != "octocat" {
+		return fmt.Errorf("edit produced notes=%q category=%q account=%q", item.Notes, item.Category, item.Account)
+	}
+	if item.Password+"\n" != before {
+		return errors.New("edit changed a field that was not given")
+	}
+	return nil
+}
+
+// extensionFormat 直接读取服务端存储的密文，确认它是扩展能解开的格式：
+// base64(IV || AES-GCM 密文)，明文为 DecryptedVaultItem 的 JSON。
+func (e *e2e) extensionFormat() error {
+	ctx := context.Background()
+	user, err := e.storage.User().FindByEmail(ctx, testEmail)
+	if err != nil {
+		return err
+	}
+	key, err := vaultcrypto.DeriveKey(testPassword, string(user.MasterSalt))
+	if err != nil {
+		return err
+	}
+	if key.AuthHash() != user.AuthHash {
+		return errors.New("stored master key hash does not match the derived key")
+	}
+
+	items, err := e.storage.Vault().FindByUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		var plain map[string]any
+		if err := key.DecryptItem(item.EncryptedData, &plain); err != nil {
+			return fmt.Errorf("item %s: %w", item.ID, err)
+		}
+		if _, ok := plain["name"].(string); !ok {
+			return fmt.Errorf("item %s has no name field", item.ID)
+		}
+		if _, ok := plain["category"]; ok {
+			return fmt.Errorf("item %s stores the category inside the ciphertext", item.ID)
+		}
+	}
+	return nil
+}
+
+func (e *e2e) lock() error {
+	if _, err := e.ep("", "lock"); err != nil {
+		return err
+	}
+	if _, err := e.ep("", "list"); err == nil {
+		return errors.New("list succeeded on a locked session without a master password")
+	}
+	e.env["EP_MASTER_PASSWORD"] = "wrong password"
+	if _, err := e.ep("", "list"); err == nil || !strings.Contains(err.Error(), "wrong master password") {
+		return fmt.Errorf("list with a wrong master password returned %v", err)
+	}
+	return nil
+}
+
+func (e *e2e) unlock() error {
+	delete(e.env, "EP_MASTER_PASSWORD")
+	out, err := e.ep(testPassword+"\n", "unlock")
+	if err != nil {
+		return err
+	}
+	if err := e.exportSession(out); err != nil {
+		return err
+	}
+	_, err = e.ep("", "list")
+	return err
+}
+
+// tokenRenewal 把保存的访问令牌换成无效令牌，确认客户端会用保险库密钥重新登录并保存新令牌。
+func (e *e2e) tokenRenewal() error {
+	c := &cli{getenv: func(name string) string { return e.env[name] }}
+	s, err := c.loadSession()
+	if err != nil {
+		return err
+	}
+	key, ok := c.sessionKey(s)
+	if !ok {
+		return errors.New("session is not unlocked")
+	}
+	if s.Token, err = key.Encrypt([]byte("not-a-token")); err != nil {
+		return err
+	}
+	if err := c.saveSession(s); err != nil {
+		return err
+	}
+
+	if _, err := e.ep("", "field", "GitHub", "account"); err != nil {
+		return err
+	}
+	if s, err = c.loadSession(); err != nil {
+		return err
+	}
+	token, err := key.Decrypt(s.Token)
+	if err != nil {
+		return err
+	}
+	if string(token) == "not-a-token" {
+		return errors.New("the renewed token was not saved")
+	}
+	return nil
+}
+
+func (e *e2e) delete() error {
+	if _, err := e.ep("n\n", "delete", "GitHub"); err == nil {
+		return errors.New("delete went ahead after answering no")
+	}
+	if _, err := e.ep("y\n", "delete", "GitHub"); err != nil {
+		return err
+	}
+	out, err := e.ep("", "list")
+	if err != nil {
+		return err
+	}
+	if strings.Contains(out, "GitHub") {
+		return errors.New("GitHub is still listed after delete")
+	}
+	return nil
+}
+
+func (e *e2e) logout() error {
+	if _, err := e.ep("", "logout"); err != nil {
+		return err
+	}
+	if _, err := e.ep("", "list"); !errors.Is(err, errNoSession) {
+		return fmt.Errorf("list after logout returned %v, want %v", err, errNoSession)
+	}
+	return nil
+}
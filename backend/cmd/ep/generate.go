@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"math/big"
+)
+
+// 字符集和长度限制与扩展的密码生成器一致。
+const (
+	lowercaseChars = "abcdefghijklmnopqrstuvwxyz"
+	uppercaseChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	numberChars    = "0123456789"
+	symbolChars    = "!@#$%^&*()_+-=[]{}|;:,.<>?"
+
+	defaultPasswordLength = 16
+	minPasswordLength     = 8
+	maxPasswordLength     = 128
+)
+
+const generateUsage = `Usage: ep generate [-length N] [-no-numbers] [-no-symbols]
+
+Prints a random password. It does not need a session.
+`
+
+// passwordOptions 是密码生成选项，generate、create 和 edit 共用。
+type passwordOptions struct {
+	length    *int
+	noNumbers *bool
+	noSymbols *bool
+}
+
+func (o *passwordOptions) register(fs *flag.FlagSet) {
+	o.length = fs.Int("length", defaultPasswordLength, fmt.Sprintf("password length (%d-%d)", minPasswordLength, maxPasswordLength))
+	o.noNumbers = fs.Bool("no-numbers", false, "leave out digits")
+	o.noSymbols = fs.Bool("no-symbols", false, "leave out symbols")
+}
+
+func (c *cli) runGenerate(args []string) error {
+	fs := c.flagSet("generate", generateUsage)
+	var opts passwordOptions
+	opts.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	password, err := generatePassword(opts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stdout, password)
+	return nil
+}
+
+// generatePassword 用 crypto/rand 从字符集中均匀选取字符生成密码。
+func generatePassword(opts passwordOptions) (string, error) {
+	length := *opts.length
+	if length < minPasswordLength || length > maxPasswordLength {
+		return "", fmt.Errorf("password length must be between %d and %d", minPasswordLength, maxPasswordLength)
+	}
+
+	charset := lowercaseChars + uppercaseChars
+	if !*opts.noNumbers {
+		charset += numberChars
+	}
+	if !*opts.noSymbols {
+		charset += symbolChars
+	}
+
+	password := make([]byte, length)
+	limit := big.NewInt(int64(len(charset)))
+	for i := range password {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", err
+		}
+		password[i] = charset[n.Int64()]
+	}
+	return string(password), nil
+}
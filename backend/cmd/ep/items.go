@@ -0,0 +1,391 @@
+package main
+
+import (
+	"easy-password-backend/pkg/client"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const listUsage = `Usage: ep list [-category NAME] [-json] [-password-file FILE]
+`
+
+const searchUsage = `Usage: ep search [-json] [-password-file FILE] <query>
+
+Lists items whose name, account or website contains query, ignoring case.
+`
+
+const getUsage = `Usage: ep get [-reveal] [-json] [-password-file FILE] <id|name>
+
+Shows an item. The password is masked unless -reveal or -json is given.
+`
+
+const fieldUsage = `Usage: ep field [-n] [-password-file FILE] <id|name> <field>
+
+Prints one field of an item to stdout, for use in scripts:
+  ep field github password | pbcopy
+Fields: id, name, account, website, password, notes, category.
+`
+
+const createUsage = `Usage: ep create -name NAME [-account ACCOUNT] [-website URL] [-notes TEXT] [-category NAME]
+                 [-generate [-length N] [-no-numbers] [-no-symbols] | -password-stdin]
+
+Adds an item and prints its id. With -generate a random password is stored;
+with -password-stdin the password is read from the first line of stdin.
+`
+
+const editUsage = `Usage: ep edit [-name NAME] [-account ACCOUNT] [-website URL] [-notes TEXT] [-category NAME]
+               [-generate [-length N] [-no-numbers] [-no-symbols] | -password-stdin] <id|name>
+
+Changes only the given fields of an item.
+`
+
+const deleteUsage = `Usage: ep delete [-yes] [-password-file FILE] <id|name>
+`
+
+func (c *cli) runList(args []string) error {
+	fs := c.flagSet("list", listUsage)
+	category := fs.String("category", "", "only list items in this category")
+	asJSON := fs.Bool("json", false, "print items as JSON, including passwords")
+	passwordFile := fs.String("password-file", "", "read the master password from this file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	v, err := c.openVault(*passwordFile)
+	if err != nil {
+		return err
+	}
+	items, err := v.list()
+	if err != nil {
+		return err
+	}
+	if *category != "" {
+		items = filterItems(items, func(item client.Item) bool {
+			return strings.EqualFold(item.Category, *category)
+		})
+	}
+	return c.printItems(items, *asJSON)
+}
+
+func (c *cli) runSearch(args []string) error {
+	fs := c.flagSet("search", searchUsage)
+	asJSON := fs.Bool("json", false, "print items as JSON, including passwords")
+	passwordFile := fs.String("password-file", "", "read the master password from this file")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		fs.Usage()
+		return errors.New("search needs exactly one query")
+	}
+	query := strings.ToLower(rest[0])
+
+	v, err := c.openVault(*passwordFile)
+	if err != nil {
+		return err
+	}
+	items, err := v.list()
+	if err != nil {
+		return err
+	}
+	items = filterItems(items, func(item client.Item) bool {
+		return strings.Contains(strings.ToLower(item.Name), query) ||
+			strings.Contains(strings.ToLower(item.Account), query) ||
+			strings.Contains(strings.ToLower(item.Website), query)
+	})
+	return c.printItems(items, *asJSON)
+}
+
+func (c *cli) runGet(args []string) error {
+	fs := c.flagSet("get", getUsage)
+	reveal := fs.Bool("reveal", false, "show the password")
+	asJSON := fs.Bool("json", false, "print the item as JSON, including the password")
+	passwordFile := fs.String("password-file", "", "read the master password from this file")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		fs.Usage()
+		return errors.New("get needs exactly one id or name")
+	}
+
+	v, err := c.openVault(*passwordFile)
+	if err != nil {
+		return err
+	}
+	item, err := v.resolve(rest[0])
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(c.stdout, item)
+	}
+
+	password := item.Password
+	if !*reveal && password != "" {
+		password = "********"
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%s\n", item.ID)
+	fmt.Fprintf(w, "Name:\t%s\n", item.Name)
+	fmt.Fprintf(w, "Account:\t%s\n", item.Account)
+	fmt.Fprintf(w, "Website:\t%s\n", item.Website)
+	fmt.Fprintf(w, "Password:\t%s\n", password)
+	fmt.Fprintf(w, "Category:\t%s\n", item.Category)
+	fmt.Fprintf(w, "Notes:\t%s\n", item.Notes)
+	fmt.Fprintf(w, "Updated:\t%s\n", item.UpdatedAt.Local().Format(time.DateTime))
+	return w.Flush()
+}
+
+func (c *cli) runField(args []string) error {
+	fs := c.flagSet("field", fieldUsage)
+	noNewline := fs.Bool("n", false, "do not print a trailing newline")
+	passwordFile := fs.String("password-file", "", "read the master password from this file")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 2 {
+		fs.Usage()
+		return errors.New("field needs an id or name and a field")
+	}
+
+	v, err := c.openVault(*passwordFile)
+	if err != nil {
+		return err
+	}
+	item, err := v.resolve(rest[0])
+	if err != nil {
+		return err
+	}
+
+	var value string
+	switch strings.ToLower(rest[1]) {
+	case "id":
+		value = item.ID.String()
+	case "name":
+		value = item.Name
+	case "account":
+		value = item.Account
+	case "website":
+		value = item.Website
+	case "password":
+		value = item.Password
+	case "notes":
+		value = item.Notes
+	case "category":
+		value = item.Category
+	default:
+		return fmt.Errorf("unknown field %q", rest[1])
+	}
+	if *noNewline {
+		_, err = fmt.Fprint(c.stdout, value)
+	} else {
+		_, err = fmt.Fprintln(c.stdout, value)
+	}
+	return err
+}
+
+// itemFlags 是 create 和 edit 共用的条目选项。
+type itemFlags struct {
+	name, account, website, notes, category *string
+	generate, passwordStdin                 *bool
+	password                                passwordOptions
+	passwordFile                            *string
+}
+
+func (c *cli) itemFlagSet(name, usage string) (*flag.FlagSet, *itemFlags) {
+	fs := c.flagSet(name, usage)
+	f := &itemFlags{
+		name:          fs.String("name", "", "item name"),
+		account:       fs.String("account", "", "account or username"),
+		website:       fs.String("website", "", "website URL"),
+		notes:         fs.String("notes", "", "notes"),
+		category:      fs.String("category", "", "category"),
+		generate:      fs.Bool("generate", false, "store a newly generated password"),
+		passwordStdin: fs.Bool("password-stdin", false, "read the password from the first line of stdin"),
+		passwordFile:  fs.String("password-file", "", "read the master password from this file"),
+	}
+	f.password.register(fs)
+	return fs, f
+}
+
+// newPassword 按 -generate 或 -password-stdin 返回新密码；两者都未指定时 ok 为 false。
+// 主密码也可能从标准输入读取，所以必须在打开保险库之后调用。
+func (c *cli) newPassword(f *itemFlags) (password string, ok bool, err error) {
+	switch {
+	case *f.generate && *f.passwordStdin:
+		return "", false, errors.New("-generate and -password-stdin cannot be used together")
+	case *f.generate:
+		password, err = generatePassword(f.password)
+		return password, err == nil, err
+	case *f.passwordStdin:
+		line, err := c.stdin.ReadString('\n')
+		if err != nil && line == "" {
+			return "", false, errors.New("no password on stdin")
+		}
+		return strings.TrimRight(line, "\r\n"), true, nil
+	default:
+		return "", false, nil
+	}
+}
+
+func (c *cli) runCreate(args []string) error {
+	fs, f := c.itemFlagSet("create", createUsage)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	if *f.name == "" {
+		fs.Usage()
+		return errors.New("-name is required")
+	}
+
+	v, err := c.openVault(*f.passwordFile)
+	if err != nil {
+		return err
+	}
+	data := client.ItemData{Name: *f.name, Account: *f.account, Website: *f.website, Notes: *f.notes}
+	if data.Password, _, err = c.newPassword(f); err != nil {
+		return err
+	}
+
+	item, err := v.items.Create(v.ctx, data, *f.category)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stdout, item.ID)
+	return nil
+}
+
+func (c *cli) runEdit(args []string) error {
+	fs, f := c.itemFlagSet("edit", editUsage)
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		fs.Usage()
+		return errors.New("edit needs exactly one id or name")
+	}
+
+	v, err := c.openVault(*f.passwordFile)
+	if err != nil {
+		return err
+	}
+	item, err := v.resolve(rest[0])
+	if err != nil {
+		return err
+	}
+
+	// 只修改命令行中出现过的选项，允许用 -website= 清空字段。
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "name":
+			item.Name = *f.name
+		case "account":
+			item.Account = *f.account
+		case "website":
+			item.Website = *f.website
+		case "notes":
+			item.Notes = *f.notes
+		case "category":
+			item.Category = *f.category
+		}
+	})
+	if password, ok, err := c.newPassword(f); err != nil {
+		return err
+	} else if ok {
+		item.Password = password
+	}
+	if item.Name == "" {
+		return errors.New("item name cannot be empty")
+	}
+
+	if _, err := v.items.Update(v.ctx, item.ID, item.ItemData, &item.Category); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "Updated %s.\n", item.ID)
+	return nil
+}
+
+func (c *cli) runDelete(args []string) error {
+	fs := c.flagSet("delete", deleteUsage)
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	passwordFile := fs.String("password-file", "", "read the master password from this file")
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		fs.Usage()
+		return errors.New("delete needs exactly one id or name")
+	}
+
+	v, err := c.openVault(*passwordFile)
+	if err != nil {
+		return err
+	}
+	item, err := v.resolve(rest[0])
+	if err != nil {
+		return err
+	}
+	if !*yes {
+		fmt.Fprintf(c.stderr, "Delete %q (%s)? [y/N] ", item.Name, item.ID)
+		answer, _ := c.stdin.ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			return errors.New("aborted")
+		}
+	}
+
+	if err := v.items.Delete(v.ctx, item.ID); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "Deleted %s.\n", item.ID)
+	return nil
+}
+
+// printItems 按名称排序输出条目列表；表格中不包含密码。
+func (c *cli) printItems(items []client.Item, asJSON bool) error {
+	sort.Slice(items, func(i, j int) bool {
+		return strings.ToLower(items[i].Name) < strings.ToLower(items[j].Name)
+	})
+	if asJSON {
+		return printJSON(c.stdout, items)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tACCOUNT\tWEBSITE\tCATEGORY")
+	for _, item := range items {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", item.ID, item.Name, item.Account, item.Website, item.Category)
+	}
+	return w.Flush()
+}
+
+func filterItems(items []client.Item, keep func(client.Item) bool) []client.Item {
+	filtered := items[:0]
+	for _, item := range items {
+		if keep(item) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,100 @@
+// ep 是 EasyPassword 保险库的命令行客户端。
+// 条目在本地用由主密码派生的密钥加解密，与浏览器扩展的格式相同，服务端永远看不到明文。
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `Usage: ep <command> [arguments]
+
+Commands:
+  login      sign in, save an encrypted session and print the EP_SESSION export line
+  unlock     unlock the saved session and print the EP_SESSION export line
+  lock       forget the unlocked session key; the next command asks for the master password
+  logout     delete the saved session
+  list       list items, optionally only those in -category
+  search     list items whose name, account or website contains a query
+  get        show an item by id or name
+  field      print one field of an item to stdout, for scripts
+  create     add an item
+  edit       change fields of an item
+  delete     delete an item
+  generate   print a random password
+
+The master password is read from -password-file, EP_MASTER_PASSWORD or stdin.
+After "ep login" or "ep unlock", export EP_SESSION so later commands do not ask for it.
+The session is kept in EP_SESSION_FILE, or ep/session.json under the user config directory.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		fmt.Print(usage)
+		return
+	}
+
+	c := &cli{
+		stdin:  bufio.NewReader(os.Stdin),
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		getenv: os.Getenv,
+	}
+	if err := c.run(os.Args[1], os.Args[2:]); err != nil {
+		if err == errUnknownCommand {
+			fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// cli 保存一次命令执行的输入输出和环境，测试用它在进程内运行命令。
+type cli struct {
+	stdin  *bufio.Reader
+	stdout io.Writer
+	stderr io.Writer
+	getenv func(string) string
+}
+
+func (c *cli) run(command string, args []string) error {
+	switch command {
+	case "login":
+		return c.runLogin(args)
+	case "unlock":
+		return c.runUnlock(args)
+	case "lock":
+		return c.runLock(args)
+	case "logout":
+		return c.runLogout(args)
+	case "list":
+		return c.runList(args)
+	case "search":
+		return c.runSearch(args)
+	case "get":
+		return c.runGet(args)
+	case "field":
+		return c.runField(args)
+	case "create":
+		return c.runCreate(args)
+	case "edit":
+		return c.runEdit(args)
+	case "delete":
+		return c.runDelete(args)
+	case "generate":
+		return c.runGenerate(args)
+	default:
+		return errUnknownCommand
+	}
+}
+
+// errUnknownCommand 表示命令名无法识别，main 会打印用法并以状态 2 退出。
+var errUnknownCommand = errors.New("unknown command")
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/rand"
+	"easy-password-backend/pkg/vaultcrypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// errNoSession 表示本地还没有保存的会话。
+var errNoSession = errors.New(`not logged in; run "ep login" first`)
+
+// session 是保存在本地会话文件中的登录状态。访问令牌用保险库密钥加密，
+// 所以仅凭会话文件无法访问服务端；Unlocked 保存用 EP_SESSION 中的会话密钥
+// 加密的保险库密钥，"ep lock" 会清除它。
+type session struct {
+	Server     string `json:"server"`
+	Identifier string `json:"identifier"`
+	MasterSalt string `json:"master_salt"`
+	DeviceID   string `json:"device_id"`
+	Token      string `json:"token"`
+	Unlocked   string `json:"unlocked,omitempty"`
+}
+
+// sessionPath 返回会话文件路径：EP_SESSION_FILE，或用户配置目录下的 ep/session.json。
+func (c *cli) sessionPath() (string, error) {
+	if path := c.getenv("EP_SESSION_FILE"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locate session file: %w; set EP_SESSION_FILE", err)
+	}
+	return filepath.Join(dir, "ep", "session.json"), nil
+}
+
+func (c *cli) loadSession() (*session, error) {
+	path, err := c.sessionPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errNoSession
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("read session file %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// saveSession 以 0600 权限写入会话文件；先写临时文件再重命名，中途失败不会留下半个文件。
+func (c *cli) saveSession(s *session) error {
+	path, err := c.sessionPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".session-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (c *cli) removeSession() error {
+	path, err := c.sessionPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// unlockSession 用新的随机会话密钥加密保险库密钥并保存，返回 EP_SESSION 的值。
+func (c *cli) unlockSession(s *session, key vaultcrypto.Key) (string, error) {
+	var sessionKey vaultcrypto.Key
+	if _, err := rand.Read(sessionKey[:]); err != nil {
+		return "", err
+	}
+	sealed, err := sessionKey.Encrypt(key[:])
+	if err != nil {
+		return "", err
+	}
+	s.Unlocked = sealed
+	if err := c.saveSession(s); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sessionKey[:]), nil
+}
+
+// sessionKey 用 EP_SESSION 解开会话中的保险库密钥；会话未解锁或 EP_SESSION
+// 不匹配时返回 false，调用方改为询问主密码。
+func (c *cli) sessionKey(s *session) (vaultcrypto.Key, bool) {
+	var key vaultcrypto.Key
+	encoded := c.getenv("EP_SESSION")
+	if encoded == "" || s.Unlocked == "" {
+		return key, false
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) != vaultcrypto.KeySize {
+		return key, false
+	}
+	var sessionKey vaultcrypto.Key
+	copy(sessionKey[:], raw)
+	plain, err := sessionKey.Decrypt(s.Unlocked)
+	if err != nil || len(plain) != vaultcrypto.KeySize {
+		return key, false
+	}
+	copy(key[:], plain)
+	return key, true
+}
+
+// printExport 输出设置 EP_SESSION 的 shell 命令，可直接用 eval "$(ep unlock)" 执行。
+func (c *cli) printExport(sessionKey string) {
+	fmt.Fprintf(c.stdout, "export EP_SESSION=%q\n", sessionKey)
+	fmt.Fprintln(c.stderr, `Session unlocked. Run the line above, or eval "$(ep unlock)", to use it in this shell.`)
+}
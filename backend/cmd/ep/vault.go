@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"easy-password-backend/pkg/client"
+	"easy-password-backend/pkg/vaultcrypto"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// vault 是已解锁的保险库：持有保险库密钥和已认证的客户端。
+type vault struct {
+	cli   *cli
+	ctx   context.Context
+	api   *client.Client
+	items *client.Vault
+}
+
+// openVault 加载会话并取得保险库密钥：优先用 EP_SESSION 解锁，否则询问主密码。
+// 令牌过期时客户端会用保险库密钥重新登录，新令牌加密后写回会话文件。
+func (c *cli) openVault(passwordFile string) (*vault, error) {
+	s, err := c.loadSession()
+	if err != nil {
+		return nil, err
+	}
+	key, ok := c.sessionKey(s)
+	if !ok {
+		if key, err = c.keyFromPassword(s, passwordFile); err != nil {
+			return nil, err
+		}
+	}
+	token, err := key.Decrypt(s.Token)
+	if err != nil {
+		return nil, errors.New("saved session is corrupted; run \"ep login\" again")
+	}
+
+	api := c.newClient(s.Server,
+		client.WithToken(string(token)),
+		client.WithLogin(s.Identifier, key.AuthHash(), epDevice(s.DeviceID)),
+		client.WithTokenHandler(func(token string) {
+			if err := c.saveToken(s, key, token); err != nil {
+				fmt.Fprintln(c.stderr, "warning: could not save the renewed session token:", err)
+			}
+		}),
+	)
+	return &vault{cli: c, ctx: context.Background(), api: api, items: api.Vault(key)}, nil
+}
+
+// saveToken 用保险库密钥加密访问令牌并写入会话文件。
+func (c *cli) saveToken(s *session, key vaultcrypto.Key, token string) error {
+	sealed, err := key.Encrypt([]byte(token))
+	if err != nil {
+		return err
+	}
+	s.Token = sealed
+	return c.saveSession(s)
+}
+
+// list 获取并解密所有条目；无法解密的条目给出警告后跳过。
+func (v *vault) list() ([]client.Item, error) {
+	raw, err := v.api.Items(v.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]client.Item, 0, len(raw))
+	for _, item := range raw {
+		decrypted, err := v.items.Decrypt(item)
+		if err != nil {
+			fmt.Fprintf(v.cli.stderr, "warning: skipping %v\n", err)
+			continue
+		}
+		items = append(items, decrypted)
+	}
+	return items, nil
+}
+
+// resolve 按 ID 或名称（不区分大小写、完全匹配）查找条目；名称重复时报错并列出候选 ID。
+func (v *vault) resolve(ref string) (client.Item, error) {
+	items, err := v.list()
+	if err != nil {
+		return client.Item{}, err
+	}
+	if id, err := uuid.Parse(ref); err == nil {
+		for _, item := range items {
+			if item.ID == id {
+				return item, nil
+			}
+		}
+		return client.Item{}, fmt.Errorf("no item with id %s", id)
+	}
+
+	var matches []client.Item
+	for _, item := range items {
+		if strings.EqualFold(item.Name, ref) {
+			matches = append(matches, item)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return client.Item{}, fmt.Errorf("no item named %q", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		ids := make([]string, len(matches))
+		for i, item := range matches {
+			ids[i] = item.ID.String()
+		}
+		return client.Item{}, fmt.Errorf("%d items are named %q; use an id: %s", len(matches), ref, strings.Join(ids, ", "))
+	}
+}
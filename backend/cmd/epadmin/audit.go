@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"easy-password-backend/config"
+	"easy-password-backend/internal/audit"
+	"errors"
+	"fmt"
+)
+
+func runAudit(cfg *config.Config, args []string) error {
+	if len(args) < 1 || args[0] != "verify" {
+		return errors.New("usage: epadmin audit verify")
+	}
+
+	storage, closeFn, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	result, err := audit.VerifyChain(context.Background(), storage.Audit())
+	if err != nil {
+		return err
+	}
+	if result.Break != nil {
+		fmt.Printf("verified %d events before the first broken link\n", result.Events)
+		fmt.Printf("sequence: %d\nevent:    %s\nuser:     %s\nreason:   %s\n",
+			result.Break.Sequence, result.Break.EventID, result.Break.UserID, result.Break.Reason)
+		return result.Break
+	}
+	fmt.Printf("audit chain intact: %d events across %d users\n", result.Events, result.Users)
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"easy-password-backend/config"
+	"easy-password-backend/internal/audit"
+	"easy-password-backend/internal/core"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// writeAuditEvents 创建一个 BoltDB 数据库，按 tamper 修改依次产生的三条事件后原样写入。
+func writeAuditEvents(t *testing.T, tamper func(events []*core.AuditEvent) []*core.AuditEvent) *config.Config {
+	t.Helper()
+	ctx := context.Background()
+	cfg := &config.Config{DBType: "boltdb", DBPath: filepath.Join(t.TempDir(), "ep.db")}
+
+	storage, closeFn, err := openStorageWithSchema(cfg, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFn()
+
+	// 先在一个临时数据库中生成合法的链，再把修改后的事件写入 cfg 指向的数据库。
+	scratch, closeScratch, err := openStorageWithSchema(&config.Config{DBType: "boltdb", DBPath: filepath.Join(t.TempDir(), "scratch.db")}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeScratch()
+	userID := uuid.New()
+	for range 3 {
+		event := &core.AuditEvent{UserID: userID, Type: core.AuditEventLoginSuccess, CreatedAt: time.Now()}
+		if err := scratch.Audit().Create(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var events []*core.AuditEvent
+	err = scratch.Audit().ForEach(ctx, func(event *core.AuditEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var records []any
+	for _, event := range tamper(events) {
+		records = append(records, event)
+	}
+	if err := storage.Bulk().Insert(ctx, core.RecordAuditEvents, records); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestRunAuditVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		tamper   func(events []*core.AuditEvent) []*core.AuditEvent
+		sequence int64
+		reason   string
+	}{
+		{
+			name: "modified content",
+			tamper: func(events []*core.AuditEvent) []*core.AuditEvent {
+				events[1].UserAgent = "curl/8.0"
+				return events
+			},
+			sequence: 2,
+			reason:   "event hash mismatch, record content was modified",
+		},
+		{
+			name: "broken previous hash",
+			tamper: func(events []*core.AuditEvent) []*core.AuditEvent {
+				events[1].PrevHash = ""
+				events[1].Hash = events[1].ComputeHash()
+				return events
+			},
+			sequence: 2,
+			reason:   "global previous hash mismatch",
+		},
+		{
+			name: "broken user previous hash",
+			tamper: func(events []*core.AuditEvent) []*core.AuditEvent {
+				events[1].UserPrevHash = ""
+				events[1].Hash = events[1].ComputeHash()
+				return events
+			},
+			sequence: 2,
+			reason:   "user previous hash mismatch",
+		},
+		{
+			name: "skipped sequence",
+			tamper: func(events []*core.AuditEvent) []*core.AuditEvent {
+				return []*core.AuditEvent{events[0], events[2]}
+			},
+			sequence: 3,
+			reason:   "expected sequence 2, found 3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := writeAuditEvents(t, tt.tamper)
+			err := runAudit(cfg, []string{"verify"})
+			var chainBreak *audit.ChainBreak
+			if !errors.As(err, &chainBreak) {
+				t.Fatalf("runAudit: %v, want a chain break", err)
+			}
+			if chainBreak.Sequence != tt.sequence || chainBreak.Reason != tt.reason {
+				t.Fatalf("break at sequence %d (%s), want %d (%s)",
+					chainBreak.Sequence, chainBreak.Reason, tt.sequence, tt.reason)
+			}
+		})
+	}
+
+	t.Run("intact", func(t *testing.T) {
+		cfg := writeAuditEvents(t, func(events []*core.AuditEvent) []*core.AuditEvent { return events })
+		if err := runAudit(cfg, []string{"verify"}); err != nil {
+			t.Fatalf("runAudit on an intact chain: %v", err)
+		}
+	})
+}
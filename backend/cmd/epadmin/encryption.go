@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"easy-password-backend/config"
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/repository/boltdb"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+const encryptionUsage = `usage:
+  epadmin encryption genkey
+  epadmin encryption status [-json]
+  epadmin encryption rotate
+  epadmin encryption reencrypt
+  epadmin encryption compact -o file
+
+At-rest encryption applies to the BoltDB backend and is enabled by configuring
+a storage key: DB_ENCRYPTION_KEY (or DB_ENCRYPTION_KEY_FILE) set to a key printed
+by genkey, or a storage key in the keyring or transit service (see epadmin keys).
+The next time the database is opened, existing records are encrypted in the
+background. To replace the server key, make a new storage key active while
+keeping the old one available (with env keys, move the old key to
+DB_ENCRYPTION_PREVIOUS_KEYS); the data keys are re-wrapped on the next open.
+
+rotate replaces the data key and re-encrypts every record before returning;
+reencrypt finishes an interrupted re-encryption. Both need exclusive access, so
+stop the server first or use POST /api/v1/admin/encryption/rotate instead.
+
+Records that were overwritten while being encrypted can linger in free pages of
+the file. compact writes a copy without free pages; replace the database with
+it while the server is stopped.`
+
+func runEncryption(cfg *config.Config, args []string) error {
+	if len(args) < 1 {
+		return errors.New(encryptionUsage)
+	}
+
+	fs := flag.NewFlagSet("encryption "+args[0], flag.ContinueOnError)
+	var asJSON *bool
+	var output *string
+	switch args[0] {
+	case "genkey", "rotate", "reencrypt":
+	case "status":
+		asJSON = outputFlag(fs)
+	case "compact":
+		output = fs.String("o", "", "path of the compacted copy (must not exist)")
+	default:
+		return errors.New(encryptionUsage)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "genkey":
+		key := make([]byte, boltdb.EncryptionKeySize)
+		if _, err := rand.Read(key); err != nil {
+			return err
+		}
+		fmt.Println(base64.StdEncoding.EncodeToString(key))
+		return nil
+	case "compact":
+		return compactDatabase(cfg, *output)
+	}
+
+	storage, closeFn, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+	boltStorage, ok := storage.(*boltdb.Storage)
+	if !ok || boltStorage.Encryption() == nil {
+		return errors.New("at-rest encryption is not enabled; it requires DB_TYPE=boltdb and a storage key")
+	}
+	enc := boltStorage.Encryption()
+
+	ctx := context.Background()
+	switch args[0] {
+	case "rotate":
+		if err := enc.Rotate(ctx); err != nil {
+			return err
+		}
+		fmt.Printf("active data key is now %d\n", enc.Status().ActiveKey)
+		fallthrough
+	case "reencrypt":
+		start := time.Now()
+		n, err := enc.Reencrypt(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("re-encrypted %d record(s) in %s\n", n, time.Since(start).Round(time.Millisecond))
+		return nil
+	}
+
+	status := enc.Status()
+	if *asJSON {
+		return printJSON(newEncryptionStatusJSON(status))
+	}
+	return printTable([]string{"ACTIVE KEY", "DATA KEYS", "REENCRYPTION PENDING", "ROTATED AT"}, [][]string{{
+		strconv.FormatUint(uint64(status.ActiveKey), 10),
+		strconv.Itoa(status.DataKeys),
+		strconv.FormatBool(status.Pending),
+		status.RotatedAt.Local().Format("2006-01-02 15:04:05"),
+	}})
+}
+
+// encryptionStatusJSON 是加密状态的 JSON 输出格式，与管理员 API 一致。
+type encryptionStatusJSON struct {
+	ActiveKey uint32    `json:"active_key"`
+	DataKeys  int       `json:"data_keys"`
+	Pending   bool      `json:"reencryption_pending"`
+	RotatedAt time.Time `json:"rotated_at"`
+}
+
+func newEncryptionStatusJSON(status *core.EncryptionStatus) *encryptionStatusJSON {
+	if status == nil {
+		return nil
+	}
+	return &encryptionStatusJSON{status.ActiveKey, status.DataKeys, status.Pending, status.RotatedAt}
+}
+
+// compactDatabase 把已配置的 BoltDB 数据库复制到 output，新文件不包含空闲页。
+func compactDatabase(cfg *config.Config, output string) error {
+	if output == "" {
+		return errors.New("compact requires -o")
+	}
+	if cfg.DBType != "boltdb" {
+		return errors.New("compact only applies to DB_TYPE=boltdb")
+	}
+	if _, err := os.Stat(output); err == nil {
+		return fmt.Errorf("%s already exists", output)
+	}
+
+	src, err := bbolt.Open(cfg.DBPath, 0600, &bbolt.Options{Timeout: time.Second, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("could not open boltdb %s: %w", cfg.DBPath, err)
+	}
+	defer src.Close()
+	dst, err := bbolt.Open(output, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	if err := bbolt.Compact(dst, src, 64<<20); err != nil {
+		dst.Close()
+		os.Remove(output)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	before, _ := os.Stat(cfg.DBPath)
+	after, err := os.Stat(output)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s (%d bytes, was %d)\n", output, after.Size(), before.Size())
+	return nil
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"easy-password-backend/config"
+	"easy-password-backend/internal/kms"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+const keysUsage = `usage:
+  epadmin keys generate [-alg <EdDSA|ES256>]
+  epadmin keys check [-json]
+  epadmin keys init
+  epadmin keys list [-json]
+  epadmin keys add -purpose <jwt|storage> [-alg <HS256|EdDSA|ES256>] [-from-env] [-activate]
+  epadmin keys activate -purpose <jwt|storage> <kid>
+  epadmin keys remove -purpose <jwt|storage> <kid>
+
+Server keys come from KEY_PROVIDER: env (JWT_SIGNING_KEY or JWT_SECRET,
+DB_ENCRYPTION_KEY, and their _FILE and _PREVIOUS variants), keyring (the file at
+KEYRING_PATH, encrypted with KEYRING_PASSPHRASE) or transit (a Vault transit
+compatible service at TRANSIT_ADDR). check signs and encrypts with the
+configured provider.
+
+generate prints a random base64 key for JWT_SECRET or DB_ENCRYPTION_KEY, or with
+-alg a PKCS#8 PEM private key for JWT_SIGNING_KEY. Tokens signed with EdDSA or
+ES256 keys can be verified by other services with the public keys published at
+/.well-known/jwks.json.
+
+init, list, add, activate and remove manage the keyring file. add generates a
+new key (JWT keys default to HS256); -from-env imports the keys currently set in
+the environment instead, keeping their kids, so switching from env to keyring
+needs no re-encryption and does not invalidate tokens. Retired keys stay usable
+for verification and decryption until they are removed.
+
+transit-standin serves the subset of the Vault transit API the server uses, with
+keys held in memory, for testing KEY_PROVIDER=transit without Vault.`
+
+func runKeys(cfg *config.Config, args []string) error {
+	if len(args) < 1 {
+		return errors.New(keysUsage)
+	}
+
+	fs := flag.NewFlagSet("keys "+args[0], flag.ContinueOnError)
+	var asJSON, fromEnv, activate *bool
+	var purpose, alg *string
+	switch args[0] {
+	case "generate":
+		alg = fs.String("alg", "", "generate a PEM private key for EdDSA or ES256 instead of a random secret")
+	case "init":
+	case "check", "list":
+		asJSON = outputFlag(fs)
+	case "add":
+		purpose = fs.String("purpose", "", "key purpose: jwt or storage")
+		alg = fs.String("alg", string(kms.AlgHS256), "JWT key algorithm: HS256, EdDSA or ES256")
+		fromEnv = fs.Bool("from-env", false, "import the keys set in the environment instead of generating one")
+		activate = fs.Bool("activate", false, "make the new key the active key of its purpose")
+	case "activate", "remove":
+		purpose = fs.String("purpose", "", "key purpose: jwt or storage")
+	default:
+		return errors.New(keysUsage)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if purpose != nil && !kms.Purpose(*purpose).Valid() {
+		return errors.New("-purpose must be jwt or storage")
+	}
+
+	switch args[0] {
+	case "generate":
+		if *alg != "" {
+			der, err := kms.GenerateSigningKey(kms.Algorithm(*alg))
+			if err != nil {
+				return err
+			}
+			return pem.Encode(os.Stdout, &pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		}
+		key, err := kms.GenerateKey()
+		if err != nil {
+			return err
+		}
+		fmt.Println(base64.StdEncoding.EncodeToString(key))
+		return nil
+	case "check":
+		return checkKeyProvider(cfg, *asJSON)
+	}
+
+	passphrase, err := kms.KeyringPassphrase(cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.KeyringPath == "" {
+		return errors.New("KEYRING_PATH is required")
+	}
+	if args[0] == "init" {
+		if _, err := kms.CreateKeyring(cfg.KeyringPath, passphrase); err != nil {
+			return err
+		}
+		fmt.Printf("created empty keyring %s\n", cfg.KeyringPath)
+		return nil
+	}
+	keyring, err := kms.OpenKeyring(cfg.KeyringPath, passphrase)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		entries := keyring.Entries()
+		if *asJSON {
+			type keyView struct {
+				ID        string        `json:"id"`
+				Purpose   kms.Purpose   `json:"purpose"`
+				Algorithm kms.Algorithm `json:"algorithm"`
+				Active    bool          `json:"active"`
+				CreatedAt time.Time     `json:"created_at"`
+			}
+			views := make([]keyView, 0, len(entries))
+			for _, entry := range entries {
+				views = append(views, keyView{entry.ID, entry.Purpose, entry.KeyAlgorithm(), entry.Active, entry.CreatedAt})
+			}
+			return printJSON(views)
+		}
+		rows := make([][]string, 0, len(entries))
+		for _, entry := range entries {
+			state := "retired"
+			if entry.Active {
+				state = "active"
+			}
+			rows = append(rows, []string{string(entry.Purpose), entry.ID, string(entry.KeyAlgorithm()), state, entry.CreatedAt.Local().Format("2006-01-02 15:04:05")})
+		}
+		return printTable([]string{"PURPOSE", "KID", "ALG", "STATE", "CREATED"}, rows)
+
+	case "add":
+		var keys []kms.Key
+		if *fromEnv {
+			env, err := kms.NewEnvProvider(cfg)
+			if err != nil {
+				return err
+			}
+			// 先加入旧密钥，最后加入当前密钥，使当前密钥成为活动密钥。
+			envKeys := env.LocalKeys(kms.Purpose(*purpose))
+			if len(envKeys) == 0 {
+				return fmt.Errorf("no %s key is set in the environment", *purpose)
+			}
+			for i := len(envKeys) - 1; i >= 0; i-- {
+				if len(envKeys[i].Material) == 0 {
+					fmt.Printf("skipped %s public key %s: the keyring only holds private keys\n", *purpose, envKeys[i].ID)
+					continue
+				}
+				keys = append(keys, envKeys[i])
+			}
+		} else {
+			key, err := newKeyringKey(kms.Purpose(*purpose), kms.Algorithm(*alg))
+			if err != nil {
+				return err
+			}
+			keys = append(keys, key)
+		}
+		for i, key := range keys {
+			last := i == len(keys)-1
+			entry, err := keyring.Add(kms.Purpose(*purpose), key, last && (*activate || *fromEnv))
+			if err != nil {
+				return err
+			}
+			state := "retired"
+			if entry.Active {
+				state = "active"
+			}
+			fmt.Printf("added %s %s key %s (%s)\n", entry.Purpose, entry.Algorithm, entry.ID, state)
+		}
+
+	case "activate", "remove":
+		if fs.NArg() != 1 {
+			return errors.New(keysUsage)
+		}
+		if args[0] == "activate" {
+			err = keyring.Activate(kms.Purpose(*purpose), fs.Arg(0))
+		} else {
+			err = keyring.Remove(kms.Purpose(*purpose), fs.Arg(0))
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%sd %s key %s\n", args[0], *purpose, fs.Arg(0))
+	}
+
+	if err := keyring.Save(); err != nil {
+		return err
+	}
+	fmt.Println("restart the server to use the updated keyring")
+	return nil
+}
+
+// newKeyringKey 生成 purpose 的新密钥。存储密钥总是 A256GCM，JWT 密钥使用 alg。
+func newKeyringKey(purpose kms.Purpose, alg kms.Algorithm) (kms.Key, error) {
+	if purpose == kms.PurposeJWT && alg.Asymmetric() {
+		der, err := kms.GenerateSigningKey(alg)
+		if err != nil {
+			return kms.Key{}, err
+		}
+		return kms.NewSigningKey(der)
+	}
+	if purpose == kms.PurposeStorage {
+		alg = kms.AlgA256GCM
+	} else if alg != kms.AlgHS256 {
+		return kms.Key{}, errors.New("-alg must be HS256, EdDSA or ES256")
+	}
+	material, err := kms.GenerateKey()
+	if err != nil {
+		return kms.Key{}, err
+	}
+	return kms.NewKey(alg, material), nil
+}
+
+// keyCheck 是对一个用途的检查结果。
+type keyCheck struct {
+	Purpose   kms.Purpose   `json:"purpose"`
+	KeyID     string        `json:"kid,omitempty"`
+	Algorithm kms.Algorithm `json:"alg,omitempty"`
+	Result    string        `json:"result"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// checkKeyProvider 用已配置的提供者签名、验证、加密和解密，并确认篡改的数据会被拒绝。
+func checkKeyProvider(cfg *config.Config, asJSON bool) error {
+	keys, err := kms.FromConfig(cfg)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	var checks []keyCheck
+	failed := 0
+	for _, purpose := range kms.Purposes {
+		check := keyCheck{Purpose: purpose}
+		var key kms.KeyInfo
+		key, err = keys.ActiveKey(ctx, purpose)
+		check.KeyID, check.Algorithm = key.ID, key.Algorithm
+		switch {
+		case errors.Is(err, kms.ErrNoKey):
+			check.Result, err = "not configured", nil
+		case err == nil:
+			err = exerciseKey(ctx, keys, purpose, check.KeyID)
+		}
+		if err != nil {
+			check.Result = "FAIL"
+			check.Error = err.Error()
+			failed++
+		} else if check.Result == "" {
+			check.Result = "ok"
+		}
+		checks = append(checks, check)
+	}
+
+	if asJSON {
+		if err := printJSON(checks); err != nil {
+			return err
+		}
+	} else {
+		rows := make([][]string, 0, len(checks))
+		for _, check := range checks {
+			rows = append(rows, []string{string(check.Purpose), check.KeyID, string(check.Algorithm), check.Result, check.Error})
+		}
+		if err := printTable([]string{"PURPOSE", "KID", "ALG", "RESULT", "ERROR"}, rows); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d purpose(s) failed", failed)
+	}
+	return nil
+}
+
+// exerciseKey 检查 purpose 的活动密钥 kid：JWT 密钥用于签名，存储密钥用于加密。
+func exerciseKey(ctx context.Context, keys kms.KeyProvider, purpose kms.Purpose, kid string) error {
+	data := []byte("epadmin keys check")
+	if purpose == kms.PurposeJWT {
+		sig, err := keys.Sign(ctx, purpose, kid, data)
+		if err != nil {
+			return fmt.Errorf("sign: %w", err)
+		}
+		if err := keys.Verify(ctx, purpose, kid, data, sig); err != nil {
+			return fmt.Errorf("verify: %w", err)
+		}
+		sig[0] ^= 0xff
+		if err := keys.Verify(ctx, purpose, kid, data, sig); !errors.Is(err, kms.ErrInvalidSignature) {
+			return fmt.Errorf("a tampered signature was not rejected: %v", err)
+		}
+		return nil
+	}
+
+	aad := []byte("epadmin")
+	encKID, ciphertext, err := keys.Encrypt(ctx, purpose, data, aad)
+	if err != nil {
+		return fmt.Errorf("encrypt: %w", err)
+	}
+	plaintext, err := keys.Decrypt(ctx, purpose, encKID, ciphertext, aad)
+	if err != nil {
+		return fmt.Errorf("decrypt: %w", err)
+	}
+	if !bytes.Equal(plaintext, data) {
+		return errors.New("decrypt returned different data")
+	}
+	if _, err := keys.Decrypt(ctx, purpose, encKID, ciphertext, []byte("other")); !errors.Is(err, kms.ErrDecrypt) {
+		return fmt.Errorf("mismatched associated data was not rejected: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// epadmin 是直接操作已配置存储后端的运维命令行工具。
+package main
+
+import (
+	"easy-password-backend/config"
+	"easy-password-backend/pkg/logger"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+const usage = `Usage: epadmin <command> [arguments]
+
+Commands:
+  users list             list users, optionally filtered with -q
+  users find             show a single user by id, username or email
+  users disable          suspend a user: block logins and invalidate their tokens
+  users unlock           set a user back to active
+  users status           set a user to active, suspended, locked or pending_deletion
+  users expire-sessions  invalidate every token issued to a user so far
+  users role             grant or revoke the admin role
+  items                  show the number of vault items per user
+  purge                  delete expired verification codes and password reset tokens
+  stats                  print record counts and storage size
+  backup snapshot        write a consistent snapshot, locally or from a running server
+  backup check           validate every record and the audit chain in a snapshot
+  migrate                copy all data into an empty BoltDB, SQLite or PostgreSQL database
+  audit verify           walk the audit hash chain and report the first broken link
+  schema status          list schema migrations and whether they have been applied
+  schema up              apply pending schema migrations
+  schema down            roll schema migrations back to a given version
+  openapi                print the OpenAPI document
+  encryption             manage BoltDB at-rest encryption: genkey, status, rotate, reencrypt, compact
+  keys                   check the key provider and manage the local keyring of server keys
+
+Commands that print data accept -json for machine-readable output.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg := config.Load()
+	logger.Init(slog.LevelWarn, cfg.LogFormat, os.Stderr)
+
+	var err error
+	switch os.Args[1] {
+	case "users":
+		err = runUsers(cfg, os.Args[2:])
+	case "items":
+		err = runItems(cfg, os.Args[2:])
+	case "purge":
+		err = runPurge(cfg, os.Args[2:])
+	case "stats":
+		err = runStats(cfg, os.Args[2:])
+	case "backup":
+		err = runBackup(cfg, os.Args[2:])
+	case "migrate":
+		err = runMigrate(cfg, os.Args[2:])
+	case "audit":
+		err = runAudit(cfg, os.Args[2:])
+	case "schema":
+		err = runSchema(cfg, os.Args[2:])
+	case "openapi":
+		err = runOpenAPI(cfg, os.Args[2:])
+	case "encryption":
+		err = runEncryption(cfg, os.Args[2:])
+	case "keys":
+		err = runKeys(cfg, os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"easy-password-backend/config"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func runPurge(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ContinueOnError)
+	asJSON := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	storage, closeFn, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	ctx := context.Background()
+	now := time.Now()
+	codes, err := storage.VerificationCode().DeleteExpired(ctx, now)
+	if err != nil {
+		return fmt.Errorf("purge verification codes: %w", err)
+	}
+	tokens, err := storage.User().ClearExpiredResetTokens(ctx, now)
+	if err != nil {
+		return fmt.Errorf("purge reset tokens: %w", err)
+	}
+
+	if *asJSON {
+		return printJSON(map[string]int64{
+			"verification_codes": codes,
+			"reset_tokens":       tokens,
+		})
+	}
+	return printTable([]string{"RECORD", "PURGED"}, [][]string{
+		{"verification codes", strconv.FormatInt(codes, 10)},
+		{"reset tokens", strconv.FormatInt(tokens, 10)},
+	})
+}
+
+func runItems(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("items", flag.ContinueOnError)
+	asJSON := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	storage, closeFn, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	ctx := context.Background()
+	counts, err := storage.Vault().CountByUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	type userItems struct {
+		UserID   uuid.UUID `json:"user_id"`
+		Username string    `json:"username"`
+		Items    int64     `json:"items"`
+	}
+	result := make([]userItems, 0, len(counts))
+	for userID, n := range counts {
+		entry := userItems{UserID: userID, Items: n}
+		// 用户已被删除但仍残留项目时用户名为空。
+		if user, err := storage.User().FindByID(ctx, userID); err == nil {
+			entry.Username = user.Username
+		}
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Items != result[j].Items {
+			return result[i].Items > result[j].Items
+		}
+		return result[i].Username < result[j].Username
+	})
+
+	if *asJSON {
+		return printJSON(result)
+	}
+	rows := make([][]string, len(result))
+	for i, r := range result {
+		rows[i] = []string{r.UserID.String(), r.Username, strconv.FormatInt(r.Items, 10)}
+	}
+	return printTable([]string{"USER ID", "USERNAME", "ITEMS"}, rows)
+}
+
+func runStats(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	asJSON := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	storage, closeFn, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	stats, err := storage.Stats(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(struct {
+			Backend    string                `json:"backend"`
+			SizeBytes  int64                 `json:"size_bytes"`
+			Records    map[string]int64      `json:"records"`
+			Encryption *encryptionStatusJSON `json:"encryption,omitempty"`
+		}{stats.Backend, stats.SizeBytes, stats.Records, newEncryptionStatusJSON(stats.Encryption)})
+	}
+
+	names := make([]string, 0, len(stats.Records))
+	for name := range stats.Records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rows := make([][]string, len(names))
+	for i, name := range names {
+		rows[i] = []string{name, strconv.FormatInt(stats.Records[name], 10)}
+	}
+	fmt.Printf("backend: %s\nsize:    %d bytes\n", stats.Backend, stats.SizeBytes)
+	if stats.Encryption != nil {
+		fmt.Printf("encryption: data key %d", stats.Encryption.ActiveKey)
+		if stats.Encryption.Pending {
+			fmt.Print(", re-encryption pending")
+		}
+		fmt.Println()
+	}
+	fmt.Println()
+	return printTable([]string{"TABLE", "RECORDS"}, rows)
+}
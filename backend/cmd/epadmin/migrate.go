@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"easy-password-backend/config"
+	"easy-password-backend/internal/transfer"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const migrateUsage = `usage: epadmin migrate -to <boltdb|sqlite|postgres> [-to-path file] [-to-url dsn] [-dry-run] [-json]
+
+Copies every record from the configured storage (DB_TYPE) into an empty target
+storage, preserving IDs and timestamps, then verifies counts and checksums.`
+
+func runMigrate(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, migrateUsage); fs.PrintDefaults() }
+	toType := fs.String("to", "", "target backend: boltdb, sqlite or postgres")
+	toPath := fs.String("to-path", "", "target BoltDB or SQLite file")
+	toURL := fs.String("to-url", "", "target PostgreSQL connection string")
+	dryRun := fs.Bool("dry-run", false, "read the source and print counts and checksums without writing")
+	asJSON := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	target := *cfg
+	target.DBType = *toType
+	switch *toType {
+	case "boltdb", "sqlite":
+		if *toPath == "" {
+			return fmt.Errorf("-to-path is required for a %s target", *toType)
+		}
+		target.DBPath = *toPath
+		if cfg.DBType == *toType && cfg.DBPath == *toPath {
+			return errors.New("source and target are the same database")
+		}
+	case "postgres":
+		if *toURL == "" {
+			return errors.New("-to-url is required for a postgres target")
+		}
+		target.DatabaseURL = *toURL
+		if cfg.DBType == "postgres" && cfg.DatabaseURL == *toURL {
+			return errors.New("source and target are the same database")
+		}
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	src, closeSrc, err := openStorage(cfg)
+	if err != nil {
+		return fmt.Errorf("open source: %w", err)
+	}
+	defer closeSrc()
+
+	opts := transfer.Options{DryRun: *dryRun}
+	if !*asJSON {
+		opts.Progress = func(r *transfer.KindResult) {
+			fmt.Fprintf(os.Stderr, "%-20s %d records\n", r.Kind, r.SourceCount)
+		}
+	}
+
+	var results []*transfer.KindResult
+	if *dryRun {
+		results, err = transfer.Run(context.Background(), src.Bulk(), nil, opts)
+	} else {
+		dst, closeDst, openErr := openStorageWithSchema(&target, true)
+		if openErr != nil {
+			return fmt.Errorf("open target: %w", openErr)
+		}
+		defer closeDst()
+		results, err = transfer.Run(context.Background(), src.Bulk(), dst.Bulk(), opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	verified := true
+	for _, r := range results {
+		if !*dryRun && !r.Verified() {
+			verified = false
+		}
+	}
+
+	if *asJSON {
+		type kindJSON struct {
+			Kind           string `json:"kind"`
+			SourceCount    int64  `json:"source_count"`
+			SourceChecksum string `json:"source_checksum"`
+			TargetCount    *int64 `json:"target_count,omitempty"`
+			TargetChecksum string `json:"target_checksum,omitempty"`
+		}
+		out := struct {
+			DryRun   bool       `json:"dry_run"`
+			Verified bool       `json:"verified"`
+			Kinds    []kindJSON `json:"kinds"`
+		}{DryRun: *dryRun, Verified: verified && !*dryRun}
+		for _, r := range results {
+			k := kindJSON{Kind: string(r.Kind), SourceCount: r.SourceCount, SourceChecksum: r.SourceChecksum}
+			if !*dryRun {
+				k.TargetCount = &r.TargetCount
+				k.TargetChecksum = r.TargetChecksum
+			}
+			out.Kinds = append(out.Kinds, k)
+		}
+		if err := printJSON(out); err != nil {
+			return err
+		}
+	} else {
+		rows := make([][]string, len(results))
+		for i, r := range results {
+			status := "dry run"
+			if !*dryRun {
+				status = "ok"
+				if !r.Verified() {
+					status = fmt.Sprintf("MISMATCH (target %d, %s)", r.TargetCount, r.TargetChecksum[:16])
+				}
+			}
+			rows[i] = []string{string(r.Kind), strconv.FormatInt(r.SourceCount, 10), r.SourceChecksum[:16], status}
+		}
+		if err := printTable([]string{"KIND", "RECORDS", "CHECKSUM", "STATUS"}, rows); err != nil {
+			return err
+		}
+	}
+
+	if !verified {
+		return errors.New("target does not match source")
+	}
+	return nil
+}
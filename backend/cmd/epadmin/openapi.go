@@ -0,0 +1,33 @@
+package main
+
+import (
+	v1 "easy-password-backend/api/v1"
+	"easy-password-backend/config"
+	"flag"
+	"fmt"
+	"os"
+)
+
+const openAPIUsage = `usage: epadmin openapi [-o file]
+
+Prints the OpenAPI document that the server serves at /api/v1/openapi.json.`
+
+func runOpenAPI(_ *config.Config, args []string) error {
+	fs := flag.NewFlagSet("openapi", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, openAPIUsage); fs.PrintDefaults() }
+	output := fs.String("o", "", "write the document to this file instead of standard output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	data, err := v1.OpenAPIDocument()
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*output, data, 0o644)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"easy-password-backend/config"
+	"easy-password-backend/internal/repository/schema"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+)
+
+const schemaUsage = `usage:
+  epadmin schema status [-json]
+  epadmin schema up [-to version]
+  epadmin schema down -to version`
+
+func runSchema(cfg *config.Config, args []string) error {
+	if len(args) < 1 {
+		return errors.New(schemaUsage)
+	}
+
+	fs := flag.NewFlagSet("schema "+args[0], flag.ContinueOnError)
+	var jsonOut *bool
+	target := -1
+	switch args[0] {
+	case "status":
+		jsonOut = outputFlag(fs)
+	case "up":
+		fs.IntVar(&target, "to", 0, "migrate up to this version (default latest)")
+	case "down":
+		fs.IntVar(&target, "to", -1, "roll back every migration above this version (required; 0 removes all)")
+	default:
+		return errors.New(schemaUsage)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if target < 0 && args[0] == "down" {
+		return errors.New("schema down requires -to")
+	}
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	migrator, err := db.Migrator()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx, target)
+		return printMigrations("applied", applied, err)
+	case "down":
+		reverted, err := migrator.Down(ctx, target)
+		return printMigrations("rolled back", reverted, err)
+	}
+
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if *jsonOut {
+		return printJSON(status)
+	}
+	rows := make([][]string, 0, len(status))
+	for _, migration := range status {
+		applied := "pending"
+		if migration.AppliedAt != nil {
+			applied = migration.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		rows = append(rows, []string{strconv.Itoa(migration.Version), migration.Name, applied})
+	}
+	if err := printTable([]string{"VERSION", "NAME", "APPLIED"}, rows); err != nil {
+		return err
+	}
+	fmt.Printf("\n%d of %d migrations pending\n", len(schema.Pending(status)), len(status))
+	return nil
+}
+
+// printMigrations 列出已执行的步骤；err 不为 nil 时这些是失败之前完成的步骤。
+func printMigrations(verb string, migrations []schema.Migration, err error) error {
+	for _, migration := range migrations {
+		fmt.Printf("%s %d %s\n", verb, migration.Version, migration.Name)
+	}
+	if err == nil && len(migrations) == 0 {
+		fmt.Println("nothing to do: schema is already at the requested version")
+	}
+	return err
+}
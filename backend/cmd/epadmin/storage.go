@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"easy-password-backend/config"
+	"easy-password-backend/internal/kms"
+	"easy-password-backend/internal/repository"
+	"errors"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+	"gorm.io/gorm"
+)
+
+// database 是按配置打开的数据库连接。
+type database struct {
+	cfg    *config.Config
+	gormDB *gorm.DB
+	boltDB *bbolt.DB
+}
+
+// openDatabase 按配置打开数据库，不检查 schema 版本。
+func openDatabase(cfg *config.Config) (*database, error) {
+	db := &database{cfg: cfg}
+	var err error
+	switch cfg.DBType {
+	case "postgres":
+		db.gormDB, err = repository.Connect(cfg)
+		if err != nil {
+			return nil, err
+		}
+	case "sqlite":
+		db.gormDB, err = repository.ConnectSQLite(cfg)
+		if err != nil {
+			return nil, err
+		}
+	case "boltdb":
+		db.boltDB, err = repository.OpenBoltDB(cfg.DBPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not open boltdb %s: %w", cfg.DBPath, err)
+		}
+	case "memory":
+		// 内存存储只存在于服务进程中，epadmin 无法访问其中的数据。
+		return nil, errors.New("DB_TYPE=memory keeps data inside the server process; epadmin cannot open it")
+	default:
+		return nil, fmt.Errorf("unsupported DB_TYPE: %s", cfg.DBType)
+	}
+	return db, nil
+}
+
+// Close 释放数据库连接。
+func (db *database) Close() {
+	if db.boltDB != nil {
+		db.boltDB.Close()
+	}
+	if db.gormDB != nil {
+		if sqlDB, err := db.gormDB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+}
+
+// Migrator 返回该数据库的 schema 迁移器。
+func (db *database) Migrator() (repository.Migrator, error) {
+	return repository.NewMigrator(db.cfg, db.gormDB, db.boltDB)
+}
+
+// openStorage 按配置打开存储后端，并返回用于释放连接的关闭函数。
+// schema 不是最新版本时返回错误。
+func openStorage(cfg *config.Config) (repository.Storage, func(), error) {
+	return openStorageWithSchema(cfg, false)
+}
+
+// openStorageWithSchema 与 openStorage 相同；createSchema 为 true 时会先把 schema 迁移到最新版本。
+func openStorageWithSchema(cfg *config.Config, createSchema bool) (repository.Storage, func(), error) {
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	migrator, err := db.Migrator()
+	if err == nil {
+		_, err = repository.PrepareSchema(context.Background(), migrator, createSchema)
+	}
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	keys, err := kms.FromConfig(cfg)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	storage, err := repository.NewStorage(cfg, keys, db.gormDB, db.boltDB)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return storage, db.Close, nil
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"easy-password-backend/config"
+	"easy-password-backend/internal/audit"
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/repository"
+	"easy-password-backend/internal/service"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// adminActor 标识审计记录中由本工具执行的操作。
+const adminActor = "epadmin"
+
+const usersUsage = `usage:
+  epadmin users list [-q query] [-offset n] [-limit n] [-json]
+  epadmin users find [-json] <id|username|email>
+  epadmin users disable [-reason text] <id|username|email>
+  epadmin users unlock [-reason text] <id|username|email>
+  epadmin users status [-reason text] <id|username|email> <active|suspended|locked|pending_deletion>
+  epadmin users expire-sessions <id|username|email>
+  epadmin users role <id|username|email> <user|admin>`
+
+// userView 是用户在命令输出中的表示，不包含任何认证材料。
+type userView struct {
+	ID                    uuid.UUID       `json:"id"`
+	Username              string          `json:"username"`
+	Email                 string          `json:"email"`
+	Status                core.UserStatus `json:"status"`
+	Role                  core.UserRole   `json:"role"`
+	RequireDeviceApproval bool            `json:"require_device_approval"`
+	SessionsRevokedAt     *time.Time      `json:"sessions_revoked_at,omitempty"`
+	Items                 int64           `json:"items"`
+	CreatedAt             time.Time       `json:"created_at"`
+	UpdatedAt             time.Time       `json:"updated_at"`
+}
+
+func newUserView(user *core.User, items int64) userView {
+	return userView{
+		ID:                    user.ID,
+		Username:              user.Username,
+		Email:                 user.Email,
+		Status:                user.AccountStatus(),
+		Role:                  user.AccountRole(),
+		RequireDeviceApproval: user.RequireDeviceApproval,
+		SessionsRevokedAt:     user.SessionsRevokedAt,
+		Items:                 items,
+		CreatedAt:             user.CreatedAt,
+		UpdatedAt:             user.UpdatedAt,
+	}
+}
+
+func runUsers(cfg *config.Config, args []string) error {
+	if len(args) < 1 {
+		return errors.New(usersUsage)
+	}
+
+	storage, closeFn, err := openStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	ctx := context.Background()
+	switch args[0] {
+	case "list":
+		return listUsers(ctx, storage, args[1:])
+	case "find":
+		return findUser(ctx, storage, args[1:])
+	case "disable":
+		return setUserStatus(ctx, storage, "users disable", args[1:], core.UserStatusSuspended)
+	case "unlock":
+		return setUserStatus(ctx, storage, "users unlock", args[1:], core.UserStatusActive)
+	case "status":
+		return setUserStatus(ctx, storage, "users status", args[1:], "")
+	case "expire-sessions":
+		return expireSessions(ctx, storage, args[1:])
+	case "role":
+		return setUserRole(ctx, storage, args[1:])
+	default:
+		return errors.New(usersUsage)
+	}
+}
+
+func listUsers(ctx context.Context, storage repository.Storage, args []string) error {
+	fs := flag.NewFlagSet("users list", flag.ContinueOnError)
+	query := fs.String("q", "", "only users whose username or email contains this text")
+	offset := fs.Int("offset", 0, "number of users to skip")
+	limit := fs.Int("limit", 50, "maximum number of users to print (0 for all)")
+	asJSON := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	users, total, err := storage.User().List(ctx, *query, *offset, *limit)
+	if err != nil {
+		return err
+	}
+	counts, err := storage.Vault().CountByUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	views := make([]userView, len(users))
+	for i := range users {
+		views[i] = newUserView(&users[i], counts[users[i].ID])
+	}
+	if *asJSON {
+		return printJSON(struct {
+			Total int64      `json:"total"`
+			Users []userView `json:"users"`
+		}{total, views})
+	}
+
+	rows := make([][]string, len(views))
+	for i, v := range views {
+		rows[i] = []string{
+			v.ID.String(), v.Username, v.Email, string(v.Status), string(v.Role),
+			strconv.FormatInt(v.Items, 10), v.CreatedAt.Format(time.DateTime),
+		}
+	}
+	if err := printTable([]string{"ID", "USERNAME", "EMAIL", "STATUS", "ROLE", "ITEMS", "CREATED"}, rows); err != nil {
+		return err
+	}
+	fmt.Printf("\n%d of %d users\n", len(views), total)
+	return nil
+}
+
+func findUser(ctx context.Context, storage repository.Storage, args []string) error {
+	fs := flag.NewFlagSet("users find", flag.ContinueOnError)
+	asJSON := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	user, err := resolveUser(ctx, storage, fs.Args())
+	if err != nil {
+		return err
+	}
+	counts, err := storage.Vault().CountByUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	v := newUserView(user, counts[user.ID])
+	if *asJSON {
+		return printJSON(v)
+	}
+	revoked := "-"
+	if v.SessionsRevokedAt != nil {
+		revoked = v.SessionsRevokedAt.Format(time.DateTime)
+	}
+	return printTable([]string{"FIELD", "VALUE"}, [][]string{
+		{"id", v.ID.String()},
+		{"username", v.Username},
+		{"email", v.Email},
+		{"status", string(v.Status)},
+		{"role", string(v.Role)},
+		{"device approval", strconv.FormatBool(v.RequireDeviceApproval)},
+		{"sessions revoked", revoked},
+		{"items", strconv.FormatInt(v.Items, 10)},
+		{"created", v.CreatedAt.Format(time.DateTime)},
+		{"updated", v.UpdatedAt.Format(time.DateTime)},
+	})
+}
+
+// setUserStatus 修改账户状态；status 为空时从最后一个参数读取目标状态。
+func setUserStatus(ctx context.Context, storage repository.Storage, name string, args []string, status core.UserStatus) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	reason := fs.String("reason", "", "reason recorded in the audit trail")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+	if status == "" {
+		if len(args) == 0 {
+			return errors.New(usersUsage)
+		}
+		status = core.UserStatus(args[len(args)-1])
+		args = args[:len(args)-1]
+		if !status.Valid() {
+			return fmt.Errorf("unknown status %q", status)
+		}
+	}
+
+	user, err := resolveUser(ctx, storage, args)
+	if err != nil {
+		return err
+	}
+
+	user, err = newAdminService(storage).SetUserStatus(ctx, user.ID, status, adminActor, *reason)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: %s\n", user.Username, user.AccountStatus())
+	return nil
+}
+
+func setUserRole(ctx context.Context, storage repository.Storage, args []string) error {
+	if len(args) != 2 {
+		return errors.New(usersUsage)
+	}
+	role := core.UserRole(args[1])
+	if !role.Valid() {
+		return fmt.Errorf("unknown role %q", role)
+	}
+	user, err := resolveUser(ctx, storage, args[:1])
+	if err != nil {
+		return err
+	}
+
+	user, err = newAdminService(storage).SetUserRole(ctx, user.ID, role, adminActor)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: %s\n", user.Username, user.AccountRole())
+	return nil
+}
+
+func newAdminService(storage repository.Storage) *service.AdminService {
+	return service.NewAdminService(storage.User(), storage.Vault(), storage.Device(), storage, audit.NewAuditService(storage.Audit()))
+}
+
+func expireSessions(ctx context.Context, storage repository.Storage, args []string) error {
+	user, err := resolveUser(ctx, storage, args)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.SessionsRevokedAt = &now
+	if err := storage.User().Update(ctx, user); err != nil {
+		return err
+	}
+
+	recordAdminEvent(ctx, storage, user.ID, core.AuditEventSessionsRevoke)
+	fmt.Printf("%s: all tokens issued before %s are now invalid\n", user.Username, now.Format(time.DateTime))
+	return nil
+}
+
+// resolveUser 按 ID、邮箱或用户名查找 args 中唯一的用户参数。
+func resolveUser(ctx context.Context, storage repository.Storage, args []string) (*core.User, error) {
+	if len(args) != 1 {
+		return nil, errors.New("expected exactly one user id, username or email")
+	}
+	ident := args[0]
+
+	var user *core.User
+	var err error
+	if id, parseErr := uuid.Parse(ident); parseErr == nil {
+		user, err = storage.User().FindByID(ctx, id)
+	} else if strings.Contains(ident, "@") {
+		user, err = storage.User().FindByEmail(ctx, ident)
+	} else {
+		user, err = storage.User().FindByUsername(ctx, ident)
+	}
+	if err == core.ErrUserNotFound {
+		return nil, fmt.Errorf("user %q not found", ident)
+	}
+	return user, err
+}
+
+// recordAdminEvent 在用户的审计链上记录一次管理操作。
+func recordAdminEvent(ctx context.Context, storage repository.Storage, userID uuid.UUID, eventType core.AuditEventType) {
+	audit.NewAuditService(storage.Audit()).Record(ctx, userID, eventType, map[string]string{"actor": adminActor})
+}
@@ -0,0 +1,172 @@
+// epimport 将其他密码管理器的导出文件导入 EasyPassword 保险库。
+// 条目在本地使用由主密码派生的密钥加密后，通过保险库导入接口一次性上传，服务端永远看不到明文。
+package main
+
+import (
+	"bufio"
+	"context"
+	"easy-password-backend/pkg/client"
+	"easy-password-backend/pkg/importer"
+	"easy-password-backend/pkg/vaultcrypto"
+	"easy-password-backend/pkg/vaultexport"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"golang.org/x/term"
+)
+
+func main() {
+	server := flag.String("server", "http://localhost:8080", "EasyPassword server URL")
+	identifier := flag.String("identifier", "", "username or email of the account to import into")
+	format := flag.String("format", "", "source format: bitwarden, 1pux, keepass or csv")
+	file := flag.String("file", "", "path of the export file")
+	passwordFile := flag.String("password-file", "", "read the master password from this file instead of EP_MASTER_PASSWORD or stdin")
+	dryRun := flag.Bool("dry-run", false, "parse the file and print a summary without uploading")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: epimport -format <format> -file <path> -identifier <user> [flags]\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*server, *identifier, importer.Format(*format), *file, *passwordFile, *dryRun); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(server, identifier string, format importer.Format, file, passwordFile string, dryRun bool) error {
+	if file == "" || format == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	result, err := importer.Parse(format, data)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Parsed %d items (%d skipped)\n", len(result.Items), result.Skipped)
+	if len(result.Items) > vaultexport.MaxItems {
+		return fmt.Errorf("export holds %d items, at most %d can be imported at once", len(result.Items), vaultexport.MaxItems)
+	}
+
+	if dryRun {
+		for _, item := range result.Items {
+			fmt.Printf("%-30s  %-30s  %s\n", item.Name, item.Account, item.Category)
+		}
+		return nil
+	}
+	if identifier == "" {
+		return errors.New("-identifier is required")
+	}
+
+	password, err := readMasterPassword(passwordFile)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	api := client.New(server, client.WithApprovalHandler(func(client.PendingApproval) {
+		fmt.Fprintln(os.Stderr, "Login from this device needs approval; follow the link in the email sent to your account.")
+	}))
+	salt, err := api.Salt(ctx, identifier)
+	if err != nil {
+		return err
+	}
+	key, err := vaultcrypto.DeriveKey(password, salt)
+	if err != nil {
+		return err
+	}
+	if err := api.Login(ctx, identifier, key.AuthHash(), client.Device{ID: "epimport", Name: "epimport", Type: "cli"}); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	env := &vaultexport.Envelope{
+		Format:     vaultexport.FormatName,
+		Version:    vaultexport.FormatVersion,
+		ExportedAt: now,
+		KDF:        vaultexport.DefaultKDF(salt),
+	}
+	for _, item := range result.Items {
+		encrypted, err := key.EncryptItem(item)
+		if err != nil {
+			return err
+		}
+		env.Items = append(env.Items, vaultexport.Item{
+			ID:            uuid.New(),
+			Category:      truncateCategory(item.Category),
+			EncryptedData: encrypted,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		})
+	}
+	if err := env.Seal(); err != nil {
+		return err
+	}
+	if err := env.Validate(); err != nil {
+		return err
+	}
+
+	imported, err := api.ImportVault(ctx, env)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Imported %d items\n", imported)
+	return nil
+}
+
+// truncateCategory 把分类截断到不超过 vaultexport.MaxCategoryLength 字节，且不切断多字节字符，
+// 否则得到的无效 UTF-8 会被 PostgreSQL 拒绝，使整个导入失败。
+func truncateCategory(category string) string {
+	if len(category) <= vaultexport.MaxCategoryLength {
+		return category
+	}
+	cut := vaultexport.MaxCategoryLength
+	for cut > 0 && !utf8.RuneStart(category[cut]) {
+		cut--
+	}
+	return category[:cut]
+}
+
+// readMasterPassword 依次从 -password-file、EP_MASTER_PASSWORD 环境变量和标准输入读取主密码。
+// 标准输入是终端时不回显输入的密码。
+func readMasterPassword(passwordFile string) (string, error) {
+	if passwordFile != "" {
+		data, err := os.ReadFile(passwordFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	if password := os.Getenv("EP_MASTER_PASSWORD"); password != "" {
+		return password, nil
+	}
+
+	fmt.Fprint(os.Stderr, "Master password: ")
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		if len(password) == 0 {
+			return "", errors.New("no master password given")
+		}
+		return string(password), nil
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("no master password given")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"easy-password-backend/pkg/vaultexport"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateCategory(t *testing.T) {
+	tests := []struct {
+		name     string
+		category string
+		want     string
+	}{
+		{"short", "工作", "工作"},
+		{"ascii", strings.Repeat("a", 120), strings.Repeat("a", vaultexport.MaxCategoryLength)},
+		// 每个汉字 3 字节，第 34 个汉字跨过第 100 字节，整个去掉。
+		{"multibyte", strings.Repeat("保险库", 20), strings.Repeat("保险库", 11)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateCategory(tt.category)
+			if got != tt.want {
+				t.Fatalf("truncateCategory() = %q, want %q", got, tt.want)
+			}
+			if !utf8.ValidString(got) || len(got) > vaultexport.MaxCategoryLength {
+				t.Fatalf("truncateCategory() = %q is not valid UTF-8 within %d bytes", got, vaultexport.MaxCategoryLength)
+			}
+		})
+	}
+}
@@ -1,15 +1,19 @@
 package main
 
 import (
+	"context"
 	v1 "easy-password-backend/api/v1"
 	"easy-password-backend/config"
+	"easy-password-backend/internal/audit"
 	"easy-password-backend/internal/auth"
-	"easy-password-backend/internal/core"
 	"easy-password-backend/internal/email"
+	"easy-password-backend/internal/kms"
 	"easy-password-backend/internal/repository"
+	"easy-password-backend/internal/repository/boltdb"
 	"easy-password-backend/internal/service"
 	"easy-password-backend/pkg/logger"
 
+	"errors"
 	"log/slog"
 	"os"
 	"strings"
@@ -29,10 +33,29 @@ func main() {
 
 	slog.Info("Configuration loaded successfully")
 
+	// 初始化密钥提供者
+	keys, err := kms.FromConfig(cfg)
+	if err != nil {
+		slog.Error("could not initialize key provider", "provider", cfg.KeyProvider, "error", err)
+		os.Exit(1)
+	}
+	jwtKey, err := keys.ActiveKey(context.Background(), kms.PurposeJWT)
+	if errors.Is(err, kms.ErrNoKey) && cfg.KeyProvider == "env" {
+		slog.Warn("Neither JWT_SIGNING_KEY nor JWT_SECRET is set; signing tokens with a random key. Tokens will not survive a restart and are not shared between instances.")
+		keys, err = kms.WithEphemeralKey(keys, kms.PurposeJWT)
+		if err == nil {
+			jwtKey, err = keys.ActiveKey(context.Background(), kms.PurposeJWT)
+		}
+	}
+	if err != nil {
+		slog.Error("could not load the JWT signing key", "provider", cfg.KeyProvider, "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Key provider initialized", "provider", cfg.KeyProvider, "jwt_kid", jwtKey.ID, "jwt_alg", jwtKey.Algorithm)
+
 	// 初始化数据库连接
 	var gormDB *gorm.DB
 	var boltDB *bbolt.DB
-	var err error
 
 	switch cfg.DBType {
 	case "postgres":
@@ -42,58 +65,95 @@ func main() {
 			slog.Error("could not connect to postgres", "error", err)
 			os.Exit(1)
 		}
-		// 自动迁移模式
-		err = gormDB.AutoMigrate(&core.User{}, &core.VaultItem{}, &core.VerificationCode{})
+	case "sqlite":
+		slog.Info("Using SQLite database.", "path", cfg.DBPath)
+		gormDB, err = repository.ConnectSQLite(cfg)
 		if err != nil {
-			slog.Error("Failed to migrate database", "error", err)
+			slog.Error("could not open sqlite", "error", err)
 			os.Exit(1)
 		}
-		slog.Info("Database migrated successfully.")
 	case "boltdb":
 		slog.Info("Using BoltDB database.")
-		boltDB, err = repository.InitBoltDB(cfg.DBPath)
+		boltDB, err = repository.OpenBoltDB(cfg.DBPath)
 		if err != nil {
-			slog.Error("could not initialize boltdb", "error", err)
+			slog.Error("could not open boltdb", "error", err)
 			os.Exit(1)
 		}
 		defer boltDB.Close()
+
+		// 定期快照
+		if cfg.BackupDir != "" && cfg.BackupInterval > 0 {
+			slog.Info("Scheduled snapshots enabled", "dir", cfg.BackupDir, "interval", cfg.BackupInterval, "retain", cfg.BackupRetain)
+			go boltdb.RunScheduledSnapshots(context.Background(), boltDB, cfg.BackupDir, cfg.BackupInterval, cfg.BackupRetain)
+		}
+	case "memory":
+		slog.Warn("Using in-memory storage; all data will be lost when the server stops.")
 	default:
 		slog.Error("Unsupported DB_TYPE", "db_type", cfg.DBType)
 		os.Exit(1)
 	}
 
+	// 执行或检查 schema 迁移
+	migrator, err := repository.NewMigrator(cfg, gormDB, boltDB)
+	if err != nil {
+		slog.Error("could not create schema migrator", "error", err)
+		os.Exit(1)
+	}
+	applied, err := repository.PrepareSchema(context.Background(), migrator, cfg.DBAutoMigrate)
+	if err != nil {
+		slog.Error("Failed to prepare database schema", "error", err)
+		os.Exit(1)
+	}
+	for _, migration := range applied {
+		slog.Info("Applied schema migration", "version", migration.Version, "name", migration.Name)
+	}
+	slog.Info("Database schema is up to date.", "version", migrator.Latest())
+
 	// 创建存储后端
-	storage, err := repository.NewStorage(cfg, gormDB, boltDB)
+	storage, err := repository.NewStorage(cfg, keys, gormDB, boltDB)
 	if err != nil {
 		slog.Error("could not create storage", "error", err)
 		os.Exit(1)
 	}
+	if boltStorage, ok := storage.(*boltdb.Storage); ok {
+		if status := boltStorage.Encryption().Status(); status != nil {
+			slog.Info("At-rest encryption enabled", "active_key", status.ActiveKey, "pending", status.Pending)
+		}
+		// 继续刚启用加密或上次未完成的重新加密
+		boltStorage.ResumeReencryption()
+	}
 
 	// 初始化服务
 	emailService := email.NewSMTPEmailService(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPassword, cfg.SMTPFrom)
-	authService := auth.NewAuthService(storage.User(), storage.VerificationCode(), emailService, cfg)
+	auditService := audit.NewAuditService(storage.Audit())
+	authService := auth.NewAuthService(storage.User(), storage.VerificationCode(), storage.Device(), storage.LoginApproval(), storage.APIKey(), emailService, auditService, cfg, keys)
 	slog.Info("AuthService initialized.")
-	vaultService := service.NewVaultService(storage.Vault())
+	vaultService := service.NewVaultService(storage.Vault(), storage.User(), auditService)
 	slog.Info("VaultService initialized.")
+	deviceService := service.NewDeviceService(storage.Device(), auditService)
+	adminService := service.NewAdminService(storage.User(), storage.Vault(), storage.Device(), storage, auditService)
 
 	// 初始化 Gin 路由
 	gin.SetMode(gin.ReleaseMode) // 设置为生产模式
-	router := gin.Default()
+	router := gin.New()
+	router.Use(gin.Logger())
+
+	// 请求 ID 需要在恢复和日志中间件之前设置，以便写入错误响应和日志
+	router.Use(v1.RequestIDMiddleware())
+	router.Use(v1.RecoveryMiddleware())
 
 	// 使用日志中间件
 	router.Use(v1.LoggingMiddleware())
-
-	// 初始化处理程序
-	authHandler := v1.NewAuthHandler(authService)
-	authHandler.RegisterRoutes(router)
-
-	// 受保护的路由
-	vaultAPI := router.Group("/api/v1")
-	vaultAPI.Use(v1.AuthMiddleware(cfg))
-	{
-		vaultHandler := v1.NewVaultHandler(vaultService)
-		vaultHandler.RegisterRoutes(vaultAPI)
-	}
+	router.Use(v1.ClientInfoMiddleware())
+
+	// 注册全部 API 路由
+	v1.RegisterRoutes(router, v1.Services{
+		Auth:   authService,
+		Vault:  vaultService,
+		Device: deviceService,
+		Admin:  adminService,
+		Audit:  auditService,
+	})
 
 	// 启动服务器
 	slog.Info("Starting server", "address", ":8081")
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,6 +14,8 @@ type Config struct {
 	JWTExpiration  time.Duration
 	DBType         string
 	DBPath         string
+	// 为 true 时服务器启动时执行待执行的 schema 迁移，否则只检查 schema 是否最新
+	DBAutoMigrate  bool
 	SMTPHost       string
 	SMTPPort       int
 	SMTPUser       string
@@ -21,6 +24,34 @@ type Config struct {
 	FrontendURL    string
 	LogLevel       string
 	LogFormat      string
+	// 定期快照，仅适用于 BoltDB；BackupDir 为空或 BackupInterval 为 0 时不启用
+	BackupDir      string
+	BackupInterval time.Duration
+	BackupRetain   int
+	// BoltDB 静态加密的服务器密钥（base64 编码的 32 字节），也可以从 DBEncryptionKeyFile 读取；都为空时不加密
+	DBEncryptionKey     string
+	DBEncryptionKeyFile string
+	// 轮换服务器密钥后仍需保留的旧密钥，启动时用当前密钥重新包装由它们包装的数据密钥
+	DBEncryptionPreviousKeys []string
+	// JWT 签名密钥，也可以从 JWTSecretFile 读取；JWTPreviousSecrets 中的旧密钥只用于验证轮换前签发的令牌
+	JWTSecretFile      string
+	JWTPreviousSecrets []string
+	// PEM 编码的 Ed25519 或 P-256 私钥，设置后用非对称算法签发令牌，公钥发布在 /.well-known/jwks.json；
+	// 其后的 PEM 块只用于验证。也可以从 JWTSigningKeyFile 读取
+	JWTSigningKey     string
+	JWTSigningKeyFile string
+	// 服务器密钥的来源：env（默认，上面的环境变量）、keyring（本地加密密钥环）或 transit（Vault transit 兼容服务）
+	KeyProvider           string
+	KeyringPath           string
+	KeyringPassphrase     string
+	KeyringPassphraseFile string
+	TransitAddr           string
+	TransitMount          string
+	TransitToken          string
+	TransitTokenFile      string
+	// transit 服务中 JWT 签名和存储加密所用的密钥名
+	TransitJWTKey     string
+	TransitStorageKey string
 }
 
 // Load 从环境变量加载配置。
@@ -31,11 +62,6 @@ func Load() *Config {
 		dbURL = "host=localhost user=postgres password=postgres dbname=easypassword port=5432 sslmode=disable TimeZone=Asia/Shanghai"
 	}
 
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "a-very-secret-key" // 开发环境默认值
-	}
-
 	jwtExpStr := os.Getenv("JWT_EXPIRATION_HOURS")
 	jwtExpHours, err := strconv.Atoi(jwtExpStr)
 	if err != nil || jwtExpHours <= 0 {
@@ -48,10 +74,17 @@ func Load() *Config {
 	}
 
 	dbPath := os.Getenv("DB_PATH")
-	if dbPath == "" {
+	if dbPath == "" && dbType == "sqlite" {
+		dbPath = "easypassword.sqlite" // sqlite 的默认路径
+	} else if dbPath == "" {
 		dbPath = "easypassword.db" // boltdb 的默认路径
 	}
 
+	dbAutoMigrate, err := strconv.ParseBool(os.Getenv("DB_AUTO_MIGRATE"))
+	if err != nil {
+		dbAutoMigrate = true // 默认启动时自动迁移
+	}
+
 	smtpHost := os.Getenv("SMTP_HOST")
 	if smtpHost == "" {
 		smtpHost = "localhost" // dev default
@@ -93,12 +126,45 @@ func Load() *Config {
 		logFormat = "text"
 	}
 
+	backupDir := os.Getenv("BACKUP_DIR")
+
+	backupInterval, err := time.ParseDuration(os.Getenv("BACKUP_INTERVAL"))
+	if err != nil || backupInterval < 0 {
+		backupInterval = 24 * time.Hour
+	}
+
+	backupRetain, err := strconv.Atoi(os.Getenv("BACKUP_RETAIN"))
+	if err != nil || backupRetain <= 0 {
+		backupRetain = 7
+	}
+
+	keyProvider := os.Getenv("KEY_PROVIDER")
+	if keyProvider == "" {
+		keyProvider = "env"
+	}
+
+	transitMount := os.Getenv("TRANSIT_MOUNT")
+	if transitMount == "" {
+		transitMount = "transit"
+	}
+
+	transitJWTKey := os.Getenv("TRANSIT_JWT_KEY")
+	if transitJWTKey == "" {
+		transitJWTKey = "easypassword-jwt"
+	}
+
+	transitStorageKey := os.Getenv("TRANSIT_STORAGE_KEY")
+	if transitStorageKey == "" {
+		transitStorageKey = "easypassword-storage"
+	}
+
 	return &Config{
 		DatabaseURL:    dbURL,
-		JWTSecret:      jwtSecret,
+		JWTSecret:      os.Getenv("JWT_SECRET"),
 		JWTExpiration:  time.Hour * time.Duration(jwtExpHours),
 		DBType:         dbType,
 		DBPath:         dbPath,
+		DBAutoMigrate:  dbAutoMigrate,
 		SMTPHost:       smtpHost,
 		SMTPPort:       smtpPort,
 		SMTPUser:       smtpUser,
@@ -107,5 +173,38 @@ func Load() *Config {
 		FrontendURL:    frontendURL,
 		LogLevel:       logLevel,
 		LogFormat:      logFormat,
+		BackupDir:      backupDir,
+		BackupInterval: backupInterval,
+		BackupRetain:   backupRetain,
+
+		DBEncryptionKey:          os.Getenv("DB_ENCRYPTION_KEY"),
+		DBEncryptionKeyFile:      os.Getenv("DB_ENCRYPTION_KEY_FILE"),
+		DBEncryptionPreviousKeys: splitList(os.Getenv("DB_ENCRYPTION_PREVIOUS_KEYS")),
+
+		JWTSecretFile:         os.Getenv("JWT_SECRET_FILE"),
+		JWTPreviousSecrets:    splitList(os.Getenv("JWT_PREVIOUS_SECRETS")),
+		JWTSigningKey:         os.Getenv("JWT_SIGNING_KEY"),
+		JWTSigningKeyFile:     os.Getenv("JWT_SIGNING_KEY_FILE"),
+		KeyProvider:           keyProvider,
+		KeyringPath:           os.Getenv("KEYRING_PATH"),
+		KeyringPassphrase:     os.Getenv("KEYRING_PASSPHRASE"),
+		KeyringPassphraseFile: os.Getenv("KEYRING_PASSPHRASE_FILE"),
+		TransitAddr:           os.Getenv("TRANSIT_ADDR"),
+		TransitMount:          transitMount,
+		TransitToken:          os.Getenv("TRANSIT_TOKEN"),
+		TransitTokenFile:      os.Getenv("TRANSIT_TOKEN_FILE"),
+		TransitJWTKey:         transitJWTKey,
+		TransitStorageKey:     transitStorageKey,
+	}
+}
+
+// splitList 把逗号分隔的列表拆开，忽略空项。
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
 }
\ No newline at end of file
@@ -1,11 +1,18 @@
 package apierror
 
-import "net/http"
+import (
+	"maps"
+	"net/http"
+)
 
 // APIError 表示用于 API 响应的结构化错误。
+// Code 是稳定的机器可读错误码，客户端应依据它而不是 Message 判断错误类型；
+// Message 是面向开发者的英文说明，可能随版本调整。
 type APIError struct {
-	Code    int    `json:"-"` // HTTP 状态码，在 JSON 响应体中忽略
-	Message string `json:"message"`
+	Status  int               `json:"-"` // HTTP 状态码，在 JSON 响应体中忽略
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"` // 字段级的错误，例如请求校验失败的字段和规则
 }
 
 // Error 使 APIError 满足错误接口。
@@ -13,28 +20,70 @@ func (e *APIError) Error() string {
 	return e.Message
 }
 
+// Is 使带有不同 Details 的副本在 errors.Is 中与预定义的错误相等。
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	return ok && t.Code == e.Code
+}
+
+// WithDetails 返回附带 details 的副本，预定义的错误实例不会被修改。
+func (e *APIError) WithDetails(details map[string]string) *APIError {
+	copied := *e
+	copied.Details = maps.Clone(details)
+	return &copied
+}
+
 // New 创建一个新的 APIError。
-func New(code int, message string) *APIError {
+func New(status int, code, message string) *APIError {
 	return &APIError{
+		Status:  status,
 		Code:    code,
 		Message: message,
 	}
 }
 
-// 预定义的、可重用的错误实例。
+// 预定义的、可重用的错误实例。错误码一经发布不应修改。
 var (
-	ErrInvalidRequest     = New(http.StatusBadRequest, "Invalid request body")
-	ErrUnauthorized       = New(http.StatusUnauthorized, "Authorization is required")
-	ErrInvalidCredentials = New(http.StatusUnauthorized, "Invalid username or password")
-	ErrInvalidToken       = New(http.StatusUnauthorized, "Invalid or expired token")
-	ErrForbidden          = New(http.StatusForbidden, "Access denied")
-	ErrNotFound           = New(http.StatusNotFound, "Resource not found")
-	ErrUsernameExists     = New(http.StatusConflict, "Username already exists")
-	ErrEmailExists             = New(http.StatusConflict, "Email already exists")
-	ErrUserOrEmailExists       = New(http.StatusConflict, "Username or email already exists")
-	ErrInvalidVerificationCode = New(http.StatusBadRequest, "Invalid verification code")
-	ErrVerificationCodeExpired = New(http.StatusBadRequest, "Verification code has expired")
-	ErrInvalidResetToken       = New(http.StatusBadRequest, "Invalid or expired password reset token")
-	ErrResetTokenExpired       = New(http.StatusBadRequest, "Password reset token has expired")
-	ErrInternalServer          = New(http.StatusInternalServerError, "An unexpected error occurred")
-)
\ No newline at end of file
+	ErrInvalidRequest          = New(http.StatusBadRequest, "REQUEST_INVALID", "Invalid request body")
+	ErrUnauthorized            = New(http.StatusUnauthorized, "AUTH_REQUIRED", "Authorization is required")
+	ErrInvalidCredentials      = New(http.StatusUnauthorized, "AUTH_INVALID_CREDENTIALS", "Invalid username or password")
+	ErrInvalidToken            = New(http.StatusUnauthorized, "AUTH_INVALID_TOKEN", "Invalid or expired token")
+	ErrForbidden               = New(http.StatusForbidden, "ACCESS_DENIED", "Access denied")
+	ErrNotFound                = New(http.StatusNotFound, "RESOURCE_NOT_FOUND", "Resource not found")
+	ErrUsernameExists          = New(http.StatusConflict, "ACCOUNT_USERNAME_EXISTS", "Username already exists")
+	ErrEmailExists             = New(http.StatusConflict, "ACCOUNT_EMAIL_EXISTS", "Email already exists")
+	ErrUserOrEmailExists       = New(http.StatusConflict, "ACCOUNT_USERNAME_OR_EMAIL_EXISTS", "Username or email already exists")
+	ErrInvalidVerificationCode = New(http.StatusBadRequest, "VERIFICATION_CODE_INVALID", "Invalid verification code")
+	ErrVerificationCodeExpired = New(http.StatusBadRequest, "VERIFICATION_CODE_EXPIRED", "Verification code has expired")
+	ErrInvalidResetToken       = New(http.StatusBadRequest, "PASSWORD_RESET_TOKEN_INVALID", "Invalid or expired password reset token")
+	ErrResetTokenExpired       = New(http.StatusBadRequest, "PASSWORD_RESET_TOKEN_EXPIRED", "Password reset token has expired")
+	ErrLoginApprovalNotFound   = New(http.StatusNotFound, "LOGIN_APPROVAL_NOT_FOUND", "Login approval request not found")
+	ErrLoginApprovalExpired    = New(http.StatusGone, "LOGIN_APPROVAL_EXPIRED", "Login approval request has expired")
+	ErrInvalidApprovalCode     = New(http.StatusBadRequest, "LOGIN_APPROVAL_CODE_INVALID", "Invalid approval code")
+	ErrTooManyAttempts         = New(http.StatusTooManyRequests, "TOO_MANY_ATTEMPTS", "Too many attempts, please try again later")
+	ErrInvalidImportFile       = New(http.StatusBadRequest, "VAULT_IMPORT_INVALID_FILE", "Invalid vault import file")
+	ErrImportSaltMismatch      = New(http.StatusBadRequest, "VAULT_IMPORT_SALT_MISMATCH", "Import file was encrypted with a different master salt")
+	ErrExportTooLarge          = New(http.StatusUnprocessableEntity, "VAULT_EXPORT_TOO_LARGE", "Vault has too many items to export in one file")
+	ErrAccountSuspended        = New(http.StatusForbidden, "ACCOUNT_SUSPENDED", "Account has been suspended")
+	ErrAccountLocked           = New(http.StatusLocked, "ACCOUNT_LOCKED", "Account is locked")
+	ErrAccountPendingDeletion  = New(http.StatusForbidden, "ACCOUNT_PENDING_DELETION", "Account is scheduled for deletion")
+	ErrInvalidUserStatus       = New(http.StatusBadRequest, "ADMIN_INVALID_USER_STATUS", "Invalid account status")
+	ErrBackupNotSupported      = New(http.StatusNotImplemented, "ADMIN_BACKUP_NOT_SUPPORTED", "Online backup is only available for the BoltDB backend")
+	ErrEncryptionNotEnabled    = New(http.StatusConflict, "ADMIN_ENCRYPTION_NOT_ENABLED", "At-rest encryption is not enabled for this storage")
+	ErrAPIKeyNotFound          = New(http.StatusNotFound, "API_KEY_NOT_FOUND", "API key not found")
+	ErrInvalidAPIKey           = New(http.StatusUnauthorized, "API_KEY_INVALID_CREDENTIALS", "Invalid API key credentials")
+	ErrInvalidAPIKeyScope      = New(http.StatusBadRequest, "API_KEY_INVALID_SCOPE", "Invalid API key scope")
+	ErrInvalidAPIKeyExpiry     = New(http.StatusBadRequest, "API_KEY_INVALID_EXPIRY", "API key expiry must be in the future")
+	ErrAPIKeyExpired           = New(http.StatusUnauthorized, "API_KEY_EXPIRED", "API key has expired")
+	ErrAPIKeyNotAllowed        = New(http.StatusForbidden, "API_KEY_NOT_ALLOWED", "This endpoint cannot be accessed with an API key")
+	ErrInsufficientScope       = New(http.StatusForbidden, "API_KEY_INSUFFICIENT_SCOPE", "API key does not have the required scope")
+	ErrAPIKeyRestricted        = New(http.StatusForbidden, "API_KEY_RESTRICTED", "API key is not allowed to access this item")
+	ErrInvalidAuthHeader       = New(http.StatusUnauthorized, "AUTH_INVALID_HEADER", "Authorization header format must be Bearer {token}")
+	ErrInvalidItemID           = New(http.StatusBadRequest, "VAULT_INVALID_ITEM_ID", "Invalid item ID")
+	ErrInvalidDeviceID         = New(http.StatusBadRequest, "DEVICE_INVALID_ID", "Invalid device ID")
+	ErrInvalidAPIKeyID         = New(http.StatusBadRequest, "API_KEY_INVALID_ID", "Invalid API key ID")
+	ErrPasswordResetInactive   = New(http.StatusConflict, "ADMIN_PASSWORD_RESET_INACTIVE", "Password reset is only available for active accounts")
+	ErrRouteNotFound           = New(http.StatusNotFound, "ROUTE_NOT_FOUND", "No route matches the request path")
+	ErrMethodNotAllowed        = New(http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Method is not allowed for this path")
+	ErrInternalServer          = New(http.StatusInternalServerError, "INTERNAL_ERROR", "An unexpected error occurred")
+)
@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"context"
+	"easy-password-backend/internal/apierror"
+	"easy-password-backend/internal/core"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type clientInfoKey struct{}
+
+// ClientInfo 描述发起请求的客户端。
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+// WithClientInfo 返回一个携带客户端信息的上下文。
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// ClientInfoFromContext 从上下文中取出客户端信息，不存在时返回零值。
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
+
+// AuditService 负责写入和查询账户审计事件。
+type AuditService struct {
+	repo core.AuditRepository
+}
+
+// NewAuditService 创建一个新的 AuditService。
+func NewAuditService(repo core.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// Record 为用户写入一条审计事件。
+// 审计写入失败只记录日志，不会影响调用方的业务流程。
+func (s *AuditService) Record(ctx context.Context, userID uuid.UUID, eventType core.AuditEventType, metadata map[string]string) {
+	if s == nil || s.repo == nil {
+		return
+	}
+
+	info := ClientInfoFromContext(ctx)
+	event := &core.AuditEvent{
+		UserID:    userID,
+		Type:      eventType,
+		IPAddress: info.IPAddress,
+		UserAgent: info.UserAgent,
+		Metadata:  metadata,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.repo.Create(ctx, event); err != nil {
+		slog.Error("Failed to record audit event", "user_id", userID, "type", eventType, "error", err)
+	}
+}
+
+// ListUserEvents 分页返回用户自己的审计事件（按时间倒序）以及事件总数。
+func (s *AuditService) ListUserEvents(ctx context.Context, userID uuid.UUID, page, pageSize int) ([]core.AuditEvent, int64, error) {
+	events, total, err := s.repo.FindByUser(ctx, userID, (page-1)*pageSize, pageSize)
+	if err != nil {
+		slog.Error("Failed to list audit events", "user_id", userID, "error", err)
+		return nil, 0, apierror.ErrInternalServer
+	}
+	return events, total, nil
+}
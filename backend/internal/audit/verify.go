@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"context"
+	"easy-password-backend/internal/core"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ChainBreak 描述审计链上第一个被破坏的链接。
+type ChainBreak struct {
+	Sequence int64
+	EventID  uuid.UUID
+	UserID   uuid.UUID
+	Reason   string
+}
+
+func (b *ChainBreak) Error() string {
+	return fmt.Sprintf("audit chain broken at sequence %d (event %s): %s", b.Sequence, b.EventID, b.Reason)
+}
+
+// VerifyResult 汇总一次链校验的结果。
+type VerifyResult struct {
+	Events int64
+	Users  int
+	Break  *ChainBreak
+}
+
+// VerifyChain 按序号遍历全部审计事件，校验全局链和每个用户链的前驱哈希以及事件自身的哈希。
+// 遇到第一个断裂的链接时停止，并在结果的 Break 字段中返回。
+func VerifyChain(ctx context.Context, repo core.AuditRepository) (*VerifyResult, error) {
+	result := &VerifyResult{}
+	var prevSeq int64
+	var prevHash string
+	userHeads := make(map[uuid.UUID]string)
+
+	err := repo.ForEach(ctx, func(event *core.AuditEvent) error {
+		fail := func(format string, args ...any) error {
+			return &ChainBreak{
+				Sequence: event.Sequence,
+				EventID:  event.ID,
+				UserID:   event.UserID,
+				Reason:   fmt.Sprintf(format, args...),
+			}
+		}
+
+		if event.Sequence != prevSeq+1 {
+			return fail("expected sequence %d, found %d", prevSeq+1, event.Sequence)
+		}
+		if event.PrevHash != prevHash {
+			return fail("global previous hash mismatch")
+		}
+		if event.UserPrevHash != userHeads[event.UserID] {
+			return fail("user previous hash mismatch")
+		}
+		if event.Hash != event.ComputeHash() {
+			return fail("event hash mismatch, record content was modified")
+		}
+
+		prevSeq = event.Sequence
+		prevHash = event.Hash
+		userHeads[event.UserID] = event.Hash
+		result.Events++
+		return nil
+	})
+
+	var chainBreak *ChainBreak
+	if errors.As(err, &chainBreak) {
+		result.Break = chainBreak
+		err = nil
+	}
+	result.Users = len(userHeads)
+	return result, err
+}
@@ -0,0 +1,147 @@
+package audit
+
+import (
+	"context"
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/repository/memory"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// chainEvents 在内存存储中写入五条由两个用户交替产生的事件，返回按序号排列的事件。
+func chainEvents(t *testing.T) []*core.AuditEvent {
+	t.Helper()
+	ctx := context.Background()
+	storage := memory.NewMemoryStorage()
+	alice, bob := uuid.New(), uuid.New()
+	for i, userID := range []uuid.UUID{alice, bob, alice, bob, alice} {
+		event := &core.AuditEvent{
+			UserID:    userID,
+			Type:      core.AuditEventLoginSuccess,
+			IPAddress: "192.0.2.1",
+			Metadata:  map[string]string{"device_id": "laptop"},
+			CreatedAt: time.Now().Add(time.Duration(i) * time.Second),
+		}
+		if err := storage.Audit().Create(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var events []*core.AuditEvent
+	err := storage.Audit().ForEach(ctx, func(event *core.AuditEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+// storeEvents 把 events 原样写入新的内存存储，不重新计算序号和哈希。
+func storeEvents(t *testing.T, events []*core.AuditEvent) core.AuditRepository {
+	t.Helper()
+	storage := memory.NewMemoryStorage()
+	records := make([]any, len(events))
+	for i, event := range events {
+		records[i] = event
+	}
+	if err := storage.Bulk().Insert(context.Background(), core.RecordAuditEvents, records); err != nil {
+		t.Fatal(err)
+	}
+	return storage.Audit()
+}
+
+func TestVerifyChainIntact(t *testing.T) {
+	result, err := VerifyChain(context.Background(), storeEvents(t, chainEvents(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Break != nil {
+		t.Fatalf("intact chain reported broken: %v", result.Break)
+	}
+	if result.Events != 5 || result.Users != 2 {
+		t.Fatalf("verified %d events across %d users, want 5 across 2", result.Events, result.Users)
+	}
+}
+
+// TestVerifyChainBreaks 逐一破坏事件内容、全局前驱哈希、用户前驱哈希和序号，
+// 检查报告的是第一个断裂的链接。修改链接字段后重新计算哈希，确保是链接检查发现了问题。
+// 被修改事件之后的事件同样无法通过校验，因此报告的序号也说明了校验在第一处断裂时停止。
+func TestVerifyChainBreaks(t *testing.T) {
+	tests := []struct {
+		name     string
+		tamper   func(events []*core.AuditEvent) []*core.AuditEvent
+		sequence int64
+		verified int64
+		reason   string
+	}{
+		{
+			name: "modified content",
+			tamper: func(events []*core.AuditEvent) []*core.AuditEvent {
+				events[2].IPAddress = "198.51.100.7"
+				return events
+			},
+			sequence: 3,
+			verified: 2,
+			reason:   "event hash mismatch, record content was modified",
+		},
+		{
+			name: "broken previous hash",
+			tamper: func(events []*core.AuditEvent) []*core.AuditEvent {
+				events[1].PrevHash = events[1].UserPrevHash
+				events[1].Hash = events[1].ComputeHash()
+				return events
+			},
+			sequence: 2,
+			verified: 1,
+			reason:   "global previous hash mismatch",
+		},
+		{
+			name: "broken user previous hash",
+			tamper: func(events []*core.AuditEvent) []*core.AuditEvent {
+				events[2].UserPrevHash = events[1].Hash
+				events[2].Hash = events[2].ComputeHash()
+				return events
+			},
+			sequence: 3,
+			verified: 2,
+			reason:   "user previous hash mismatch",
+		},
+		{
+			name: "skipped sequence",
+			tamper: func(events []*core.AuditEvent) []*core.AuditEvent {
+				return slices.Delete(events, 3, 4)
+			},
+			sequence: 5,
+			verified: 3,
+			reason:   "expected sequence 4, found 5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := tt.tamper(chainEvents(t))
+			result, err := VerifyChain(context.Background(), storeEvents(t, events))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Break == nil {
+				t.Fatal("broken chain reported intact")
+			}
+			if result.Break.Sequence != tt.sequence || result.Break.Reason != tt.reason {
+				t.Fatalf("break at sequence %d (%s), want %d (%s)",
+					result.Break.Sequence, result.Break.Reason, tt.sequence, tt.reason)
+			}
+			if want := events[tt.verified]; result.Break.EventID != want.ID || result.Break.UserID != want.UserID {
+				t.Fatalf("break names event %s of user %s, want %s of %s",
+					result.Break.EventID, result.Break.UserID, want.ID, want.UserID)
+			}
+			if result.Events != tt.verified {
+				t.Fatalf("verified %d events before the break, want %d", result.Events, tt.verified)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"easy-password-backend/internal/apierror"
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/crypto"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// checkMasterKeyHash 以恒定时间比较提供的主密钥哈希与用户存储的哈希。
+func checkMasterKeyHash(user *core.User, masterKeyHash string) bool {
+	if len(user.AuthHash) != len(masterKeyHash) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(user.AuthHash), []byte(masterKeyHash)) == 1
+}
+
+// DeleteAccount 在用户重新验证主密钥哈希后，永久删除账户及其全部数据，并发送确认邮件。
+// 账户的审计事件会被保留，保留的内容见 core.UserRepository.Delete。
+func (s *AuthService) DeleteAccount(ctx context.Context, userID uuid.UUID, masterKeyHash string) error {
+	slog.Info("Attempting to delete account", "user_id", userID)
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if err == core.ErrUserNotFound {
+			return apierror.ErrNotFound
+		}
+		slog.Error("Failed to find user for account deletion", "user_id", userID, "error", err)
+		return apierror.ErrInternalServer
+	}
+
+	if !checkMasterKeyHash(user, masterKeyHash) {
+		slog.Warn("Account deletion failed: invalid credentials", "user_id", userID)
+		return apierror.ErrInvalidCredentials
+	}
+
+	// 在删除之前写入审计事件，使其成为该用户链上的最后一条记录。
+	s.auditor.Record(ctx, userID, core.AuditEventAccountDelete, nil)
+
+	if err := s.userRepo.Delete(ctx, userID); err != nil {
+		slog.Error("Failed to delete account", "user_id", userID, "error", err)
+		return apierror.ErrInternalServer
+	}
+	slog.Info("Account deleted successfully", "user_id", userID)
+
+	// 在一个 goroutine 中发送以避免阻塞请求
+	go func() {
+		err := s.emailSvc.SendAccountDeletedEmail(user.Email, user.Username)
+		if err != nil {
+			slog.Error("Failed to send account deletion email", "recipient", user.Email, "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// RequestEmailChange 在重新验证主密钥哈希后，向新邮箱发送更换邮箱的验证码。
+func (s *AuthService) RequestEmailChange(ctx context.Context, userID uuid.UUID, newEmail, masterKeyHash string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if err == core.ErrUserNotFound {
+			return apierror.ErrNotFound
+		}
+		return apierror.ErrInternalServer
+	}
+	if !checkMasterKeyHash(user, masterKeyHash) {
+		slog.Warn("Email change failed: invalid credentials", "user_id", userID)
+		return apierror.ErrInvalidCredentials
+	}
+
+	// 1. 检查新邮箱是否已经被注册
+	_, err = s.userRepo.FindByEmail(ctx, newEmail)
+	if err == nil {
+		return apierror.ErrEmailExists
+	}
+	if err != core.ErrUserNotFound {
+		return apierror.ErrInternalServer
+	}
+
+	// 2. 生成并存储发往新邮箱的验证码
+	code, err := crypto.GenerateNumericCode(6)
+	if err != nil {
+		return apierror.ErrInternalServer
+	}
+	slog.Debug("Email change code generated", "email", newEmail, "code", code)
+
+	// 待确认的更换记录保存在用户自己的记录上，只能由该用户、用于更换邮箱时确认。
+	// 再次请求会覆盖之前的记录，之前发出的验证码随之失效。
+	codeHash := crypto.HashString(code)
+	expiresAt := time.Now().Add(5 * time.Minute) // 5分钟有效期
+	user.PendingEmail = &newEmail
+	user.PendingEmailCodeHash = &codeHash
+	user.PendingEmailExpiresAt = &expiresAt
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		slog.Error("Failed to store pending email change", "user_id", userID, "error", err)
+		return apierror.ErrInternalServer
+	}
+
+	// 3. 发送邮件
+	// 在一个 goroutine 中发送以避免阻塞请求
+	go func() {
+		err := s.emailSvc.SendEmailChangeCodeEmail(newEmail, code)
+		if err != nil {
+			slog.Error("Failed to send email change code", "recipient", newEmail, "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// ConfirmEmailChange 校验该用户待确认的邮箱更换：新邮箱、验证码和有效期都必须与请求时一致，
+// 然后将账户邮箱更换为新地址。
+func (s *AuthService) ConfirmEmailChange(ctx context.Context, userID uuid.UUID, newEmail, code string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if err == core.ErrUserNotFound {
+			return apierror.ErrNotFound
+		}
+		return apierror.ErrInternalServer
+	}
+
+	if user.PendingEmail == nil || user.PendingEmailCodeHash == nil || user.PendingEmailExpiresAt == nil ||
+		*user.PendingEmail != newEmail ||
+		subtle.ConstantTimeCompare([]byte(*user.PendingEmailCodeHash), []byte(crypto.HashString(code))) == 0 {
+		slog.Warn("Email change failed: invalid verification code", "user_id", userID)
+		return apierror.ErrInvalidVerificationCode
+	}
+	if time.Now().After(*user.PendingEmailExpiresAt) {
+		return apierror.ErrVerificationCodeExpired
+	}
+
+	oldEmail := user.Email
+	user.Email = newEmail
+	user.PendingEmail = nil
+	user.PendingEmailCodeHash = nil
+	user.PendingEmailExpiresAt = nil
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		var dupErr *core.DuplicateEntryError
+		if errors.As(err, &dupErr) {
+			return apierror.ErrEmailExists
+		}
+		slog.Error("Failed to update user email", "user_id", userID, "error", err)
+		return apierror.ErrInternalServer
+	}
+
+	slog.Info("User email changed", "user_id", userID)
+	s.auditor.Record(ctx, userID, core.AuditEventEmailChange, nil)
+
+	// 通知旧邮箱，以便用户在非本人操作时及时发现。
+	go func() {
+		if err := s.emailSvc.SendEmailChangedNoticeEmail(oldEmail, newEmail); err != nil {
+			slog.Error("Failed to send email change notice", "recipient", oldEmail, "error", err)
+		}
+	}()
+	return nil
+}
+
+// ChangeUsername 在重新验证主密钥哈希后，将账户的用户名更换为一个未被占用的新用户名。
+func (s *AuthService) ChangeUsername(ctx context.Context, userID uuid.UUID, newUsername, masterKeyHash string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if err == core.ErrUserNotFound {
+			return apierror.ErrNotFound
+		}
+		return apierror.ErrInternalServer
+	}
+	if !checkMasterKeyHash(user, masterKeyHash) {
+		slog.Warn("Username change failed: invalid credentials", "user_id", userID)
+		return apierror.ErrInvalidCredentials
+	}
+	if user.Username == newUsername {
+		return nil
+	}
+
+	_, err = s.userRepo.FindByUsername(ctx, newUsername)
+	if err == nil {
+		return apierror.ErrUsernameExists
+	}
+	if err != core.ErrUserNotFound {
+		return apierror.ErrInternalServer
+	}
+
+	oldUsername := user.Username
+	user.Username = newUsername
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		var dupErr *core.DuplicateEntryError
+		if errors.As(err, &dupErr) {
+			return apierror.ErrUsernameExists
+		}
+		slog.Error("Failed to update username", "user_id", userID, "error", err)
+		return apierror.ErrInternalServer
+	}
+
+	slog.Info("Username changed", "user_id", userID)
+	s.auditor.Record(ctx, userID, core.AuditEventUsernameChange, map[string]string{
+		"old_username": oldUsername,
+		"new_username": newUsername,
+	})
+	return nil
+}
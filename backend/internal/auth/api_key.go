@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"easy-password-backend/internal/apierror"
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/crypto"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// apiKeyTokenTTL 是用 API 密钥换取的访问令牌的有效期。
+	apiKeyTokenTTL = 15 * time.Minute
+	// apiKeySecretPrefix 让泄露的密钥容易被密钥扫描工具识别。
+	apiKeySecretPrefix = "epk_"
+)
+
+// APIKeyRequest 描述要创建的 API 密钥。
+type APIKeyRequest struct {
+	Name       string
+	Scopes     []core.APIKeyScope
+	ItemIDs    []uuid.UUID
+	Categories []string
+	ExpiresAt  *time.Time
+}
+
+// APIKeyToken 是用 API 密钥换取的访问令牌。MasterSalt 与登录结果中的相同，
+// 客户端仍需用主密码和它派生密钥才能解密项目。
+type APIKeyToken struct {
+	Token      string
+	ExpiresAt  time.Time
+	Scopes     []core.APIKeyScope
+	MasterSalt string
+}
+
+// CreateAPIKey 为用户创建一个 API 密钥，返回密钥记录和明文密钥。明文密钥只在此时返回一次。
+func (s *AuthService) CreateAPIKey(ctx context.Context, userID uuid.UUID, req APIKeyRequest) (*core.APIKey, string, error) {
+	if len(req.Scopes) == 0 {
+		return nil, "", apierror.ErrInvalidAPIKeyScope
+	}
+	var scopes []core.APIKeyScope
+	for _, scope := range req.Scopes {
+		if !scope.Valid() {
+			return nil, "", apierror.ErrInvalidAPIKeyScope
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, "", apierror.ErrInvalidAPIKeyExpiry
+	}
+
+	random, err := crypto.GenerateRandomString(32)
+	if err != nil {
+		return nil, "", apierror.ErrInternalServer
+	}
+	secret := apiKeySecretPrefix + random
+
+	key := &core.APIKey{
+		UserID:     userID,
+		Name:       req.Name,
+		SecretHash: crypto.HashString(secret),
+		Scopes:     scopes,
+		ItemIDs:    req.ItemIDs,
+		Categories: req.Categories,
+		ExpiresAt:  req.ExpiresAt,
+	}
+	if err := s.apiKeyRepo.Create(ctx, key); err != nil {
+		slog.Error("Failed to create API key", "user_id", userID, "error", err)
+		return nil, "", apierror.ErrInternalServer
+	}
+
+	slog.Info("API key created", "user_id", userID, "api_key_id", key.ID)
+	s.auditor.Record(ctx, userID, core.AuditEventAPIKeyCreate, map[string]string{
+		"api_key_id": key.ID.String(),
+		"name":       key.Name,
+		"scopes":     joinScopes(key.Scopes),
+	})
+	return key, secret, nil
+}
+
+// ListAPIKeys 按创建时间返回用户的 API 密钥。
+func (s *AuthService) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]core.APIKey, error) {
+	keys, err := s.apiKeyRepo.FindByUser(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to list API keys", "user_id", userID, "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey 删除用户的一个 API 密钥。用它换取的令牌随即失效。
+func (s *AuthService) RevokeAPIKey(ctx context.Context, userID, keyID uuid.UUID) error {
+	key, err := s.apiKeyRepo.FindByID(ctx, keyID)
+	if err != nil {
+		if err == core.ErrAPIKeyNotFound {
+			return apierror.ErrAPIKeyNotFound
+		}
+		slog.Error("Failed to find API key", "api_key_id", keyID, "error", err)
+		return apierror.ErrInternalServer
+	}
+	// 不属于当前用户的密钥按不存在处理，不泄露其他用户的密钥 ID。
+	if key.UserID != userID {
+		return apierror.ErrAPIKeyNotFound
+	}
+	if err := s.apiKeyRepo.Delete(ctx, keyID); err != nil && err != core.ErrAPIKeyNotFound {
+		slog.Error("Failed to delete API key", "api_key_id", keyID, "error", err)
+		return apierror.ErrInternalServer
+	}
+
+	slog.Info("API key revoked", "user_id", userID, "api_key_id", keyID)
+	s.auditor.Record(ctx, userID, core.AuditEventAPIKeyRevoke, map[string]string{
+		"api_key_id": keyID.String(),
+		"name":       key.Name,
+	})
+	return nil
+}
+
+// ExchangeAPIKey 用 API 密钥的 client ID 和密钥换取一个短期访问令牌。
+// 令牌不会超过密钥本身的过期时间。
+func (s *AuthService) ExchangeAPIKey(ctx context.Context, clientID, secret string) (*APIKeyToken, error) {
+	id, err := uuid.Parse(clientID)
+	if err != nil {
+		return nil, apierror.ErrInvalidAPIKey
+	}
+	key, err := s.apiKeyRepo.FindByID(ctx, id)
+	if err != nil {
+		if err == core.ErrAPIKeyNotFound {
+			slog.Warn("API key exchange failed: unknown client id", "api_key_id", id)
+			return nil, apierror.ErrInvalidAPIKey
+		}
+		slog.Error("Failed to find API key", "api_key_id", id, "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+	if subtle.ConstantTimeCompare([]byte(crypto.HashString(secret)), []byte(key.SecretHash)) != 1 {
+		slog.Warn("API key exchange failed: wrong secret", "api_key_id", id)
+		return nil, apierror.ErrInvalidAPIKey
+	}
+	now := time.Now()
+	if key.Expired(now) {
+		return nil, apierror.ErrAPIKeyExpired
+	}
+
+	user, err := s.userRepo.FindByID(ctx, key.UserID)
+	if err != nil {
+		if err == core.ErrUserNotFound {
+			return nil, apierror.ErrInvalidAPIKey
+		}
+		slog.Error("Failed to find API key owner", "api_key_id", id, "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+	if err := checkAccountStatus(user); err != nil {
+		return nil, err
+	}
+
+	expiresAt := now.Add(apiKeyTokenTTL)
+	if key.ExpiresAt != nil && key.ExpiresAt.Before(expiresAt) {
+		expiresAt = *key.ExpiresAt
+	}
+	token, err := crypto.GenerateAPIKeyJWT(ctx, s.keys, user.ID, key.ID, joinScopes(key.Scopes), expiresAt)
+	if err != nil {
+		slog.Error("Failed to generate JWT for API key", "api_key_id", id, "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+
+	key.LastUsedAt = &now
+	if err := s.apiKeyRepo.Update(ctx, key); err != nil {
+		// 最后使用时间只用于展示，更新失败不影响本次换取。
+		slog.Warn("Failed to record API key use", "api_key_id", id, "error", err)
+	}
+	s.auditor.Record(ctx, user.ID, core.AuditEventAPIKeyExchange, map[string]string{
+		"api_key_id": key.ID.String(),
+	})
+	return &APIKeyToken{Token: token, ExpiresAt: expiresAt, Scopes: key.Scopes, MasterSalt: string(user.MasterSalt)}, nil
+}
+
+// validateTokenAPIKey 确认用 API 密钥换取的令牌所用的密钥仍然存在、属于令牌的用户且未过期。
+func (s *AuthService) validateTokenAPIKey(ctx context.Context, claims *crypto.Claims) (*core.APIKey, error) {
+	key, err := s.apiKeyRepo.FindByID(ctx, claims.APIKeyID)
+	if err != nil {
+		if err == core.ErrAPIKeyNotFound {
+			return nil, apierror.ErrInvalidToken
+		}
+		slog.Error("Failed to check token API key", "api_key_id", claims.APIKeyID, "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+	if key.UserID != claims.UserID || key.Expired(time.Now()) {
+		return nil, apierror.ErrInvalidToken
+	}
+	return key, nil
+}
+
+// joinScopes 返回以空格分隔的作用域列表。
+func joinScopes(scopes []core.APIKeyScope) string {
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+	return strings.Join(names, " ")
+}
@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"easy-password-backend/internal/apierror"
+	"easy-password-backend/internal/audit"
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/crypto"
+	"easy-password-backend/internal/email"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	loginApprovalTTL         = 10 * time.Minute
+	maxLoginApprovalAttempts = 5
+)
+
+// requestLoginApproval 挂起一次来自未知设备的登录，并向用户发送批准邮件。
+func (s *AuthService) requestLoginApproval(ctx context.Context, user *core.User, device DeviceInfo) (*LoginResult, error) {
+	code, err := crypto.GenerateNumericCode(6)
+	if err != nil {
+		return nil, apierror.ErrInternalServer
+	}
+	token, err := crypto.GenerateRandomString(32)
+	if err != nil {
+		return nil, apierror.ErrInternalServer
+	}
+
+	approval := &core.LoginApproval{
+		UserID:     user.ID,
+		DeviceID:   device.ID,
+		DeviceName: device.Name,
+		DeviceType: device.Type,
+		IPAddress:  audit.ClientInfoFromContext(ctx).IPAddress,
+		CodeHash:   crypto.HashString(code),
+		TokenHash:  crypto.HashString(token),
+		Status:     core.LoginApprovalPending,
+		ExpiresAt:  time.Now().Add(loginApprovalTTL),
+	}
+	if err := s.approvalRepo.Create(ctx, approval); err != nil {
+		slog.Error("Failed to create login approval", "user_id", user.ID, "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+
+	slog.Info("Login from unknown device held for approval", "user_id", user.ID, "approval_id", approval.ID)
+	s.auditor.Record(ctx, user.ID, core.AuditEventLoginApprovalRequest, map[string]string{
+		"approval_id": approval.ID.String(),
+		"device_name": approval.DeviceName,
+	})
+
+	// 在一个 goroutine 中发送以避免阻塞请求
+	go func() {
+		approvalLink := fmt.Sprintf("%s/approve-login/%s", s.cfg.FrontendURL, token)
+
+		// 打印验证码到控制台以供测试
+		slog.Debug("Login approval generated", "email", user.Email, "code", code, "link", approvalLink)
+
+		err := s.emailSvc.SendLoginApprovalEmail(user.Email, email.LoginApprovalTemplateData{
+			Code:         code,
+			ApprovalLink: approvalLink,
+			DeviceName:   approval.DeviceName,
+			DeviceType:   approval.DeviceType,
+			IPAddress:    approval.IPAddress,
+		})
+		if err != nil {
+			slog.Error("Failed to send login approval email", "recipient", user.Email, "error", err)
+		}
+	}()
+
+	return &LoginResult{PendingApprovalID: approval.ID}, nil
+}
+
+// findLiveApproval 查找未过期的待批准登录；过期的请求会被删除。
+func (s *AuthService) findLiveApproval(ctx context.Context, find func() (*core.LoginApproval, error)) (*core.LoginApproval, error) {
+	approval, err := find()
+	if err != nil {
+		if err == core.ErrLoginApprovalNotFound {
+			return nil, apierror.ErrLoginApprovalNotFound
+		}
+		slog.Error("Failed to find login approval", "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+	if time.Now().After(approval.ExpiresAt) {
+		_ = s.approvalRepo.Delete(ctx, approval.ID)
+		return nil, apierror.ErrLoginApprovalExpired
+	}
+	return approval, nil
+}
+
+// ApproveLogin 通过邮件中的批准链接令牌批准一次挂起的登录。
+// 批准后设备即成为已知设备，等待中的客户端在下一次轮询时获得令牌。
+func (s *AuthService) ApproveLogin(ctx context.Context, token string) error {
+	if token == "" {
+		return apierror.ErrLoginApprovalNotFound
+	}
+	approval, err := s.findLiveApproval(ctx, func() (*core.LoginApproval, error) {
+		return s.approvalRepo.FindByTokenHash(ctx, crypto.HashString(token))
+	})
+	if err != nil {
+		return err
+	}
+	if approval.Status == core.LoginApprovalApproved {
+		return nil
+	}
+
+	if _, err := s.grantApproval(ctx, approval); err != nil {
+		return err
+	}
+	approval.Status = core.LoginApprovalApproved
+	if err := s.approvalRepo.Update(ctx, approval); err != nil {
+		slog.Error("Failed to update login approval", "approval_id", approval.ID, "error", err)
+		return apierror.ErrInternalServer
+	}
+	return nil
+}
+
+// VerifyLoginApprovalCode 使用邮件中的验证码在等待中的设备上完成登录。
+func (s *AuthService) VerifyLoginApprovalCode(ctx context.Context, id uuid.UUID, code string) (*LoginResult, error) {
+	approval, err := s.findLiveApproval(ctx, func() (*core.LoginApproval, error) {
+		return s.approvalRepo.FindByID(ctx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if approval.Status != core.LoginApprovalApproved {
+		if approval.Attempts >= maxLoginApprovalAttempts {
+			return nil, apierror.ErrTooManyAttempts
+		}
+		if subtle.ConstantTimeCompare([]byte(approval.CodeHash), []byte(crypto.HashString(code))) == 0 {
+			approval.Attempts++
+			_ = s.approvalRepo.Update(ctx, approval)
+			slog.Warn("Login approval failed: invalid code", "approval_id", approval.ID, "attempts", approval.Attempts)
+			return nil, apierror.ErrInvalidApprovalCode
+		}
+		if _, err := s.grantApproval(ctx, approval); err != nil {
+			return nil, err
+		}
+	}
+	return s.completeApproval(ctx, approval)
+}
+
+// PollLoginApproval 供等待中的客户端轮询批准状态。
+// 仍在等待时返回仅包含 PendingApprovalID 的结果；批准后签发令牌并删除该请求。
+func (s *AuthService) PollLoginApproval(ctx context.Context, id uuid.UUID) (*LoginResult, error) {
+	approval, err := s.findLiveApproval(ctx, func() (*core.LoginApproval, error) {
+		return s.approvalRepo.FindByID(ctx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if approval.Status != core.LoginApprovalApproved {
+		return &LoginResult{PendingApprovalID: approval.ID}, nil
+	}
+	return s.completeApproval(ctx, approval)
+}
+
+// grantApproval 将挂起登录的设备登记为已知设备。
+func (s *AuthService) grantApproval(ctx context.Context, approval *core.LoginApproval) (*core.User, error) {
+	user, err := s.userRepo.FindByID(ctx, approval.UserID)
+	if err != nil {
+		slog.Error("Failed to find user for login approval", "user_id", approval.UserID, "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+
+	device := DeviceInfo{ID: approval.DeviceID, Name: approval.DeviceName, Type: approval.DeviceType}
+	if _, err := s.trackDevice(ctx, user, device, approval.IPAddress, false); err != nil {
+		slog.Error("Failed to register approved device", "user_id", user.ID, "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+
+	slog.Info("Login from new device approved", "user_id", user.ID, "approval_id", approval.ID)
+	s.auditor.Record(ctx, user.ID, core.AuditEventLoginApprovalGrant, map[string]string{
+		"approval_id": approval.ID.String(),
+		"device_name": approval.DeviceName,
+	})
+	return user, nil
+}
+
+// completeApproval 为已批准的登录签发令牌，并使该请求失效。
+func (s *AuthService) completeApproval(ctx context.Context, approval *core.LoginApproval) (*LoginResult, error) {
+	user, err := s.userRepo.FindByID(ctx, approval.UserID)
+	if err != nil {
+		slog.Error("Failed to find user for login approval", "user_id", approval.UserID, "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+	if err := checkAccountStatus(user); err != nil {
+		return nil, err
+	}
+	// 批准后、取得令牌前设备可能已被撤销，此时需要重新登录。
+	device, err := s.deviceRepo.FindByUserAndDeviceID(ctx, user.ID, approval.DeviceID)
+	if err == core.ErrDeviceNotFound {
+		return nil, apierror.ErrLoginApprovalNotFound
+	}
+	if err != nil {
+		slog.Error("Failed to find approved device", "user_id", user.ID, "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+	if err := s.approvalRepo.Delete(ctx, approval.ID); err != nil {
+		slog.Error("Failed to delete login approval", "approval_id", approval.ID, "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+	return s.issueToken(ctx, user, device)
+}
+
+// SetLoginApprovalPolicy 开启或关闭账户的未知设备登录批准策略。
+func (s *AuthService) SetLoginApprovalPolicy(ctx context.Context, userID uuid.UUID, enabled bool) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if err == core.ErrUserNotFound {
+			return apierror.ErrNotFound
+		}
+		return apierror.ErrInternalServer
+	}
+
+	user.RequireDeviceApproval = enabled
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		slog.Error("Failed to update login approval policy", "user_id", userID, "error", err)
+		return apierror.ErrInternalServer
+	}
+
+	s.auditor.Record(ctx, userID, core.AuditEventLoginApprovalSetting, map[string]string{
+		"enabled": fmt.Sprintf("%t", enabled),
+	})
+	return nil
+}
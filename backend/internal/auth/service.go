@@ -2,34 +2,55 @@ package auth
 
 import (
 	"context"
-	"crypto/subtle"
 	"easy-password-backend/config"
 	"easy-password-backend/internal/apierror"
+	"easy-password-backend/internal/audit"
 	"easy-password-backend/internal/core"
 	"easy-password-backend/internal/crypto"
 	"easy-password-backend/internal/email"
+	"easy-password-backend/internal/kms"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math/rand"
 	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // AuthService 提供用户身份验证相关的服务。
 type AuthService struct {
-	userRepo core.UserRepository
-	vcRepo   core.VerificationCodeRepository
-	emailSvc email.EmailService
-	cfg      *config.Config
+	userRepo     core.UserRepository
+	vcRepo       core.VerificationCodeRepository
+	deviceRepo   core.DeviceRepository
+	approvalRepo core.LoginApprovalRepository
+	apiKeyRepo   core.APIKeyRepository
+	emailSvc     email.EmailService
+	auditor      *audit.AuditService
+	cfg          *config.Config
+	keys         kms.KeyProvider
+}
+
+// DeviceInfo 描述客户端在登录时提交的设备信息。
+type DeviceInfo struct {
+	ID   string
+	Name string
+	Type string
 }
 
-// NewAuthService 创建一个新的 AuthService。
-func NewAuthService(userRepo core.UserRepository, vcRepo core.VerificationCodeRepository, emailSvc email.EmailService, cfg *config.Config) *AuthService {
+// NewAuthService 创建一个新的 AuthService。keys 提供签发和验证访问令牌所用的 JWT 密钥。
+func NewAuthService(userRepo core.UserRepository, vcRepo core.VerificationCodeRepository, deviceRepo core.DeviceRepository, approvalRepo core.LoginApprovalRepository, apiKeyRepo core.APIKeyRepository, emailSvc email.EmailService, auditor *audit.AuditService, cfg *config.Config, keys kms.KeyProvider) *AuthService {
 	return &AuthService{
-		userRepo: userRepo,
-		vcRepo:   vcRepo,
-		emailSvc: emailSvc,
-		cfg:      cfg,
+		userRepo:     userRepo,
+		vcRepo:       vcRepo,
+		deviceRepo:   deviceRepo,
+		approvalRepo: approvalRepo,
+		apiKeyRepo:   apiKeyRepo,
+		emailSvc:     emailSvc,
+		auditor:      auditor,
+		cfg:          cfg,
+		keys:         keys,
 	}
 }
 
@@ -99,8 +120,20 @@ func (s *AuthService) Register(ctx context.Context, username, email, masterKeyHa
 	return newUser, nil
 }
 
+// LoginResult 是一次登录尝试的结果。
+// 当 PendingApprovalID 非空时，登录正在等待邮件批准，其余字段为空。
+type LoginResult struct {
+	Token             string
+	MasterSalt        string
+	Username          string
+	DeviceID          string
+	PendingApprovalID uuid.UUID
+}
+
 // Login 处理用户登录的业务逻辑，并返回一个 JWT 和用户的主盐。
-func (s *AuthService) Login(ctx context.Context, identifier, masterKeyHash string) (string, string, string, error) {
+// 令牌绑定到客户端提供的设备，首次出现的设备会触发提醒邮件；未提供设备 ID 的登录视为来自一台新设备。
+// 若账户开启了登录批准，未知设备的登录将被挂起，直到用户通过邮件批准。
+func (s *AuthService) Login(ctx context.Context, identifier, masterKeyHash string, device DeviceInfo) (*LoginResult, error) {
 	slog.Info("Login attempt", "identifier", identifier)
 	var user *core.User
 	var err error
@@ -113,31 +146,189 @@ func (s *AuthService) Login(ctx context.Context, identifier, masterKeyHash strin
 
 	if err != nil {
 		slog.Warn("Login failed: user not found", "identifier", identifier, "error", err)
-		return "", "", "", apierror.ErrInvalidCredentials
+		return nil, apierror.ErrInvalidCredentials
 	}
 
 	// 2. 将提供的主密钥哈希与存储的哈希进行比较。
 	// 使用恒定时间比较函数来防止时序攻击。
-	if len(user.AuthHash) != len(masterKeyHash) {
-		slog.Warn("Login failed: invalid credentials (hash length mismatch)", "user_id", user.ID)
-		return "", "", "", apierror.ErrInvalidCredentials
+	if !checkMasterKeyHash(user, masterKeyHash) {
+		slog.Warn("Login failed: invalid credentials", "user_id", user.ID)
+		s.auditor.Record(ctx, user.ID, core.AuditEventLoginFailure, map[string]string{"reason": "invalid_credentials"})
+		return nil, apierror.ErrInvalidCredentials
+	}
+	if err := checkAccountStatus(user); err != nil {
+		slog.Warn("Login failed: account not active", "user_id", user.ID, "status", user.AccountStatus())
+		s.auditor.Record(ctx, user.ID, core.AuditEventLoginFailure, map[string]string{
+			"reason": "account_" + string(user.AccountStatus()),
+		})
+		return nil, err
 	}
 
-	if subtle.ConstantTimeCompare([]byte(user.AuthHash), []byte(masterKeyHash)) == 0 {
-		slog.Warn("Login failed: invalid credentials (hash mismatch)", "user_id", user.ID)
-		return "", "", "", apierror.ErrInvalidCredentials
+	// 3. 记录登录设备；开启登录批准时，未知设备需要先获得批准。
+	// 省略设备 ID 不能绕过登录批准：服务器为其生成一个新的设备 ID，客户端可以在之后的登录中沿用。
+	if device.ID == "" {
+		device.ID = uuid.NewString()
+	}
+	if user.RequireDeviceApproval {
+		_, err := s.deviceRepo.FindByUserAndDeviceID(ctx, user.ID, device.ID)
+		if err == core.ErrDeviceNotFound {
+			return s.requestLoginApproval(ctx, user, device)
+		}
+		if err != nil {
+			slog.Error("Failed to look up login device", "user_id", user.ID, "error", err)
+			return nil, apierror.ErrInternalServer
+		}
 	}
 
-	// 3. 生成 JWT。
-	token, err := crypto.GenerateJWT(user.ID, s.cfg.JWTSecret, s.cfg.JWTExpiration)
+	ip := audit.ClientInfoFromContext(ctx).IPAddress
+	tracked, err := s.trackDevice(ctx, user, device, ip, true)
+	if err != nil {
+		slog.Error("Failed to track login device", "user_id", user.ID, "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+
+	// 4. 生成 JWT 并返回令牌和主盐、用户名。
+	return s.issueToken(ctx, user, tracked)
+}
+
+// issueToken 为已通过验证的用户签发 JWT。令牌绑定到服务器生成的设备记录 ID 而不是客户端提供的设备 ID，
+// 设备被撤销后即使同一设备再次登录，撤销前签发的令牌也不会恢复有效。
+func (s *AuthService) issueToken(ctx context.Context, user *core.User, device *core.Device) (*LoginResult, error) {
+	token, err := crypto.GenerateJWT(ctx, s.keys, user.ID, device.ID, s.cfg.JWTExpiration)
 	if err != nil {
 		slog.Error("Failed to generate JWT for user", "user_id", user.ID, "error", err)
-		return "", "", "", apierror.ErrInternalServer
+		return nil, apierror.ErrInternalServer
 	}
 
 	slog.Info("User logged in successfully", "user_id", user.ID)
-	// 4. 返回令牌和主盐、用户名。
-	return token, string(user.MasterSalt), user.Username, nil
+	s.auditor.Record(ctx, user.ID, core.AuditEventLoginSuccess, nil)
+	return &LoginResult{
+		Token:      token,
+		MasterSalt: string(user.MasterSalt),
+		Username:   user.Username,
+		DeviceID:   device.DeviceID,
+	}, nil
+}
+
+// PublicKeys 返回用于验证访问令牌的公钥集合（JWKS）。只使用 HS256 密钥时集合为空。
+func (s *AuthService) PublicKeys(ctx context.Context) (crypto.JWKS, error) {
+	set, err := crypto.PublicJWKS(ctx, s.keys)
+	if err != nil {
+		slog.Error("Failed to list JWT verification keys", "error", err)
+		return crypto.JWKS{}, apierror.ErrInternalServer
+	}
+	return set, nil
+}
+
+// ValidateToken 验证访问令牌，并确认其所属用户仍然存在且处于 active 状态、
+// 令牌未被强制失效、所绑定的设备未被撤销，用 API 密钥换取的令牌所用的密钥未被撤销。
+// 返回令牌声明、令牌所属的用户，以及换取令牌所用的 API 密钥（用户登录签发的令牌为 nil）。
+func (s *AuthService) ValidateToken(ctx context.Context, tokenString string) (*crypto.Claims, *core.User, *core.APIKey, error) {
+	claims, err := crypto.ValidateJWT(ctx, s.keys, tokenString)
+	if errors.Is(err, crypto.ErrInvalidToken) {
+		return nil, nil, nil, apierror.ErrInvalidToken
+	}
+	if err != nil {
+		slog.Error("Failed to verify token signature", "error", err)
+		return nil, nil, nil, apierror.ErrInternalServer
+	}
+
+	user, err := s.userRepo.FindByID(ctx, claims.UserID)
+	if err != nil {
+		if err == core.ErrUserNotFound {
+			return nil, nil, nil, apierror.ErrInvalidToken
+		}
+		slog.Error("Failed to find token user", "user_id", claims.UserID, "error", err)
+		return nil, nil, nil, apierror.ErrInternalServer
+	}
+	if err := checkAccountStatus(user); err != nil {
+		return nil, nil, nil, err
+	}
+	// 签发时间只精确到秒，因此失效时刻所在那一秒内签发的令牌也会被拒绝。
+	if user.SessionsRevokedAt != nil &&
+		(claims.IssuedAt == nil || claims.IssuedAt.Before(*user.SessionsRevokedAt)) {
+		return nil, nil, nil, apierror.ErrInvalidToken
+	}
+
+	// 用 API 密钥换取的令牌随密钥撤销；用户登录签发的令牌必须绑定到一条仍然存在的设备记录。
+	var apiKey *core.APIKey
+	if claims.APIKeyID != uuid.Nil {
+		if apiKey, err = s.validateTokenAPIKey(ctx, claims); err != nil {
+			return nil, nil, nil, err
+		}
+		return claims, user, apiKey, nil
+	}
+
+	device, err := s.deviceRepo.FindByID(ctx, claims.DeviceID)
+	if err == core.ErrDeviceNotFound || (err == nil && device.UserID != claims.UserID) {
+		return nil, nil, nil, apierror.ErrInvalidToken
+	}
+	if err != nil {
+		slog.Error("Failed to check token device", "user_id", claims.UserID, "error", err)
+		return nil, nil, nil, apierror.ErrInternalServer
+	}
+	return claims, user, nil, nil
+}
+
+// trackDevice 更新已知设备的最后活跃信息，或登记一个新设备，并返回该设备记录。
+// notify 为 true 时，新设备会触发提醒邮件。
+func (s *AuthService) trackDevice(ctx context.Context, user *core.User, info DeviceInfo, ip string, notify bool) (*core.Device, error) {
+	now := time.Now()
+
+	existing, err := s.deviceRepo.FindByUserAndDeviceID(ctx, user.ID, info.ID)
+	if err == nil {
+		existing.LastSeenAt = now
+		existing.IPAddress = ip
+		if info.Name != "" {
+			existing.Name = info.Name
+		}
+		if info.Type != "" {
+			existing.Type = info.Type
+		}
+		return existing, s.deviceRepo.Update(ctx, existing)
+	}
+	if err != core.ErrDeviceNotFound {
+		return nil, err
+	}
+
+	// 账户的第一台设备通常就是注册时使用的设备，无需提醒。
+	known, err := s.deviceRepo.FindByUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	device := &core.Device{
+		UserID:      user.ID,
+		DeviceID:    info.ID,
+		Name:        info.Name,
+		Type:        info.Type,
+		IPAddress:   ip,
+		FirstSeenAt: now,
+		LastSeenAt:  now,
+	}
+	if err := s.deviceRepo.Create(ctx, device); err != nil {
+		return nil, err
+	}
+	slog.Info("New device registered for user", "user_id", user.ID, "device_id", device.ID)
+	s.auditor.Record(ctx, user.ID, core.AuditEventNewDeviceLogin, map[string]string{
+		"device_id":   device.ID.String(),
+		"device_name": device.Name,
+	})
+
+	if notify && len(known) > 0 {
+		go func() {
+			err := s.emailSvc.SendNewDeviceLoginEmail(user.Email, email.NewDeviceLoginTemplateData{
+				DeviceName: device.Name,
+				DeviceType: device.Type,
+				IPAddress:  device.IPAddress,
+				LoginTime:  now,
+			})
+			if err != nil {
+				slog.Error("Failed to send new device login email", "recipient", user.Email, "error", err)
+			}
+		}()
+	}
+	return device, nil
 }
 
 // GetMasterSalt 检索给定用户的主盐。
@@ -223,6 +414,9 @@ func (s *AuthService) VerifyPasswordResetToken(ctx context.Context, token string
 		_ = s.userRepo.Update(ctx, user)
 		return "", apierror.ErrResetTokenExpired
 	}
+	if err := checkAccountStatus(user); err != nil {
+		return "", err
+	}
 
 	return string(user.MasterSalt), nil
 }
@@ -241,6 +435,11 @@ func (s *AuthService) RequestPasswordReset(ctx context.Context, emailAddr string
 		slog.Error("Error finding user for password reset", "email", emailAddr, "error", err)
 		return apierror.ErrInternalServer
 	}
+	// 同样不向请求方透露账户状态，只是不发送重置邮件。
+	if user.AccountStatus() != core.UserStatusActive {
+		slog.Warn("Password reset requested for inactive account", "user_id", user.ID, "status", user.AccountStatus())
+		return nil
+	}
 
 	// 2. 生成一个安全的随机令牌。
 	token, err := crypto.GenerateRandomString(32)
@@ -259,6 +458,7 @@ func (s *AuthService) RequestPasswordReset(ctx context.Context, emailAddr string
 	if err := s.userRepo.Update(ctx, user); err != nil {
 		return apierror.ErrInternalServer
 	}
+	s.auditor.Record(ctx, user.ID, core.AuditEventPasswordResetRequest, nil)
 
 	// 4. 发送密码重置邮件。
 	// 在一个 goroutine 中发送以避免阻塞。
@@ -306,6 +506,10 @@ func (s *AuthService) ResetPassword(ctx context.Context, token, newMasterKeyHash
 		_ = s.userRepo.Update(ctx, user)
 		return apierror.ErrResetTokenExpired
 	}
+	if err := checkAccountStatus(user); err != nil {
+		slog.Warn("Password reset failed: account not active", "user_id", user.ID, "status", user.AccountStatus())
+		return err
+	}
 
 	// 3. 更新用户的 AuthHash 和 MasterSalt。
 	user.AuthHash = newMasterKeyHash
@@ -321,5 +525,6 @@ func (s *AuthService) ResetPassword(ctx context.Context, token, newMasterKeyHash
 	}
 
 	slog.Info("Password reset successfully", "user_id", user.ID)
+	s.auditor.Record(ctx, user.ID, core.AuditEventPasswordResetComplete, nil)
 	return nil
 }
@@ -0,0 +1,778 @@
+package auth
+
+import (
+	"context"
+	"easy-password-backend/config"
+	"easy-password-backend/internal/apierror"
+	"easy-password-backend/internal/audit"
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/email"
+	"easy-password-backend/internal/kms"
+	"easy-password-backend/internal/repository/memory"
+	"easy-password-backend/internal/service"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	testUsername = "alice"
+	testEmail    = "alice@example.com"
+	testHash     = "master-key-hash"
+	testSalt     = "master-salt"
+)
+
+// sentEmail 记录测试邮件服务收到的一封邮件。
+type sentEmail struct {
+	kind     string
+	to       string
+	code     string
+	link     string
+	newEmail string
+}
+
+// recordingEmailService 把邮件写入通道而不是发送。AuthService 在 goroutine 中发送邮件，
+// 测试用 next 等待。
+type recordingEmailService struct {
+	sent chan sentEmail
+}
+
+func (r *recordingEmailService) record(m sentEmail) error {
+	select {
+	case r.sent <- m:
+	default:
+	}
+	return nil
+}
+
+func (r *recordingEmailService) SendEmail(to, subject, body string) error {
+	return r.record(sentEmail{kind: "plain", to: to})
+}
+
+func (r *recordingEmailService) SendPasswordResetEmail(to, resetLink string) error {
+	return r.record(sentEmail{kind: "reset", to: to, link: resetLink})
+}
+
+func (r *recordingEmailService) SendVerificationCodeEmail(to, code string) error {
+	return r.record(sentEmail{kind: "verification", to: to, code: code})
+}
+
+func (r *recordingEmailService) SendNewDeviceLoginEmail(to string, data email.NewDeviceLoginTemplateData) error {
+	return r.record(sentEmail{kind: "new_device", to: to})
+}
+
+func (r *recordingEmailService) SendLoginApprovalEmail(to string, data email.LoginApprovalTemplateData) error {
+	return r.record(sentEmail{kind: "approval", to: to, code: data.Code, link: data.ApprovalLink})
+}
+
+func (r *recordingEmailService) SendAccountDeletedEmail(to, username string) error {
+	return r.record(sentEmail{kind: "deleted", to: to})
+}
+
+func (r *recordingEmailService) SendEmailChangeCodeEmail(to, code string) error {
+	return r.record(sentEmail{kind: "email_change", to: to, code: code})
+}
+
+func (r *recordingEmailService) SendEmailChangedNoticeEmail(to, newEmail string) error {
+	return r.record(sentEmail{kind: "email_changed", to: to, newEmail: newEmail})
+}
+
+// next 返回下一封类型为 kind 的邮件，跳过其他类型的邮件。
+func (r *recordingEmailService) next(t *testing.T, kind string) sentEmail {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case m := <-r.sent:
+			if m.kind == kind {
+				return m
+			}
+		case <-timeout:
+			t.Fatalf("no %s email was sent", kind)
+		}
+	}
+}
+
+// none 确认在短时间内没有发出类型为 kind 的邮件。
+func (r *recordingEmailService) none(t *testing.T, kind string) {
+	t.Helper()
+	timeout := time.After(200 * time.Millisecond)
+	for {
+		select {
+		case m := <-r.sent:
+			if m.kind == kind {
+				t.Fatalf("unexpected %s email to %s", kind, m.to)
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
+
+type testEnv struct {
+	auth    *AuthService
+	storage *memory.Storage
+	emails  *recordingEmailService
+	auditor *audit.AuditService
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	storage := memory.NewMemoryStorage()
+	emails := &recordingEmailService{sent: make(chan sentEmail, 32)}
+	auditor := audit.NewAuditService(storage.Audit())
+	cfg := &config.Config{JWTExpiration: time.Hour, FrontendURL: "http://localhost:5173"}
+	keys := kms.NewStaticProvider(map[kms.Purpose][]kms.Key{
+		kms.PurposeJWT: {kms.NewKey(kms.AlgHS256, []byte("test jwt secret"))},
+	})
+	return &testEnv{
+		auth:    NewAuthService(storage.User(), storage.VerificationCode(), storage.Device(), storage.LoginApproval(), storage.APIKey(), emails, auditor, cfg, keys),
+		storage: storage,
+		emails:  emails,
+		auditor: auditor,
+	}
+}
+
+// createUser 直接在存储中创建一个 active 用户，跳过注册流程。
+func (e *testEnv) createUser(t *testing.T, requireApproval bool) *core.User {
+	t.Helper()
+	user := &core.User{
+		Username:              testUsername,
+		Email:                 testEmail,
+		AuthHash:              testHash,
+		MasterSalt:            []byte(testSalt),
+		RequireDeviceApproval: requireApproval,
+	}
+	if err := e.storage.User().Create(context.Background(), user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// login 登录并要求登录立即成功。
+func (e *testEnv) login(t *testing.T, device DeviceInfo) *LoginResult {
+	t.Helper()
+	result, err := e.auth.Login(context.Background(), testUsername, testHash, device)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if result.Token == "" {
+		t.Fatalf("login is pending approval %s, want a token", result.PendingApprovalID)
+	}
+	return result
+}
+
+func TestLoginWithoutDeviceIDRequiresApproval(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	user := env.createUser(t, true)
+	if err := env.storage.Device().Create(ctx, &core.Device{UserID: user.ID, DeviceID: "laptop", FirstSeenAt: time.Now(), LastSeenAt: time.Now()}); err != nil {
+		t.Fatalf("create device: %v", err)
+	}
+
+	result, err := env.auth.Login(ctx, testUsername, testHash, DeviceInfo{Name: "no id"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if result.Token != "" || result.PendingApprovalID == uuid.Nil {
+		t.Fatalf("login without device_id returned a token, want it held for approval")
+	}
+	approvalEmail := env.emails.next(t, "approval")
+
+	approved, err := env.auth.VerifyLoginApprovalCode(ctx, result.PendingApprovalID, approvalEmail.code)
+	if err != nil {
+		t.Fatalf("verify approval code: %v", err)
+	}
+	if approved.Token == "" || approved.DeviceID == "" {
+		t.Fatalf("approved login = %+v, want a token and the generated device ID", approved)
+	}
+
+	// 沿用服务器生成的设备 ID 再次登录时，设备已知，无需再次批准。
+	env.login(t, DeviceInfo{ID: approved.DeviceID})
+}
+
+func TestLoginWithoutDeviceIDTracksDevice(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	user := env.createUser(t, false)
+
+	result := env.login(t, DeviceInfo{})
+	if result.DeviceID == "" {
+		t.Fatal("login without device_id returned no device ID")
+	}
+	if _, err := env.storage.Device().FindByUserAndDeviceID(ctx, user.ID, result.DeviceID); err != nil {
+		t.Fatalf("generated device was not recorded: %v", err)
+	}
+}
+
+// TestLoginWithReturnedDeviceID 检查客户端在之后的登录中沿用服务器生成的设备 ID 时，
+// 不会登记新的设备，也不会再次发送新设备提醒。
+func TestLoginWithReturnedDeviceID(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	user := env.createUser(t, false)
+	env.login(t, DeviceInfo{ID: "laptop", Name: "Laptop"})
+
+	first := env.login(t, DeviceInfo{Name: "Browser extension"})
+	env.emails.next(t, "new_device")
+	second := env.login(t, DeviceInfo{ID: first.DeviceID, Name: "Browser extension"})
+	if second.DeviceID != first.DeviceID {
+		t.Fatalf("second login bound to device %q, want %q", second.DeviceID, first.DeviceID)
+	}
+	env.emails.none(t, "new_device")
+
+	devices, err := env.storage.Device().FindByUser(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("user has %d devices, want 2", len(devices))
+	}
+}
+
+func TestRevokedDeviceTokenStaysRejected(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	user := env.createUser(t, false)
+	devices := service.NewDeviceService(env.storage.Device(), env.auditor)
+
+	device := DeviceInfo{ID: "laptop", Name: "Laptop"}
+	before := env.login(t, device)
+	if _, _, _, err := env.auth.ValidateToken(ctx, before.Token); err != nil {
+		t.Fatalf("token before revocation: %v", err)
+	}
+
+	record, err := env.storage.Device().FindByUserAndDeviceID(ctx, user.ID, device.ID)
+	if err != nil {
+		t.Fatalf("find device: %v", err)
+	}
+	if err := devices.RevokeDevice(ctx, record.ID, user.ID); err != nil {
+		t.Fatalf("revoke device: %v", err)
+	}
+	if _, _, _, err := env.auth.ValidateToken(ctx, before.Token); !errors.Is(err, apierror.ErrInvalidToken) {
+		t.Fatalf("token after revocation: error = %v, want %s", err, apierror.ErrInvalidToken.Code)
+	}
+
+	// 同一设备再次登录会登记一条新的设备记录，撤销前签发的令牌仍然无效。
+	after := env.login(t, device)
+	if _, _, _, err := env.auth.ValidateToken(ctx, after.Token); err != nil {
+		t.Fatalf("token after logging in again: %v", err)
+	}
+	if _, _, _, err := env.auth.ValidateToken(ctx, before.Token); !errors.Is(err, apierror.ErrInvalidToken) {
+		t.Fatalf("revoked token after logging in again: error = %v, want %s", err, apierror.ErrInvalidToken.Code)
+	}
+}
+
+// expectAPIError 确认 err 是 want；want 为 nil 时确认没有错误。
+func expectAPIError(t *testing.T, op string, err error, want *apierror.APIError) {
+	t.Helper()
+	switch {
+	case want == nil && err != nil:
+		t.Fatalf("%s: unexpected error: %v", op, err)
+	case want != nil && !errors.Is(err, want):
+		t.Fatalf("%s: error = %v, want %s", op, err, want.Code)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	const code = "123456"
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, env *testEnv)
+		code  string
+		want  *apierror.APIError
+	}{
+		{
+			name: "valid code",
+			code: code,
+		},
+		{
+			name: "wrong code",
+			code: "654321",
+			want: apierror.ErrInvalidVerificationCode,
+		},
+		{
+			name: "no code sent",
+			setup: func(t *testing.T, env *testEnv) {
+				if err := env.storage.VerificationCode().Delete(context.Background(), testEmail); err != nil {
+					t.Fatalf("delete code: %v", err)
+				}
+			},
+			code: code,
+			want: apierror.ErrInvalidVerificationCode,
+		},
+		{
+			name: "expired code",
+			setup: func(t *testing.T, env *testEnv) {
+				env.storeCode(t, testEmail, code, time.Now().Add(-time.Minute))
+			},
+			code: code,
+			want: apierror.ErrVerificationCodeExpired,
+		},
+		{
+			name: "username taken",
+			setup: func(t *testing.T, env *testEnv) {
+				user := env.createUser(t, false)
+				env.updateUser(t, user.ID, func(u *core.User) { u.Email = "other@example.com" })
+			},
+			code: code,
+			want: apierror.ErrUserOrEmailExists,
+		},
+		{
+			name: "email taken",
+			setup: func(t *testing.T, env *testEnv) {
+				user := env.createUser(t, false)
+				env.updateUser(t, user.ID, func(u *core.User) { u.Username = "other" })
+			},
+			code: code,
+			want: apierror.ErrUserOrEmailExists,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			env := newTestEnv(t)
+			env.storeCode(t, testEmail, code, time.Now().Add(5*time.Minute))
+			if tt.setup != nil {
+				tt.setup(t, env)
+			}
+
+			user, err := env.auth.Register(ctx, testUsername, testEmail, testHash, testSalt, tt.code)
+			expectAPIError(t, "register", err, tt.want)
+			if tt.want != nil {
+				return
+			}
+			stored, err := env.storage.User().FindByUsername(ctx, testUsername)
+			if err != nil {
+				t.Fatalf("registered user was not stored: %v", err)
+			}
+			if stored.ID != user.ID || stored.Email != testEmail || stored.AuthHash != testHash || string(stored.MasterSalt) != testSalt {
+				t.Errorf("stored user = %+v, want the registered account", stored)
+			}
+			// 验证码只能使用一次。
+			if _, err := env.storage.VerificationCode().Find(ctx, testEmail); !errors.Is(err, core.ErrVerificationCodeNotFound) {
+				t.Errorf("verification code after register: error = %v, want it deleted", err)
+			}
+		})
+	}
+}
+
+// storeCode 直接在存储中写入 addr 的验证码。
+func (e *testEnv) storeCode(t *testing.T, addr, code string, expiresAt time.Time) {
+	t.Helper()
+	vc := &core.VerificationCode{Email: addr, Code: code, ExpiresAt: expiresAt}
+	if err := e.storage.VerificationCode().Create(context.Background(), vc); err != nil {
+		t.Fatalf("store verification code: %v", err)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	tests := []struct {
+		name            string
+		requireApproval bool
+		status          core.UserStatus
+		knownDevice     bool
+		identifier      string
+		hash            string
+		want            *apierror.APIError
+		wantPending     bool
+	}{
+		{name: "username", identifier: testUsername, hash: testHash},
+		{name: "email", identifier: testEmail, hash: testHash},
+		{name: "wrong hash", identifier: testUsername, hash: "wrong", want: apierror.ErrInvalidCredentials},
+		{name: "unknown user", identifier: "bob", hash: testHash, want: apierror.ErrInvalidCredentials},
+		{name: "suspended", status: core.UserStatusSuspended, identifier: testUsername, hash: testHash, want: apierror.ErrAccountSuspended},
+		{name: "locked", status: core.UserStatusLocked, identifier: testUsername, hash: testHash, want: apierror.ErrAccountLocked},
+		{name: "pending deletion", status: core.UserStatusPendingDeletion, identifier: testUsername, hash: testHash, want: apierror.ErrAccountPendingDeletion},
+		{name: "approval for unknown device", requireApproval: true, identifier: testUsername, hash: testHash, wantPending: true},
+		{name: "approval for known device", requireApproval: true, knownDevice: true, identifier: testUsername, hash: testHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			env := newTestEnv(t)
+			user := env.createUser(t, tt.requireApproval)
+			if tt.status != "" {
+				user.Status = tt.status
+				if err := env.storage.User().Update(ctx, user); err != nil {
+					t.Fatalf("update user: %v", err)
+				}
+			}
+			if tt.knownDevice {
+				if err := env.storage.Device().Create(ctx, &core.Device{UserID: user.ID, DeviceID: "laptop", FirstSeenAt: time.Now(), LastSeenAt: time.Now()}); err != nil {
+					t.Fatalf("create device: %v", err)
+				}
+			}
+
+			result, err := env.auth.Login(ctx, tt.identifier, tt.hash, DeviceInfo{ID: "laptop"})
+			expectAPIError(t, "login", err, tt.want)
+			if tt.want != nil {
+				return
+			}
+			if tt.wantPending {
+				if result.Token != "" || result.PendingApprovalID == uuid.Nil {
+					t.Fatalf("login = %+v, want it held for approval", result)
+				}
+				return
+			}
+			if result.Token == "" || result.MasterSalt != testSalt || result.Username != testUsername || result.DeviceID != "laptop" {
+				t.Fatalf("login = %+v, want a token for alice on laptop", result)
+			}
+			_, tokenUser, _, err := env.auth.ValidateToken(ctx, result.Token)
+			if err != nil {
+				t.Fatalf("validate token: %v", err)
+			}
+			if tokenUser.ID != user.ID {
+				t.Errorf("token user = %s, want %s", tokenUser.ID, user.ID)
+			}
+		})
+	}
+}
+
+func TestLoginApproval(t *testing.T) {
+	tests := []struct {
+		name string
+		// resolve 在登录挂起后完成批准流程，返回最终的登录结果。
+		resolve func(t *testing.T, env *testEnv, id uuid.UUID, mail sentEmail) (*LoginResult, error)
+		want    *apierror.APIError
+	}{
+		{
+			name: "code",
+			resolve: func(t *testing.T, env *testEnv, id uuid.UUID, mail sentEmail) (*LoginResult, error) {
+				return env.auth.VerifyLoginApprovalCode(context.Background(), id, mail.code)
+			},
+		},
+		{
+			name: "link then poll",
+			resolve: func(t *testing.T, env *testEnv, id uuid.UUID, mail sentEmail) (*LoginResult, error) {
+				ctx := context.Background()
+				pending, err := env.auth.PollLoginApproval(ctx, id)
+				if err != nil || pending.Token != "" {
+					t.Fatalf("poll before approval = %+v, %v; want still pending", pending, err)
+				}
+				token := mail.link[strings.LastIndex(mail.link, "/")+1:]
+				if err := env.auth.ApproveLogin(ctx, token); err != nil {
+					t.Fatalf("approve login: %v", err)
+				}
+				return env.auth.PollLoginApproval(ctx, id)
+			},
+		},
+		{
+			name: "wrong code",
+			resolve: func(t *testing.T, env *testEnv, id uuid.UUID, mail sentEmail) (*LoginResult, error) {
+				return env.auth.VerifyLoginApprovalCode(context.Background(), id, "000000x")
+			},
+			want: apierror.ErrInvalidApprovalCode,
+		},
+		{
+			name: "too many attempts",
+			resolve: func(t *testing.T, env *testEnv, id uuid.UUID, mail sentEmail) (*LoginResult, error) {
+				ctx := context.Background()
+				for range maxLoginApprovalAttempts {
+					_, _ = env.auth.VerifyLoginApprovalCode(ctx, id, "000000x")
+				}
+				// 次数用尽后，正确的验证码也不再被接受。
+				return env.auth.VerifyLoginApprovalCode(ctx, id, mail.code)
+			},
+			want: apierror.ErrTooManyAttempts,
+		},
+		{
+			name: "expired",
+			resolve: func(t *testing.T, env *testEnv, id uuid.UUID, mail sentEmail) (*LoginResult, error) {
+				ctx := context.Background()
+				approval, err := env.storage.LoginApproval().FindByID(ctx, id)
+				if err != nil {
+					t.Fatalf("find approval: %v", err)
+				}
+				approval.ExpiresAt = time.Now().Add(-time.Second)
+				if err := env.storage.LoginApproval().Update(ctx, approval); err != nil {
+					t.Fatalf("update approval: %v", err)
+				}
+				return env.auth.VerifyLoginApprovalCode(ctx, id, mail.code)
+			},
+			want: apierror.ErrLoginApprovalExpired,
+		},
+		{
+			name: "unknown request",
+			resolve: func(t *testing.T, env *testEnv, id uuid.UUID, mail sentEmail) (*LoginResult, error) {
+				return env.auth.PollLoginApproval(context.Background(), uuid.New())
+			},
+			want: apierror.ErrLoginApprovalNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			env := newTestEnv(t)
+			user := env.createUser(t, true)
+
+			pending, err := env.auth.Login(ctx, testUsername, testHash, DeviceInfo{ID: "phone", Name: "Phone"})
+			if err != nil {
+				t.Fatalf("login: %v", err)
+			}
+			if pending.PendingApprovalID == uuid.Nil {
+				t.Fatalf("login = %+v, want it held for approval", pending)
+			}
+			mail := env.emails.next(t, "approval")
+			if mail.to != testEmail {
+				t.Errorf("approval email sent to %q, want %q", mail.to, testEmail)
+			}
+
+			result, err := tt.resolve(t, env, pending.PendingApprovalID, mail)
+			expectAPIError(t, "resolve approval", err, tt.want)
+			_, deviceErr := env.storage.Device().FindByUserAndDeviceID(ctx, user.ID, "phone")
+			if tt.want != nil {
+				if !errors.Is(deviceErr, core.ErrDeviceNotFound) {
+					t.Errorf("device after failed approval: error = %v, want it unknown", deviceErr)
+				}
+				return
+			}
+			if result.Token == "" || result.DeviceID != "phone" {
+				t.Fatalf("approved login = %+v, want a token for phone", result)
+			}
+			if deviceErr != nil {
+				t.Errorf("approved device was not recorded: %v", deviceErr)
+			}
+			// 批准请求只能兑换一次令牌。
+			if _, err := env.auth.PollLoginApproval(ctx, pending.PendingApprovalID); !errors.Is(err, apierror.ErrLoginApprovalNotFound) {
+				t.Errorf("poll after completion: error = %v, want %s", err, apierror.ErrLoginApprovalNotFound.Code)
+			}
+		})
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	const newHash, newSalt = "new-master-key-hash", "new-master-salt"
+	tests := []struct {
+		name string
+		// prepare 在重置邮件发出后调整存储或令牌，返回用于重置的令牌。
+		prepare func(t *testing.T, env *testEnv, user *core.User, token string) string
+		want    *apierror.APIError
+	}{
+		{
+			name:    "valid token",
+			prepare: func(t *testing.T, env *testEnv, user *core.User, token string) string { return token },
+		},
+		{
+			name:    "wrong token",
+			prepare: func(t *testing.T, env *testEnv, user *core.User, token string) string { return token + "x" },
+			want:    apierror.ErrInvalidResetToken,
+		},
+		{
+			name:    "empty token",
+			prepare: func(t *testing.T, env *testEnv, user *core.User, token string) string { return "" },
+			want:    apierror.ErrInvalidResetToken,
+		},
+		{
+			name: "expired token",
+			prepare: func(t *testing.T, env *testEnv, user *core.User, token string) string {
+				env.updateUser(t, user.ID, func(u *core.User) {
+					expired := time.Now().Add(-time.Minute)
+					u.ResetPasswordTokenExpiresAt = &expired
+				})
+				return token
+			},
+			want: apierror.ErrResetTokenExpired,
+		},
+		{
+			name: "suspended after request",
+			prepare: func(t *testing.T, env *testEnv, user *core.User, token string) string {
+				env.updateUser(t, user.ID, func(u *core.User) { u.Status = core.UserStatusSuspended })
+				return token
+			},
+			want: apierror.ErrAccountSuspended,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			env := newTestEnv(t)
+			user := env.createUser(t, false)
+
+			if err := env.auth.RequestPasswordReset(ctx, testEmail); err != nil {
+				t.Fatalf("request reset: %v", err)
+			}
+			mail := env.emails.next(t, "reset")
+			token := tt.prepare(t, env, user, mail.link[strings.LastIndex(mail.link, "/")+1:])
+
+			if tt.want == nil {
+				salt, err := env.auth.VerifyPasswordResetToken(ctx, token)
+				if err != nil || salt != testSalt {
+					t.Fatalf("verify reset token = %q, %v; want the current salt", salt, err)
+				}
+			}
+			err := env.auth.ResetPassword(ctx, token, newHash, newSalt)
+			expectAPIError(t, "reset password", err, tt.want)
+
+			wantHash := testHash
+			if tt.want == nil {
+				wantHash = newHash
+				// 令牌只能使用一次。
+				if err := env.auth.ResetPassword(ctx, token, "again", "again"); !errors.Is(err, apierror.ErrInvalidResetToken) {
+					t.Errorf("reusing reset token: error = %v, want %s", err, apierror.ErrInvalidResetToken.Code)
+				}
+			}
+			stored, err := env.storage.User().FindByID(ctx, user.ID)
+			if err != nil {
+				t.Fatalf("find user: %v", err)
+			}
+			if stored.AuthHash != wantHash {
+				t.Errorf("auth hash after reset = %q, want %q", stored.AuthHash, wantHash)
+			}
+		})
+	}
+}
+
+func TestPasswordResetForUnknownEmail(t *testing.T) {
+	env := newTestEnv(t)
+	env.createUser(t, false)
+	// 不向请求方透露邮箱是否已注册。
+	if err := env.auth.RequestPasswordReset(context.Background(), "bob@example.com"); err != nil {
+		t.Fatalf("request reset for unknown email: %v", err)
+	}
+	select {
+	case m := <-env.emails.sent:
+		t.Fatalf("sent a %s email for an unknown address", m.kind)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// updateUser 直接修改存储中的用户。
+func (e *testEnv) updateUser(t *testing.T, id uuid.UUID, change func(u *core.User)) {
+	t.Helper()
+	user, err := e.storage.User().FindByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("find user: %v", err)
+	}
+	change(user)
+	if err := e.storage.User().Update(context.Background(), user); err != nil {
+		t.Fatalf("update user: %v", err)
+	}
+}
+
+func TestEmailChange(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.createUser(t, false)
+	const newEmail = "alice@example.org"
+
+	if err := env.auth.RequestEmailChange(ctx, user.ID, newEmail, testHash); err != nil {
+		t.Fatalf("RequestEmailChange: %v", err)
+	}
+	code := env.emails.next(t, "email_change")
+	if code.to != newEmail {
+		t.Fatalf("verification code sent to %s, want %s", code.to, newEmail)
+	}
+	if err := env.auth.ConfirmEmailChange(ctx, user.ID, newEmail, code.code); err != nil {
+		t.Fatalf("ConfirmEmailChange: %v", err)
+	}
+
+	// 旧邮箱收到通知，其中写明新邮箱。
+	notice := env.emails.next(t, "email_changed")
+	if notice.to != testEmail || notice.newEmail != newEmail {
+		t.Fatalf("notice = %+v, want one to %s naming %s", notice, testEmail, newEmail)
+	}
+	stored, err := env.storage.User().FindByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Email != newEmail {
+		t.Fatalf("email = %s, want %s", stored.Email, newEmail)
+	}
+	if stored.PendingEmail != nil || stored.PendingEmailCodeHash != nil || stored.PendingEmailExpiresAt != nil {
+		t.Fatal("pending email change was not cleared")
+	}
+}
+
+// TestEmailChangeCodeScope 检查更换邮箱的验证码只对请求它的用户、请求时的新邮箱和有效期内有效，
+// 发往同一地址的注册验证码也不能用来更换邮箱。
+func TestEmailChangeCodeScope(t *testing.T) {
+	const newEmail = "alice@example.org"
+	tests := []struct {
+		name    string
+		confirm func(t *testing.T, env *testEnv, alice, bob *core.User, code string) error
+		want    *apierror.APIError
+	}{
+		{
+			name: "registration code",
+			confirm: func(t *testing.T, env *testEnv, alice, bob *core.User, code string) error {
+				env.storeCode(t, newEmail, "654321", time.Now().Add(time.Minute))
+				return env.auth.ConfirmEmailChange(context.Background(), alice.ID, newEmail, "654321")
+			},
+			want: apierror.ErrInvalidVerificationCode,
+		},
+		{
+			name: "another user's code",
+			confirm: func(t *testing.T, env *testEnv, alice, bob *core.User, code string) error {
+				return env.auth.ConfirmEmailChange(context.Background(), bob.ID, newEmail, code)
+			},
+			want: apierror.ErrInvalidVerificationCode,
+		},
+		{
+			name: "different address",
+			confirm: func(t *testing.T, env *testEnv, alice, bob *core.User, code string) error {
+				return env.auth.ConfirmEmailChange(context.Background(), alice.ID, "mallory@example.org", code)
+			},
+			want: apierror.ErrInvalidVerificationCode,
+		},
+		{
+			name: "expired",
+			confirm: func(t *testing.T, env *testEnv, alice, bob *core.User, code string) error {
+				env.updateUser(t, alice.ID, func(u *core.User) {
+					expired := time.Now().Add(-time.Minute)
+					u.PendingEmailExpiresAt = &expired
+				})
+				return env.auth.ConfirmEmailChange(context.Background(), alice.ID, newEmail, code)
+			},
+			want: apierror.ErrVerificationCodeExpired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			alice := env.createUser(t, false)
+			bob := &core.User{Username: "bob", Email: "bob@example.com", AuthHash: "bob-hash", MasterSalt: []byte(testSalt)}
+			if err := env.storage.User().Create(context.Background(), bob); err != nil {
+				t.Fatal(err)
+			}
+			if err := env.auth.RequestEmailChange(context.Background(), alice.ID, newEmail, testHash); err != nil {
+				t.Fatalf("RequestEmailChange: %v", err)
+			}
+			code := env.emails.next(t, "email_change").code
+
+			err := tt.confirm(t, env, alice, bob, code)
+			expectAPIError(t, "ConfirmEmailChange", err, tt.want)
+			for _, user := range []*core.User{alice, bob} {
+				stored, err := env.storage.User().FindByID(context.Background(), user.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if stored.Email != user.Email {
+					t.Fatalf("%s's email changed to %s", user.Username, stored.Email)
+				}
+			}
+		})
+	}
+}
+
+func TestChangeUsername(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	user := env.createUser(t, false)
+
+	err := env.auth.ChangeUsername(ctx, user.ID, "alice2", "wrong-hash")
+	expectAPIError(t, "ChangeUsername(wrong hash)", err, apierror.ErrInvalidCredentials)
+	if err := env.auth.ChangeUsername(ctx, user.ID, "alice2", testHash); err != nil {
+		t.Fatalf("ChangeUsername: %v", err)
+	}
+	stored, err := env.storage.User().FindByID(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Username != "alice2" {
+		t.Fatalf("username = %s, want alice2", stored.Username)
+	}
+}
@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"easy-password-backend/internal/apierror"
+	"easy-password-backend/internal/core"
+)
+
+// checkAccountStatus 在账户不处于 active 状态时返回对应的错误。
+func checkAccountStatus(user *core.User) error {
+	switch user.AccountStatus() {
+	case core.UserStatusActive:
+		return nil
+	case core.UserStatusLocked:
+		return apierror.ErrAccountLocked
+	case core.UserStatusPendingDeletion:
+		return apierror.ErrAccountPendingDeletion
+	default:
+		return apierror.ErrAccountSuspended
+	}
+}
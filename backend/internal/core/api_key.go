@@ -0,0 +1,71 @@
+package core
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyScope 是授予 API 密钥的一项权限。
+type APIKeyScope string
+
+// 预定义的 API 密钥作用域。
+const (
+	APIKeyScopeVaultRead  APIKeyScope = "vault:read"
+	APIKeyScopeVaultWrite APIKeyScope = "vault:write"
+)
+
+// APIKeyScopes 列出所有可授予的作用域。
+var APIKeyScopes = []APIKeyScope{APIKeyScopeVaultRead, APIKeyScopeVaultWrite}
+
+// Valid 报告 s 是否是已知的作用域。
+func (s APIKeyScope) Valid() bool {
+	return slices.Contains(APIKeyScopes, s)
+}
+
+// APIKey 表示用户为脚本和 CI 创建的个人 API 密钥。
+// 客户端使用 ID（即 client ID）和密钥换取一个短期访问令牌，令牌只拥有密钥的作用域；
+// 与 LoginApproval 一样，密钥只以哈希形式存储。
+// ItemIDs 或 Categories 非空时，令牌只能访问列出的项目或属于列出类别的项目。
+type APIKey struct {
+	ID         uuid.UUID     `gorm:"type:uuid;primary_key"`
+	UserID     uuid.UUID     `gorm:"type:uuid;not null;index"`
+	Name       string        `gorm:"type:varchar(255);not null"`
+	SecretHash string        `gorm:"type:varchar(64);not null"`
+	Scopes     []APIKeyScope `gorm:"type:jsonb;serializer:json;not null"`
+	ItemIDs    []uuid.UUID   `gorm:"type:jsonb;serializer:json"`
+	Categories []string      `gorm:"type:jsonb;serializer:json"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
+
+// HasScope 报告密钥是否被授予了 scope。
+func (k *APIKey) HasScope(scope APIKeyScope) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// Expired 报告密钥在 now 时是否已过期。没有过期时间的密钥永不过期。
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// Restricted 报告密钥是否被限制为只能访问部分项目。
+func (k *APIKey) Restricted() bool {
+	return len(k.ItemIDs) > 0 || len(k.Categories) > 0
+}
+
+// AllowsItem 报告密钥是否可以访问 item。
+func (k *APIKey) AllowsItem(item *VaultItem) bool {
+	if !k.Restricted() {
+		return true
+	}
+	return slices.Contains(k.ItemIDs, item.ID) || slices.Contains(k.Categories, item.Category)
+}
+
+// AllowsCategory 报告密钥是否可以在 category 中创建项目。
+// 只按项目 ID 限制的密钥不能创建新项目。
+func (k *APIKey) AllowsCategory(category string) bool {
+	return !k.Restricted() || slices.Contains(k.Categories, category)
+}
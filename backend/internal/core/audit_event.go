@@ -0,0 +1,89 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEventType 标识审计事件的类别。
+type AuditEventType string
+
+// 预定义的审计事件类型。
+const (
+	AuditEventLoginSuccess          AuditEventType = "login.success"
+	AuditEventLoginFailure          AuditEventType = "login.failure"
+	AuditEventPasswordResetRequest  AuditEventType = "password_reset.request"
+	AuditEventPasswordResetComplete AuditEventType = "password_reset.complete"
+	AuditEventVaultItemCreate       AuditEventType = "vault_item.create"
+	AuditEventVaultItemUpdate       AuditEventType = "vault_item.update"
+	AuditEventVaultItemDelete       AuditEventType = "vault_item.delete"
+	AuditEventVaultExport           AuditEventType = "vault.export"
+	AuditEventVaultImport           AuditEventType = "vault.import"
+	AuditEventNewDeviceLogin        AuditEventType = "device.new_login"
+	AuditEventDeviceRevoke          AuditEventType = "device.revoke"
+	AuditEventLoginApprovalRequest  AuditEventType = "login_approval.request"
+	AuditEventLoginApprovalGrant    AuditEventType = "login_approval.grant"
+	AuditEventLoginApprovalSetting  AuditEventType = "login_approval.setting"
+	AuditEventAccountDelete         AuditEventType = "account.delete"
+	AuditEventEmailChange           AuditEventType = "account.email_change"
+	AuditEventUsernameChange        AuditEventType = "account.username_change"
+	AuditEventAccountStatusChange   AuditEventType = "account.status_change"
+	AuditEventRoleChange            AuditEventType = "account.role_change"
+	AuditEventBackupDownload        AuditEventType = "admin.backup_download"
+	AuditEventSessionsRevoke        AuditEventType = "account.sessions_revoke"
+	AuditEventEncryptionKeyRotate   AuditEventType = "admin.encryption_key_rotate"
+	AuditEventAPIKeyCreate          AuditEventType = "api_key.create"
+	AuditEventAPIKeyRevoke          AuditEventType = "api_key.revoke"
+	AuditEventAPIKeyExchange        AuditEventType = "api_key.exchange"
+)
+
+// AuditEvent 表示一条与账户安全相关的审计记录。
+// 每条记录都通过 PrevHash（全局链）和 UserPrevHash（用户链）
+// 链接到前一条记录，任何篡改都会使后续记录的哈希校验失败。
+type AuditEvent struct {
+	ID           uuid.UUID         `gorm:"type:uuid;primary_key"`
+	Sequence     int64             `gorm:"not null;uniqueIndex"`
+	UserID       uuid.UUID         `gorm:"type:uuid;not null;index"`
+	Type         AuditEventType    `gorm:"type:varchar(100);not null;index"`
+	IPAddress    string            `gorm:"type:varchar(64)"`
+	UserAgent    string            `gorm:"type:text"`
+	Metadata     map[string]string `gorm:"type:jsonb;serializer:json"`
+	CreatedAt    time.Time         `gorm:"not null;index"`
+	PrevHash     string            `gorm:"type:varchar(64);not null"`
+	UserPrevHash string            `gorm:"type:varchar(64);not null"`
+	Hash         string            `gorm:"type:varchar(64);not null"`
+}
+
+// ComputeHash 计算事件内容（不含 Hash 本身）的 SHA-256 哈希值。
+// CreatedAt 以微秒精度的 UTC 时间参与计算，以便在各存储后端之间保持一致。
+func (e *AuditEvent) ComputeHash() string {
+	payload, _ := json.Marshal(struct {
+		ID           uuid.UUID         `json:"id"`
+		Sequence     int64             `json:"sequence"`
+		UserID       uuid.UUID         `json:"user_id"`
+		Type         AuditEventType    `json:"type"`
+		IPAddress    string            `json:"ip_address"`
+		UserAgent    string            `json:"user_agent"`
+		Metadata     map[string]string `json:"metadata"`
+		CreatedAt    string            `json:"created_at"`
+		PrevHash     string            `json:"prev_hash"`
+		UserPrevHash string            `json:"user_prev_hash"`
+	}{
+		ID:           e.ID,
+		Sequence:     e.Sequence,
+		UserID:       e.UserID,
+		Type:         e.Type,
+		IPAddress:    e.IPAddress,
+		UserAgent:    e.UserAgent,
+		Metadata:     e.Metadata,
+		CreatedAt:    e.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+		PrevHash:     e.PrevHash,
+		UserPrevHash: e.UserPrevHash,
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
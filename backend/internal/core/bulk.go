@@ -0,0 +1,56 @@
+package core
+
+import "context"
+
+// RecordKind 标识一类可在存储后端之间迁移的记录。
+type RecordKind string
+
+// 可迁移的记录类型。
+const (
+	RecordUsers             RecordKind = "users"
+	RecordVaultItems        RecordKind = "vault_items"
+	RecordVerificationCodes RecordKind = "verification_codes"
+	RecordDevices           RecordKind = "devices"
+	RecordLoginApprovals    RecordKind = "login_approvals"
+	RecordAPIKeys           RecordKind = "api_keys"
+	RecordAuditEvents       RecordKind = "audit_events"
+)
+
+// RecordKinds 按写入顺序列出所有记录类型：用户在前，引用用户的记录在后。
+var RecordKinds = []RecordKind{
+	RecordUsers, RecordVaultItems, RecordVerificationCodes,
+	RecordDevices, RecordLoginApprovals, RecordAPIKeys, RecordAuditEvents,
+}
+
+// NewRecord 返回指定类型的一条空记录的指针，例如 RecordUsers 对应 *User。
+func NewRecord(kind RecordKind) any {
+	switch kind {
+	case RecordUsers:
+		return &User{}
+	case RecordVaultItems:
+		return &VaultItem{}
+	case RecordVerificationCodes:
+		return &VerificationCode{}
+	case RecordDevices:
+		return &Device{}
+	case RecordLoginApprovals:
+		return &LoginApproval{}
+	case RecordAPIKeys:
+		return &APIKey{}
+	case RecordAuditEvents:
+		return &AuditEvent{}
+	default:
+		return nil
+	}
+}
+
+// BulkRepository 提供跨存储后端迁移所需的原样读写操作。
+// 与各存储库的 Create 不同，Insert 不会分配 ID、序号、哈希或时间戳，而是原样保留记录中的值。
+type BulkRepository interface {
+	Count(ctx context.Context, kind RecordKind) (int64, error)
+	// ForEach 遍历一类记录，每条记录都是 NewRecord(kind) 返回类型的新值。
+	// 审计事件按序号升序返回。
+	ForEach(ctx context.Context, kind RecordKind, fn func(record any) error) error
+	// Insert 在一个事务中写入一批同类记录。审计事件必须按序号升序写入。
+	Insert(ctx context.Context, kind RecordKind, records []any) error
+}
@@ -0,0 +1,20 @@
+package core
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Device 表示用户登录过的一个客户端设备。
+// DeviceID 由客户端生成并在每次登录时提交。
+type Device struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_devices_user_device"`
+	DeviceID    string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_devices_user_device"`
+	Name        string    `gorm:"type:varchar(255)"`
+	Type        string    `gorm:"type:varchar(50)"`
+	IPAddress   string    `gorm:"type:varchar(64)"`
+	FirstSeenAt time.Time `gorm:"not null"`
+	LastSeenAt  time.Time `gorm:"not null"`
+}
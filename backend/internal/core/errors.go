@@ -7,6 +7,13 @@ var (
 	ErrUserNotFound      = errors.New("user not found")
 	ErrVaultItemNotFound = errors.New("vault item not found")
 	ErrVerificationCodeNotFound = errors.New("verification code not found")
+	ErrDeviceNotFound           = errors.New("device not found")
+	ErrLoginApprovalNotFound    = errors.New("login approval not found")
+	ErrAPIKeyNotFound           = errors.New("api key not found")
+	// 存储后端不支持在线备份时返回
+	ErrBackupNotSupported = errors.New("online backup is not supported by this storage backend")
+	// 存储后端未启用静态加密时返回
+	ErrEncryptionNotEnabled = errors.New("at-rest encryption is not enabled for this storage backend")
 )
 
 // 当违反唯一约束时返回 DuplicateEntryError。
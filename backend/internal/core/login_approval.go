@@ -0,0 +1,33 @@
+package core
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginApprovalStatus 表示待批准登录的状态。
+type LoginApprovalStatus string
+
+const (
+	LoginApprovalPending  LoginApprovalStatus = "pending"
+	LoginApprovalApproved LoginApprovalStatus = "approved"
+)
+
+// LoginApproval 表示一次来自未知设备、等待用户通过邮件批准的登录。
+// 与 VerificationCode 一样，它是一个短期有效的凭据：邮件中同时包含
+// 一个可在新设备上输入的验证码和一个批准链接，两者都只以哈希形式存储。
+type LoginApproval struct {
+	ID         uuid.UUID           `gorm:"type:uuid;primary_key"`
+	UserID     uuid.UUID           `gorm:"type:uuid;not null;index"`
+	DeviceID   string              `gorm:"type:varchar(255);not null"`
+	DeviceName string              `gorm:"type:varchar(255)"`
+	DeviceType string              `gorm:"type:varchar(50)"`
+	IPAddress  string              `gorm:"type:varchar(64)"`
+	CodeHash   string              `gorm:"type:varchar(64);not null"`
+	TokenHash  string              `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Attempts   int                 `gorm:"not null;default:0"`
+	Status     LoginApprovalStatus `gorm:"type:varchar(20);not null"`
+	ExpiresAt  time.Time           `gorm:"not null;index"`
+	CreatedAt  time.Time           `gorm:"autoCreateTime"`
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -9,17 +10,32 @@ import (
 // UserRepository 定义了用户数据操作的接口。
 type UserRepository interface {
 	Create(ctx context.Context, user *User) error
+	FindByID(ctx context.Context, id uuid.UUID) (*User, error)
 	FindByUsername(ctx context.Context, username string) (*User, error)
 	FindByEmail(ctx context.Context, email string) (*User, error)
 	FindByResetPasswordToken(ctx context.Context, token string) (*User, error)
+	// List 按注册时间升序分页返回用户，以及匹配的用户总数。
+	// query 非空时只返回用户名或邮箱包含该字符串（不区分大小写）的用户。
+	List(ctx context.Context, query string, offset, limit int) ([]User, int64, error)
 	Update(ctx context.Context, user *User) error
+	// ClearExpiredResetTokens 清除在 before 之前过期的密码重置令牌，返回受影响的用户数。
+	ClearExpiredResetTokens(ctx context.Context, before time.Time) (int64, error)
+	// Delete 在一个事务中删除用户及其全部关联数据：保险库项目、验证码、待确认的邮箱更换、设备、
+	// 待批准登录和 API 密钥。
+	// 审计事件不会删除：事件中的 IP 地址、User-Agent 和元数据（设备 ID 与设备名、更换前后的用户名等）
+	// 都参与事件哈希，删除或改写任何一条都会使之后的审计哈希链无法通过校验，因此这些内容会随事件保留。
+	Delete(ctx context.Context, id uuid.UUID) error
 }
 
 // VaultRepository 定义了保险库数据操作的接口。
 type VaultRepository interface {
 	Create(ctx context.Context, item *VaultItem) error
+	// CreateMany 在一个事务中创建多个项目，任一项目失败时全部回滚。
+	CreateMany(ctx context.Context, items []VaultItem) error
 	FindByID(ctx context.Context, id uuid.UUID) (*VaultItem, error)
 	FindByUser(ctx context.Context, userID uuid.UUID) ([]VaultItem, error)
+	// CountByUser 返回每个拥有项目的用户的项目数量。
+	CountByUser(ctx context.Context) (map[uuid.UUID]int64, error)
 	Update(ctx context.Context, item *VaultItem) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
@@ -29,4 +45,44 @@ type VerificationCodeRepository interface {
 	Create(ctx context.Context, vc *VerificationCode) error
 	Find(ctx context.Context, email string) (*VerificationCode, error)
 	Delete(ctx context.Context, email string) error
-}
\ No newline at end of file
+	// DeleteExpired 删除在 before 之前过期的验证码，返回删除的数量。
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// AuditRepository 定义了审计事件数据操作的接口。
+type AuditRepository interface {
+	// Create 分配 ID 和序号，链接到全局和用户的上一条事件并计算哈希后写入。
+	Create(ctx context.Context, event *AuditEvent) error
+	// FindByUser 按时间倒序返回用户的审计事件，以及该用户的事件总数。
+	FindByUser(ctx context.Context, userID uuid.UUID, offset, limit int) ([]AuditEvent, int64, error)
+	// ForEach 按序号升序遍历所有审计事件，fn 返回错误时停止遍历。
+	ForEach(ctx context.Context, fn func(event *AuditEvent) error) error
+}
+
+// DeviceRepository 定义了设备数据操作的接口。
+type DeviceRepository interface {
+	Create(ctx context.Context, device *Device) error
+	FindByID(ctx context.Context, id uuid.UUID) (*Device, error)
+	FindByUserAndDeviceID(ctx context.Context, userID uuid.UUID, deviceID string) (*Device, error)
+	FindByUser(ctx context.Context, userID uuid.UUID) ([]Device, error)
+	Update(ctx context.Context, device *Device) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// LoginApprovalRepository 定义了待批准登录数据操作的接口。
+type LoginApprovalRepository interface {
+	Create(ctx context.Context, approval *LoginApproval) error
+	FindByID(ctx context.Context, id uuid.UUID) (*LoginApproval, error)
+	FindByTokenHash(ctx context.Context, tokenHash string) (*LoginApproval, error)
+	Update(ctx context.Context, approval *LoginApproval) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+// APIKeyRepository 定义了 API 密钥数据操作的接口。
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *APIKey) error
+	FindByID(ctx context.Context, id uuid.UUID) (*APIKey, error)
+	// FindByUser 按创建时间升序返回用户的 API 密钥。
+	FindByUser(ctx context.Context, userID uuid.UUID) ([]APIKey, error)
+	Update(ctx context.Context, key *APIKey) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
@@ -0,0 +1,25 @@
+package core
+
+import "time"
+
+// StorageStats 汇总存储后端的记录数量和占用空间。
+type StorageStats struct {
+	Backend string
+	// Records 以表名或存储桶名为键的记录数量。
+	Records map[string]int64
+	// SizeBytes 数据库文件或数据库的磁盘占用。
+	SizeBytes int64
+	// Encryption 是静态加密状态，未启用加密时为 nil。
+	Encryption *EncryptionStatus
+}
+
+// EncryptionStatus 描述存储后端的静态加密状态。
+type EncryptionStatus struct {
+	// ActiveKey 是加密新记录所用的数据密钥编号，每次轮换加一。
+	ActiveKey uint32
+	// DataKeys 是密钥环中仍保留的数据密钥数量，后台重新加密完成后只剩活动密钥。
+	DataKeys int
+	// Pending 为 true 表示后台重新加密尚未完成。
+	Pending   bool
+	RotatedAt time.Time
+}
@@ -6,16 +6,78 @@ import (
 	"github.com/google/uuid"
 )
 
+// UserStatus 表示账户的状态。
+type UserStatus string
+
+// 账户状态。
+const (
+	UserStatusActive          UserStatus = "active"
+	UserStatusSuspended       UserStatus = "suspended"
+	UserStatusLocked          UserStatus = "locked"
+	UserStatusPendingDeletion UserStatus = "pending_deletion"
+)
+
+// Valid 报告 s 是否为已定义的账户状态。
+func (s UserStatus) Valid() bool {
+	switch s {
+	case UserStatusActive, UserStatusSuspended, UserStatusLocked, UserStatusPendingDeletion:
+		return true
+	}
+	return false
+}
+
+// UserRole 表示用户的角色。
+type UserRole string
+
+// 用户角色。
+const (
+	UserRoleUser  UserRole = "user"
+	UserRoleAdmin UserRole = "admin"
+)
+
+// Valid 报告 r 是否为已定义的角色。
+func (r UserRole) Valid() bool {
+	return r == UserRoleUser || r == UserRoleAdmin
+}
+
 // User 表示系统中的一个用户。
 type User struct {
 	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	Username   string    `gorm:"type:varchar(255);unique_index;not null"`
-	Email      string    `gorm:"type:varchar(255);unique_index;not null"`
+	Username   string    `gorm:"type:varchar(255);uniqueIndex;not null"`
+	Email      string    `gorm:"type:varchar(255);uniqueIndex;not null"`
 	AuthHash   string    `gorm:"type:text;not null"`
 	MasterSalt []byte `gorm:"type:bytea;not null"`
 	// for password reset
-	ResetPasswordToken          *string    `gorm:"type:varchar(255);unique_index"`
+	ResetPasswordToken          *string    `gorm:"type:varchar(255);uniqueIndex"`
 	ResetPasswordTokenExpiresAt *time.Time `gorm:"index"`
+	// 待确认的邮箱更换：新邮箱，以及发往新邮箱的验证码的哈希和过期时间
+	PendingEmail          *string `gorm:"type:varchar(255)"`
+	PendingEmailCodeHash  *string `gorm:"type:varchar(64)"`
+	PendingEmailExpiresAt *time.Time
+	// 开启后，来自未知设备的登录需要通过邮件批准
+	RequireDeviceApproval       bool       `gorm:"not null;default:false"`
+	// 非 active 状态的账户无法登录、重置密码，已签发的令牌也会失效
+	Status UserStatus `gorm:"type:varchar(20);not null;default:'active';index"`
+	// 角色只能通过 epadmin 修改，任何 API 都不接受该字段
+	Role UserRole `gorm:"type:varchar(20);not null;default:'user'"`
+	// 在此时间之前签发的令牌全部失效，用于强制用户重新登录
+	SessionsRevokedAt *time.Time
 	CreatedAt                   time.Time `gorm:"autoCreateTime"`
 	UpdatedAt                   time.Time `gorm:"autoUpdateTime"`
-}
\ No newline at end of file
+}
+
+// AccountStatus 返回账户状态；在引入状态字段之前创建的记录视为 active。
+func (u *User) AccountStatus() UserStatus {
+	if u.Status == "" {
+		return UserStatusActive
+	}
+	return u.Status
+}
+
+// AccountRole 返回用户角色；在引入角色字段之前创建的记录视为普通用户。
+func (u *User) AccountRole() UserRole {
+	if u.Role == "" {
+		return UserRoleUser
+	}
+	return u.Role
+}
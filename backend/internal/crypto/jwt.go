@@ -1,45 +1,169 @@
 package crypto
 
 import (
+	"context"
+	"easy-password-backend/internal/kms"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
+// ErrInvalidToken 表示令牌格式错误、签名无效或已过期。
+// ValidateJWT 返回的其他错误表示密钥提供者无法完成验证。
+var ErrInvalidToken = errors.New("invalid token")
+
 // Claims 表示 JWT 的声明。
 type Claims struct {
-	UserID uuid.UUID `json:"user_id"`
+	UserID   uuid.UUID `json:"user_id"`
+	// DeviceID 是服务器生成的设备记录 ID（core.Device.ID），不是客户端提供的设备 ID。
+	DeviceID uuid.UUID `json:"device_id,omitzero"`
+	// APIKeyID 和 Scope 只出现在用 API 密钥换取的令牌中。Scope 是以空格分隔的作用域列表（RFC 8693），
+	// 仅供其他服务参考；本服务始终以存储的 API 密钥为准。
+	APIKeyID uuid.UUID `json:"api_key_id,omitzero"`
+	Scope    string    `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT 为给定的用户 ID 生成一个新的 JWT。
-func GenerateJWT(userID uuid.UUID, secretKey string, expiration time.Duration) (string, error) {
-	expirationTime := time.Now().Add(expiration)
-	claims := &Claims{
-		UserID: userID,
+// GenerateJWT 为给定的用户 ID 和设备记录 ID 生成一个新的 JWT，使用 keys 中 JWT 用途的活动密钥签名，
+// 头部的 alg 是该密钥的算法，kid 记录所用密钥。
+func GenerateJWT(ctx context.Context, keys kms.KeyProvider, userID, deviceID uuid.UUID, expiration time.Duration) (string, error) {
+	now := time.Now()
+	return signClaims(ctx, keys, &Claims{
+		UserID:   userID,
+		DeviceID: deviceID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiration)),
 		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secretKey))
+	})
 }
 
-// ValidateJWT 验证 JWT 令牌，如果有效则返回声明。
-func ValidateJWT(tokenString string, secretKey string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secretKey), nil
+// GenerateAPIKeyJWT 为用 API 密钥换取的访问令牌签名，令牌记录密钥 ID 和以空格分隔的作用域 scope。
+func GenerateAPIKeyJWT(ctx context.Context, keys kms.KeyProvider, userID, apiKeyID uuid.UUID, scope string, expiresAt time.Time) (string, error) {
+	return signClaims(ctx, keys, &Claims{
+		UserID:   userID,
+		APIKeyID: apiKeyID,
+		Scope:    scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
 	})
+}
+
+// signClaims 用 JWT 用途的活动密钥为 claims 签名。
+func signClaims(ctx context.Context, keys kms.KeyProvider, claims *Claims) (string, error) {
+	key, err := keys.ActiveKey(ctx, kms.PurposeJWT)
+	if err != nil {
+		return "", fmt.Errorf("jwt signing key: %w", err)
+	}
+	method := jwt.GetSigningMethod(string(key.Algorithm))
+	if method == nil {
+		return "", fmt.Errorf("jwt signing key %s has unsupported algorithm %s", key.ID, key.Algorithm)
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = key.ID
+	signingString, err := token.SigningString()
+	if err != nil {
+		return "", err
+	}
+	sig, err := keys.Sign(ctx, kms.PurposeJWT, key.ID, []byte(signingString))
+	if err != nil {
+		return "", err
+	}
+	return signingString + "." + token.EncodeSegment(sig), nil
+}
 
+// ValidateJWT 验证 JWT 令牌，如果有效则返回声明。签名由 keys 中头部 kid 对应的 JWT 密钥验证，
+// 没有 kid 的令牌使用活动密钥验证。头部的 alg 必须与该密钥的算法相同，
+// 因此不能用公钥作为 HMAC 密钥伪造令牌，也不能用 none 跳过验证。
+func ValidateJWT(ctx context.Context, keys kms.KeyProvider, tokenString string) (*Claims, error) {
+	parser := jwt.NewParser()
+	claims := &Claims{}
+	token, parts, err := parser.ParseUnverified(tokenString, claims)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	key, err := verificationKey(ctx, keys, token)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != string(key.Algorithm) {
+		return nil, fmt.Errorf("%w: signing method %s does not match key %s (%s)", ErrInvalidToken, token.Method.Alg(), key.ID, key.Algorithm)
+	}
+	sig, err := parser.DecodeSegment(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	signed := []byte(strings.Join(parts[:2], "."))
+	if key.PublicKey != nil {
+		err = kms.VerifySignature(key.Algorithm, key.PublicKey, signed, sig)
+	} else {
+		err = keys.Verify(ctx, kms.PurposeJWT, key.ID, signed, sig)
+	}
+	if errors.Is(err, kms.ErrInvalidSignature) || errors.Is(err, kms.ErrUnknownKey) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	if err := jwt.NewValidator().Validate(claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+// verificationKey 返回验证 token 所用的密钥：头部 kid 对应的密钥，没有 kid 时为活动密钥。
+func verificationKey(ctx context.Context, keys kms.KeyProvider, token *jwt.Token) (kms.KeyInfo, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		// 引入 kid 之前签发的令牌。
+		key, err := keys.ActiveKey(ctx, kms.PurposeJWT)
+		if err != nil {
+			return kms.KeyInfo{}, fmt.Errorf("jwt signing key: %w", err)
+		}
+		return key, nil
 	}
+	infos, err := keys.Keys(ctx, kms.PurposeJWT)
+	if err != nil {
+		return kms.KeyInfo{}, fmt.Errorf("jwt signing keys: %w", err)
+	}
+	for _, info := range infos {
+		if info.ID == kid {
+			return info, nil
+		}
+	}
+	return kms.KeyInfo{}, fmt.Errorf("%w: %v: %s", ErrInvalidToken, kms.ErrUnknownKey, kid)
+}
+
+// JWKS 是 RFC 7517 的 JWK Set。
+type JWKS struct {
+	Keys []kms.JWK `json:"keys"`
+}
 
-	return nil, jwt.ErrInvalidKey
-}
\ No newline at end of file
+// PublicJWKS 返回 keys 中全部非对称 JWT 密钥的公钥，供其他服务离线验证令牌。
+// HS256 密钥不能公开，不包含在内；只使用 HS256 时返回空集合。
+func PublicJWKS(ctx context.Context, keys kms.KeyProvider) (JWKS, error) {
+	infos, err := keys.Keys(ctx, kms.PurposeJWT)
+	if err != nil {
+		return JWKS{}, fmt.Errorf("jwt signing keys: %w", err)
+	}
+	set := JWKS{Keys: []kms.JWK{}}
+	for _, info := range infos {
+		if !info.Algorithm.Asymmetric() {
+			continue
+		}
+		jwk, err := kms.NewJWK(info)
+		if err != nil {
+			return JWKS{}, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"html/template"
+	"time"
 
 	"gopkg.in/gomail.v2"
 )
@@ -13,6 +14,11 @@ type EmailService interface {
 	SendEmail(to, subject, body string) error
 	SendPasswordResetEmail(to, resetLink string) error
 	SendVerificationCodeEmail(to, code string) error
+	SendNewDeviceLoginEmail(to string, data NewDeviceLoginTemplateData) error
+	SendLoginApprovalEmail(to string, data LoginApprovalTemplateData) error
+	SendAccountDeletedEmail(to, username string) error
+	SendEmailChangeCodeEmail(to, code string) error
+	SendEmailChangedNoticeEmail(to, newEmail string) error
 }
 
 // SMTPEmailService 是 EmailService 的一个实现，使用 SMTP 发送邮件。
@@ -148,4 +154,240 @@ func (s *SMTPEmailService) SendVerificationCodeEmail(to, code string) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// NewDeviceLoginTemplateData 是新设备登录提醒邮件模板所需的数据。
+type NewDeviceLoginTemplateData struct {
+	DeviceName string
+	DeviceType string
+	IPAddress  string
+	LoginTime  time.Time
+}
+
+// SendNewDeviceLoginEmail 在账户从未见过的设备登录时发送提醒邮件。
+func (s *SMTPEmailService) SendNewDeviceLoginEmail(to string, data NewDeviceLoginTemplateData) error {
+	const templateStr = `
+	<html>
+	<body>
+	<p>您好,</p>
+	<p>您的 EasyPassword 账户刚刚在一台新设备上登录：</p>
+	<p>设备：{{.DeviceName}} ({{.DeviceType}})</p>
+	<p>IP 地址：{{.IPAddress}}</p>
+	<p>时间：{{.LoginTime.Format "2006-01-02 15:04:05 MST"}}</p>
+	<p>如果这是您本人的操作，请忽略此邮件。否则，请立即在设备管理中移除该设备并修改主密码。</p>
+	<p>谢谢,</p>
+	<p>EasyPassword 团队</p>
+	</body>
+	</html>
+	`
+
+	tmpl, err := template.New("newDeviceLogin").Parse(templateStr)
+	if err != nil {
+		return fmt.Errorf("无法解析新设备登录邮件模板: %w", err)
+	}
+
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		return fmt.Errorf("无法执行新设备登录邮件模板: %w", err)
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.From)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", "您的 EasyPassword 账户在新设备上登录")
+	m.SetBody("text/html", body.String())
+
+	d := gomail.NewDialer(s.Host, s.Port, s.Username, s.Password)
+
+	if err := d.DialAndSend(m); err != nil {
+		return fmt.Errorf("发送新设备登录邮件失败: %w", err)
+	}
+
+	return nil
+}
+
+// LoginApprovalTemplateData 是登录批准邮件模板所需的数据。
+type LoginApprovalTemplateData struct {
+	Code         string
+	ApprovalLink string
+	DeviceName   string
+	DeviceType   string
+	IPAddress    string
+}
+
+// SendLoginApprovalEmail 发送一封请求用户批准未知设备登录的邮件。
+func (s *SMTPEmailService) SendLoginApprovalEmail(to string, data LoginApprovalTemplateData) error {
+	const templateStr = `
+	<html>
+	<body>
+	<p>您好,</p>
+	<p>有一台未知设备正在尝试登录您的 EasyPassword 账户：</p>
+	<p>设备：{{.DeviceName}} ({{.DeviceType}})</p>
+	<p>IP 地址：{{.IPAddress}}</p>
+	<p>如果这是您本人的操作，请点击下面的链接批准登录，或在该设备上输入验证码：</p>
+	<p><a href="{{.ApprovalLink}}">批准登录</a></p>
+	<p style="font-size: 24px; font-weight: bold; color: #333;">{{.Code}}</p>
+	<p>此请求将在10分钟后失效。如果这不是您本人的操作，请忽略此邮件并尽快修改主密码。</p>
+	<p>谢谢,</p>
+	<p>EasyPassword 团队</p>
+	</body>
+	</html>
+	`
+
+	tmpl, err := template.New("loginApproval").Parse(templateStr)
+	if err != nil {
+		return fmt.Errorf("无法解析登录批准邮件模板: %w", err)
+	}
+
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		return fmt.Errorf("无法执行登录批准邮件模板: %w", err)
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.From)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", "请批准您的 EasyPassword 新设备登录")
+	m.SetBody("text/html", body.String())
+
+	d := gomail.NewDialer(s.Host, s.Port, s.Username, s.Password)
+
+	if err := d.DialAndSend(m); err != nil {
+		return fmt.Errorf("发送登录批准邮件失败: %w", err)
+	}
+
+	return nil
+}
+
+// AccountDeletedTemplateData 是账户删除确认邮件模板所需的数据。
+type AccountDeletedTemplateData struct {
+	Username string
+}
+
+// SendAccountDeletedEmail 发送一封账户已删除的确认邮件。
+func (s *SMTPEmailService) SendAccountDeletedEmail(to, username string) error {
+	const templateStr = `
+	<html>
+	<body>
+	<p>{{.Username}}，您好,</p>
+	<p>您的 EasyPassword 账户及其保存的全部数据已被永久删除。</p>
+	<p>为了安全审计，账户的操作记录会被保留，其中包括操作时间、IP 地址、浏览器或设备信息，以及更换过的用户名。</p>
+	<p>如果这不是您本人的操作，请立即联系我们。</p>
+	<p>谢谢,</p>
+	<p>EasyPassword 团队</p>
+	</body>
+	</html>
+	`
+
+	tmpl, err := template.New("accountDeleted").Parse(templateStr)
+	if err != nil {
+		return fmt.Errorf("无法解析账户删除邮件模板: %w", err)
+	}
+
+	var body bytes.Buffer
+	data := AccountDeletedTemplateData{Username: username}
+	if err := tmpl.Execute(&body, data); err != nil {
+		return fmt.Errorf("无法执行账户删除邮件模板: %w", err)
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.From)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", "您的 EasyPassword 账户已删除")
+	m.SetBody("text/html", body.String())
+
+	d := gomail.NewDialer(s.Host, s.Port, s.Username, s.Password)
+
+	if err := d.DialAndSend(m); err != nil {
+		return fmt.Errorf("发送账户删除邮件失败: %w", err)
+	}
+
+	return nil
+}
+
+// SendEmailChangeCodeEmail 向新邮箱发送一封包含更换邮箱验证码的邮件。
+func (s *SMTPEmailService) SendEmailChangeCodeEmail(to, code string) error {
+	const templateStr = `
+	<html>
+	<body>
+	<p>您好,</p>
+	<p>您正在将 EasyPassword 账户的邮箱更换为此地址，验证码是：</p>
+	<p style="font-size: 24px; font-weight: bold; color: #333;">{{.Code}}</p>
+	<p>此验证码将在5分钟后失效。</p>
+	<p>如果您没有请求更换邮箱，请忽略此邮件。</p>
+	<p>谢谢,</p>
+	<p>EasyPassword 团队</p>
+	</body>
+	</html>
+	`
+
+	tmpl, err := template.New("emailChangeCode").Parse(templateStr)
+	if err != nil {
+		return fmt.Errorf("无法解析更换邮箱验证码模板: %w", err)
+	}
+
+	var body bytes.Buffer
+	data := VerificationCodeTemplateData{Code: code}
+	if err := tmpl.Execute(&body, data); err != nil {
+		return fmt.Errorf("无法执行更换邮箱验证码模板: %w", err)
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.From)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", "您的 EasyPassword 更换邮箱验证码")
+	m.SetBody("text/html", body.String())
+
+	d := gomail.NewDialer(s.Host, s.Port, s.Username, s.Password)
+
+	if err := d.DialAndSend(m); err != nil {
+		return fmt.Errorf("发送更换邮箱验证码邮件失败: %w", err)
+	}
+
+	return nil
+}
+
+// EmailChangedNoticeTemplateData 是邮箱已更换通知邮件模板所需的数据。
+type EmailChangedNoticeTemplateData struct {
+	NewEmail string
+}
+
+// SendEmailChangedNoticeEmail 通知旧邮箱账户邮箱已更换为 newEmail，以便用户在非本人操作时及时发现。
+func (s *SMTPEmailService) SendEmailChangedNoticeEmail(to, newEmail string) error {
+	const templateStr = `
+	<html>
+	<body>
+	<p>您好,</p>
+	<p>您的 EasyPassword 账户邮箱已更换为 {{.NewEmail}}。</p>
+	<p>如果这不是您本人的操作，请立即联系我们。</p>
+	<p>谢谢,</p>
+	<p>EasyPassword 团队</p>
+	</body>
+	</html>
+	`
+
+	tmpl, err := template.New("emailChangedNotice").Parse(templateStr)
+	if err != nil {
+		return fmt.Errorf("无法解析邮箱已更换通知模板: %w", err)
+	}
+
+	var body bytes.Buffer
+	data := EmailChangedNoticeTemplateData{NewEmail: newEmail}
+	if err := tmpl.Execute(&body, data); err != nil {
+		return fmt.Errorf("无法执行邮箱已更换通知模板: %w", err)
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.From)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", "您的 EasyPassword 账户邮箱已更换")
+	m.SetBody("text/html", body.String())
+
+	d := gomail.NewDialer(s.Host, s.Port, s.Username, s.Password)
+
+	if err := d.DialAndSend(m); err != nil {
+		return fmt.Errorf("发送邮箱已更换通知邮件失败: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,304 @@
+package kms
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"easy-password-backend/config"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	keyringVersion = 1
+	// keyringAAD 作为密钥环密文的附加数据，防止把其他文件的密文当作密钥环解密。
+	keyringAAD = "easypassword keyring v1"
+)
+
+var (
+	// ErrKeyringPassphrase 表示口令错误或密钥环文件被篡改。
+	ErrKeyringPassphrase = errors.New("wrong keyring passphrase, or the keyring file is corrupted")
+	// ErrKeyringExists 表示要创建的密钥环文件已存在。
+	ErrKeyringExists = errors.New("keyring file already exists")
+)
+
+// KeyringEntry 是密钥环中的一个密钥。
+type KeyringEntry struct {
+	ID        string    `json:"id"`
+	Purpose   Purpose   `json:"purpose"`
+	Algorithm Algorithm `json:"algorithm,omitempty"`
+	// Material 是对称密钥本身，或 PKCS#8 DER 编码的私钥。
+	Material  []byte    `json:"material"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// keyringFile 是密钥环在磁盘上的格式：密钥列表以 JSON 编码后，用由口令经 Argon2id 派生的密钥加密。
+type keyringFile struct {
+	Version    int        `json:"version"`
+	KDF        keyringKDF `json:"kdf"`
+	Nonce      []byte     `json:"nonce"`
+	Ciphertext []byte     `json:"ciphertext"`
+}
+
+type keyringKDF struct {
+	Name    string `json:"name"`
+	Salt    []byte `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+// Keyring 是用口令加密的本地密钥环文件。每个用途最多有一个活动密钥，其余密钥只用于验证和解密。
+type Keyring struct {
+	path       string
+	passphrase []byte
+	kdf        keyringKDF
+	entries    []KeyringEntry
+}
+
+// CreateKeyring 在 path 创建一个空的密钥环。文件已存在时返回 ErrKeyringExists。
+func CreateKeyring(path string, passphrase []byte) (*Keyring, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("the keyring passphrase must not be empty")
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrKeyringExists, path)
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	k := &Keyring{
+		path:       path,
+		passphrase: passphrase,
+		kdf:        keyringKDF{Name: "argon2id", Salt: salt, Time: 3, Memory: 64 * 1024, Threads: 4},
+	}
+	if err := k.Save(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// OpenKeyring 读取并解密 path 处的密钥环。
+func OpenKeyring(path string, passphrase []byte) (*Keyring, error) {
+	if path == "" {
+		return nil, errors.New("KEYRING_PATH is required")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read keyring: %w", err)
+	}
+	var file keyringFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("decode keyring %s: %w", path, err)
+	}
+	if file.Version != keyringVersion || file.KDF.Name != "argon2id" {
+		return nil, fmt.Errorf("unsupported keyring format in %s", path)
+	}
+
+	aead, err := keyringAEAD(passphrase, file.KDF)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, []byte(keyringAAD))
+	if err != nil {
+		return nil, ErrKeyringPassphrase
+	}
+	k := &Keyring{path: path, passphrase: passphrase, kdf: file.KDF}
+	if err := json.Unmarshal(plaintext, &k.entries); err != nil {
+		return nil, fmt.Errorf("decode keyring entries: %w", err)
+	}
+	return k, nil
+}
+
+func keyringAEAD(passphrase []byte, kdf keyringKDF) (cipher.AEAD, error) {
+	key := argon2.IDKey(passphrase, kdf.Salt, kdf.Time, kdf.Memory, kdf.Threads, KeySize)
+	return newAEAD("keyring", key)
+}
+
+// Save 加密密钥环并原子地写回文件，文件权限为 0600。
+func (k *Keyring) Save() error {
+	plaintext, err := json.Marshal(k.entries)
+	if err != nil {
+		return err
+	}
+	aead, err := keyringAEAD(k.passphrase, k.kdf)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(keyringFile{
+		Version:    keyringVersion,
+		KDF:        k.kdf,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, []byte(keyringAAD)),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(k.path), ".keyring-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), k.path)
+}
+
+// Entries 返回密钥环中的全部密钥，按用途分组，活动密钥在前。
+func (k *Keyring) Entries() []KeyringEntry {
+	entries := slices.Clone(k.entries)
+	slices.SortStableFunc(entries, func(a, b KeyringEntry) int {
+		switch {
+		case a.Purpose != b.Purpose:
+			return slices.Index(Purposes, a.Purpose) - slices.Index(Purposes, b.Purpose)
+		case a.Active != b.Active:
+			if a.Active {
+				return -1
+			}
+			return 1
+		default:
+			return b.CreatedAt.Compare(a.CreatedAt)
+		}
+	})
+	return entries
+}
+
+// Add 把 key 加入密钥环。该用途还没有活动密钥，或 activate 为 true 时，新密钥成为活动密钥。
+// 存储密钥必须是 A256GCM 密钥，JWT 密钥可以是 HS256、EdDSA 或 ES256 密钥，只用于验证的公钥不能加入。
+func (k *Keyring) Add(purpose Purpose, key Key, activate bool) (KeyringEntry, error) {
+	switch {
+	case !purpose.Valid():
+		return KeyringEntry{}, fmt.Errorf("unknown key purpose %q", purpose)
+	case purpose == PurposeStorage && (key.Algorithm != AlgA256GCM || len(key.Material) != KeySize):
+		return KeyringEntry{}, fmt.Errorf("storage keys must be %d-byte %s keys", KeySize, AlgA256GCM)
+	case purpose == PurposeJWT && key.Algorithm != AlgHS256 && !key.Algorithm.Asymmetric():
+		return KeyringEntry{}, fmt.Errorf("%s keys cannot sign tokens", key.Algorithm)
+	case len(key.Material) == 0:
+		return KeyringEntry{}, errors.New("the key has no private material")
+	}
+	entry := KeyringEntry{
+		ID:        key.ID,
+		Purpose:   purpose,
+		Algorithm: key.Algorithm,
+		Material:  key.Material,
+		CreatedAt: time.Now().UTC(),
+	}
+	for _, existing := range k.entries {
+		if existing.ID == entry.ID && existing.Purpose == purpose {
+			return KeyringEntry{}, fmt.Errorf("key %s is already in the keyring", entry.ID)
+		}
+	}
+	if !slices.ContainsFunc(k.entries, func(e KeyringEntry) bool { return e.Purpose == purpose && e.Active }) {
+		activate = true
+	}
+	k.entries = append(k.entries, entry)
+	if activate {
+		if err := k.Activate(purpose, entry.ID); err != nil {
+			return KeyringEntry{}, err
+		}
+		entry.Active = true
+	}
+	return entry, nil
+}
+
+// Activate 把 kid 设为 purpose 的活动密钥，原活动密钥保留用于验证和解密。
+func (k *Keyring) Activate(purpose Purpose, kid string) error {
+	i := k.index(purpose, kid)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+	for j := range k.entries {
+		if k.entries[j].Purpose == purpose {
+			k.entries[j].Active = j == i
+		}
+	}
+	return nil
+}
+
+// Remove 从密钥环中删除 purpose 的 kid。活动密钥不能删除。
+func (k *Keyring) Remove(purpose Purpose, kid string) error {
+	i := k.index(purpose, kid)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+	if k.entries[i].Active {
+		return fmt.Errorf("key %s is the active %s key; activate another key first", kid, purpose)
+	}
+	k.entries = slices.Delete(k.entries, i, i+1)
+	return nil
+}
+
+func (k *Keyring) index(purpose Purpose, kid string) int {
+	return slices.IndexFunc(k.entries, func(e KeyringEntry) bool { return e.Purpose == purpose && e.ID == kid })
+}
+
+// Provider 返回使用密钥环当前内容的 StaticProvider。
+func (k *Keyring) Provider() (*StaticProvider, error) {
+	keys := make(map[Purpose][]Key)
+	for _, entry := range k.Entries() {
+		key, err := entry.key()
+		if err != nil {
+			return nil, err
+		}
+		keys[entry.Purpose] = append(keys[entry.Purpose], key)
+	}
+	return NewStaticProvider(keys), nil
+}
+
+// KeyAlgorithm 返回密钥的算法。早期的密钥环不记录算法，其中的 JWT 密钥都是 HS256。
+func (e KeyringEntry) KeyAlgorithm() Algorithm {
+	switch {
+	case e.Algorithm != "":
+		return e.Algorithm
+	case e.Purpose == PurposeStorage:
+		return AlgA256GCM
+	default:
+		return AlgHS256
+	}
+}
+
+func (e KeyringEntry) key() (Key, error) {
+	alg := e.KeyAlgorithm()
+	if !alg.Asymmetric() {
+		return Key{ID: e.ID, Algorithm: alg, Material: e.Material}, nil
+	}
+	key, err := NewSigningKey(e.Material)
+	if err != nil {
+		return Key{}, fmt.Errorf("keyring entry %s: %w", e.ID, err)
+	}
+	return key, nil
+}
+
+// KeyringPassphrase 返回 KEYRING_PASSPHRASE，或 KEYRING_PASSPHRASE_FILE 的内容。
+func KeyringPassphrase(cfg *config.Config) ([]byte, error) {
+	passphrase, err := readSecret(cfg.KeyringPassphrase, cfg.KeyringPassphraseFile, "KEYRING_PASSPHRASE")
+	if err != nil {
+		return nil, err
+	}
+	if passphrase == "" {
+		return nil, errors.New("KEYRING_PASSPHRASE or KEYRING_PASSPHRASE_FILE is required")
+	}
+	return []byte(passphrase), nil
+}
@@ -0,0 +1,139 @@
+// Package kms 管理服务器密钥：JWT 签名密钥和存储静态加密所用的服务器密钥（KEK）。
+//
+// 调用方只通过 KeyProvider 使用密钥，不接触密钥本身，因此密钥可以保存在环境变量或文件中、
+// 本地的加密密钥环中，或者远程的 Vault transit 兼容服务中。每个用途可以同时配置多个密钥，
+// 它们以 kid 区分：活动密钥用于签名和加密，其余密钥仍可用于验证和解密，以便平滑轮换。
+package kms
+
+import (
+	"context"
+	"easy-password-backend/config"
+	"errors"
+	"fmt"
+)
+
+// Purpose 表示密钥的用途。不同用途的密钥互相独立。
+type Purpose string
+
+const (
+	// PurposeJWT 用于签发和验证访问令牌。
+	PurposeJWT Purpose = "jwt"
+	// PurposeStorage 用于包装存储静态加密的数据密钥。
+	PurposeStorage Purpose = "storage"
+)
+
+// Purposes 列出所有用途。
+var Purposes = []Purpose{PurposeJWT, PurposeStorage}
+
+// Valid 报告 p 是否是已知的用途。
+func (p Purpose) Valid() bool {
+	return p == PurposeJWT || p == PurposeStorage
+}
+
+var (
+	// ErrNoKey 表示该用途没有配置任何密钥。
+	ErrNoKey = errors.New("no key is configured for this purpose")
+	// ErrUnknownKey 表示该用途没有给定 kid 的密钥，通常是密钥已被移除。
+	ErrUnknownKey = errors.New("unknown key id")
+	// ErrInvalidSignature 表示签名与数据不匹配。
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrDecrypt 表示密文被篡改，或附加数据与加密时不同。
+	ErrDecrypt = errors.New("ciphertext could not be decrypted")
+)
+
+// KeyProvider 使用服务器密钥签名和加密，密钥本身不离开提供者。
+// 返回的其他错误（如远程服务不可用）表示操作未能完成，而不是数据无效。
+type KeyProvider interface {
+	// ActiveKey 返回 purpose 当前的活动密钥。没有配置密钥时返回 ErrNoKey。
+	ActiveKey(ctx context.Context, purpose Purpose) (KeyInfo, error)
+	// Keys 返回 purpose 的全部可用密钥，活动密钥在前。
+	Keys(ctx context.Context, purpose Purpose) ([]KeyInfo, error)
+	// Sign 用 kid 对应的密钥签名 data：HS256 密钥计算 HMAC-SHA256，
+	// EdDSA 和 ES256 密钥返回 JWS 格式的签名。
+	Sign(ctx context.Context, purpose Purpose, kid string, data []byte) ([]byte, error)
+	// Verify 检查 sig 是否是 kid 对应的密钥对 data 的签名，不匹配时返回 ErrInvalidSignature。
+	Verify(ctx context.Context, purpose Purpose, kid string, data, sig []byte) error
+	// Encrypt 用活动密钥加密 plaintext，aad 作为附加数据参与认证。返回所用密钥的 kid 和密文。
+	Encrypt(ctx context.Context, purpose Purpose, plaintext, aad []byte) (kid string, ciphertext []byte, err error)
+	// Decrypt 用 kid 对应的密钥解密 Encrypt 返回的密文，aad 必须与加密时相同。
+	Decrypt(ctx context.Context, purpose Purpose, kid string, ciphertext, aad []byte) ([]byte, error)
+}
+
+// FromConfig 按 cfg.KeyProvider 创建密钥提供者：
+//
+//   - env（默认）：JWT_SECRET 和 DB_ENCRYPTION_KEY 等环境变量或文件中的密钥。
+//   - keyring：KEYRING_PATH 处用口令加密的本地密钥环。
+//   - transit：TRANSIT_ADDR 处的 Vault transit 兼容服务。
+func FromConfig(cfg *config.Config) (KeyProvider, error) {
+	switch cfg.KeyProvider {
+	case "", "env":
+		return NewEnvProvider(cfg)
+	case "keyring":
+		passphrase, err := KeyringPassphrase(cfg)
+		if err != nil {
+			return nil, err
+		}
+		keyring, err := OpenKeyring(cfg.KeyringPath, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return keyring.Provider()
+	case "transit":
+		return transitFromConfig(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported KEY_PROVIDER: %s", cfg.KeyProvider)
+	}
+}
+
+// WithEphemeralKey 返回一个提供者：purpose 使用一个只存在于本进程内存中的随机密钥，
+// 其他用途交给 p 处理。用于未配置 JWT 密钥的开发环境，令牌在重启后全部失效。
+// 随机密钥是 HS256 密钥，因此只适用于 PurposeJWT。
+func WithEphemeralKey(p KeyProvider, purpose Purpose) (KeyProvider, error) {
+	material, err := GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	return &overrideProvider{
+		KeyProvider: p,
+		purpose:     purpose,
+		override:    NewStaticProvider(map[Purpose][]Key{purpose: {NewKey(AlgHS256, material)}}),
+	}, nil
+}
+
+// overrideProvider 把一个用途交给 override 处理，其余交给内嵌的提供者。
+type overrideProvider struct {
+	KeyProvider
+	purpose  Purpose
+	override KeyProvider
+}
+
+func (p *overrideProvider) pick(purpose Purpose) KeyProvider {
+	if purpose == p.purpose {
+		return p.override
+	}
+	return p.KeyProvider
+}
+
+func (p *overrideProvider) ActiveKey(ctx context.Context, purpose Purpose) (KeyInfo, error) {
+	return p.pick(purpose).ActiveKey(ctx, purpose)
+}
+
+func (p *overrideProvider) Keys(ctx context.Context, purpose Purpose) ([]KeyInfo, error) {
+	return p.pick(purpose).Keys(ctx, purpose)
+}
+
+func (p *overrideProvider) Sign(ctx context.Context, purpose Purpose, kid string, data []byte) ([]byte, error) {
+	return p.pick(purpose).Sign(ctx, purpose, kid, data)
+}
+
+func (p *overrideProvider) Verify(ctx context.Context, purpose Purpose, kid string, data, sig []byte) error {
+	return p.pick(purpose).Verify(ctx, purpose, kid, data, sig)
+}
+
+func (p *overrideProvider) Encrypt(ctx context.Context, purpose Purpose, plaintext, aad []byte) (string, []byte, error) {
+	return p.pick(purpose).Encrypt(ctx, purpose, plaintext, aad)
+}
+
+func (p *overrideProvider) Decrypt(ctx context.Context, purpose Purpose, kid string, ciphertext, aad []byte) ([]byte, error) {
+	return p.pick(purpose).Decrypt(ctx, purpose, kid, ciphertext, aad)
+}
@@ -0,0 +1,247 @@
+package kms
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Algorithm 是密钥所用的算法，签名算法使用 JWS 中的名称。
+type Algorithm string
+
+const (
+	// AlgHS256 是 HMAC-SHA256，签名和验证使用同一个密钥。
+	AlgHS256 Algorithm = "HS256"
+	// AlgEdDSA 是 Ed25519 签名。
+	AlgEdDSA Algorithm = "EdDSA"
+	// AlgES256 是 P-256 曲线上的 ECDSA-SHA256 签名。
+	AlgES256 Algorithm = "ES256"
+	// AlgA256GCM 是 AES-256-GCM 加密。
+	AlgA256GCM Algorithm = "A256GCM"
+)
+
+// Asymmetric 报告 a 是否是非对称签名算法。
+func (a Algorithm) Asymmetric() bool {
+	return a == AlgEdDSA || a == AlgES256
+}
+
+// KeyInfo 是密钥的公开信息，不包含私有材料。
+type KeyInfo struct {
+	ID        string
+	Algorithm Algorithm
+	Active    bool
+	// PublicKey 是非对称签名密钥的公钥（ed25519.PublicKey 或 *ecdsa.PublicKey），对称密钥为 nil。
+	PublicKey crypto.PublicKey
+}
+
+// GenerateSigningKey 生成 alg（EdDSA 或 ES256）的私钥，返回 PKCS#8 DER 编码。
+func GenerateSigningKey(alg Algorithm) ([]byte, error) {
+	var private any
+	var err error
+	switch alg {
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	case AlgES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("%s is not an asymmetric signing algorithm", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return x509.MarshalPKCS8PrivateKey(private)
+}
+
+// NewSigningKey 从 PKCS#8 DER 编码的 Ed25519 或 P-256 私钥创建签名密钥。
+// 算法由密钥类型决定，kid 是公钥的 JWK 指纹（RFC 7638）。
+func NewSigningKey(pkcs8 []byte) (Key, error) {
+	private, err := x509.ParsePKCS8PrivateKey(pkcs8)
+	if err != nil {
+		return Key{}, fmt.Errorf("parse private key: %w", err)
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return Key{}, errors.New("unsupported private key type")
+	}
+	key, err := NewVerificationKey(signer.Public())
+	if err != nil {
+		return Key{}, err
+	}
+	key.Material = pkcs8
+	key.signer = signer
+	return key, nil
+}
+
+// NewVerificationKey 创建只能用于验证签名的密钥，用于轮换后仍需接受的旧公钥。
+func NewVerificationKey(public crypto.PublicKey) (Key, error) {
+	var alg Algorithm
+	switch pub := public.(type) {
+	case ed25519.PublicKey:
+		alg = AlgEdDSA
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return Key{}, errors.New("only P-256 ECDSA keys are supported")
+		}
+		alg = AlgES256
+	default:
+		return Key{}, fmt.Errorf("unsupported public key type %T", public)
+	}
+	key := Key{Algorithm: alg, public: public}
+	jwk, err := NewJWK(KeyInfo{Algorithm: alg, PublicKey: public})
+	if err != nil {
+		return Key{}, err
+	}
+	key.ID = jwk.Thumbprint()
+	return key, nil
+}
+
+// ParsePEMKeys 解析 PEM 中的全部 PRIVATE KEY（PKCS#8）和 PUBLIC KEY（PKIX）块，按出现顺序返回。
+func ParsePEMKeys(data []byte) ([]Key, error) {
+	var keys []Key
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err := NewSigningKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		case "PUBLIC KEY":
+			public, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("parse public key: %w", err)
+			}
+			key, err := NewVerificationKey(public)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		default:
+			return nil, fmt.Errorf("unsupported PEM block %q; use PKCS#8 PRIVATE KEY or PUBLIC KEY", block.Type)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no PEM encoded keys found")
+	}
+	return keys, nil
+}
+
+// signAsymmetric 用 signer 按 JWS 的格式签名：EdDSA 为 64 字节，ES256 为 r||s 各 32 字节。
+func signAsymmetric(alg Algorithm, signer crypto.Signer, data []byte) ([]byte, error) {
+	switch alg {
+	case AlgEdDSA:
+		return signer.Sign(rand.Reader, data, crypto.Hash(0))
+	case AlgES256:
+		private, ok := signer.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("ES256 requires an ECDSA private key")
+		}
+		digest := sha256.Sum256(data)
+		r, s, err := ecdsa.Sign(rand.Reader, private, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig, nil
+	default:
+		return nil, fmt.Errorf("%s is not an asymmetric signing algorithm", alg)
+	}
+}
+
+// VerifySignature 用公钥验证 JWS 格式的 EdDSA 或 ES256 签名，不匹配时返回 ErrInvalidSignature。
+// 验证非对称签名不需要密钥提供者。
+func VerifySignature(alg Algorithm, public crypto.PublicKey, data, sig []byte) error {
+	valid := false
+	switch pub := public.(type) {
+	case ed25519.PublicKey:
+		valid = alg == AlgEdDSA && ed25519.Verify(pub, data, sig)
+	case *ecdsa.PublicKey:
+		if alg == AlgES256 && len(sig) == 64 {
+			digest := sha256.Sum256(data)
+			r := new(big.Int).SetBytes(sig[:32])
+			s := new(big.Int).SetBytes(sig[32:])
+			valid = ecdsa.Verify(pub, digest[:], r, s)
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", public)
+	}
+	if !valid {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// JWK 是 RFC 7517 中的公钥表示，只包含本项目支持的 OKP（Ed25519）和 EC（P-256）密钥。
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+}
+
+// NewJWK 返回 info 的公钥的 JWK，kid、alg 和 use 已填写。
+func NewJWK(info KeyInfo) (JWK, error) {
+	jwk := JWK{Kid: info.ID, Alg: string(info.Algorithm), Use: "sig"}
+	switch pub := info.PublicKey.(type) {
+	case ed25519.PublicKey:
+		jwk.Kty, jwk.Crv = "OKP", "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	case *ecdsa.PublicKey:
+		point, err := pub.ECDH()
+		if err != nil || point.Curve() != ecdh.P256() {
+			return JWK{}, errors.New("only P-256 ECDSA keys are supported")
+		}
+		// 未压缩点的格式为 0x04 || X || Y。
+		raw := point.Bytes()
+		jwk.Kty, jwk.Crv = "EC", "P-256"
+		jwk.X = base64.RawURLEncoding.EncodeToString(raw[1:33])
+		jwk.Y = base64.RawURLEncoding.EncodeToString(raw[33:])
+	default:
+		return JWK{}, fmt.Errorf("key %s has no public key", info.ID)
+	}
+	return jwk, nil
+}
+
+// Thumbprint 返回 RFC 7638 定义的 JWK SHA-256 指纹（base64url 编码）。
+func (j JWK) Thumbprint() string {
+	// 指纹只包含必需成员，按字典序排列且没有空白；encoding/json 按字段声明顺序输出，因此单独构造。
+	var members any
+	if j.Kty == "EC" {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{j.Crv, j.Kty, j.X, j.Y}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{j.Crv, j.Kty, j.X}
+	}
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
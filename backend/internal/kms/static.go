@@ -0,0 +1,289 @@
+package kms
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"easy-password-backend/config"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeySize 是加密密钥的长度（AES-256）。HMAC 签名密钥可以是任意长度。
+const KeySize = 32
+
+// Key 是本地保存的一个密钥。
+type Key struct {
+	ID        string
+	Algorithm Algorithm
+	// Material 是对称密钥本身，或 PKCS#8 DER 编码的私钥；只用于验证的公钥没有 Material。
+	Material []byte
+
+	signer crypto.Signer
+	public crypto.PublicKey
+}
+
+// NewKey 返回以 KeyID(material) 为 ID 的对称密钥，alg 为 AlgHS256 或 AlgA256GCM。
+func NewKey(alg Algorithm, material []byte) Key {
+	return Key{ID: KeyID(material), Algorithm: alg, Material: material}
+}
+
+// KeyID 返回对称密钥的指纹，用作其 kid。指纹只用于识别密钥，不泄露密钥本身；
+// 同一个密钥无论来自环境变量还是密钥环，kid 都相同。非对称密钥的 kid 是 JWK 指纹。
+func KeyID(material []byte) string {
+	// 前缀沿用最初的 BoltDB 服务器密钥指纹，已加密的数据库不需要重新包装。
+	sum := sha256.Sum256(append([]byte("easypassword kek id:"), material...))
+	return hex.EncodeToString(sum[:8])
+}
+
+// GenerateKey 返回一个 KeySize 字节的随机密钥。
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// StaticProvider 在本进程内使用一组固定的密钥，每个用途的第一个密钥是活动密钥。
+type StaticProvider struct {
+	keys map[Purpose][]Key
+}
+
+// NewStaticProvider 创建使用 keys 的提供者。每个用途的第一个密钥是活动密钥，其余只用于验证和解密。
+func NewStaticProvider(keys map[Purpose][]Key) *StaticProvider {
+	return &StaticProvider{keys: keys}
+}
+
+// LocalKeys 返回 purpose 的全部密钥，活动密钥在前。
+func (p *StaticProvider) LocalKeys(purpose Purpose) []Key {
+	return p.keys[purpose]
+}
+
+func (k Key) info(active bool) KeyInfo {
+	return KeyInfo{ID: k.ID, Algorithm: k.Algorithm, Active: active, PublicKey: k.public}
+}
+
+// ActiveKey 实现 KeyProvider。
+func (p *StaticProvider) ActiveKey(ctx context.Context, purpose Purpose) (KeyInfo, error) {
+	keys := p.keys[purpose]
+	if len(keys) == 0 {
+		return KeyInfo{}, ErrNoKey
+	}
+	return keys[0].info(true), nil
+}
+
+// Keys 实现 KeyProvider。
+func (p *StaticProvider) Keys(ctx context.Context, purpose Purpose) ([]KeyInfo, error) {
+	infos := make([]KeyInfo, 0, len(p.keys[purpose]))
+	for i, key := range p.keys[purpose] {
+		infos = append(infos, key.info(i == 0))
+	}
+	return infos, nil
+}
+
+func (p *StaticProvider) find(purpose Purpose, kid string) (Key, error) {
+	keys := p.keys[purpose]
+	if len(keys) == 0 {
+		return Key{}, ErrNoKey
+	}
+	for _, key := range keys {
+		if key.ID == kid {
+			return key, nil
+		}
+	}
+	return Key{}, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+}
+
+// Sign 实现 KeyProvider。
+func (p *StaticProvider) Sign(ctx context.Context, purpose Purpose, kid string, data []byte) ([]byte, error) {
+	key, err := p.find(purpose, kid)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case key.Algorithm == AlgHS256:
+		mac := hmac.New(sha256.New, key.Material)
+		mac.Write(data)
+		return mac.Sum(nil), nil
+	case key.signer != nil:
+		return signAsymmetric(key.Algorithm, key.signer, data)
+	default:
+		return nil, fmt.Errorf("key %s (%s) cannot sign", kid, key.Algorithm)
+	}
+}
+
+// Verify 实现 KeyProvider。
+func (p *StaticProvider) Verify(ctx context.Context, purpose Purpose, kid string, data, sig []byte) error {
+	key, err := p.find(purpose, kid)
+	if err != nil {
+		return err
+	}
+	if key.public != nil {
+		return VerifySignature(key.Algorithm, key.public, data, sig)
+	}
+	expected, err := p.Sign(ctx, purpose, kid, data)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(expected, sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Encrypt 实现 KeyProvider。密文格式为 nonce || AES-256-GCM 密文。
+func (p *StaticProvider) Encrypt(ctx context.Context, purpose Purpose, plaintext, aad []byte) (string, []byte, error) {
+	active, err := p.ActiveKey(ctx, purpose)
+	if err != nil {
+		return "", nil, err
+	}
+	kid := active.ID
+	key, _ := p.find(purpose, kid)
+	aead, err := newKeyAEAD(key)
+	if err != nil {
+		return "", nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	return kid, aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// Decrypt 实现 KeyProvider。
+func (p *StaticProvider) Decrypt(ctx context.Context, purpose Purpose, kid string, ciphertext, aad []byte) ([]byte, error) {
+	key, err := p.find(purpose, kid)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newKeyAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], aad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func newKeyAEAD(key Key) (cipher.AEAD, error) {
+	if key.Algorithm != AlgA256GCM {
+		return nil, fmt.Errorf("key %s (%s) cannot encrypt", key.ID, key.Algorithm)
+	}
+	return newAEAD(key.ID, key.Material)
+}
+
+func newAEAD(kid string, material []byte) (cipher.AEAD, error) {
+	if len(material) != KeySize {
+		return nil, fmt.Errorf("key %s is %d bytes; encryption keys must be %d bytes", kid, len(material), KeySize)
+	}
+	block, err := aes.NewCipher(material)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// NewEnvProvider 从环境变量或文件读取密钥：
+//
+//   - JWT：JWT_SIGNING_KEY 或 JWT_SIGNING_KEY_FILE 中 PEM 编码的 Ed25519 或 P-256 密钥，
+//     第一个必须是私钥，用于签名，其余私钥或公钥只用于验证；
+//     未设置时使用 JWT_SECRET 或 JWT_SECRET_FILE 的原始内容作为 HS256 密钥。
+//     JWT_PREVIOUS_SECRETS 中的旧密钥只用于验证；设置了签名密钥时，JWT_SECRET 也只用于验证，
+//     以便从 HS256 切换到非对称签名时已签发的令牌仍然有效。
+//   - 存储：DB_ENCRYPTION_KEY 或 DB_ENCRYPTION_KEY_FILE（base64 编码的 32 字节），
+//     DB_ENCRYPTION_PREVIOUS_KEYS 中的旧密钥只用于解开由它们包装的数据密钥。
+func NewEnvProvider(cfg *config.Config) (*StaticProvider, error) {
+	keys := make(map[Purpose][]Key)
+
+	signingKeys, err := readSecret(cfg.JWTSigningKey, cfg.JWTSigningKeyFile, "JWT_SIGNING_KEY")
+	if err != nil {
+		return nil, err
+	}
+	if signingKeys != "" {
+		parsed, err := ParsePEMKeys([]byte(signingKeys))
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT signing key: %w", err)
+		}
+		if parsed[0].signer == nil {
+			return nil, errors.New("the first key in JWT_SIGNING_KEY must be a private key")
+		}
+		keys[PurposeJWT] = parsed
+	}
+	secret, err := readSecret(cfg.JWTSecret, cfg.JWTSecretFile, "JWT_SECRET")
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" && len(cfg.JWTPreviousSecrets) > 0 {
+		return nil, errors.New("JWT_PREVIOUS_SECRETS requires a current JWT_SECRET")
+	}
+	for _, secret := range append([]string{secret}, cfg.JWTPreviousSecrets...) {
+		if secret != "" {
+			keys[PurposeJWT] = append(keys[PurposeJWT], NewKey(AlgHS256, []byte(secret)))
+		}
+	}
+
+	encoded, err := readSecret(cfg.DBEncryptionKey, cfg.DBEncryptionKeyFile, "DB_ENCRYPTION_KEY")
+	if err != nil {
+		return nil, err
+	}
+	if encoded == "" {
+		if len(cfg.DBEncryptionPreviousKeys) > 0 {
+			return nil, errors.New("DB_ENCRYPTION_PREVIOUS_KEYS requires a current encryption key")
+		}
+		return NewStaticProvider(keys), nil
+	}
+	current, err := DecodeKey(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	keys[PurposeStorage] = append(keys[PurposeStorage], NewKey(AlgA256GCM, current))
+	for i, encoded := range cfg.DBEncryptionPreviousKeys {
+		previous, err := DecodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid previous encryption key %d: %w", i+1, err)
+		}
+		keys[PurposeStorage] = append(keys[PurposeStorage], NewKey(AlgA256GCM, previous))
+	}
+	return NewStaticProvider(keys), nil
+}
+
+// readSecret 返回 value，或者 file 中去掉首尾空白的内容。两者都设置时返回错误。
+func readSecret(value, file, name string) (string, error) {
+	if file == "" {
+		return value, nil
+	}
+	if value != "" {
+		return "", fmt.Errorf("set only one of %s and %s_FILE", name, name)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("read %s_FILE: %w", name, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// DecodeKey 解码 base64 编码的 KeySize 字节加密密钥。
+func DecodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("not valid base64")
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("must decode to %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
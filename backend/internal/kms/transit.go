@@ -0,0 +1,404 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/x509"
+	"easy-password-backend/config"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keyInfoTTL 是远程服务的密钥信息在本地缓存的时间。签发和验证令牌都需要密钥信息，
+// 缓存避免每个请求多一次往返；在 Vault 中轮换密钥后，最迟在这段时间后开始使用新版本。
+const keyInfoTTL = time.Minute
+
+// TransitProvider 通过 HTTP 调用 Vault transit 兼容的服务，密钥不离开该服务。
+// 每个用途对应服务中的一个命名密钥，kid 是密钥版本，如 "v3"。
+// ed25519 和 ecdsa-p256 类型的密钥用 sign 接口签发 EdDSA 和 ES256 签名，并在本地用公钥验证；
+// 其他类型的密钥用 hmac 和 verify 接口签发 HS256 签名，用 encrypt 和 decrypt 接口加密。
+type TransitProvider struct {
+	addr   string
+	mount  string
+	token  string
+	names  map[Purpose]string
+	client *http.Client
+
+	mu     sync.Mutex
+	cached map[Purpose]transitKey
+}
+
+// transitKey 是缓存的命名密钥信息，infos 中活动密钥在前。
+type transitKey struct {
+	alg     Algorithm
+	infos   []KeyInfo
+	expires time.Time
+}
+
+// NewTransitProvider 创建访问 addr（如 https://vault:8200）上挂载于 mount 的 transit 引擎的提供者。
+// names 给出每个用途所用的密钥名；未列出的用途视为没有配置密钥。
+func NewTransitProvider(addr, mount, token string, names map[Purpose]string) *TransitProvider {
+	return &TransitProvider{
+		addr:   strings.TrimRight(addr, "/"),
+		mount:  strings.Trim(mount, "/"),
+		token:  token,
+		names:  names,
+		client: &http.Client{Timeout: 10 * time.Second},
+		cached: make(map[Purpose]transitKey),
+	}
+}
+
+func transitFromConfig(cfg *config.Config) (*TransitProvider, error) {
+	if cfg.TransitAddr == "" {
+		return nil, errors.New("TRANSIT_ADDR is required when KEY_PROVIDER=transit")
+	}
+	token, err := readSecret(cfg.TransitToken, cfg.TransitTokenFile, "TRANSIT_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[Purpose]string)
+	if cfg.TransitJWTKey != "" {
+		names[PurposeJWT] = cfg.TransitJWTKey
+	}
+	if cfg.TransitStorageKey != "" {
+		names[PurposeStorage] = cfg.TransitStorageKey
+	}
+	return NewTransitProvider(cfg.TransitAddr, cfg.TransitMount, token, names), nil
+}
+
+// transitError 是服务返回的错误。
+type transitError struct {
+	Status   int
+	Messages []string
+}
+
+func (e *transitError) Error() string {
+	if len(e.Messages) == 0 {
+		return fmt.Sprintf("transit: HTTP %d", e.Status)
+	}
+	return fmt.Sprintf("transit: HTTP %d: %s", e.Status, strings.Join(e.Messages, "; "))
+}
+
+// call 发送请求并把响应中的 data 字段解码到 out。
+func (p *TransitProvider) call(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.addr+"/v1/"+p.mount+"/"+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("transit: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []string        `json:"errors"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&envelope); err != nil && err != io.EOF {
+		return fmt.Errorf("transit: decode response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return &transitError{Status: resp.StatusCode, Messages: envelope.Errors}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(envelope.Data, out)
+}
+
+func (p *TransitProvider) keyName(purpose Purpose) (string, error) {
+	name, ok := p.names[purpose]
+	if !ok {
+		return "", ErrNoKey
+	}
+	return url.PathEscape(name), nil
+}
+
+// describe 读取 purpose 所用命名密钥的类型和各版本，结果缓存 keyInfoTTL。服务中不存在该密钥时返回 ErrNoKey。
+func (p *TransitProvider) describe(ctx context.Context, purpose Purpose) (transitKey, error) {
+	p.mu.Lock()
+	cached, ok := p.cached[purpose]
+	p.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached, nil
+	}
+
+	name, err := p.keyName(purpose)
+	if err != nil {
+		return transitKey{}, err
+	}
+	var out struct {
+		Type                 string                     `json:"type"`
+		LatestVersion        int                        `json:"latest_version"`
+		MinDecryptionVersion int                        `json:"min_decryption_version"`
+		Keys                 map[string]json.RawMessage `json:"keys"`
+	}
+	if err := p.call(ctx, http.MethodGet, "keys/"+name, nil, &out); err != nil {
+		var terr *transitError
+		if errors.As(err, &terr) && terr.Status == http.StatusNotFound {
+			return transitKey{}, ErrNoKey
+		}
+		return transitKey{}, err
+	}
+
+	key := transitKey{expires: time.Now().Add(keyInfoTTL)}
+	switch out.Type {
+	case "ed25519":
+		key.alg = AlgEdDSA
+	case "ecdsa-p256":
+		key.alg = AlgES256
+	default:
+		key.alg = AlgHS256
+		if purpose == PurposeStorage {
+			key.alg = AlgA256GCM
+		}
+	}
+	for version := out.LatestVersion; version >= max(out.MinDecryptionVersion, 1); version-- {
+		info := KeyInfo{ID: "v" + strconv.Itoa(version), Algorithm: key.alg, Active: version == out.LatestVersion}
+		if key.alg.Asymmetric() {
+			if info.PublicKey, err = parseTransitPublicKey(key.alg, out.Keys[strconv.Itoa(version)]); err != nil {
+				return transitKey{}, fmt.Errorf("transit: key %s version %d: %w", name, version, err)
+			}
+		}
+		key.infos = append(key.infos, info)
+	}
+	if len(key.infos) == 0 {
+		return transitKey{}, ErrNoKey
+	}
+
+	p.mu.Lock()
+	p.cached[purpose] = key
+	p.mu.Unlock()
+	return key, nil
+}
+
+// parseTransitPublicKey 解析读取密钥时返回的公钥：ed25519 为 base64 编码，ecdsa-p256 为 PEM。
+func parseTransitPublicKey(alg Algorithm, raw json.RawMessage) (crypto.PublicKey, error) {
+	var version struct {
+		PublicKey string `json:"public_key"`
+	}
+	if err := json.Unmarshal(raw, &version); err != nil || version.PublicKey == "" {
+		return nil, errors.New("missing public key")
+	}
+	if alg == AlgEdDSA {
+		key, err := base64.StdEncoding.DecodeString(version.PublicKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 public key")
+		}
+		return ed25519.PublicKey(key), nil
+	}
+	block, _ := pem.Decode([]byte(version.PublicKey))
+	if block == nil {
+		return nil, errors.New("invalid PEM public key")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// ActiveKey 实现 KeyProvider：活动密钥是命名密钥的最新版本。
+func (p *TransitProvider) ActiveKey(ctx context.Context, purpose Purpose) (KeyInfo, error) {
+	key, err := p.describe(ctx, purpose)
+	if err != nil {
+		return KeyInfo{}, err
+	}
+	return key.infos[0], nil
+}
+
+// Keys 实现 KeyProvider：返回不低于 min_decryption_version 的全部版本。
+func (p *TransitProvider) Keys(ctx context.Context, purpose Purpose) ([]KeyInfo, error) {
+	key, err := p.describe(ctx, purpose)
+	if errors.Is(err, ErrNoKey) {
+		return nil, nil
+	}
+	return key.infos, err
+}
+
+// keyVersion 把 "v3" 形式的 kid 解析为版本号。
+func keyVersion(kid string) (int, error) {
+	version, err := strconv.Atoi(strings.TrimPrefix(kid, "v"))
+	if err != nil || !strings.HasPrefix(kid, "v") || version <= 0 {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+	return version, nil
+}
+
+// splitTransitValue 解析 "vault:v3:<base64>" 形式的返回值。jws 格式的签名使用无填充的 base64url 编码，
+// 两种编码只在标准编码的字母表之外不同，因此标准解码失败时再按 base64url 解码。
+func splitTransitValue(value string) (kid string, data []byte, err error) {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" {
+		return "", nil, fmt.Errorf("transit: unexpected value format %q", value)
+	}
+	data, err = base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		data, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[2], "="))
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("transit: %w", err)
+	}
+	return parts[1], data, nil
+}
+
+func transitValue(kid string, data []byte) string {
+	return "vault:" + kid + ":" + base64.StdEncoding.EncodeToString(data)
+}
+
+// isKeyVersionError 报告 err 是否表示服务拒绝了请求中的密钥或密钥版本（如已被移除的旧版本）。
+func isKeyVersionError(err error) bool {
+	var terr *transitError
+	return errors.As(err, &terr) && terr.Status == http.StatusBadRequest
+}
+
+// Sign 实现 KeyProvider：非对称密钥使用服务的 sign 接口，其他密钥使用 hmac 接口。
+func (p *TransitProvider) Sign(ctx context.Context, purpose Purpose, kid string, data []byte) ([]byte, error) {
+	name, err := p.keyName(purpose)
+	if err != nil {
+		return nil, err
+	}
+	version, err := keyVersion(kid)
+	if err != nil {
+		return nil, err
+	}
+	key, err := p.describe(ctx, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if key.alg.Asymmetric() {
+		var out struct {
+			Signature string `json:"signature"`
+		}
+		err = p.call(ctx, http.MethodPost, "sign/"+name, map[string]any{
+			"input":       base64.StdEncoding.EncodeToString(data),
+			"key_version": version,
+			// jws 使 ECDSA 签名为 r||s 格式，并以 base64url 编码；对 Ed25519 没有影响。
+			"marshaling_algorithm": "jws",
+		}, &out)
+		if err != nil {
+			return nil, err
+		}
+		_, sig, err := splitTransitValue(out.Signature)
+		return sig, err
+	}
+
+	var out struct {
+		HMAC string `json:"hmac"`
+	}
+	err = p.call(ctx, http.MethodPost, "hmac/"+name+"/sha2-256", map[string]any{
+		"input":       base64.StdEncoding.EncodeToString(data),
+		"key_version": version,
+	}, &out)
+	if err != nil {
+		return nil, err
+	}
+	_, sig, err := splitTransitValue(out.HMAC)
+	return sig, err
+}
+
+// Verify 实现 KeyProvider：非对称签名用缓存的公钥在本地验证，HMAC 使用服务的 verify 接口。
+func (p *TransitProvider) Verify(ctx context.Context, purpose Purpose, kid string, data, sig []byte) error {
+	name, err := p.keyName(purpose)
+	if err != nil {
+		return err
+	}
+	if _, err := keyVersion(kid); err != nil {
+		return err
+	}
+	key, err := p.describe(ctx, purpose)
+	if err != nil {
+		return err
+	}
+	if key.alg.Asymmetric() {
+		for _, info := range key.infos {
+			if info.ID == kid {
+				return VerifySignature(info.Algorithm, info.PublicKey, data, sig)
+			}
+		}
+		return fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+
+	var out struct {
+		Valid bool `json:"valid"`
+	}
+	err = p.call(ctx, http.MethodPost, "verify/"+name+"/sha2-256", map[string]any{
+		"input": base64.StdEncoding.EncodeToString(data),
+		"hmac":  transitValue(kid, sig),
+	}, &out)
+	if isKeyVersionError(err) {
+		return fmt.Errorf("%w: %s: %v", ErrUnknownKey, kid, err)
+	}
+	if err != nil {
+		return err
+	}
+	if !out.Valid {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Encrypt 实现 KeyProvider，使用服务的 encrypt 接口；返回的密文不含 "vault:vN:" 前缀。
+func (p *TransitProvider) Encrypt(ctx context.Context, purpose Purpose, plaintext, aad []byte) (string, []byte, error) {
+	name, err := p.keyName(purpose)
+	if err != nil {
+		return "", nil, err
+	}
+	var out struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	err = p.call(ctx, http.MethodPost, "encrypt/"+name, map[string]any{
+		"plaintext":       base64.StdEncoding.EncodeToString(plaintext),
+		"associated_data": base64.StdEncoding.EncodeToString(aad),
+	}, &out)
+	if err != nil {
+		return "", nil, err
+	}
+	return splitTransitValue(out.Ciphertext)
+}
+
+// Decrypt 实现 KeyProvider，使用服务的 decrypt 接口。
+func (p *TransitProvider) Decrypt(ctx context.Context, purpose Purpose, kid string, ciphertext, aad []byte) ([]byte, error) {
+	name, err := p.keyName(purpose)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := keyVersion(kid); err != nil {
+		return nil, err
+	}
+	var out struct {
+		Plaintext string `json:"plaintext"`
+	}
+	err = p.call(ctx, http.MethodPost, "decrypt/"+name, map[string]any{
+		"ciphertext":      transitValue(kid, ciphertext),
+		"associated_data": base64.StdEncoding.EncodeToString(aad),
+	}, &out)
+	if isKeyVersionError(err) {
+		return nil, fmt.Errorf("%w: %v", ErrDecrypt, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(out.Plaintext)
+}
@@ -0,0 +1,440 @@
+package kms
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// transitStandIn 是 Vault transit 引擎的最小替身，实现 TransitProvider 用到的接口，
+// 以及创建和轮换密钥的接口，测试用它代替 Vault。密钥只保存在内存中。
+type transitStandIn struct {
+	token string
+	mux   *http.ServeMux
+
+	mu   sync.Mutex
+	keys map[string]*standInKey
+}
+
+// standInKeyTypes 把替身支持的密钥类型映射到签名算法；aes256-gcm96 用于加密和 HMAC。
+var standInKeyTypes = map[string]Algorithm{
+	"aes256-gcm96": AlgHS256,
+	"ed25519":      AlgEdDSA,
+	"ecdsa-p256":   AlgES256,
+}
+
+// standInKey 是一个命名密钥的全部版本，versions[0] 是版本 1。
+// 与 Vault 一样，每个版本都有 HMAC 密钥；非对称类型的密钥另有签名密钥 signers。
+type standInKey struct {
+	typ      string
+	versions [][]byte
+	signers  []Key
+	created  []time.Time
+}
+
+// newTransitStandIn 创建挂载于 mount 的替身，请求必须携带 token（为空时不检查）。
+func newTransitStandIn(mount, token string) *transitStandIn {
+	s := &transitStandIn{token: token, mux: http.NewServeMux(), keys: make(map[string]*standInKey)}
+	prefix := "/v1/" + mount + "/"
+	s.mux.HandleFunc("GET "+prefix+"keys/{name}", s.readKey)
+	s.mux.HandleFunc("POST "+prefix+"keys/{name}", s.createKey)
+	s.mux.HandleFunc("POST "+prefix+"keys/{name}/rotate", s.rotateKey)
+	s.mux.HandleFunc("POST "+prefix+"hmac/{name}/sha2-256", s.hmac)
+	s.mux.HandleFunc("POST "+prefix+"verify/{name}/sha2-256", s.verify)
+	s.mux.HandleFunc("POST "+prefix+"sign/{name}", s.sign)
+	s.mux.HandleFunc("POST "+prefix+"encrypt/{name}", s.encrypt)
+	s.mux.HandleFunc("POST "+prefix+"decrypt/{name}", s.decrypt)
+	return s
+}
+
+// addKey 创建名为 name、类型为 typ（aes256-gcm96、ed25519 或 ecdsa-p256）的密钥，已存在时不做任何事。
+func (s *transitStandIn) addKey(name, typ string) error {
+	if _, ok := standInKeyTypes[typ]; !ok {
+		return fmt.Errorf("unsupported key type %q", typ)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.upsert(name, typ)
+	return err
+}
+
+func (s *transitStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Vault-Token")), []byte(s.token)) != 1 {
+		writeStandInError(w, http.StatusForbidden, "permission denied")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *transitStandIn) upsert(name, typ string) (*standInKey, error) {
+	if key, ok := s.keys[name]; ok {
+		return key, nil
+	}
+	key := &standInKey{typ: typ}
+	if err := key.rotate(); err != nil {
+		return nil, err
+	}
+	s.keys[name] = key
+	return key, nil
+}
+
+func (k *standInKey) rotate() error {
+	material, err := GenerateKey()
+	if err != nil {
+		return err
+	}
+	if alg := standInKeyTypes[k.typ]; alg.Asymmetric() {
+		der, err := GenerateSigningKey(alg)
+		if err != nil {
+			return err
+		}
+		signer, err := NewSigningKey(der)
+		if err != nil {
+			return err
+		}
+		k.signers = append(k.signers, signer)
+	}
+	k.versions = append(k.versions, material)
+	k.created = append(k.created, time.Now().UTC())
+	return nil
+}
+
+// version 返回 kid 对应的密钥；kid 为空时返回最新版本。
+func (k *standInKey) version(kid string) (string, []byte, bool) {
+	if kid == "" {
+		kid = "v" + strconv.Itoa(len(k.versions))
+	}
+	n, err := keyVersion(kid)
+	if err != nil || n > len(k.versions) {
+		return "", nil, false
+	}
+	return kid, k.versions[n-1], true
+}
+
+// lookup 返回名为 name 的密钥；不存在时写出 400 错误，与 Vault 一致。
+func (s *transitStandIn) lookup(w http.ResponseWriter, name string) *standInKey {
+	key, ok := s.keys[name]
+	if !ok {
+		writeStandInError(w, http.StatusBadRequest, "encryption key not found")
+	}
+	return key
+}
+
+func (s *transitStandIn) readKey(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[r.PathValue("name")]
+	if !ok {
+		writeStandInError(w, http.StatusNotFound, "key not found")
+		return
+	}
+	// 与 Vault 一样，对称密钥的各版本只给出创建时间，非对称密钥的各版本给出公钥：
+	// ed25519 为 base64 编码，ecdsa-p256 为 PEM 编码。
+	versions := make(map[string]any, len(key.versions))
+	for i, created := range key.created {
+		if len(key.signers) == 0 {
+			versions[strconv.Itoa(i+1)] = created.Unix()
+			continue
+		}
+		var public string
+		switch pub := key.signers[i].public.(type) {
+		case ed25519.PublicKey:
+			public = base64.StdEncoding.EncodeToString(pub)
+		default:
+			der, err := x509.MarshalPKIXPublicKey(pub)
+			if err != nil {
+				writeStandInError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			public = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+		}
+		versions[strconv.Itoa(i+1)] = map[string]any{
+			"creation_time": created.Format(time.RFC3339Nano),
+			"name":          key.typ,
+			"public_key":    public,
+		}
+	}
+	writeStandInData(w, map[string]any{
+		"name":                   r.PathValue("name"),
+		"type":                   key.typ,
+		"latest_version":         len(key.versions),
+		"min_decryption_version": 1,
+		"keys":                   versions,
+	})
+}
+
+func (s *transitStandIn) createKey(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Type string `json:"type"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+			writeStandInError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+	if req.Type == "" {
+		req.Type = "aes256-gcm96"
+	}
+	if err := s.addKey(r.PathValue("name"), req.Type); err != nil {
+		writeStandInError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *transitStandIn) rotateKey(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := s.lookup(w, r.PathValue("name"))
+	if key == nil {
+		return
+	}
+	if err := key.rotate(); err != nil {
+		writeStandInError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// standInRequest 是各接口请求体字段的并集。
+type standInRequest struct {
+	Input               string `json:"input"`
+	HMAC                string `json:"hmac"`
+	KeyVersion          int    `json:"key_version"`
+	MarshalingAlgorithm string `json:"marshaling_algorithm"`
+	Plaintext           string `json:"plaintext"`
+	Ciphertext          string `json:"ciphertext"`
+	AssociatedData      string `json:"associated_data"`
+}
+
+func decodeStandInRequest(w http.ResponseWriter, r *http.Request) (*standInRequest, bool) {
+	var req standInRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeStandInError(w, http.StatusBadRequest, "invalid request body")
+		return nil, false
+	}
+	return &req, true
+}
+
+func standInMAC(material []byte, input string) ([]byte, bool) {
+	data, err := base64.StdEncoding.DecodeString(input)
+	if err != nil {
+		return nil, false
+	}
+	mac := hmac.New(sha256.New, material)
+	mac.Write(data)
+	return mac.Sum(nil), true
+}
+
+func (s *transitStandIn) hmac(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeStandInRequest(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := s.lookup(w, r.PathValue("name"))
+	if key == nil {
+		return
+	}
+	kid := ""
+	if req.KeyVersion > 0 {
+		kid = "v" + strconv.Itoa(req.KeyVersion)
+	}
+	kid, material, ok := key.version(kid)
+	if !ok {
+		writeStandInError(w, http.StatusBadRequest, "invalid key version")
+		return
+	}
+	sum, ok := standInMAC(material, req.Input)
+	if !ok {
+		writeStandInError(w, http.StatusBadRequest, "unable to decode input as base64")
+		return
+	}
+	writeStandInData(w, map[string]any{"hmac": transitValue(kid, sum)})
+}
+
+func (s *transitStandIn) verify(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeStandInRequest(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := s.lookup(w, r.PathValue("name"))
+	if key == nil {
+		return
+	}
+	kid, sig, err := splitTransitValue(req.HMAC)
+	if err != nil {
+		writeStandInError(w, http.StatusBadRequest, "invalid hmac")
+		return
+	}
+	_, material, ok := key.version(kid)
+	if !ok {
+		writeStandInError(w, http.StatusBadRequest, "invalid key version")
+		return
+	}
+	sum, ok := standInMAC(material, req.Input)
+	if !ok {
+		writeStandInError(w, http.StatusBadRequest, "unable to decode input as base64")
+		return
+	}
+	writeStandInData(w, map[string]any{"valid": hmac.Equal(sum, sig)})
+}
+
+// sign 用非对称密钥签名。签名格式与 Vault 的 marshaling_algorithm=jws 相同：
+// ES256 为 r||s，以 base64url 编码；只支持 jws 格式。
+func (s *transitStandIn) sign(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeStandInRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.MarshalingAlgorithm != "jws" {
+		writeStandInError(w, http.StatusBadRequest, "the stand-in only supports marshaling_algorithm=jws")
+		return
+	}
+	data, err := base64.StdEncoding.DecodeString(req.Input)
+	if err != nil {
+		writeStandInError(w, http.StatusBadRequest, "unable to decode input as base64")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := s.lookup(w, r.PathValue("name"))
+	if key == nil {
+		return
+	}
+	if len(key.signers) == 0 {
+		writeStandInError(w, http.StatusBadRequest, "key type "+key.typ+" does not support signing")
+		return
+	}
+	version := req.KeyVersion
+	if version == 0 {
+		version = len(key.signers)
+	}
+	if version < 0 || version > len(key.signers) {
+		writeStandInError(w, http.StatusBadRequest, "invalid key version")
+		return
+	}
+	signer := key.signers[version-1]
+	sig, err := signAsymmetric(signer.Algorithm, signer.signer, data)
+	if err != nil {
+		writeStandInError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeStandInData(w, map[string]any{
+		"signature":   "vault:v" + strconv.Itoa(version) + ":" + base64.RawURLEncoding.EncodeToString(sig),
+		"key_version": version,
+	})
+}
+
+func (s *transitStandIn) encrypt(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeStandInRequest(w, r)
+	if !ok {
+		return
+	}
+	plaintext, err1 := base64.StdEncoding.DecodeString(req.Plaintext)
+	aad, err2 := base64.StdEncoding.DecodeString(req.AssociatedData)
+	if err1 != nil || err2 != nil {
+		writeStandInError(w, http.StatusBadRequest, "unable to decode input as base64")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 与 Vault 一样，加密时密钥不存在会自动创建。
+	key, err := s.upsert(r.PathValue("name"), "aes256-gcm96")
+	if err != nil {
+		writeStandInError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(key.signers) > 0 {
+		writeStandInError(w, http.StatusBadRequest, "key type "+key.typ+" does not support encryption")
+		return
+	}
+	kid, material, _ := key.version("")
+	aead, err := newAEAD(kid, material)
+	if err != nil {
+		writeStandInError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		writeStandInError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeStandInData(w, map[string]any{
+		"ciphertext":  transitValue(kid, aead.Seal(nonce, nonce, plaintext, aad)),
+		"key_version": len(key.versions),
+	})
+}
+
+func (s *transitStandIn) decrypt(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeStandInRequest(w, r)
+	if !ok {
+		return
+	}
+	aad, err := base64.StdEncoding.DecodeString(req.AssociatedData)
+	if err != nil {
+		writeStandInError(w, http.StatusBadRequest, "unable to decode associated data as base64")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := s.lookup(w, r.PathValue("name"))
+	if key == nil {
+		return
+	}
+	if len(key.signers) > 0 {
+		writeStandInError(w, http.StatusBadRequest, "key type "+key.typ+" does not support decryption")
+		return
+	}
+	kid, ciphertext, err := splitTransitValue(req.Ciphertext)
+	if err != nil {
+		writeStandInError(w, http.StatusBadRequest, "invalid ciphertext")
+		return
+	}
+	_, material, ok := key.version(kid)
+	if !ok {
+		writeStandInError(w, http.StatusBadRequest, "invalid ciphertext: key version not found")
+		return
+	}
+	aead, err := newAEAD(kid, material)
+	if err != nil {
+		writeStandInError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		writeStandInError(w, http.StatusBadRequest, "invalid ciphertext: too short")
+		return
+	}
+	plaintext, err := aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], aad)
+	if err != nil {
+		writeStandInError(w, http.StatusBadRequest, "cipher: message authentication failed")
+		return
+	}
+	writeStandInData(w, map[string]any{"plaintext": base64.StdEncoding.EncodeToString(plaintext)})
+}
+
+func writeStandInData(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"data": data})
+}
+
+func writeStandInError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"errors": []string{message}})
+}
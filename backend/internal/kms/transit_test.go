@@ -0,0 +1,205 @@
+package kms
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	standInMount = "transit"
+	standInToken = "test-token"
+)
+
+// startTransitStandIn 启动替身，创建类型为 jwtKeyType 的 JWT 密钥和 aes256-gcm96 的存储密钥。
+func startTransitStandIn(t *testing.T, jwtKeyType string) *httptest.Server {
+	t.Helper()
+	standIn := newTransitStandIn(standInMount, standInToken)
+	if err := standIn.addKey("jwt", jwtKeyType); err != nil {
+		t.Fatal(err)
+	}
+	if err := standIn.addKey("storage", "aes256-gcm96"); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(standIn)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestTransitProvider 创建访问 server 的提供者。每次调用得到新的密钥信息缓存，轮换后用它读取最新版本。
+func newTestTransitProvider(server *httptest.Server, token string) *TransitProvider {
+	return NewTransitProvider(server.URL, standInMount, token, map[Purpose]string{
+		PurposeJWT:     "jwt",
+		PurposeStorage: "storage",
+	})
+}
+
+// rotateStandInKey 通过替身的 rotate 接口轮换 name。
+func rotateStandInKey(t *testing.T, server *httptest.Server, name string) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, server.URL+"/v1/"+standInMount+"/keys/"+name+"/rotate", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Vault-Token", standInToken)
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("rotate %s: HTTP %d", name, resp.StatusCode)
+	}
+}
+
+func TestTransitSignVerify(t *testing.T) {
+	tests := []struct {
+		keyType string
+		alg     Algorithm
+	}{
+		{"aes256-gcm96", AlgHS256},
+		{"ed25519", AlgEdDSA},
+		{"ecdsa-p256", AlgES256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keyType, func(t *testing.T) {
+			ctx := t.Context()
+			p := newTestTransitProvider(startTransitStandIn(t, tt.keyType), standInToken)
+
+			active, err := p.ActiveKey(ctx, PurposeJWT)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if active.ID != "v1" || active.Algorithm != tt.alg || !active.Active {
+				t.Fatalf("活动密钥 = %+v，期望 v1 %s", active, tt.alg)
+			}
+			if tt.alg.Asymmetric() != (active.PublicKey != nil) {
+				t.Fatalf("公钥 = %v，与算法 %s 不符", active.PublicKey, tt.alg)
+			}
+
+			data := []byte("header.payload")
+			sig, err := p.Sign(ctx, PurposeJWT, active.ID, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Verify(ctx, PurposeJWT, active.ID, data, sig); err != nil {
+				t.Fatalf("验证签名失败: %v", err)
+			}
+			if err := p.Verify(ctx, PurposeJWT, active.ID, []byte("header.other"), sig); !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("验证篡改的数据: %v，期望 ErrInvalidSignature", err)
+			}
+			if err := p.Verify(ctx, PurposeJWT, "v9", data, sig); !errors.Is(err, ErrUnknownKey) {
+				t.Fatalf("验证不存在的版本: %v，期望 ErrUnknownKey", err)
+			}
+			if _, err := p.Sign(ctx, PurposeJWT, "key-1", data); !errors.Is(err, ErrUnknownKey) {
+				t.Fatalf("用格式错误的 kid 签名: %v，期望 ErrUnknownKey", err)
+			}
+		})
+	}
+}
+
+func TestTransitEncryptDecrypt(t *testing.T) {
+	ctx := t.Context()
+	p := newTestTransitProvider(startTransitStandIn(t, "ed25519"), standInToken)
+
+	plaintext, aad := []byte("secret"), []byte("item-1")
+	kid, ciphertext, err := p.Encrypt(ctx, PurposeStorage, plaintext, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid != "v1" {
+		t.Fatalf("kid = %q，期望 v1", kid)
+	}
+	if bytes.Contains(ciphertext, plaintext) {
+		t.Fatal("密文包含明文")
+	}
+	got, err := p.Decrypt(ctx, PurposeStorage, kid, ciphertext, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("解密得到 %q，期望 %q", got, plaintext)
+	}
+
+	if _, err := p.Decrypt(ctx, PurposeStorage, kid, ciphertext, []byte("item-2")); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("附加数据不同: %v，期望 ErrDecrypt", err)
+	}
+	tampered := bytes.Clone(ciphertext)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := p.Decrypt(ctx, PurposeStorage, kid, tampered, aad); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("密文被篡改: %v，期望 ErrDecrypt", err)
+	}
+	if _, err := p.Decrypt(ctx, PurposeStorage, "v2", ciphertext, aad); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("不存在的版本: %v，期望 ErrDecrypt", err)
+	}
+}
+
+// TestTransitRotation 检查轮换后新版本成为活动密钥，旧版本的签名和密文仍然可用。
+func TestTransitRotation(t *testing.T) {
+	for _, keyType := range []string{"aes256-gcm96", "ed25519"} {
+		t.Run(keyType, func(t *testing.T) {
+			ctx := t.Context()
+			server := startTransitStandIn(t, keyType)
+			p := newTestTransitProvider(server, standInToken)
+
+			data := []byte("header.payload")
+			sig, err := p.Sign(ctx, PurposeJWT, "v1", data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			kid, ciphertext, err := p.Encrypt(ctx, PurposeStorage, []byte("secret"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rotateStandInKey(t, server, "jwt")
+			rotateStandInKey(t, server, "storage")
+			p = newTestTransitProvider(server, standInToken)
+
+			keys, err := p.Keys(ctx, PurposeJWT)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(keys) != 2 || keys[0].ID != "v2" || !keys[0].Active || keys[1].ID != "v1" || keys[1].Active {
+				t.Fatalf("轮换后的密钥 = %+v，期望活动的 v2 在前、v1 在后", keys)
+			}
+			if err := p.Verify(ctx, PurposeJWT, "v1", data, sig); err != nil {
+				t.Fatalf("轮换后验证旧签名失败: %v", err)
+			}
+			if got, err := p.Decrypt(ctx, PurposeStorage, kid, ciphertext, nil); err != nil || string(got) != "secret" {
+				t.Fatalf("轮换后解密旧密文得到 %q, %v", got, err)
+			}
+			newKid, _, err := p.Encrypt(ctx, PurposeStorage, []byte("secret"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if newKid != "v2" {
+				t.Fatalf("轮换后加密使用 %q，期望 v2", newKid)
+			}
+		})
+	}
+}
+
+func TestTransitErrors(t *testing.T) {
+	ctx := t.Context()
+	server := startTransitStandIn(t, "aes256-gcm96")
+
+	var terr *transitError
+	_, err := newTestTransitProvider(server, "wrong-token").ActiveKey(ctx, PurposeJWT)
+	if !errors.As(err, &terr) || terr.Status != http.StatusForbidden {
+		t.Fatalf("错误的令牌: %v，期望 HTTP 403", err)
+	}
+
+	p := NewTransitProvider(server.URL, standInMount, standInToken, map[Purpose]string{PurposeJWT: "missing"})
+	if _, err := p.ActiveKey(ctx, PurposeJWT); !errors.Is(err, ErrNoKey) {
+		t.Fatalf("服务中不存在的密钥: %v，期望 ErrNoKey", err)
+	}
+	if keys, err := p.Keys(ctx, PurposeJWT); err != nil || len(keys) != 0 {
+		t.Fatalf("服务中不存在的密钥: Keys() = %v, %v，期望空列表", keys, err)
+	}
+	if _, err := p.ActiveKey(ctx, PurposeStorage); !errors.Is(err, ErrNoKey) {
+		t.Fatalf("未配置的用途: %v，期望 ErrNoKey", err)
+	}
+}
@@ -0,0 +1,98 @@
+package boltdb
+
+import (
+	"context"
+	"easy-password-backend/internal/core"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+)
+
+// --- API 密钥存储库实现 ---
+
+type apiKeyRepository struct {
+	db  *bbolt.DB
+	enc *Encryption
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *core.APIKey) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		keys := tx.Bucket(apiKeyBucket)
+		if key.ID == uuid.Nil {
+			key.ID = uuid.New()
+		}
+		if key.CreatedAt.IsZero() {
+			key.CreatedAt = time.Now()
+		}
+		encoded, err := r.enc.seal(apiKeyBucket, key.ID[:], key)
+		if err != nil {
+			return err
+		}
+		return keys.Put(key.ID[:], encoded)
+	})
+}
+
+func (r *apiKeyRepository) FindByID(ctx context.Context, id uuid.UUID) (*core.APIKey, error) {
+	var key core.APIKey
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		keyBytes := tx.Bucket(apiKeyBucket).Get(id[:])
+		if keyBytes == nil {
+			return core.ErrAPIKeyNotFound
+		}
+		return r.enc.open(apiKeyBucket, id[:], keyBytes, &key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) FindByUser(ctx context.Context, userID uuid.UUID) ([]core.APIKey, error) {
+	var keys []core.APIKey
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(apiKeyBucket).ForEach(func(k, v []byte) error {
+			var key core.APIKey
+			if err := r.enc.open(apiKeyBucket, k, v, &key); err != nil {
+				return fmt.Errorf("decode %s/%x: %w", apiKeyBucket, k, err)
+			}
+			if key.UserID == userID {
+				keys = append(keys, key)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+func (r *apiKeyRepository) Update(ctx context.Context, key *core.APIKey) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		keys := tx.Bucket(apiKeyBucket)
+		if existing := keys.Get(key.ID[:]); existing == nil {
+			return core.ErrAPIKeyNotFound
+		}
+		encoded, err := r.enc.seal(apiKeyBucket, key.ID[:], key)
+		if err != nil {
+			return err
+		}
+		return keys.Put(key.ID[:], encoded)
+	})
+}
+
+func (r *apiKeyRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		keys := tx.Bucket(apiKeyBucket)
+		if keys.Get(id[:]) == nil {
+			return core.ErrAPIKeyNotFound
+		}
+		return keys.Delete(id[:])
+	})
+}
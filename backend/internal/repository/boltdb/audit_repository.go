@@ -0,0 +1,193 @@
+package boltdb
+
+import (
+	"bytes"
+	"context"
+	"easy-password-backend/internal/core"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+)
+
+// --- 审计事件存储库实现 ---
+
+// 事件以大端序的序号为键存储，游标顺序即链的顺序；
+// auditChainBucket 保存每个用户链上最后一条事件的哈希。
+// auditUserBucket 是按用户查询的索引，键为用户 ID 后接序号，值为空，便于按用户前缀倒序遍历；
+// 其中 auditIndexedKey 记录已建立索引的最大序号。
+type auditRepository struct {
+	db  *bbolt.DB
+	enc *Encryption
+}
+
+var auditIndexedKey = []byte("indexed")
+
+func sequenceKey(seq int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(seq))
+	return key
+}
+
+func auditUserKey(userID uuid.UUID, seq int64) []byte {
+	return append(userID[:len(userID):len(userID)], sequenceKey(seq)...)
+}
+
+// indexedSequence 返回已建立用户索引的最大序号。
+func indexedSequence(tx *bbolt.Tx) int64 {
+	v := tx.Bucket(auditUserBucket).Get(auditIndexedKey)
+	if len(v) != 8 {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(v))
+}
+
+// auditIndexBehind 报告是否有事件尚未加入用户索引。
+func auditIndexBehind(tx *bbolt.Tx) bool {
+	k, _ := tx.Bucket(auditEventBucket).Cursor().Last()
+	return k != nil && int64(binary.BigEndian.Uint64(k)) > indexedSequence(tx)
+}
+
+// indexAuditEvent 把事件加入用户索引。事件紧接在已索引的事件之后时推进 auditIndexedKey，
+// 否则留给 indexAuditEvents 补齐。
+func indexAuditEvent(tx *bbolt.Tx, event *core.AuditEvent) error {
+	index := tx.Bucket(auditUserBucket)
+	if err := index.Put(auditUserKey(event.UserID, event.Sequence), []byte{}); err != nil {
+		return err
+	}
+	if event.Sequence != indexedSequence(tx)+1 {
+		return nil
+	}
+	return index.Put(auditIndexedKey, sequenceKey(event.Sequence))
+}
+
+// indexAuditEvents 为尚未加入用户索引的事件补建索引，例如迁移 5 之前写入的事件。
+func indexAuditEvents(tx *bbolt.Tx, enc *Encryption) error {
+	c := tx.Bucket(auditEventBucket).Cursor()
+	for k, v := c.Seek(sequenceKey(indexedSequence(tx) + 1)); k != nil; k, v = c.Next() {
+		var event core.AuditEvent
+		if err := enc.open(auditEventBucket, k, v, &event); err != nil {
+			return fmt.Errorf("decode %s/%x: %w", auditEventBucket, k, err)
+		}
+		if err := indexAuditEvent(tx, &event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *auditRepository) Create(ctx context.Context, event *core.AuditEvent) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		events := tx.Bucket(auditEventBucket)
+		heads := tx.Bucket(auditChainBucket)
+		if err := indexAuditEvents(tx, r.enc); err != nil {
+			return err
+		}
+
+		id, err := uuid.NewV7()
+		if err != nil {
+			return err
+		}
+		event.ID = id
+		event.CreatedAt = event.CreatedAt.UTC().Truncate(time.Microsecond)
+
+		// 链接到全局链上的上一条事件。
+		event.Sequence = 1
+		event.PrevHash = ""
+		if k, last := events.Cursor().Last(); last != nil {
+			var prev core.AuditEvent
+			if err := r.enc.open(auditEventBucket, k, last, &prev); err != nil {
+				return err
+			}
+			event.Sequence = prev.Sequence + 1
+			event.PrevHash = prev.Hash
+		}
+
+		// 链接到该用户链上的上一条事件。
+		event.UserPrevHash = string(heads.Get(event.UserID[:]))
+		event.Hash = event.ComputeHash()
+
+		key := sequenceKey(event.Sequence)
+		encoded, err := r.enc.seal(auditEventBucket, key, event)
+		if err != nil {
+			return err
+		}
+		if err := events.Put(key, encoded); err != nil {
+			return err
+		}
+		if err := indexAuditEvent(tx, event); err != nil {
+			return err
+		}
+		return heads.Put(event.UserID[:], []byte(event.Hash))
+	})
+}
+
+func (r *auditRepository) FindByUser(ctx context.Context, userID uuid.UUID, offset, limit int) ([]core.AuditEvent, int64, error) {
+	var behind bool
+	if err := r.db.View(func(tx *bbolt.Tx) error {
+		behind = auditIndexBehind(tx)
+		return nil
+	}); err != nil {
+		return nil, 0, err
+	}
+	if behind {
+		if err := r.db.Update(func(tx *bbolt.Tx) error { return indexAuditEvents(tx, r.enc) }); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	var events []core.AuditEvent
+	var total int64
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(auditEventBucket)
+		c := tx.Bucket(auditUserBucket).Cursor()
+		// 从该用户最新的事件开始倒序遍历。
+		upper := auditUserKey(userID, math.MaxInt64)
+		k, _ := c.Seek(upper)
+		switch {
+		case k == nil:
+			k, _ = c.Last()
+		case !bytes.Equal(k, upper):
+			k, _ = c.Prev()
+		}
+		for ; k != nil && bytes.HasPrefix(k, userID[:]); k, _ = c.Prev() {
+			if total >= int64(offset) && len(events) < limit {
+				key := k[len(userID):]
+				v := bucket.Get(key)
+				if v == nil {
+					return fmt.Errorf("audit user index refers to missing event %d", binary.BigEndian.Uint64(key))
+				}
+				var event core.AuditEvent
+				if err := r.enc.open(auditEventBucket, key, v, &event); err != nil {
+					return fmt.Errorf("decode %s/%x: %w", auditEventBucket, key, err)
+				}
+				events = append(events, event)
+			}
+			total++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+func (r *auditRepository) ForEach(ctx context.Context, fn func(event *core.AuditEvent) error) error {
+	return r.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(auditEventBucket).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var event core.AuditEvent
+			if err := r.enc.open(auditEventBucket, k, v, &event); err != nil {
+				return err
+			}
+			if err := fn(&event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
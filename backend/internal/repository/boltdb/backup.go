@@ -0,0 +1,138 @@
+package boltdb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+const (
+	snapshotPrefix     = "easypassword-"
+	snapshotSuffix     = ".db"
+	snapshotTimeFormat = "20060102T150405Z"
+)
+
+// Backup 在一个只读事务中将数据库的一致快照写入 w，返回写入的字节数。
+// 只读事务不会阻塞写入，因此可以在服务运行时执行。
+func (s *Storage) Backup(ctx context.Context, w io.Writer) (int64, error) {
+	return WriteSnapshot(s.db, w)
+}
+
+// WriteSnapshot 将 db 的一致快照写入 w。
+func WriteSnapshot(db *bbolt.DB, w io.Writer) (int64, error) {
+	var n int64
+	err := db.View(func(tx *bbolt.Tx) error {
+		var err error
+		n, err = tx.WriteTo(w)
+		return err
+	})
+	return n, err
+}
+
+// SaveSnapshot 将快照写入 dir 下以 UTC 时间命名的文件并返回文件路径。
+// 快照先写入临时文件，完成并同步到磁盘后再重命名，目录中不会出现不完整的快照。
+func SaveSnapshot(db *bbolt.DB, dir string, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, snapshotPrefix+now.UTC().Format(snapshotTimeFormat)+snapshotSuffix)
+
+	tmp, err := os.CreateTemp(dir, ".snapshot-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := WriteSnapshot(db, tmp); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// PruneSnapshots 只保留 dir 中最新的 keep 个快照，返回被删除的文件路径。
+func PruneSnapshots(dir string, keep int) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, snapshotPrefix) && strings.HasSuffix(name, snapshotSuffix) {
+			snapshots = append(snapshots, name)
+		}
+	}
+	// 文件名中的时间戳按字典序即按时间排序。
+	sort.Sort(sort.Reverse(sort.StringSlice(snapshots)))
+
+	var removed []string
+	for i := keep; i < len(snapshots); i++ {
+		path := filepath.Join(dir, snapshots[i])
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
+
+// RunScheduledSnapshots 每隔 interval 向 dir 写入一个快照，并只保留最新的 keep 个，
+// 直到 ctx 被取消。单次快照失败只记录日志，不会中止调度。
+func RunScheduledSnapshots(ctx context.Context, db *bbolt.DB, dir string, interval time.Duration, keep int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			path, err := SaveSnapshot(db, dir, now)
+			if err != nil {
+				slog.Error("Scheduled snapshot failed", "dir", dir, "error", err)
+				continue
+			}
+			slog.Info("Scheduled snapshot written", "path", path)
+
+			removed, err := PruneSnapshots(dir, keep)
+			if err != nil {
+				slog.Error("Failed to prune old snapshots", "dir", dir, "error", err)
+			}
+			for _, path := range removed {
+				slog.Info("Old snapshot removed", "path", path)
+			}
+		}
+	}
+}
+
+// OpenSnapshot 以只读方式打开快照文件。
+func OpenSnapshot(path string) (*bbolt.DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db, err := bbolt.Open(path, 0400, &bbolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open snapshot %s: %w", path, err)
+	}
+	return db, nil
+}
@@ -0,0 +1,137 @@
+package boltdb
+
+import (
+	"context"
+	"easy-password-backend/internal/core"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+)
+
+// --- 批量迁移存储库实现 ---
+
+type bulkRepository struct {
+	db  *bbolt.DB
+	enc *Encryption
+}
+
+// recordBucket 返回保存 kind 类记录的存储桶。
+func recordBucket(kind core.RecordKind) ([]byte, error) {
+	switch kind {
+	case core.RecordUsers:
+		return userBucket, nil
+	case core.RecordVaultItems:
+		return vaultBucket, nil
+	case core.RecordVerificationCodes:
+		return verificationCodeBucket, nil
+	case core.RecordDevices:
+		return deviceBucket, nil
+	case core.RecordLoginApprovals:
+		return loginApprovalBucket, nil
+	case core.RecordAPIKeys:
+		return apiKeyBucket, nil
+	case core.RecordAuditEvents:
+		return auditEventBucket, nil
+	default:
+		return nil, fmt.Errorf("unknown record kind %q", kind)
+	}
+}
+
+func (r *bulkRepository) Count(ctx context.Context, kind core.RecordKind) (int64, error) {
+	name, err := recordBucket(kind)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	err = r.db.View(func(tx *bbolt.Tx) error {
+		n = int64(tx.Bucket(name).Stats().KeyN)
+		return nil
+	})
+	return n, err
+}
+
+func (r *bulkRepository) ForEach(ctx context.Context, kind core.RecordKind, fn func(record any) error) error {
+	name, err := recordBucket(kind)
+	if err != nil {
+		return err
+	}
+	return r.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(name).ForEach(func(k, v []byte) error {
+			record := core.NewRecord(kind)
+			if err := r.enc.open(name, k, v, record); err != nil {
+				return fmt.Errorf("decode %s/%x: %w", name, k, err)
+			}
+			return fn(record)
+		})
+	})
+}
+
+func (r *bulkRepository) Insert(ctx context.Context, kind core.RecordKind, records []any) error {
+	name, err := recordBucket(kind)
+	if err != nil {
+		return err
+	}
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(name)
+		for _, record := range records {
+			key, err := r.index(tx, record)
+			if err != nil {
+				return err
+			}
+			if bucket.Get(key) != nil {
+				return fmt.Errorf("%s/%x already exists", name, key)
+			}
+			encoded, err := r.enc.seal(name, key, record)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(key, encoded); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// index 返回记录的键，并维护与之关联的索引存储桶。
+func (r *bulkRepository) index(tx *bbolt.Tx, record any) ([]byte, error) {
+	switch rec := record.(type) {
+	case *core.User:
+		if err := putIndex(tx.Bucket(usernameBucket), "username", r.enc.indexKey(rec.Username), rec.ID); err != nil {
+			return nil, err
+		}
+		if err := putIndex(tx.Bucket(emailBucket), "email", r.enc.indexKey(rec.Email), rec.ID); err != nil {
+			return nil, err
+		}
+		return rec.ID[:], nil
+	case *core.VaultItem:
+		return rec.ID[:], nil
+	case *core.VerificationCode:
+		return r.enc.indexKey(rec.Email), nil
+	case *core.Device:
+		return r.enc.deviceKey(rec.UserID, rec.DeviceID), nil
+	case *core.LoginApproval:
+		return rec.ID[:], nil
+	case *core.APIKey:
+		return rec.ID[:], nil
+	case *core.AuditEvent:
+		// 按序号写入时，最后写入的事件就是该用户链的头。
+		if err := tx.Bucket(auditChainBucket).Put(rec.UserID[:], []byte(rec.Hash)); err != nil {
+			return nil, err
+		}
+		if err := indexAuditEvent(tx, rec); err != nil {
+			return nil, err
+		}
+		return sequenceKey(rec.Sequence), nil
+	default:
+		return nil, fmt.Errorf("unsupported record type %T", record)
+	}
+}
+
+func putIndex(index *bbolt.Bucket, field string, key []byte, userID uuid.UUID) error {
+	if index.Get(key) != nil {
+		return &core.DuplicateEntryError{Field: field}
+	}
+	return index.Put(key, userID[:])
+}
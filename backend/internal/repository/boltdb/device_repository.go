@@ -0,0 +1,123 @@
+package boltdb
+
+import (
+	"bytes"
+	"context"
+	"easy-password-backend/internal/core"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+)
+
+// --- 设备存储库实现 ---
+
+// 设备以 用户ID + 客户端设备ID 为键存储，便于按用户前缀遍历。
+type deviceRepository struct {
+	db  *bbolt.DB
+	enc *Encryption
+}
+
+func (r *deviceRepository) Create(ctx context.Context, device *core.Device) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		devices := tx.Bucket(deviceBucket)
+		key := r.enc.deviceKey(device.UserID, device.DeviceID)
+		if devices.Get(key) != nil {
+			return &core.DuplicateEntryError{Field: "device_id"}
+		}
+		device.ID = uuid.New()
+		encoded, err := r.enc.seal(deviceBucket, key, device)
+		if err != nil {
+			return err
+		}
+		return devices.Put(key, encoded)
+	})
+}
+
+func (r *deviceRepository) FindByID(ctx context.Context, id uuid.UUID) (*core.Device, error) {
+	var found *core.Device
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(deviceBucket).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var device core.Device
+			if err := r.enc.open(deviceBucket, k, v, &device); err != nil {
+				return fmt.Errorf("decode %s/%x: %w", deviceBucket, k, err)
+			}
+			if device.ID == id {
+				found = &device
+				return nil
+			}
+		}
+		return core.ErrDeviceNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+func (r *deviceRepository) FindByUserAndDeviceID(ctx context.Context, userID uuid.UUID, deviceID string) (*core.Device, error) {
+	var device core.Device
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		key := r.enc.deviceKey(userID, deviceID)
+		deviceBytes := tx.Bucket(deviceBucket).Get(key)
+		if deviceBytes == nil {
+			return core.ErrDeviceNotFound
+		}
+		return r.enc.open(deviceBucket, key, deviceBytes, &device)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (r *deviceRepository) FindByUser(ctx context.Context, userID uuid.UUID) ([]core.Device, error) {
+	var devices []core.Device
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(deviceBucket).Cursor()
+		prefix := userID[:]
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var device core.Device
+			if err := r.enc.open(deviceBucket, k, v, &device); err == nil {
+				devices = append(devices, device)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+func (r *deviceRepository) Update(ctx context.Context, device *core.Device) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		devices := tx.Bucket(deviceBucket)
+		key := r.enc.deviceKey(device.UserID, device.DeviceID)
+		if existing := devices.Get(key); existing == nil {
+			return core.ErrDeviceNotFound
+		}
+		encoded, err := r.enc.seal(deviceBucket, key, device)
+		if err != nil {
+			return err
+		}
+		return devices.Put(key, encoded)
+	})
+}
+
+func (r *deviceRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(deviceBucket).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var device core.Device
+			if err := r.enc.open(deviceBucket, k, v, &device); err != nil {
+				return fmt.Errorf("decode %s/%x: %w", deviceBucket, k, err)
+			}
+			if device.ID == id {
+				return c.Delete()
+			}
+		}
+		return core.ErrDeviceNotFound
+	})
+}
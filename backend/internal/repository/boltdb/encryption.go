@@ -0,0 +1,707 @@
+package boltdb
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/kms"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+)
+
+// 静态加密
+//
+// 配置服务器密钥后，encryptedBuckets 中的每条记录都用 AES-256-GCM 加密后存储：
+//
+//	0xE1 | 数据密钥编号（4 字节大端序）| nonce（12 字节）| 密文和认证标签
+//
+// 附加数据是存储桶名和记录的键，密文无法在记录之间调换。
+// 数据密钥随机生成，由 KeyProvider 用存储用途的服务器密钥包装后保存在 encryptionBucket 中，轮换时生成新的数据密钥，
+// 旧记录在后台重新加密，完成后删除旧的数据密钥。
+// 以用户名、邮箱和客户端设备 ID 为键的记录改为使用 HMAC-SHA256 盲索引，
+// 盲索引密钥同样由服务器密钥包装，但不随数据密钥轮换，否则每次轮换都要重建索引。
+
+// encryptionBucket 保存密钥环，由迁移 3 创建。
+var encryptionBucket = []byte("encryption_keys")
+
+var keyringKey = []byte("keyring")
+
+// encryptedBuckets 是记录值需要加密的存储桶。索引存储桶的值只是随机的用户 ID，不需要加密。
+var encryptedBuckets = [][]byte{
+	userBucket, vaultBucket, verificationCodeBucket, auditEventBucket, deviceBucket, loginApprovalBucket,
+	apiKeyBucket,
+}
+
+const (
+	sealedMagic = 0xE1
+	// sealedOverhead 是加密记录相对于明文增加的字节数：魔数、密钥编号、nonce 和认证标签。
+	sealedOverhead = 1 + 4 + 12 + 16
+	// EncryptionKeySize 是数据密钥的字节数。
+	EncryptionKeySize = 32
+	// reencryptBatchSize 是后台重新加密时每个写事务处理的记录数，避免长时间阻塞其他写入。
+	reencryptBatchSize = 500
+)
+
+var (
+	// ErrDatabaseEncrypted 表示数据库已加密，但没有提供服务器密钥。
+	ErrDatabaseEncrypted = errors.New("the database is encrypted at rest; a server encryption key is required")
+	// ErrWrongEncryptionKey 表示提供的服务器密钥都不是加密该数据库的密钥。
+	ErrWrongEncryptionKey = errors.New("none of the configured encryption keys matches the key this database was encrypted with")
+)
+
+// keyring 是 encryptionBucket 中保存的密钥环。
+type keyring struct {
+	// KEKID 是包装这些密钥的服务器密钥的 kid。它不再是活动密钥时，打开数据库会重新包装。
+	KEKID string `json:"kek_id"`
+	// Active 是加密新记录所用的数据密钥编号。
+	Active uint32 `json:"active"`
+	// DataKeys 是被包装的数据密钥，以编号为键。
+	DataKeys map[uint32][]byte `json:"data_keys"`
+	// IndexKey 是被包装的盲索引密钥。
+	IndexKey []byte `json:"index_key"`
+	// Pending 为 true 表示可能还有记录未使用活动数据密钥加密。
+	Pending   bool      `json:"pending"`
+	RotatedAt time.Time `json:"rotated_at"`
+}
+
+// Encryption 是 BoltDB 存储库使用的静态加密层。nil 表示不加密，所有方法都按明文处理记录。
+type Encryption struct {
+	db   *bbolt.DB
+	keys kms.KeyProvider
+
+	mu   sync.RWMutex
+	ring keyring
+	// raw 是解开后的数据密钥，重新包装时需要；aeads 是由它们创建的 AEAD。
+	raw   map[uint32][]byte
+	aeads map[uint32]cipher.AEAD
+	index []byte
+
+	// reencrypting 保证同一时间只有一个后台重新加密任务。
+	reencrypting sync.Mutex
+}
+
+// OpenEncryption 加载 db 的密钥环并返回加密层，服务器密钥由 keys 的 kms.PurposeStorage 用途提供。
+//
+//   - 数据库未加密且没有配置存储密钥时返回 nil, nil。
+//   - 数据库未加密而配置了存储密钥时启用加密：创建密钥环、重建盲索引，已有记录需要之后调用 Reencrypt 加密。
+//   - 密钥环由非活动的服务器密钥包装时，用活动密钥重新包装。
+func OpenEncryption(ctx context.Context, db *bbolt.DB, keys kms.KeyProvider) (*Encryption, error) {
+	e, ring, active, err := loadEncryption(ctx, db, keys, true)
+	switch {
+	case err != nil || e != nil:
+		return e, err
+	case ring != nil:
+		return nil, ErrDatabaseEncrypted
+	case active == "":
+		return nil, nil
+	}
+	return enableEncryption(ctx, db, keys)
+}
+
+// LoadEncryption 与 OpenEncryption 相同，但从不修改数据库，用于只读打开的快照。
+// 数据库未加密时返回 nil, nil。
+func LoadEncryption(ctx context.Context, db *bbolt.DB, keys kms.KeyProvider) (*Encryption, error) {
+	e, ring, _, err := loadEncryption(ctx, db, keys, false)
+	if err == nil && e == nil && ring != nil {
+		return nil, ErrDatabaseEncrypted
+	}
+	return e, err
+}
+
+// loadEncryption 读取密钥环。数据库未加密或没有配置存储密钥时返回的 Encryption 为 nil，
+// ring 为读到的密钥环，active 为活动服务器密钥的 kid（没有时为空）。
+// writable 为 true 时，把由旧密钥包装的密钥环改用活动密钥包装。
+func loadEncryption(ctx context.Context, db *bbolt.DB, keys kms.KeyProvider, writable bool) (*Encryption, *keyring, string, error) {
+	activeKey, err := keys.ActiveKey(ctx, kms.PurposeStorage)
+	if errors.Is(err, kms.ErrNoKey) {
+		err = nil
+	}
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("storage encryption key: %w", err)
+	}
+	active := activeKey.ID
+
+	var ring *keyring
+	err = db.View(func(tx *bbolt.Tx) error {
+		var err error
+		ring, err = readKeyring(tx)
+		return err
+	})
+	if err != nil || ring == nil || active == "" {
+		return nil, ring, active, err
+	}
+
+	e := &Encryption{db: db, keys: keys}
+	if err := e.unwrapKeyring(ctx, ring); err != nil {
+		return nil, ring, active, err
+	}
+	if writable && ring.KEKID != active {
+		// 用活动服务器密钥重新包装；记录本身不需要重新加密。
+		if err := e.rewrap(ctx); err != nil {
+			return nil, ring, active, err
+		}
+		slog.Info("Re-wrapped data keys with the current server encryption key", "kek_id", e.ring.KEKID)
+	}
+	return e, ring, active, nil
+}
+
+func readKeyring(tx *bbolt.Tx) (*keyring, error) {
+	bucket := tx.Bucket(encryptionBucket)
+	if bucket == nil {
+		return nil, nil
+	}
+	data := bucket.Get(keyringKey)
+	if data == nil {
+		return nil, nil
+	}
+	var ring keyring
+	if err := json.Unmarshal(data, &ring); err != nil {
+		return nil, fmt.Errorf("decode keyring: %w", err)
+	}
+	return &ring, nil
+}
+
+// unwrapKeyring 解开密钥环中的全部密钥并载入内存。
+func (e *Encryption) unwrapKeyring(ctx context.Context, ring *keyring) error {
+	e.ring = *ring
+	e.raw = make(map[uint32][]byte, len(ring.DataKeys))
+	e.aeads = make(map[uint32]cipher.AEAD, len(ring.DataKeys))
+	for id, wrapped := range ring.DataKeys {
+		key, err := e.unwrapKey(ctx, ring.KEKID, dataKeyLabel(id), wrapped)
+		if err != nil {
+			return fmt.Errorf("unwrap data key %d: %w", id, err)
+		}
+		if e.aeads[id], err = newAEAD(key); err != nil {
+			return err
+		}
+		e.raw[id] = key
+	}
+	if _, ok := e.aeads[ring.Active]; !ok {
+		return fmt.Errorf("keyring has no active data key %d", ring.Active)
+	}
+	index, err := e.unwrapKey(ctx, ring.KEKID, indexKeyLabel, ring.IndexKey)
+	if err != nil {
+		return fmt.Errorf("unwrap index key: %w", err)
+	}
+	e.index = index
+	return nil
+}
+
+// rewrap 用活动服务器密钥重新包装全部密钥并保存密钥环。
+func (e *Encryption) rewrap(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ring := e.ring
+	ring.DataKeys = make(map[uint32][]byte, len(e.raw))
+	kekID, wrappedIndex, err := e.wrapKey(ctx, indexKeyLabel, e.index)
+	if err != nil {
+		return err
+	}
+	ring.KEKID = kekID
+	ring.IndexKey = wrappedIndex
+	for id, key := range e.raw {
+		kekID, wrapped, err := e.wrapKey(ctx, dataKeyLabel(id), key)
+		if err != nil {
+			return err
+		}
+		if kekID != ring.KEKID {
+			return errServerKeyChanged
+		}
+		ring.DataKeys[id] = wrapped
+	}
+
+	previous := e.ring
+	e.ring = ring
+	if err := e.db.Update(e.saveKeyring); err != nil {
+		e.ring = previous
+		return err
+	}
+	return nil
+}
+
+// enableEncryption 在一个写事务中为未加密的数据库创建密钥环，并把以明文为键的记录改用盲索引。
+func enableEncryption(ctx context.Context, db *bbolt.DB, keys kms.KeyProvider) (*Encryption, error) {
+	dataKey, err := randomKey()
+	if err != nil {
+		return nil, err
+	}
+	indexKey, err := randomKey()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	e := &Encryption{
+		db:    db,
+		keys:  keys,
+		raw:   map[uint32][]byte{1: dataKey},
+		aeads: map[uint32]cipher.AEAD{1: aead},
+		index: indexKey,
+	}
+	kekID, wrappedData, err := e.wrapKey(ctx, dataKeyLabel(1), dataKey)
+	if err != nil {
+		return nil, err
+	}
+	indexKEKID, wrappedIndex, err := e.wrapKey(ctx, indexKeyLabel, indexKey)
+	if err != nil {
+		return nil, err
+	}
+	if indexKEKID != kekID {
+		return nil, errServerKeyChanged
+	}
+	e.ring = keyring{
+		KEKID:     kekID,
+		Active:    1,
+		DataKeys:  map[uint32][]byte{1: wrappedData},
+		IndexKey:  wrappedIndex,
+		Pending:   true,
+		RotatedAt: time.Now().UTC(),
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		if ring, err := readKeyring(tx); err != nil || ring != nil {
+			if err == nil {
+				err = errors.New("the database was encrypted concurrently")
+			}
+			return err
+		}
+		if err := e.rekeyIndexes(tx); err != nil {
+			return err
+		}
+		return e.saveKeyring(tx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("enable encryption: %w", err)
+	}
+	slog.Info("Enabled at-rest encryption; existing records will be encrypted in the background")
+	return e, nil
+}
+
+// rekeyIndexes 把以用户名、邮箱和客户端设备 ID 明文为键的记录改为以盲索引为键。
+func (e *Encryption) rekeyIndexes(tx *bbolt.Tx) error {
+	// 用户名和邮箱索引从用户记录重建。
+	var users []core.User
+	err := tx.Bucket(userBucket).ForEach(func(k, v []byte) error {
+		var user core.User
+		if err := e.open(userBucket, k, v, &user); err != nil {
+			return err
+		}
+		users = append(users, user)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	usernames, err := recreateBucket(tx, usernameBucket)
+	if err != nil {
+		return err
+	}
+	emails, err := recreateBucket(tx, emailBucket)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if err := usernames.Put(e.indexKey(user.Username), user.ID[:]); err != nil {
+			return err
+		}
+		if err := emails.Put(e.indexKey(user.Email), user.ID[:]); err != nil {
+			return err
+		}
+	}
+
+	// 验证码和设备的键本身包含明文，需要换键重写，顺便加密。
+	rekey := func(name []byte, record func() any, key func(record any) []byte) error {
+		var records []any
+		err := tx.Bucket(name).ForEach(func(k, v []byte) error {
+			rec := record()
+			if err := e.open(name, k, v, rec); err != nil {
+				return err
+			}
+			records = append(records, rec)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		bucket, err := recreateBucket(tx, name)
+		if err != nil {
+			return err
+		}
+		for _, rec := range records {
+			k := key(rec)
+			sealed, err := e.seal(name, k, rec)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(k, sealed); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err = rekey(verificationCodeBucket,
+		func() any { return &core.VerificationCode{} },
+		func(rec any) []byte { return e.indexKey(rec.(*core.VerificationCode).Email) })
+	if err != nil {
+		return err
+	}
+	return rekey(deviceBucket,
+		func() any { return &core.Device{} },
+		func(rec any) []byte {
+			device := rec.(*core.Device)
+			return e.deviceKey(device.UserID, device.DeviceID)
+		})
+}
+
+func recreateBucket(tx *bbolt.Tx, name []byte) (*bbolt.Bucket, error) {
+	if err := tx.DeleteBucket(name); err != nil && err != bbolt.ErrBucketNotFound {
+		return nil, err
+	}
+	return tx.CreateBucket(name)
+}
+
+// saveKeyring 把内存中的密钥环写入 tx。调用方必须持有 e.mu 或独占 e。
+func (e *Encryption) saveKeyring(tx *bbolt.Tx) error {
+	bucket := tx.Bucket(encryptionBucket)
+	if bucket == nil {
+		return errors.New("encryption bucket is missing; run the schema migrations first")
+	}
+	data, err := json.Marshal(&e.ring)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(keyringKey, data)
+}
+
+// --- 记录编解码 ---
+
+// seal 把 v 编码为 JSON；启用加密时再用活动数据密钥加密。
+func (e *Encryption) seal(bucket, key []byte, v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || e == nil {
+		return data, err
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.sealWith(e.ring.Active, bucket, key, data), nil
+}
+
+func (e *Encryption) sealWith(id uint32, bucket, key, plaintext []byte) []byte {
+	aead := e.aeads[id]
+	out := make([]byte, 5+aead.NonceSize(), sealedOverhead+len(plaintext))
+	out[0] = sealedMagic
+	binary.BigEndian.PutUint32(out[1:5], id)
+	nonce := out[5:]
+	if _, err := rand.Read(nonce); err != nil {
+		// crypto/rand 在受支持的平台上不会失败。
+		panic(err)
+	}
+	return aead.Seal(out, nonce, plaintext, additionalData(bucket, key))
+}
+
+// open 解密（如有必要）并解码记录。
+// 启用加密后、后台加密完成之前，尚未加密的旧记录按明文读取；完成之后明文记录会被拒绝。
+func (e *Encryption) open(bucket, key, data []byte, v any) error {
+	plaintext, err := e.decrypt(bucket, key, data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(plaintext, v)
+}
+
+func (e *Encryption) decrypt(bucket, key, data []byte) ([]byte, error) {
+	if !isSealed(data) {
+		if e != nil && !e.Pending() {
+			return nil, fmt.Errorf("%s/%x is not encrypted", bucket, key)
+		}
+		return data, nil
+	}
+	if e == nil {
+		return nil, ErrDatabaseEncrypted
+	}
+	id := binary.BigEndian.Uint32(data[1:5])
+	e.mu.RLock()
+	aead, ok := e.aeads[id]
+	e.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%s/%x is encrypted with unknown data key %d", bucket, key, id)
+	}
+	nonce := data[5 : 5+aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, data[5+aead.NonceSize():], additionalData(bucket, key))
+	if err != nil {
+		return nil, fmt.Errorf("%s/%x: decryption failed", bucket, key)
+	}
+	return plaintext, nil
+}
+
+func isSealed(data []byte) bool {
+	return len(data) >= sealedOverhead && data[0] == sealedMagic
+}
+
+func additionalData(bucket, key []byte) []byte {
+	ad := make([]byte, 0, len(bucket)+1+len(key))
+	ad = append(ad, bucket...)
+	ad = append(ad, 0)
+	return append(ad, key...)
+}
+
+// indexKey 返回唯一索引中 value 对应的键：不加密时是明文，加密时是 HMAC-SHA256 盲索引。
+func (e *Encryption) indexKey(value string) []byte {
+	if e == nil {
+		return []byte(value)
+	}
+	mac := hmac.New(sha256.New, e.index)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// deviceKey 返回设备记录的键：用户 ID 后接客户端设备 ID 的索引键，便于按用户前缀遍历。
+func (e *Encryption) deviceKey(userID uuid.UUID, deviceID string) []byte {
+	return append(userID[:len(userID):len(userID)], e.indexKey(deviceID)...)
+}
+
+// --- 轮换与重新加密 ---
+
+// Status 返回加密状态；e 为 nil 时返回 nil。
+func (e *Encryption) Status() *core.EncryptionStatus {
+	if e == nil {
+		return nil
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return &core.EncryptionStatus{
+		ActiveKey: e.ring.Active,
+		DataKeys:  len(e.ring.DataKeys),
+		Pending:   e.ring.Pending,
+		RotatedAt: e.ring.RotatedAt,
+	}
+}
+
+// Pending 报告是否可能还有记录未使用活动数据密钥加密。
+func (e *Encryption) Pending() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.ring.Pending
+}
+
+// Rotate 生成新的数据密钥并设为活动密钥。之后写入的记录使用新密钥，
+// 已有记录需要调用 Reencrypt 重新加密，在此之前旧密钥会保留在密钥环中。
+func (e *Encryption) Rotate(ctx context.Context) error {
+	key, err := randomKey()
+	if err != nil {
+		return err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	// 包装可能需要请求远程服务，不在持有锁时进行。
+	e.mu.RLock()
+	id := e.nextDataKeyID()
+	kekID := e.ring.KEKID
+	e.mu.RUnlock()
+	wrappedKEKID, wrapped, err := e.wrapKey(ctx, dataKeyLabel(id), key)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.nextDataKeyID() != id {
+		return errors.New("the data key was rotated concurrently")
+	}
+	if wrappedKEKID != kekID {
+		return errServerKeyChanged
+	}
+	previous := e.ring
+	e.ring.DataKeys = maps.Clone(previous.DataKeys)
+	e.ring.DataKeys[id] = wrapped
+	e.ring.Active = id
+	e.ring.Pending = true
+	e.ring.RotatedAt = time.Now().UTC()
+	if err := e.db.Update(e.saveKeyring); err != nil {
+		e.ring = previous
+		return err
+	}
+	e.raw[id] = key
+	e.aeads[id] = aead
+	slog.Info("Rotated at-rest data encryption key", "key_id", id)
+	return nil
+}
+
+// nextDataKeyID 返回下一个数据密钥的编号。调用方必须持有 e.mu。
+func (e *Encryption) nextDataKeyID() uint32 {
+	var id uint32
+	for existing := range e.ring.DataKeys {
+		id = max(id, existing)
+	}
+	return id + 1
+}
+
+// Reencrypt 把所有未使用活动数据密钥加密的记录（包括明文记录）用活动密钥重写，
+// 然后从密钥环中删除不再使用的数据密钥，返回重写的记录数。
+// 每个写事务最多处理 reencryptBatchSize 条记录，期间服务可以继续读写。
+func (e *Encryption) Reencrypt(ctx context.Context) (int, error) {
+	e.reencrypting.Lock()
+	defer e.reencrypting.Unlock()
+
+	total := 0
+	for {
+		e.mu.RLock()
+		target := e.ring.Active
+		e.mu.RUnlock()
+
+		for _, name := range encryptedBuckets {
+			n, err := e.reencryptBucket(ctx, name)
+			total += n
+			if err != nil {
+				return total, err
+			}
+		}
+
+		// 重新加密期间如果又发生了轮换，再处理一遍。
+		done := false
+		err := e.db.Update(func(tx *bbolt.Tx) error {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			if e.ring.Active != target {
+				return nil
+			}
+			previous := e.ring
+			e.ring.DataKeys = map[uint32][]byte{target: previous.DataKeys[target]}
+			e.ring.Pending = false
+			if err := e.saveKeyring(tx); err != nil {
+				e.ring = previous
+				return err
+			}
+			for id := range e.aeads {
+				if id != target {
+					delete(e.aeads, id)
+					delete(e.raw, id)
+				}
+			}
+			done = true
+			return nil
+		})
+		if err != nil {
+			return total, err
+		}
+		if done {
+			return total, nil
+		}
+	}
+}
+
+// reencryptBucket 分批重写一个存储桶中未使用活动数据密钥加密的记录。
+func (e *Encryption) reencryptBucket(ctx context.Context, name []byte) (int, error) {
+	total := 0
+	var after []byte
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		finished := false
+		err := e.db.Update(func(tx *bbolt.Tx) error {
+			bucket := tx.Bucket(name)
+			e.mu.RLock()
+			active := e.ring.Active
+			e.mu.RUnlock()
+
+			var updates [][2][]byte
+			c := bucket.Cursor()
+			k, v := c.First()
+			if after != nil {
+				k, v = c.Seek(after)
+				if bytes.Equal(k, after) {
+					k, v = c.Next()
+				}
+			}
+			for n := 0; k != nil && n < reencryptBatchSize; k, v = c.Next() {
+				n++
+				after = append(after[:0], k...)
+				if isSealed(v) && binary.BigEndian.Uint32(v[1:5]) == active {
+					continue
+				}
+				plaintext, err := e.decrypt(name, k, v)
+				if err != nil {
+					return err
+				}
+				e.mu.RLock()
+				sealed := e.sealWith(active, name, k, plaintext)
+				e.mu.RUnlock()
+				updates = append(updates, [2][]byte{append([]byte(nil), k...), sealed})
+			}
+			finished = k == nil
+			// 遍历期间不能修改存储桶，所以在遍历结束后统一写回。
+			for _, update := range updates {
+				if err := bucket.Put(update[0], update[1]); err != nil {
+					return err
+				}
+			}
+			total += len(updates)
+			return nil
+		})
+		if err != nil || finished {
+			return total, err
+		}
+	}
+}
+
+// --- 密钥包装 ---
+
+const indexKeyLabel = "easypassword index key"
+
+func dataKeyLabel(id uint32) string {
+	return fmt.Sprintf("easypassword data key %d", id)
+}
+
+func randomKey() ([]byte, error) {
+	key := make([]byte, EncryptionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// errServerKeyChanged 表示包装密钥期间活动服务器密钥发生了变化，重试即可。
+var errServerKeyChanged = errors.New("the server encryption key changed while wrapping data keys; try again")
+
+// wrapKey 用活动服务器密钥加密 key，label 作为附加数据，使包装后的密钥不能被当作另一个密钥使用。
+// 返回所用服务器密钥的 kid。
+func (e *Encryption) wrapKey(ctx context.Context, label string, key []byte) (string, []byte, error) {
+	return e.keys.Encrypt(ctx, kms.PurposeStorage, key, []byte(label))
+}
+
+// unwrapKey 用 kekID 对应的服务器密钥解开 wrapped。提供者没有该密钥或解密失败时返回 ErrWrongEncryptionKey。
+func (e *Encryption) unwrapKey(ctx context.Context, kekID, label string, wrapped []byte) ([]byte, error) {
+	key, err := e.keys.Decrypt(ctx, kms.PurposeStorage, kekID, wrapped, []byte(label))
+	if errors.Is(err, kms.ErrUnknownKey) || errors.Is(err, kms.ErrDecrypt) {
+		return nil, ErrWrongEncryptionKey
+	}
+	return key, err
+}
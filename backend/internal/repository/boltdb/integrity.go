@@ -0,0 +1,248 @@
+package boltdb
+
+import (
+	"bytes"
+	"easy-password-backend/internal/core"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+)
+
+// maxIntegrityProblems 限制报告中记录的问题数量，避免损坏严重的文件产生过大的报告。
+const maxIntegrityProblems = 100
+
+// IntegrityReport 是一次完整性检查的结果。
+type IntegrityReport struct {
+	// Records 以存储桶名为键的记录数量。
+	Records map[string]int
+	// Problems 描述发现的问题，为空表示检查通过。
+	Problems []string
+	// Truncated 为 true 表示问题数量超过上限，Problems 只包含前一部分。
+	Truncated bool
+}
+
+// OK 报告检查是否未发现任何问题。
+func (r *IntegrityReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+func (r *IntegrityReport) addf(format string, args ...any) {
+	if len(r.Problems) >= maxIntegrityProblems {
+		r.Truncated = true
+		return
+	}
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+// CheckIntegrity 校验数据库的页面结构，并确认每个存储桶中的每条记录都能解码、
+// 键与记录内容一致，且索引和关联记录指向存在的用户。加密的数据库需要提供 enc，
+// 此时每条记录都必须能用密钥环中的数据密钥解密。
+func CheckIntegrity(db *bbolt.DB, enc *Encryption) (*IntegrityReport, error) {
+	report := &IntegrityReport{Records: make(map[string]int)}
+	err := db.View(func(tx *bbolt.Tx) error {
+		for err := range tx.Check() {
+			report.addf("page structure: %v", err)
+		}
+
+		known := map[string]bool{string(schemaBucket): true, string(encryptionBucket): true}
+		for _, name := range recordBuckets {
+			known[string(name)] = true
+			report.Records[string(name)] = 0
+			if tx.Bucket(name) == nil {
+				report.addf("bucket %s is missing", name)
+			}
+		}
+		err := tx.ForEach(func(name []byte, _ *bbolt.Bucket) error {
+			if !known[string(name)] {
+				report.addf("unknown bucket %s", name)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		users := make(map[uuid.UUID]*core.User)
+		checkBucket(tx, report, userBucket, func(k, v []byte) error {
+			var user core.User
+			if err := enc.open(userBucket, k, v, &user); err != nil {
+				return err
+			}
+			if !bytes.Equal(k, user.ID[:]) {
+				return fmt.Errorf("key does not match user id %s", user.ID)
+			}
+			users[user.ID] = &user
+			return nil
+		})
+		userExists := func(id uuid.UUID) error {
+			if users[id] == nil {
+				return fmt.Errorf("references missing user %s", id)
+			}
+			return nil
+		}
+
+		checkBucket(tx, report, usernameBucket, func(k, v []byte) error {
+			user, err := indexedUser(users, v)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(enc.indexKey(user.Username), k) {
+				return fmt.Errorf("points to user %s whose username is %q", user.ID, user.Username)
+			}
+			return nil
+		})
+		checkBucket(tx, report, emailBucket, func(k, v []byte) error {
+			user, err := indexedUser(users, v)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(enc.indexKey(user.Email), k) {
+				return fmt.Errorf("points to user %s whose email is %q", user.ID, user.Email)
+			}
+			return nil
+		})
+		checkBucket(tx, report, vaultBucket, func(k, v []byte) error {
+			var item core.VaultItem
+			if err := enc.open(vaultBucket, k, v, &item); err != nil {
+				return err
+			}
+			if !bytes.Equal(k, item.ID[:]) {
+				return fmt.Errorf("key does not match item id %s", item.ID)
+			}
+			return userExists(item.UserID)
+		})
+		checkBucket(tx, report, verificationCodeBucket, func(k, v []byte) error {
+			var vc core.VerificationCode
+			if err := enc.open(verificationCodeBucket, k, v, &vc); err != nil {
+				return err
+			}
+			if !bytes.Equal(enc.indexKey(vc.Email), k) {
+				return fmt.Errorf("key does not match email %q", vc.Email)
+			}
+			return nil
+		})
+		checkBucket(tx, report, auditEventBucket, func(k, v []byte) error {
+			var event core.AuditEvent
+			if err := enc.open(auditEventBucket, k, v, &event); err != nil {
+				return err
+			}
+			if len(k) != 8 || int64(binary.BigEndian.Uint64(k)) != event.Sequence {
+				return fmt.Errorf("key does not match sequence %d", event.Sequence)
+			}
+			return nil
+		})
+		checkBucket(tx, report, auditChainBucket, func(k, v []byte) error {
+			if len(k) != len(uuid.UUID{}) {
+				return fmt.Errorf("key is not a user id")
+			}
+			if _, err := hex.DecodeString(string(v)); err != nil || len(v) != 64 {
+				return fmt.Errorf("value is not a SHA-256 hash")
+			}
+			return nil
+		})
+		events := tx.Bucket(auditEventBucket)
+		checkBucket(tx, report, auditUserBucket, func(k, v []byte) error {
+			if bytes.Equal(k, auditIndexedKey) {
+				if len(v) != 8 {
+					return fmt.Errorf("value is not a sequence")
+				}
+				return nil
+			}
+			if len(k) != len(uuid.UUID{})+8 {
+				return fmt.Errorf("key is not a user id and sequence")
+			}
+			if events == nil || events.Get(k[len(uuid.UUID{}):]) == nil {
+				return fmt.Errorf("references missing event %d", binary.BigEndian.Uint64(k[len(uuid.UUID{}):]))
+			}
+			return nil
+		})
+		checkBucket(tx, report, schemaBucket, func(k, v []byte) error {
+			var record schemaRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+			if len(k) != 8 {
+				return fmt.Errorf("key is not a schema version")
+			}
+			return nil
+		})
+		checkBucket(tx, report, encryptionBucket, func(k, v []byte) error {
+			if !bytes.Equal(k, keyringKey) {
+				return fmt.Errorf("unexpected key")
+			}
+			var ring keyring
+			return json.Unmarshal(v, &ring)
+		})
+		checkBucket(tx, report, deviceBucket, func(k, v []byte) error {
+			var device core.Device
+			if err := enc.open(deviceBucket, k, v, &device); err != nil {
+				return err
+			}
+			if !bytes.Equal(k, enc.deviceKey(device.UserID, device.DeviceID)) {
+				return fmt.Errorf("key does not match device %s", device.ID)
+			}
+			return userExists(device.UserID)
+		})
+		checkBucket(tx, report, loginApprovalBucket, func(k, v []byte) error {
+			var approval core.LoginApproval
+			if err := enc.open(loginApprovalBucket, k, v, &approval); err != nil {
+				return err
+			}
+			if !bytes.Equal(k, approval.ID[:]) {
+				return fmt.Errorf("key does not match approval id %s", approval.ID)
+			}
+			return userExists(approval.UserID)
+		})
+		checkBucket(tx, report, apiKeyBucket, func(k, v []byte) error {
+			var key core.APIKey
+			if err := enc.open(apiKeyBucket, k, v, &key); err != nil {
+				return err
+			}
+			if !bytes.Equal(k, key.ID[:]) {
+				return fmt.Errorf("key does not match api key id %s", key.ID)
+			}
+			return userExists(key.UserID)
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// checkBucket 对存储桶中的每条记录调用 check，并把失败记录到报告中。
+func checkBucket(tx *bbolt.Tx, report *IntegrityReport, name []byte, check func(k, v []byte) error) {
+	bucket := tx.Bucket(name)
+	if bucket == nil {
+		return
+	}
+	_ = bucket.ForEach(func(k, v []byte) error {
+		report.Records[string(name)]++
+		if v == nil {
+			report.addf("%s/%x: unexpected nested bucket", name, k)
+			return nil
+		}
+		if err := check(k, v); err != nil {
+			report.addf("%s/%x: %v", name, k, err)
+		}
+		return nil
+	})
+}
+
+// indexedUser 解析索引存储桶中的用户 ID，并返回它指向的用户。
+func indexedUser(users map[uuid.UUID]*core.User, value []byte) (*core.User, error) {
+	id, err := uuid.FromBytes(value)
+	if err != nil {
+		return nil, fmt.Errorf("value is not a user id")
+	}
+	user := users[id]
+	if user == nil {
+		return nil, fmt.Errorf("references missing user %s", id)
+	}
+	return user, nil
+}
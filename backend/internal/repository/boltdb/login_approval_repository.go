@@ -0,0 +1,88 @@
+package boltdb
+
+import (
+	"context"
+	"easy-password-backend/internal/core"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+)
+
+// --- 待批准登录存储库实现 ---
+
+type loginApprovalRepository struct {
+	db  *bbolt.DB
+	enc *Encryption
+}
+
+func (r *loginApprovalRepository) Create(ctx context.Context, approval *core.LoginApproval) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		approvals := tx.Bucket(loginApprovalBucket)
+		if approval.ID == uuid.Nil {
+			approval.ID = uuid.New()
+		}
+		encoded, err := r.enc.seal(loginApprovalBucket, approval.ID[:], approval)
+		if err != nil {
+			return err
+		}
+		return approvals.Put(approval.ID[:], encoded)
+	})
+}
+
+func (r *loginApprovalRepository) FindByID(ctx context.Context, id uuid.UUID) (*core.LoginApproval, error) {
+	var approval core.LoginApproval
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		approvalBytes := tx.Bucket(loginApprovalBucket).Get(id[:])
+		if approvalBytes == nil {
+			return core.ErrLoginApprovalNotFound
+		}
+		return r.enc.open(loginApprovalBucket, id[:], approvalBytes, &approval)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &approval, nil
+}
+
+func (r *loginApprovalRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*core.LoginApproval, error) {
+	var found *core.LoginApproval
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(loginApprovalBucket).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var approval core.LoginApproval
+			if err := r.enc.open(loginApprovalBucket, k, v, &approval); err != nil {
+				return fmt.Errorf("decode %s/%x: %w", loginApprovalBucket, k, err)
+			}
+			if approval.TokenHash == tokenHash {
+				found = &approval
+				return nil
+			}
+		}
+		return core.ErrLoginApprovalNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+func (r *loginApprovalRepository) Update(ctx context.Context, approval *core.LoginApproval) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		approvals := tx.Bucket(loginApprovalBucket)
+		if existing := approvals.Get(approval.ID[:]); existing == nil {
+			return core.ErrLoginApprovalNotFound
+		}
+		encoded, err := r.enc.seal(loginApprovalBucket, approval.ID[:], approval)
+		if err != nil {
+			return err
+		}
+		return approvals.Put(approval.ID[:], encoded)
+	})
+}
+
+func (r *loginApprovalRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(loginApprovalBucket).Delete(id[:])
+	})
+}
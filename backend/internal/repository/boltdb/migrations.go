@@ -0,0 +1,204 @@
+package boltdb
+
+import (
+	"context"
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/repository/schema"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// schemaBucket 记录已应用的迁移，键为 8 字节大端序版本号。
+var schemaBucket = []byte("schema_migrations")
+
+// schemaRecord 是 schemaBucket 中的值。
+type schemaRecord struct {
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// migrations 是 BoltDB 的全部迁移步骤。已发布的步骤不可修改，变更只能追加新步骤。
+var migrations = []schema.Step[*bbolt.Tx]{
+	{
+		Version: 1,
+		Name:    "create_buckets",
+		Up: func(tx *bbolt.Tx) error {
+			for _, name := range dataBuckets {
+				if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *bbolt.Tx) error {
+			for _, name := range dataBuckets {
+				if err := tx.DeleteBucket(name); err != nil && err != bbolt.ErrBucketNotFound {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// 早期版本写入的用户没有状态和角色，这里补上默认值，使记录与 PostgreSQL 的列默认值一致。
+		Version: 2,
+		Name:    "backfill_user_status_role",
+		Up: func(tx *bbolt.Tx) error {
+			bucket := tx.Bucket(userBucket)
+			var updates [][2][]byte
+			err := bucket.ForEach(func(k, v []byte) error {
+				var user core.User
+				if err := json.Unmarshal(v, &user); err != nil {
+					return err
+				}
+				if user.Status != "" && user.Role != "" {
+					return nil
+				}
+				user.Status = user.AccountStatus()
+				user.Role = user.AccountRole()
+				data, err := json.Marshal(&user)
+				if err != nil {
+					return err
+				}
+				updates = append(updates, [2][]byte{k, data})
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			// 遍历期间不能修改存储桶，所以在遍历结束后统一写回。
+			for _, update := range updates {
+				if err := bucket.Put(update[0], update[1]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}, {
+		Version: 3,
+		Name:    "create_encryption_bucket",
+		Up: func(tx *bbolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(encryptionBucket)
+			return err
+		},
+		Down: func(tx *bbolt.Tx) error {
+			// 删除密钥环会使已加密的记录永久无法读取。
+			if ring, err := readKeyring(tx); err != nil || ring != nil {
+				if err == nil {
+					err = errors.New("the database is encrypted at rest; its keyring cannot be removed")
+				}
+				return err
+			}
+			if err := tx.DeleteBucket(encryptionBucket); err != nil && err != bbolt.ErrBucketNotFound {
+				return err
+			}
+			return nil
+		},
+	}, {
+		Version: 4,
+		Name:    "create_api_key_bucket",
+		Up: func(tx *bbolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(apiKeyBucket)
+			return err
+		},
+		Down: func(tx *bbolt.Tx) error {
+			if err := tx.DeleteBucket(apiKeyBucket); err != nil && err != bbolt.ErrBucketNotFound {
+				return err
+			}
+			return nil
+		},
+	}, {
+		// 已有的事件可能是加密的，迁移中无法解码，由审计存储库在第一次读写时补建索引。
+		Version: 5,
+		Name:    "create_audit_user_index",
+		Up: func(tx *bbolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(auditUserBucket)
+			return err
+		},
+		Down: func(tx *bbolt.Tx) error {
+			if err := tx.DeleteBucket(auditUserBucket); err != nil && err != bbolt.ErrBucketNotFound {
+				return err
+			}
+			return nil
+		},
+	},
+}
+
+// dataBuckets 是迁移 1 创建的存储桶。
+var dataBuckets = [][]byte{
+	userBucket, vaultBucket, usernameBucket, emailBucket, verificationCodeBucket,
+	auditEventBucket, auditChainBucket, deviceBucket, loginApprovalBucket,
+}
+
+// recordBuckets 是当前 schema 中的全部记录存储桶：dataBuckets 和之后的迁移创建的存储桶。
+var recordBuckets = append(slices.Clone(dataBuckets), apiKeyBucket, auditUserBucket)
+
+// NewMigrator 返回 BoltDB 的 schema 迁移器。
+//
+// bbolt 打开文件时持有独占的文件锁，其他进程无法同时打开同一个数据库；
+// 同一进程内的写事务是串行的，每个步骤和它的版本记录在同一个写事务中提交，
+// 因此不需要额外的迁移锁。
+func NewMigrator(db *bbolt.DB) *schema.Migrator[*bbolt.Tx] {
+	return schema.NewMigrator[*bbolt.Tx](&migrationDriver{db: db}, migrations)
+}
+
+type migrationDriver struct {
+	db *bbolt.DB
+}
+
+func (d *migrationDriver) Lock(ctx context.Context, fn func(conn schema.Conn[*bbolt.Tx]) error) error {
+	return fn(d)
+}
+
+func (d *migrationDriver) Applied(ctx context.Context) (map[int]time.Time, error) {
+	applied := make(map[int]time.Time)
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(schemaBucket)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var record schemaRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+			applied[int(binary.BigEndian.Uint64(k))] = record.AppliedAt
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+func (d *migrationDriver) Apply(ctx context.Context, version int, name string, up bool, fn func(tx *bbolt.Tx) error) error {
+	return d.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(schemaBucket)
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, uint64(version))
+		// 在事务内再次确认版本状态，同一进程内并发调用时只会执行一次。
+		if (bucket.Get(key) != nil) == up {
+			return nil
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if !up {
+			return bucket.Delete(key)
+		}
+		data, err := json.Marshal(schemaRecord{Name: name, AppliedAt: time.Now().UTC()})
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, data)
+	})
+}
@@ -1,7 +1,10 @@
 package boltdb
 
 import (
+	"context"
 	"easy-password-backend/internal/core"
+	"log/slog"
+	"time"
 
 	"go.etcd.io/bbolt"
 )
@@ -12,31 +15,117 @@ var (
 	usernameBucket         = []byte("usernames")
 	emailBucket            = []byte("emails")
 	verificationCodeBucket = []byte("verification_codes")
+	auditEventBucket       = []byte("audit_events")
+	auditChainBucket       = []byte("audit_chain_heads")
+	auditUserBucket        = []byte("audit_user_events")
+	deviceBucket           = []byte("devices")
+	loginApprovalBucket    = []byte("login_approvals")
+	apiKeyBucket           = []byte("api_keys")
 )
 
 // Storage 为 BoltDB 实现了 repository.Storage 接口。
 type Storage struct {
-	db *bbolt.DB
+	db  *bbolt.DB
+	enc *Encryption
 }
 
-// NewBoltDBStorage 创建一个新的 BoltDB 存储实例。
+// NewBoltDBStorage 创建一个新的 BoltDB 存储实例，记录以明文存储。
 func NewBoltDBStorage(db *bbolt.DB) *Storage {
 	return &Storage{db: db}
 }
 
+// NewEncryptedBoltDBStorage 创建一个使用 enc 加密记录的 BoltDB 存储实例；enc 为 nil 时与 NewBoltDBStorage 相同。
+func NewEncryptedBoltDBStorage(db *bbolt.DB, enc *Encryption) *Storage {
+	return &Storage{db: db, enc: enc}
+}
+
 // User 返回一个在 BoltDB 数据库上操作的 UserRepository。
 func (s *Storage) User() core.UserRepository {
-	return &userRepository{db: s.db}
+	return &userRepository{db: s.db, enc: s.enc}
 }
 
 // Vault 返回一个在 BoltDB 数据库上操作的 VaultRepository。
 func (s *Storage) Vault() core.VaultRepository {
-	return &vaultRepository{db: s.db}
+	return &vaultRepository{db: s.db, enc: s.enc}
 }
 
 // VerificationCode 返回一个在 BoltDB 数据库上操作的 VerificationCodeRepository。
 func (s *Storage) VerificationCode() core.VerificationCodeRepository {
-	return &verificationCodeRepository{db: s.db}
+	return &verificationCodeRepository{db: s.db, enc: s.enc}
+}
+
+// Audit 返回一个在 BoltDB 数据库上操作的 AuditRepository。
+func (s *Storage) Audit() core.AuditRepository {
+	return &auditRepository{db: s.db, enc: s.enc}
+}
+
+// Device 返回一个在 BoltDB 数据库上操作的 DeviceRepository。
+func (s *Storage) Device() core.DeviceRepository {
+	return &deviceRepository{db: s.db, enc: s.enc}
+}
+
+// LoginApproval 返回一个在 BoltDB 数据库上操作的 LoginApprovalRepository。
+func (s *Storage) LoginApproval() core.LoginApprovalRepository {
+	return &loginApprovalRepository{db: s.db, enc: s.enc}
+}
+
+// APIKey 返回一个在 BoltDB 数据库上操作的 APIKeyRepository。
+func (s *Storage) APIKey() core.APIKeyRepository {
+	return &apiKeyRepository{db: s.db, enc: s.enc}
 }
 
- 
\ No newline at end of file
+// Bulk 返回一个在 BoltDB 数据库上操作的 BulkRepository。
+func (s *Storage) Bulk() core.BulkRepository {
+	return &bulkRepository{db: s.db, enc: s.enc}
+}
+
+// Stats 返回各存储桶的键数量、数据库文件大小和静态加密状态。
+func (s *Storage) Stats(ctx context.Context) (*core.StorageStats, error) {
+	stats := &core.StorageStats{Backend: "boltdb", Records: make(map[string]int64), Encryption: s.enc.Status()}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		stats.SizeBytes = tx.Size()
+		return tx.ForEach(func(name []byte, b *bbolt.Bucket) error {
+			stats.Records[string(name)] = int64(b.Stats().KeyN)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Encryption 返回存储使用的静态加密层，未启用加密时为 nil。
+func (s *Storage) Encryption() *Encryption {
+	return s.enc
+}
+
+// RotateEncryptionKey 生成新的数据密钥，并在后台用它重新加密已有记录。
+// 未启用加密时返回 core.ErrEncryptionNotEnabled。
+func (s *Storage) RotateEncryptionKey(ctx context.Context) error {
+	if s.enc == nil {
+		return core.ErrEncryptionNotEnabled
+	}
+	if err := s.enc.Rotate(ctx); err != nil {
+		return err
+	}
+	s.ResumeReencryption()
+	return nil
+}
+
+// ResumeReencryption 在有记录尚未使用活动数据密钥加密时启动后台重新加密，
+// 例如刚启用加密，或上次重新加密被进程退出打断。未启用加密时不做任何事。
+func (s *Storage) ResumeReencryption() {
+	if s.enc == nil || !s.enc.Pending() {
+		return
+	}
+	go func() {
+		start := time.Now()
+		n, err := s.enc.Reencrypt(context.Background())
+		if err != nil {
+			slog.Error("Background re-encryption failed", "records", n, "error", err)
+			return
+		}
+		slog.Info("Background re-encryption finished", "records", n, "duration", time.Since(start))
+	}()
+}
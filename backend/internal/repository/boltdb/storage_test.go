@@ -0,0 +1,192 @@
+package boltdb_test
+
+import (
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/kms"
+	"easy-password-backend/internal/repository"
+	"easy-password-backend/internal/repository/boltdb"
+	"easy-password-backend/internal/repository/repotest"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+)
+
+func TestStorageContract(t *testing.T) {
+	t.Run("plain", func(t *testing.T) {
+		repotest.Run(t, func(t *testing.T) repository.Storage {
+			return boltdb.NewBoltDBStorage(openDB(t))
+		})
+	})
+	// 启用静态加密后，查找改走盲索引，需要单独验证一遍。
+	t.Run("encrypted", func(t *testing.T) {
+		keys := kms.NewStaticProvider(map[kms.Purpose][]kms.Key{
+			kms.PurposeStorage: {kms.NewKey(kms.AlgA256GCM, []byte("0123456789abcdef0123456789abcdef"))},
+		})
+		repotest.Run(t, func(t *testing.T) repository.Storage {
+			db := openDB(t)
+			enc, err := boltdb.OpenEncryption(t.Context(), db, keys)
+			if err != nil {
+				t.Fatalf("OpenEncryption: %v", err)
+			}
+			return boltdb.NewEncryptedBoltDBStorage(db, enc)
+		})
+	})
+}
+
+// openDB 在 t 的临时目录中创建一个已迁移到最新 schema 的数据库。
+func openDB(t *testing.T) *bbolt.DB {
+	t.Helper()
+	db, err := repository.OpenBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := boltdb.NewMigrator(db).Up(t.Context(), 0); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// createAuditEvents 为 users 中的每个用户依次写入一个事件。
+func createAuditEvents(t *testing.T, s *boltdb.Storage, users ...uuid.UUID) {
+	t.Helper()
+	for _, userID := range users {
+		event := &core.AuditEvent{UserID: userID, Type: core.AuditEventLoginSuccess, CreatedAt: time.Now()}
+		if err := s.Audit().Create(t.Context(), event); err != nil {
+			t.Fatalf("create audit event: %v", err)
+		}
+	}
+}
+
+// TestAuditUserIndexBackfill 检查迁移 5 之前写入、没有用户索引的事件在查询时补建索引。
+func TestAuditUserIndexBackfill(t *testing.T) {
+	db := openDB(t)
+	s := boltdb.NewBoltDBStorage(db)
+	alice, bob := uuid.New(), uuid.New()
+	createAuditEvents(t, s, alice, bob, alice)
+
+	// 清空索引，模拟升级前的数据库。
+	err := db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.DeleteBucket([]byte("audit_user_events")); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket([]byte("audit_user_events"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, total, err := s.Audit().FindByUser(t.Context(), alice, 0, 10)
+	if err != nil {
+		t.Fatalf("FindByUser: %v", err)
+	}
+	if total != 2 || len(events) != 2 || events[0].Sequence != 3 || events[1].Sequence != 1 {
+		t.Fatalf("FindByUser(alice) = %d events, total %d; want sequences 3 and 1", len(events), total)
+	}
+
+	// 补建后新事件继续写入索引。
+	createAuditEvents(t, s, bob)
+	if _, total, err := s.Audit().FindByUser(t.Context(), bob, 0, 10); err != nil || total != 2 {
+		t.Fatalf("FindByUser(bob) total = %d, %v; want 2", total, err)
+	}
+	report, err := boltdb.CheckIntegrity(db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() {
+		t.Fatalf("integrity problems: %v", report.Problems)
+	}
+}
+
+func TestAuditFindByUserReportsDecodeErrors(t *testing.T) {
+	db := openDB(t)
+	s := boltdb.NewBoltDBStorage(db)
+	userID := uuid.New()
+	createAuditEvents(t, s, userID, userID)
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		k, _ := tx.Bucket([]byte("audit_events")).Cursor().First()
+		return tx.Bucket([]byte("audit_events")).Put(k, []byte("{not json"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Audit().FindByUser(t.Context(), userID, 0, 10); err == nil {
+		t.Fatal("FindByUser skipped an event that cannot be decoded")
+	}
+}
+
+// TestUserDeleteAbortsOnDecodeError 检查删除用户时遇到无法解码的记录会中止，而不是留下可能属于该用户的记录。
+func TestUserDeleteAbortsOnDecodeError(t *testing.T) {
+	db := openDB(t)
+	s := boltdb.NewBoltDBStorage(db)
+	ctx := t.Context()
+	user := &core.User{
+		Username:   "alice",
+		Email:      "alice@example.com",
+		AuthHash:   "hash-alice",
+		MasterSalt: []byte("salt-alice"),
+		Status:     core.UserStatusActive,
+		Role:       core.UserRoleUser,
+	}
+	if err := s.User().Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		item := &core.VaultItem{UserID: user.ID, EncryptedData: []byte(`"data"`), Category: "login", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		if err := s.Vault().Create(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		k, _ := tx.Bucket([]byte("vaults")).Cursor().First()
+		return tx.Bucket([]byte("vaults")).Put(k, []byte("{not json"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.User().Delete(ctx, user.ID); err == nil {
+		t.Fatal("Delete skipped a vault item that cannot be decoded")
+	}
+	if _, err := s.User().FindByID(ctx, user.ID); err != nil {
+		t.Fatalf("user was deleted although the transaction failed: %v", err)
+	}
+	var items int
+	err = db.View(func(tx *bbolt.Tx) error {
+		items = tx.Bucket([]byte("vaults")).Stats().KeyN
+		return nil
+	})
+	if err != nil || items != 2 {
+		t.Fatalf("vault items after the failed delete = %d, %v; want 2", items, err)
+	}
+}
+
+func TestFindByResetPasswordTokenReportsDecodeErrors(t *testing.T) {
+	db := openDB(t)
+	s := boltdb.NewBoltDBStorage(db)
+	ctx := t.Context()
+	for _, name := range []string{"alice", "bob"} {
+		user := &core.User{Username: name, Email: name + "@example.com", AuthHash: "hash-" + name, MasterSalt: []byte("salt-" + name)}
+		if err := s.User().Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		k, _ := tx.Bucket([]byte("users")).Cursor().First()
+		return tx.Bucket([]byte("users")).Put(k, []byte("{not json"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.User().FindByResetPasswordToken(ctx, "token-hash")
+	if err == nil || errors.Is(err, core.ErrUserNotFound) {
+		t.Fatalf("FindByResetPasswordToken = %v, want a decode error", err)
+	}
+}
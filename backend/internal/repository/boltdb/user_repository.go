@@ -1,9 +1,13 @@
 package boltdb
 
 import (
+	"bytes"
 	"context"
 	"easy-password-backend/internal/core"
-	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"go.etcd.io/bbolt"
@@ -11,7 +15,8 @@ import (
 
 // --- 用户存储库实现 ---
 type userRepository struct {
-	db *bbolt.DB
+	db  *bbolt.DB
+	enc *Encryption
 }
 
 func (r *userRepository) Create(ctx context.Context, user *core.User) error {
@@ -20,15 +25,19 @@ func (r *userRepository) Create(ctx context.Context, user *core.User) error {
 		usernames := tx.Bucket(usernameBucket)
 		emails := tx.Bucket(emailBucket)
 
-		if usernames.Get([]byte(user.Username)) != nil {
+		if usernames.Get(r.enc.indexKey(user.Username)) != nil {
 			return &core.DuplicateEntryError{Field: "username"}
 		}
-		if emails.Get([]byte(user.Email)) != nil {
+		if emails.Get(r.enc.indexKey(user.Email)) != nil {
 			return &core.DuplicateEntryError{Field: "email"}
 		}
 
 		user.ID = uuid.New()
-		encoded, err := json.Marshal(user)
+		// 与 gorm 的 autoCreateTime/autoUpdateTime 保持一致。
+		now := time.Now()
+		user.CreatedAt = now
+		user.UpdatedAt = now
+		encoded, err := r.enc.seal(userBucket, user.ID[:], user)
 		if err != nil {
 			return err
 		}
@@ -36,17 +45,32 @@ func (r *userRepository) Create(ctx context.Context, user *core.User) error {
 		if err := users.Put(user.ID[:], encoded); err != nil {
 			return err
 		}
-		if err := usernames.Put([]byte(user.Username), user.ID[:]); err != nil {
+		if err := usernames.Put(r.enc.indexKey(user.Username), user.ID[:]); err != nil {
 			return err
 		}
-		return emails.Put([]byte(user.Email), user.ID[:])
+		return emails.Put(r.enc.indexKey(user.Email), user.ID[:])
 	})
 }
 
+func (r *userRepository) FindByID(ctx context.Context, id uuid.UUID) (*core.User, error) {
+	var user core.User
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		userBytes := tx.Bucket(userBucket).Get(id[:])
+		if userBytes == nil {
+			return core.ErrUserNotFound
+		}
+		return r.enc.open(userBucket, id[:], userBytes, &user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r *userRepository) FindByUsername(ctx context.Context, username string) (*core.User, error) {
 	var user core.User
 	err := r.db.View(func(tx *bbolt.Tx) error {
-		userID := tx.Bucket(usernameBucket).Get([]byte(username))
+		userID := tx.Bucket(usernameBucket).Get(r.enc.indexKey(username))
 		if userID == nil {
 			return core.ErrUserNotFound
 		}
@@ -55,7 +79,7 @@ func (r *userRepository) FindByUsername(ctx context.Context, username string) (*
 		if userBytes == nil {
 			return core.ErrUserNotFound
 		}
-		return json.Unmarshal(userBytes, &user)
+		return r.enc.open(userBucket, userID, userBytes, &user)
 	})
 	if err != nil {
 		return nil, err
@@ -66,7 +90,7 @@ func (r *userRepository) FindByUsername(ctx context.Context, username string) (*
 func (r *userRepository) FindByEmail(ctx context.Context, email string) (*core.User, error) {
 	var user core.User
 	err := r.db.View(func(tx *bbolt.Tx) error {
-		userID := tx.Bucket(emailBucket).Get([]byte(email))
+		userID := tx.Bucket(emailBucket).Get(r.enc.indexKey(email))
 		if userID == nil {
 			return core.ErrUserNotFound
 		}
@@ -75,7 +99,7 @@ func (r *userRepository) FindByEmail(ctx context.Context, email string) (*core.U
 		if userBytes == nil {
 			return core.ErrUserNotFound
 		}
-		return json.Unmarshal(userBytes, &user)
+		return r.enc.open(userBucket, userID, userBytes, &user)
 	})
 	if err != nil {
 		return nil, err
@@ -91,9 +115,8 @@ func (r *userRepository) FindByResetPasswordToken(ctx context.Context, token str
 
 		for k, v := c.First(); k != nil; k, v = c.Next() {
 			var user core.User
-			if err := json.Unmarshal(v, &user); err != nil {
-				// 忽略无法解析的条目，或者记录日志
-				continue
+			if err := r.enc.open(userBucket, k, v, &user); err != nil {
+				return fmt.Errorf("decode %s/%x: %w", userBucket, k, err)
 			}
 			if user.ResetPasswordToken != nil && *user.ResetPasswordToken == token {
 				foundUser = &user
@@ -110,19 +133,185 @@ func (r *userRepository) FindByResetPasswordToken(ctx context.Context, token str
 	return foundUser, nil
 }
 
+func (r *userRepository) List(ctx context.Context, query string, offset, limit int) ([]core.User, int64, error) {
+	query = strings.ToLower(query)
+	var matched []core.User
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(userBucket).ForEach(func(k, v []byte) error {
+			var user core.User
+			if err := r.enc.open(userBucket, k, v, &user); err != nil {
+				return err
+			}
+			if query == "" ||
+				strings.Contains(strings.ToLower(user.Username), query) ||
+				strings.Contains(strings.ToLower(user.Email), query) {
+				matched = append(matched, user)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 存储桶按随机 UUID 排序，因此需要在内存中按注册时间排序后再分页。
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].CreatedAt.Before(matched[j].CreatedAt)
+	})
+	total := int64(len(matched))
+	if offset >= len(matched) {
+		return []core.User{}, total, nil
+	}
+	matched = matched[offset:]
+	if limit > 0 && limit < len(matched) {
+		matched = matched[:limit]
+	}
+	return matched, total, nil
+}
+
+func (r *userRepository) ClearExpiredResetTokens(ctx context.Context, before time.Time) (int64, error) {
+	var cleared int64
+	err := r.db.Update(func(tx *bbolt.Tx) error {
+		users := tx.Bucket(userBucket)
+		// 先收集需要更新的用户，避免在遍历过程中修改存储桶。
+		var expired []core.User
+		err := users.ForEach(func(k, v []byte) error {
+			var user core.User
+			if err := r.enc.open(userBucket, k, v, &user); err != nil {
+				return err
+			}
+			if user.ResetPasswordToken != nil && user.ResetPasswordTokenExpiresAt != nil &&
+				user.ResetPasswordTokenExpiresAt.Before(before) {
+				expired = append(expired, user)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, user := range expired {
+			user.ResetPasswordToken = nil
+			user.ResetPasswordTokenExpiresAt = nil
+			encoded, err := r.enc.seal(userBucket, user.ID[:], &user)
+			if err != nil {
+				return err
+			}
+			if err := users.Put(user.ID[:], encoded); err != nil {
+				return err
+			}
+		}
+		cleared = int64(len(expired))
+		return nil
+	})
+	return cleared, err
+}
+
 func (r *userRepository) Update(ctx context.Context, user *core.User) error {
 	return r.db.Update(func(tx *bbolt.Tx) error {
 		users := tx.Bucket(userBucket)
 
 		// 确保用户存在
-		if existing := users.Get(user.ID[:]); existing == nil {
+		existingBytes := users.Get(user.ID[:])
+		if existingBytes == nil {
 			return core.ErrUserNotFound
 		}
+		var existing core.User
+		if err := r.enc.open(userBucket, user.ID[:], existingBytes, &existing); err != nil {
+			return err
+		}
+
+		// 用户名或邮箱发生变化时，在同一事务中维护索引存储桶。
+		if err := updateIndex(tx.Bucket(usernameBucket), "username", r.enc.indexKey(existing.Username), r.enc.indexKey(user.Username), user.ID); err != nil {
+			return err
+		}
+		if err := updateIndex(tx.Bucket(emailBucket), "email", r.enc.indexKey(existing.Email), r.enc.indexKey(user.Email), user.ID); err != nil {
+			return err
+		}
 
-		encoded, err := json.Marshal(user)
+		user.UpdatedAt = time.Now()
+		encoded, err := r.enc.seal(userBucket, user.ID[:], user)
 		if err != nil {
 			return err
 		}
 		return users.Put(user.ID[:], encoded)
 	})
 }
+
+// updateIndex 将唯一索引中的旧键替换为新键；新键已被其他用户占用时返回 DuplicateEntryError。
+func updateIndex(index *bbolt.Bucket, field string, oldKey, newKey []byte, userID uuid.UUID) error {
+	if bytes.Equal(oldKey, newKey) {
+		return nil
+	}
+	if owner := index.Get(newKey); owner != nil && !bytes.Equal(owner, userID[:]) {
+		return &core.DuplicateEntryError{Field: field}
+	}
+	if err := index.Delete(oldKey); err != nil {
+		return err
+	}
+	return index.Put(newKey, userID[:])
+}
+
+func (r *userRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		users := tx.Bucket(userBucket)
+		userBytes := users.Get(id[:])
+		if userBytes == nil {
+			return core.ErrUserNotFound
+		}
+		var user core.User
+		if err := r.enc.open(userBucket, id[:], userBytes, &user); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(usernameBucket).Delete(r.enc.indexKey(user.Username)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(emailBucket).Delete(r.enc.indexKey(user.Email)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(verificationCodeBucket).Delete(r.enc.indexKey(user.Email)); err != nil {
+			return err
+		}
+		if err := deleteOwnedRecords(tx, vaultBucket, r.enc, id); err != nil {
+			return err
+		}
+		if err := deleteOwnedRecords(tx, deviceBucket, r.enc, id); err != nil {
+			return err
+		}
+		if err := deleteOwnedRecords(tx, loginApprovalBucket, r.enc, id); err != nil {
+			return err
+		}
+		if err := deleteOwnedRecords(tx, apiKeyBucket, r.enc, id); err != nil {
+			return err
+		}
+		return users.Delete(id[:])
+	})
+}
+
+// deleteOwnedRecords 删除存储桶中 UserID 字段等于给定用户的所有记录。
+func deleteOwnedRecords(tx *bbolt.Tx, name []byte, enc *Encryption, userID uuid.UUID) error {
+	bucket := tx.Bucket(name)
+	// 先收集键再删除，避免在游标遍历过程中修改存储桶。
+	var keys [][]byte
+	err := bucket.ForEach(func(k, v []byte) error {
+		var owner struct{ UserID uuid.UUID }
+		if err := enc.open(name, k, v, &owner); err != nil {
+			// 无法解码的记录可能属于该用户，跳过它会留下孤立的记录，因此中止整个删除。
+			return fmt.Errorf("decode %s/%x: %w", name, k, err)
+		}
+		if owner.UserID == userID {
+			keys = append(keys, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,7 +3,6 @@ package boltdb
 import (
 	"context"
 	"easy-password-backend/internal/core"
-	"encoding/json"
 
 	"github.com/google/uuid"
 	"go.etcd.io/bbolt"
@@ -12,14 +11,15 @@ import (
 // --- 保险库存储库实现 ---
 
 type vaultRepository struct {
-	db *bbolt.DB
+	db  *bbolt.DB
+	enc *Encryption
 }
 
 func (r *vaultRepository) Create(ctx context.Context, item *core.VaultItem) error {
 	return r.db.Update(func(tx *bbolt.Tx) error {
 		vaults := tx.Bucket(vaultBucket)
 		item.ID = uuid.New()
-		encoded, err := json.Marshal(item)
+		encoded, err := r.enc.seal(vaultBucket, item.ID[:], item)
 		if err != nil {
 			return err
 		}
@@ -27,6 +27,23 @@ func (r *vaultRepository) Create(ctx context.Context, item *core.VaultItem) erro
 	})
 }
 
+func (r *vaultRepository) CreateMany(ctx context.Context, items []core.VaultItem) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		vaults := tx.Bucket(vaultBucket)
+		for i := range items {
+			items[i].ID = uuid.New()
+			encoded, err := r.enc.seal(vaultBucket, items[i].ID[:], &items[i])
+			if err != nil {
+				return err
+			}
+			if err := vaults.Put(items[i].ID[:], encoded); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (r *vaultRepository) FindByID(ctx context.Context, id uuid.UUID) (*core.VaultItem, error) {
 	var item core.VaultItem
 	err := r.db.View(func(tx *bbolt.Tx) error {
@@ -34,7 +51,7 @@ func (r *vaultRepository) FindByID(ctx context.Context, id uuid.UUID) (*core.Vau
 		if itemBytes == nil {
 			return core.ErrVaultItemNotFound
 		}
-		return json.Unmarshal(itemBytes, &item)
+		return r.enc.open(vaultBucket, id[:], itemBytes, &item)
 	})
 	if err != nil {
 		return nil, err
@@ -48,7 +65,7 @@ func (r *vaultRepository) FindByUser(ctx context.Context, userID uuid.UUID) ([]c
 		c := tx.Bucket(vaultBucket).Cursor()
 		for k, v := c.First(); k != nil; k, v = c.Next() {
 			var item core.VaultItem
-			if err := json.Unmarshal(v, &item); err == nil {
+			if err := r.enc.open(vaultBucket, k, v, &item); err == nil {
 				if item.UserID == userID {
 					items = append(items, item)
 				}
@@ -62,13 +79,31 @@ func (r *vaultRepository) FindByUser(ctx context.Context, userID uuid.UUID) ([]c
 	return items, nil
 }
 
+func (r *vaultRepository) CountByUser(ctx context.Context) (map[uuid.UUID]int64, error) {
+	counts := make(map[uuid.UUID]int64)
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(vaultBucket).ForEach(func(k, v []byte) error {
+			// 只解码所需字段，避免为统计反序列化加密数据。
+			var owner struct{ UserID uuid.UUID }
+			if err := r.enc.open(vaultBucket, k, v, &owner); err == nil {
+				counts[owner.UserID]++
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
 func (r *vaultRepository) Update(ctx context.Context, item *core.VaultItem) error {
 	return r.db.Update(func(tx *bbolt.Tx) error {
 		vaults := tx.Bucket(vaultBucket)
 		if existing := vaults.Get(item.ID[:]); existing == nil {
 			return core.ErrVaultItemNotFound
 		}
-		encoded, err := json.Marshal(item)
+		encoded, err := r.enc.seal(vaultBucket, item.ID[:], item)
 		if err != nil {
 			return err
 		}
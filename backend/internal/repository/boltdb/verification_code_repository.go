@@ -3,7 +3,7 @@ package boltdb
 import (
 	"context"
 	"easy-password-backend/internal/core"
-	"encoding/json"
+	"time"
 
 	"go.etcd.io/bbolt"
 )
@@ -11,17 +11,19 @@ import (
 // --- 验证码存储库实现 ---
 
 type verificationCodeRepository struct {
-	db *bbolt.DB
+	db  *bbolt.DB
+	enc *Encryption
 }
 
 func (r *verificationCodeRepository) Create(ctx context.Context, vc *core.VerificationCode) error {
 	return r.db.Update(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket(verificationCodeBucket)
-		encoded, err := json.Marshal(vc)
+		key := r.enc.indexKey(vc.Email)
+		encoded, err := r.enc.seal(verificationCodeBucket, key, vc)
 		if err != nil {
 			return err
 		}
-		return bucket.Put([]byte(vc.Email), encoded)
+		return bucket.Put(key, encoded)
 	})
 }
 
@@ -29,11 +31,12 @@ func (r *verificationCodeRepository) Find(ctx context.Context, email string) (*c
 	var vc core.VerificationCode
 	err := r.db.View(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket(verificationCodeBucket)
-		vcBytes := bucket.Get([]byte(email))
+		key := r.enc.indexKey(email)
+		vcBytes := bucket.Get(key)
 		if vcBytes == nil {
 			return core.ErrVerificationCodeNotFound
 		}
-		return json.Unmarshal(vcBytes, &vc)
+		return r.enc.open(verificationCodeBucket, key, vcBytes, &vc)
 	})
 	if err != nil {
 		return nil, err
@@ -44,6 +47,34 @@ func (r *verificationCodeRepository) Find(ctx context.Context, email string) (*c
 func (r *verificationCodeRepository) Delete(ctx context.Context, email string) error {
 	return r.db.Update(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket(verificationCodeBucket)
-		return bucket.Delete([]byte(email))
+		return bucket.Delete(r.enc.indexKey(email))
 	})
-}
\ No newline at end of file
+}
+func (r *verificationCodeRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(verificationCodeBucket)
+		var keys [][]byte
+		err := bucket.ForEach(func(k, v []byte) error {
+			var vc core.VerificationCode
+			if err := r.enc.open(verificationCodeBucket, k, v, &vc); err != nil {
+				return err
+			}
+			if vc.ExpiresAt.Before(before) {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		deleted = int64(len(keys))
+		return nil
+	})
+	return deleted, err
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"easy-password-backend/config"
+	"easy-password-backend/internal/repository/sqlite"
 	"fmt"
 	"time"
 
@@ -20,32 +21,12 @@ func Connect(cfg *config.Config) (*gorm.DB, error) {
 	return db, nil
 }
 
-// InitBoltDB 初始化 BoltDB 数据库并创建必要的存储桶。
-func InitBoltDB(path string) (*bbolt.DB, error) {
-	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 1 * time.Second})
-	if err != nil {
-		return nil, err
-	}
-
-	err = db.Update(func(tx *bbolt.Tx) error {
-		buckets := [][]byte{
-			[]byte("users"),
-			[]byte("vaults"),
-			[]byte("usernames"),
-			[]byte("emails"),
-			[]byte("verification_codes"),
-		}
-		for _, bucket := range buckets {
-			_, err := tx.CreateBucketIfNotExists(bucket)
-			if err != nil {
-				return err
-			}
-		}
-		return nil
-	})
+// ConnectSQLite 打开 cfg.DBPath 处的 SQLite 数据库，不存在时创建。
+func ConnectSQLite(cfg *config.Config) (*gorm.DB, error) {
+	return sqlite.Open(cfg.DBPath)
+}
 
-	if err != nil {
-		return nil, err
-	}
-	return db, nil
-}
\ No newline at end of file
+// OpenBoltDB 打开 BoltDB 数据库文件，不存在时创建。存储桶由 schema 迁移创建。
+func OpenBoltDB(path string) (*bbolt.DB, error) {
+	return bbolt.Open(path, 0600, &bbolt.Options{Timeout: 1 * time.Second})
+}
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"easy-password-backend/internal/core"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// --- API 密钥存储库实现 ---
+
+type apiKeyRepository struct {
+	s *store
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *core.APIKey) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if key.ID == uuid.Nil {
+		key.ID = uuid.New()
+	}
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+	r.s.apiKeys[key.ID] = cloneAPIKey(key)
+	return nil
+}
+
+func (r *apiKeyRepository) FindByID(ctx context.Context, id uuid.UUID) (*core.APIKey, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	key, ok := r.s.apiKeys[id]
+	if !ok {
+		return nil, core.ErrAPIKeyNotFound
+	}
+	return cloneAPIKey(key), nil
+}
+
+func (r *apiKeyRepository) FindByUser(ctx context.Context, userID uuid.UUID) ([]core.APIKey, error) {
+	r.s.mu.RLock()
+	var keys []core.APIKey
+	for _, key := range r.s.apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, *cloneAPIKey(key))
+		}
+	}
+	r.s.mu.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+func (r *apiKeyRepository) Update(ctx context.Context, key *core.APIKey) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.apiKeys[key.ID]; !ok {
+		return core.ErrAPIKeyNotFound
+	}
+	r.s.apiKeys[key.ID] = cloneAPIKey(key)
+	return nil
+}
+
+func (r *apiKeyRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.apiKeys[id]; !ok {
+		return core.ErrAPIKeyNotFound
+	}
+	delete(r.s.apiKeys, id)
+	return nil
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"easy-password-backend/internal/core"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// --- 审计事件存储库实现 ---
+
+type auditRepository struct {
+	s *store
+}
+
+func (r *auditRepository) Create(ctx context.Context, event *core.AuditEvent) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	event.ID = id
+	event.CreatedAt = event.CreatedAt.UTC().Truncate(time.Microsecond)
+
+	// 链接到全局链上的上一条事件。
+	event.Sequence = 1
+	event.PrevHash = ""
+	if n := len(r.s.audit); n > 0 {
+		prev := r.s.audit[n-1]
+		event.Sequence = prev.Sequence + 1
+		event.PrevHash = prev.Hash
+	}
+
+	// 链接到该用户链上的上一条事件。
+	event.UserPrevHash = r.s.auditHeads[event.UserID]
+	event.Hash = event.ComputeHash()
+
+	r.s.audit = append(r.s.audit, cloneAuditEvent(event))
+	r.s.auditHeads[event.UserID] = event.Hash
+	return nil
+}
+
+func (r *auditRepository) FindByUser(ctx context.Context, userID uuid.UUID, offset, limit int) ([]core.AuditEvent, int64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var events []core.AuditEvent
+	var total int64
+	// 从最新的事件开始倒序遍历。
+	for i := len(r.s.audit) - 1; i >= 0; i-- {
+		event := r.s.audit[i]
+		if event.UserID != userID {
+			continue
+		}
+		if total >= int64(offset) && len(events) < limit {
+			events = append(events, *cloneAuditEvent(event))
+		}
+		total++
+	}
+	return events, total, nil
+}
+
+func (r *auditRepository) ForEach(ctx context.Context, fn func(event *core.AuditEvent) error) error {
+	// 先复制快照再释放锁，fn 中可以安全地调用其他存储库。
+	r.s.mu.RLock()
+	events := make([]*core.AuditEvent, len(r.s.audit))
+	for i, event := range r.s.audit {
+		events[i] = cloneAuditEvent(event)
+	}
+	r.s.mu.RUnlock()
+
+	for _, event := range events {
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package memory
+
+import (
+	"context"
+	"easy-password-backend/internal/core"
+	"fmt"
+)
+
+// --- 批量迁移存储库实现 ---
+
+type bulkRepository struct {
+	s *store
+}
+
+func (r *bulkRepository) Count(ctx context.Context, kind core.RecordKind) (int64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	switch kind {
+	case core.RecordUsers:
+		return int64(len(r.s.users)), nil
+	case core.RecordVaultItems:
+		return int64(len(r.s.vaults)), nil
+	case core.RecordVerificationCodes:
+		return int64(len(r.s.codes)), nil
+	case core.RecordDevices:
+		return int64(len(r.s.devices)), nil
+	case core.RecordLoginApprovals:
+		return int64(len(r.s.approvals)), nil
+	case core.RecordAPIKeys:
+		return int64(len(r.s.apiKeys)), nil
+	case core.RecordAuditEvents:
+		return int64(len(r.s.audit)), nil
+	default:
+		return 0, fmt.Errorf("unknown record kind %q", kind)
+	}
+}
+
+func (r *bulkRepository) ForEach(ctx context.Context, kind core.RecordKind, fn func(record any) error) error {
+	records, err := r.snapshot(kind)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshot 在读锁下复制一类记录，使 fn 在不持有锁的情况下运行。
+func (r *bulkRepository) snapshot(kind core.RecordKind) ([]any, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var records []any
+	switch kind {
+	case core.RecordUsers:
+		for _, user := range r.s.users {
+			records = append(records, cloneUser(user))
+		}
+	case core.RecordVaultItems:
+		for _, item := range r.s.vaults {
+			records = append(records, cloneVaultItem(item))
+		}
+	case core.RecordVerificationCodes:
+		for _, vc := range r.s.codes {
+			c := *vc
+			records = append(records, &c)
+		}
+	case core.RecordDevices:
+		for _, device := range r.s.devices {
+			c := *device
+			records = append(records, &c)
+		}
+	case core.RecordLoginApprovals:
+		for _, approval := range r.s.approvals {
+			c := *approval
+			records = append(records, &c)
+		}
+	case core.RecordAPIKeys:
+		for _, key := range r.s.apiKeys {
+			records = append(records, cloneAPIKey(key))
+		}
+	case core.RecordAuditEvents:
+		for _, event := range r.s.audit {
+			records = append(records, cloneAuditEvent(event))
+		}
+	default:
+		return nil, fmt.Errorf("unknown record kind %q", kind)
+	}
+	return records, nil
+}
+
+// Insert 先校验整批记录再写入，任一记录冲突时不写入任何记录，与其他后端的事务语义一致。
+func (r *bulkRepository) Insert(ctx context.Context, kind core.RecordKind, records []any) error {
+	if core.NewRecord(kind) == nil {
+		return fmt.Errorf("unknown record kind %q", kind)
+	}
+	for _, record := range records {
+		if recordKind(record) != kind {
+			return fmt.Errorf("unsupported record type %T for %s", record, kind)
+		}
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if err := r.check(kind, records); err != nil {
+		return err
+	}
+	for _, record := range records {
+		switch rec := record.(type) {
+		case *core.User:
+			r.s.users[rec.ID] = cloneUser(rec)
+			r.s.usernames[rec.Username] = rec.ID
+			r.s.emails[rec.Email] = rec.ID
+		case *core.VaultItem:
+			r.s.vaults[rec.ID] = cloneVaultItem(rec)
+		case *core.VerificationCode:
+			c := *rec
+			r.s.codes[rec.Email] = &c
+		case *core.Device:
+			c := *rec
+			r.s.devices[deviceKey{userID: rec.UserID, deviceID: rec.DeviceID}] = &c
+		case *core.LoginApproval:
+			c := *rec
+			r.s.approvals[rec.ID] = &c
+		case *core.APIKey:
+			r.s.apiKeys[rec.ID] = cloneAPIKey(rec)
+		case *core.AuditEvent:
+			// 按序号写入时，最后写入的事件就是该用户链的头。
+			r.s.audit = append(r.s.audit, cloneAuditEvent(rec))
+			r.s.auditHeads[rec.UserID] = rec.Hash
+		}
+	}
+	return nil
+}
+
+// check 确认记录与已存储的记录以及同批次的其他记录都不冲突。调用方必须持有写锁。
+func (r *bulkRepository) check(kind core.RecordKind, records []any) error {
+	seen := make(map[any]bool)
+	claim := func(key any, exists bool) error {
+		if exists || seen[key] {
+			return fmt.Errorf("%s/%v already exists", kind, key)
+		}
+		seen[key] = true
+		return nil
+	}
+
+	lastSequence := int64(0)
+	if n := len(r.s.audit); n > 0 {
+		lastSequence = r.s.audit[n-1].Sequence
+	}
+	for _, record := range records {
+		var err error
+		switch rec := record.(type) {
+		case *core.User:
+			_, usernameTaken := r.s.usernames[rec.Username]
+			_, emailTaken := r.s.emails[rec.Email]
+			if usernameTaken || seen["username:"+rec.Username] {
+				return &core.DuplicateEntryError{Field: "username"}
+			}
+			if emailTaken || seen["email:"+rec.Email] {
+				return &core.DuplicateEntryError{Field: "email"}
+			}
+			seen["username:"+rec.Username] = true
+			seen["email:"+rec.Email] = true
+			_, exists := r.s.users[rec.ID]
+			err = claim(rec.ID, exists)
+		case *core.VaultItem:
+			_, exists := r.s.vaults[rec.ID]
+			err = claim(rec.ID, exists)
+		case *core.VerificationCode:
+			_, exists := r.s.codes[rec.Email]
+			err = claim(rec.Email, exists)
+		case *core.Device:
+			key := deviceKey{userID: rec.UserID, deviceID: rec.DeviceID}
+			_, exists := r.s.devices[key]
+			err = claim(key, exists)
+		case *core.LoginApproval:
+			_, exists := r.s.approvals[rec.ID]
+			err = claim(rec.ID, exists)
+		case *core.APIKey:
+			_, exists := r.s.apiKeys[rec.ID]
+			err = claim(rec.ID, exists)
+		case *core.AuditEvent:
+			// 审计事件保存在按序号排列的切片中，只能追加在已有事件之后。
+			if rec.Sequence <= lastSequence {
+				return fmt.Errorf("%s/%d is not after sequence %d", kind, rec.Sequence, lastSequence)
+			}
+			lastSequence = rec.Sequence
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordKind 返回记录的类型，与 core.NewRecord 相对应。
+func recordKind(record any) core.RecordKind {
+	switch record.(type) {
+	case *core.User:
+		return core.RecordUsers
+	case *core.VaultItem:
+		return core.RecordVaultItems
+	case *core.VerificationCode:
+		return core.RecordVerificationCodes
+	case *core.Device:
+		return core.RecordDevices
+	case *core.LoginApproval:
+		return core.RecordLoginApprovals
+	case *core.APIKey:
+		return core.RecordAPIKeys
+	case *core.AuditEvent:
+		return core.RecordAuditEvents
+	default:
+		return ""
+	}
+}
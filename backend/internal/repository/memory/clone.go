@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"bytes"
+	"easy-password-backend/internal/core"
+	"maps"
+	"slices"
+	"time"
+)
+
+// 以下函数返回记录的深拷贝，使存储的数据不与调用方共享切片、映射或指针。
+
+func cloneUser(u *core.User) *core.User {
+	c := *u
+	c.MasterSalt = bytes.Clone(u.MasterSalt)
+	c.ResetPasswordToken = cloneString(u.ResetPasswordToken)
+	c.ResetPasswordTokenExpiresAt = cloneTime(u.ResetPasswordTokenExpiresAt)
+	c.PendingEmail = cloneString(u.PendingEmail)
+	c.PendingEmailCodeHash = cloneString(u.PendingEmailCodeHash)
+	c.PendingEmailExpiresAt = cloneTime(u.PendingEmailExpiresAt)
+	c.SessionsRevokedAt = cloneTime(u.SessionsRevokedAt)
+	return &c
+}
+
+func cloneVaultItem(item *core.VaultItem) *core.VaultItem {
+	c := *item
+	c.EncryptedData = bytes.Clone(item.EncryptedData)
+	return &c
+}
+
+func cloneAPIKey(key *core.APIKey) *core.APIKey {
+	c := *key
+	c.Scopes = slices.Clone(key.Scopes)
+	c.ItemIDs = slices.Clone(key.ItemIDs)
+	c.Categories = slices.Clone(key.Categories)
+	c.ExpiresAt = cloneTime(key.ExpiresAt)
+	c.LastUsedAt = cloneTime(key.LastUsedAt)
+	return &c
+}
+
+func cloneAuditEvent(event *core.AuditEvent) *core.AuditEvent {
+	c := *event
+	c.Metadata = maps.Clone(event.Metadata)
+	return &c
+}
+
+func cloneString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	return &c
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"easy-password-backend/internal/core"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// --- 设备存储库实现 ---
+
+// 设备以 用户ID + 客户端设备ID 为键存储，与其他后端的唯一索引一致。
+type deviceRepository struct {
+	s *store
+}
+
+func (r *deviceRepository) Create(ctx context.Context, device *core.Device) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	key := deviceKey{userID: device.UserID, deviceID: device.DeviceID}
+	if _, ok := r.s.devices[key]; ok {
+		return &core.DuplicateEntryError{Field: "device_id"}
+	}
+	device.ID = uuid.New()
+	c := *device
+	r.s.devices[key] = &c
+	return nil
+}
+
+func (r *deviceRepository) FindByID(ctx context.Context, id uuid.UUID) (*core.Device, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, device := range r.s.devices {
+		if device.ID == id {
+			c := *device
+			return &c, nil
+		}
+	}
+	return nil, core.ErrDeviceNotFound
+}
+
+func (r *deviceRepository) FindByUserAndDeviceID(ctx context.Context, userID uuid.UUID, deviceID string) (*core.Device, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	device, ok := r.s.devices[deviceKey{userID: userID, deviceID: deviceID}]
+	if !ok {
+		return nil, core.ErrDeviceNotFound
+	}
+	c := *device
+	return &c, nil
+}
+
+func (r *deviceRepository) FindByUser(ctx context.Context, userID uuid.UUID) ([]core.Device, error) {
+	r.s.mu.RLock()
+	var devices []core.Device
+	for key, device := range r.s.devices {
+		if key.userID == userID {
+			devices = append(devices, *device)
+		}
+	}
+	r.s.mu.RUnlock()
+
+	// 与 BoltDB 按键遍历的顺序一致。
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].DeviceID < devices[j].DeviceID
+	})
+	return devices, nil
+}
+
+func (r *deviceRepository) Update(ctx context.Context, device *core.Device) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	key := deviceKey{userID: device.UserID, deviceID: device.DeviceID}
+	if _, ok := r.s.devices[key]; !ok {
+		return core.ErrDeviceNotFound
+	}
+	c := *device
+	r.s.devices[key] = &c
+	return nil
+}
+
+func (r *deviceRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for key, device := range r.s.devices {
+		if device.ID == id {
+			delete(r.s.devices, key)
+			return nil
+		}
+	}
+	return core.ErrDeviceNotFound
+}
@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"context"
+	"easy-password-backend/internal/core"
+
+	"github.com/google/uuid"
+)
+
+// --- 待批准登录存储库实现 ---
+
+type loginApprovalRepository struct {
+	s *store
+}
+
+func (r *loginApprovalRepository) Create(ctx context.Context, approval *core.LoginApproval) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if approval.ID == uuid.Nil {
+		approval.ID = uuid.New()
+	}
+	c := *approval
+	r.s.approvals[approval.ID] = &c
+	return nil
+}
+
+func (r *loginApprovalRepository) FindByID(ctx context.Context, id uuid.UUID) (*core.LoginApproval, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	approval, ok := r.s.approvals[id]
+	if !ok {
+		return nil, core.ErrLoginApprovalNotFound
+	}
+	c := *approval
+	return &c, nil
+}
+
+func (r *loginApprovalRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*core.LoginApproval, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, approval := range r.s.approvals {
+		if approval.TokenHash == tokenHash {
+			c := *approval
+			return &c, nil
+		}
+	}
+	return nil, core.ErrLoginApprovalNotFound
+}
+
+func (r *loginApprovalRepository) Update(ctx context.Context, approval *core.LoginApproval) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.approvals[approval.ID]; !ok {
+		return core.ErrLoginApprovalNotFound
+	}
+	c := *approval
+	r.s.approvals[approval.ID] = &c
+	return nil
+}
+
+func (r *loginApprovalRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.approvals, id)
+	return nil
+}
@@ -0,0 +1,149 @@
+// Package memory 在进程内存中实现 repository.Storage，适用于测试和不需要持久化的临时部署。
+// 进程退出后所有数据都会丢失。
+package memory
+
+import (
+	"context"
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/repository/schema"
+	"io"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// deviceKey 是设备的唯一键，与其他后端的 (user_id, device_id) 唯一索引一致。
+type deviceKey struct {
+	userID   uuid.UUID
+	deviceID string
+}
+
+// store 保存全部数据。所有存储库共享同一把读写锁，
+// 写操作持有写锁直到完成，因此每个方法都与其他后端的单个事务一样是原子的。
+// 存入和取出的记录都是副本，调用方修改返回值不会影响已存储的数据。
+type store struct {
+	mu sync.RWMutex
+
+	users     map[uuid.UUID]*core.User
+	usernames map[string]uuid.UUID
+	emails    map[string]uuid.UUID
+
+	vaults    map[uuid.UUID]*core.VaultItem
+	codes     map[string]*core.VerificationCode
+	devices   map[deviceKey]*core.Device
+	approvals map[uuid.UUID]*core.LoginApproval
+	apiKeys   map[uuid.UUID]*core.APIKey
+
+	// audit 按序号升序保存审计事件，auditHeads 保存每个用户链上最后一条事件的哈希。
+	audit      []*core.AuditEvent
+	auditHeads map[uuid.UUID]string
+}
+
+// Storage 为进程内存实现了 repository.Storage 接口。
+type Storage struct {
+	s *store
+}
+
+// NewMemoryStorage 创建一个新的空内存存储实例。
+func NewMemoryStorage() *Storage {
+	return &Storage{s: &store{
+		users:      make(map[uuid.UUID]*core.User),
+		usernames:  make(map[string]uuid.UUID),
+		emails:     make(map[string]uuid.UUID),
+		vaults:     make(map[uuid.UUID]*core.VaultItem),
+		codes:      make(map[string]*core.VerificationCode),
+		devices:    make(map[deviceKey]*core.Device),
+		approvals:  make(map[uuid.UUID]*core.LoginApproval),
+		apiKeys:    make(map[uuid.UUID]*core.APIKey),
+		auditHeads: make(map[uuid.UUID]string),
+	}}
+}
+
+// User 返回一个在内存存储上操作的 UserRepository。
+func (s *Storage) User() core.UserRepository {
+	return &userRepository{s: s.s}
+}
+
+// Vault 返回一个在内存存储上操作的 VaultRepository。
+func (s *Storage) Vault() core.VaultRepository {
+	return &vaultRepository{s: s.s}
+}
+
+// VerificationCode 返回一个在内存存储上操作的 VerificationCodeRepository。
+func (s *Storage) VerificationCode() core.VerificationCodeRepository {
+	return &verificationCodeRepository{s: s.s}
+}
+
+// Audit 返回一个在内存存储上操作的 AuditRepository。
+func (s *Storage) Audit() core.AuditRepository {
+	return &auditRepository{s: s.s}
+}
+
+// Device 返回一个在内存存储上操作的 DeviceRepository。
+func (s *Storage) Device() core.DeviceRepository {
+	return &deviceRepository{s: s.s}
+}
+
+// LoginApproval 返回一个在内存存储上操作的 LoginApprovalRepository。
+func (s *Storage) LoginApproval() core.LoginApprovalRepository {
+	return &loginApprovalRepository{s: s.s}
+}
+
+// APIKey 返回一个在内存存储上操作的 APIKeyRepository。
+func (s *Storage) APIKey() core.APIKeyRepository {
+	return &apiKeyRepository{s: s.s}
+}
+
+// Bulk 返回一个在内存存储上操作的 BulkRepository。
+func (s *Storage) Bulk() core.BulkRepository {
+	return &bulkRepository{s: s.s}
+}
+
+// Stats 返回各类记录的数量。内存存储不占用磁盘，SizeBytes 始终为 0。
+func (s *Storage) Stats(ctx context.Context) (*core.StorageStats, error) {
+	s.s.mu.RLock()
+	defer s.s.mu.RUnlock()
+	return &core.StorageStats{
+		Backend: "memory",
+		Records: map[string]int64{
+			string(core.RecordUsers):             int64(len(s.s.users)),
+			string(core.RecordVaultItems):        int64(len(s.s.vaults)),
+			string(core.RecordVerificationCodes): int64(len(s.s.codes)),
+			string(core.RecordDevices):           int64(len(s.s.devices)),
+			string(core.RecordLoginApprovals):    int64(len(s.s.approvals)),
+			string(core.RecordAPIKeys):           int64(len(s.s.apiKeys)),
+			string(core.RecordAuditEvents):       int64(len(s.s.audit)),
+		},
+	}, nil
+}
+
+// Backup 不受支持：内存存储没有可以下载的数据库文件。
+func (s *Storage) Backup(ctx context.Context, w io.Writer) (int64, error) {
+	return 0, core.ErrBackupNotSupported
+}
+
+// RotateEncryptionKey 不受支持：内存中的数据不落盘，不需要静态加密。
+func (s *Storage) RotateEncryptionKey(ctx context.Context) error {
+	return core.ErrEncryptionNotEnabled
+}
+
+// Migrator 满足 repository.Migrator 接口。内存存储没有需要迁移的 schema，
+// 它报告最新版本为 0，且始终没有待执行的步骤。
+type Migrator struct{}
+
+// NewMigrator 返回内存存储的 Migrator。
+func NewMigrator() Migrator {
+	return Migrator{}
+}
+
+// Latest 始终返回 0。
+func (Migrator) Latest() int { return 0 }
+
+// Status 始终返回空列表。
+func (Migrator) Status(ctx context.Context) ([]schema.Migration, error) { return nil, nil }
+
+// Up 不执行任何操作。
+func (Migrator) Up(ctx context.Context, target int) ([]schema.Migration, error) { return nil, nil }
+
+// Down 不执行任何操作。
+func (Migrator) Down(ctx context.Context, target int) ([]schema.Migration, error) { return nil, nil }
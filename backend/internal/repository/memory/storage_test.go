@@ -0,0 +1,14 @@
+package memory_test
+
+import (
+	"easy-password-backend/internal/repository"
+	"easy-password-backend/internal/repository/memory"
+	"easy-password-backend/internal/repository/repotest"
+	"testing"
+)
+
+func TestStorageContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.Storage {
+		return memory.NewMemoryStorage()
+	})
+}
@@ -0,0 +1,196 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"easy-password-backend/internal/core"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// --- 用户存储库实现 ---
+
+type userRepository struct {
+	s *store
+}
+
+func (r *userRepository) Create(ctx context.Context, user *core.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.usernames[user.Username]; ok {
+		return &core.DuplicateEntryError{Field: "username"}
+	}
+	if _, ok := r.s.emails[user.Email]; ok {
+		return &core.DuplicateEntryError{Field: "email"}
+	}
+
+	user.ID = uuid.New()
+	// 与 gorm 的 autoCreateTime/autoUpdateTime 保持一致。
+	now := time.Now()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+
+	r.s.users[user.ID] = cloneUser(user)
+	r.s.usernames[user.Username] = user.ID
+	r.s.emails[user.Email] = user.ID
+	return nil
+}
+
+func (r *userRepository) FindByID(ctx context.Context, id uuid.UUID) (*core.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.find(id)
+}
+
+func (r *userRepository) FindByUsername(ctx context.Context, username string) (*core.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	id, ok := r.s.usernames[username]
+	if !ok {
+		return nil, core.ErrUserNotFound
+	}
+	return r.find(id)
+}
+
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*core.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	id, ok := r.s.emails[email]
+	if !ok {
+		return nil, core.ErrUserNotFound
+	}
+	return r.find(id)
+}
+
+// find 返回用户的副本，调用方必须持有锁。
+func (r *userRepository) find(id uuid.UUID) (*core.User, error) {
+	user, ok := r.s.users[id]
+	if !ok {
+		return nil, core.ErrUserNotFound
+	}
+	return cloneUser(user), nil
+}
+
+func (r *userRepository) FindByResetPasswordToken(ctx context.Context, token string) (*core.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, user := range r.s.users {
+		if user.ResetPasswordToken != nil && *user.ResetPasswordToken == token {
+			return cloneUser(user), nil
+		}
+	}
+	return nil, core.ErrUserNotFound
+}
+
+func (r *userRepository) List(ctx context.Context, query string, offset, limit int) ([]core.User, int64, error) {
+	query = strings.ToLower(query)
+	r.s.mu.RLock()
+	var matched []core.User
+	for _, user := range r.s.users {
+		if query == "" ||
+			strings.Contains(strings.ToLower(user.Username), query) ||
+			strings.Contains(strings.ToLower(user.Email), query) {
+			matched = append(matched, *cloneUser(user))
+		}
+	}
+	r.s.mu.RUnlock()
+
+	// 映射的遍历顺序是随机的，注册时间相同时再按 ID 排序，保证分页结果稳定。
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CreatedAt.Before(matched[j].CreatedAt)
+		}
+		return bytes.Compare(matched[i].ID[:], matched[j].ID[:]) < 0
+	})
+	total := int64(len(matched))
+	if offset >= len(matched) {
+		return []core.User{}, total, nil
+	}
+	matched = matched[offset:]
+	if limit > 0 && limit < len(matched) {
+		matched = matched[:limit]
+	}
+	return matched, total, nil
+}
+
+func (r *userRepository) Update(ctx context.Context, user *core.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	existing, ok := r.s.users[user.ID]
+	if !ok {
+		return core.ErrUserNotFound
+	}
+
+	// 先检查两个唯一索引，都可用时再修改，避免只更新了其中一个。
+	if owner, ok := r.s.usernames[user.Username]; ok && owner != user.ID {
+		return &core.DuplicateEntryError{Field: "username"}
+	}
+	if owner, ok := r.s.emails[user.Email]; ok && owner != user.ID {
+		return &core.DuplicateEntryError{Field: "email"}
+	}
+	delete(r.s.usernames, existing.Username)
+	delete(r.s.emails, existing.Email)
+	r.s.usernames[user.Username] = user.ID
+	r.s.emails[user.Email] = user.ID
+
+	user.UpdatedAt = time.Now()
+	r.s.users[user.ID] = cloneUser(user)
+	return nil
+}
+
+func (r *userRepository) ClearExpiredResetTokens(ctx context.Context, before time.Time) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var cleared int64
+	for _, user := range r.s.users {
+		if user.ResetPasswordToken != nil && user.ResetPasswordTokenExpiresAt != nil &&
+			user.ResetPasswordTokenExpiresAt.Before(before) {
+			user.ResetPasswordToken = nil
+			user.ResetPasswordTokenExpiresAt = nil
+			cleared++
+		}
+	}
+	return cleared, nil
+}
+
+func (r *userRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, ok := r.s.users[id]
+	if !ok {
+		return core.ErrUserNotFound
+	}
+
+	delete(r.s.usernames, user.Username)
+	delete(r.s.emails, user.Email)
+	delete(r.s.codes, user.Email)
+	for itemID, item := range r.s.vaults {
+		if item.UserID == id {
+			delete(r.s.vaults, itemID)
+		}
+	}
+	for key := range r.s.devices {
+		if key.userID == id {
+			delete(r.s.devices, key)
+		}
+	}
+	for approvalID, approval := range r.s.approvals {
+		if approval.UserID == id {
+			delete(r.s.approvals, approvalID)
+		}
+	}
+	for keyID, key := range r.s.apiKeys {
+		if key.UserID == id {
+			delete(r.s.apiKeys, keyID)
+		}
+	}
+	delete(r.s.users, id)
+	return nil
+}
@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"easy-password-backend/internal/core"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// --- 保险库存储库实现 ---
+
+type vaultRepository struct {
+	s *store
+}
+
+func (r *vaultRepository) Create(ctx context.Context, item *core.VaultItem) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	item.ID = uuid.New()
+	r.s.vaults[item.ID] = cloneVaultItem(item)
+	return nil
+}
+
+func (r *vaultRepository) CreateMany(ctx context.Context, items []core.VaultItem) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for i := range items {
+		items[i].ID = uuid.New()
+		r.s.vaults[items[i].ID] = cloneVaultItem(&items[i])
+	}
+	return nil
+}
+
+func (r *vaultRepository) FindByID(ctx context.Context, id uuid.UUID) (*core.VaultItem, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	item, ok := r.s.vaults[id]
+	if !ok {
+		return nil, core.ErrVaultItemNotFound
+	}
+	return cloneVaultItem(item), nil
+}
+
+func (r *vaultRepository) FindByUser(ctx context.Context, userID uuid.UUID) ([]core.VaultItem, error) {
+	r.s.mu.RLock()
+	var items []core.VaultItem
+	for _, item := range r.s.vaults {
+		if item.UserID == userID {
+			items = append(items, *cloneVaultItem(item))
+		}
+	}
+	r.s.mu.RUnlock()
+
+	// 与 BoltDB 按键遍历的顺序一致。
+	sort.Slice(items, func(i, j int) bool {
+		return bytes.Compare(items[i].ID[:], items[j].ID[:]) < 0
+	})
+	return items, nil
+}
+
+func (r *vaultRepository) CountByUser(ctx context.Context) (map[uuid.UUID]int64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	counts := make(map[uuid.UUID]int64)
+	for _, item := range r.s.vaults {
+		counts[item.UserID]++
+	}
+	return counts, nil
+}
+
+func (r *vaultRepository) Update(ctx context.Context, item *core.VaultItem) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.vaults[item.ID]; !ok {
+		return core.ErrVaultItemNotFound
+	}
+	r.s.vaults[item.ID] = cloneVaultItem(item)
+	return nil
+}
+
+func (r *vaultRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.vaults[id]; !ok {
+		return core.ErrVaultItemNotFound
+	}
+	delete(r.s.vaults, id)
+	return nil
+}
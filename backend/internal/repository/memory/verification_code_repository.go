@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+	"easy-password-backend/internal/core"
+	"time"
+)
+
+// --- 验证码存储库实现 ---
+
+// 每个邮箱最多保存一个验证码，Create 会覆盖该邮箱已有的验证码。
+type verificationCodeRepository struct {
+	s *store
+}
+
+func (r *verificationCodeRepository) Create(ctx context.Context, vc *core.VerificationCode) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	c := *vc
+	r.s.codes[vc.Email] = &c
+	return nil
+}
+
+func (r *verificationCodeRepository) Find(ctx context.Context, email string) (*core.VerificationCode, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	vc, ok := r.s.codes[email]
+	if !ok {
+		return nil, core.ErrVerificationCodeNotFound
+	}
+	c := *vc
+	return &c, nil
+}
+
+func (r *verificationCodeRepository) Delete(ctx context.Context, email string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	delete(r.s.codes, email)
+	return nil
+}
+
+func (r *verificationCodeRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var deleted int64
+	for email, vc := range r.s.codes {
+		if vc.ExpiresAt.Before(before) {
+			delete(r.s.codes, email)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"easy-password-backend/config"
+	"easy-password-backend/internal/repository/boltdb"
+	"easy-password-backend/internal/repository/memory"
+	"easy-password-backend/internal/repository/postgres"
+	"easy-password-backend/internal/repository/schema"
+	"easy-password-backend/internal/repository/sqlite"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+	"gorm.io/gorm"
+)
+
+// Migrator 对存储后端执行编号的 schema 迁移。
+type Migrator interface {
+	// Latest 返回当前程序已知的最新版本号。
+	Latest() int
+	// Status 返回所有已知迁移步骤及其是否已应用。
+	Status(ctx context.Context) ([]schema.Migration, error)
+	// Up 应用版本号不大于 target 的待执行步骤，target 为 0 时迁移到最新版本。
+	Up(ctx context.Context, target int) ([]schema.Migration, error)
+	// Down 回滚版本号大于 target 的已应用步骤。
+	Down(ctx context.Context, target int) ([]schema.Migration, error)
+}
+
+// NewMigrator 根据配置返回对应存储后端的 Migrator。
+func NewMigrator(cfg *config.Config, db *gorm.DB, boltDB *bbolt.DB) (Migrator, error) {
+	switch cfg.DBType {
+	case "postgres":
+		return postgres.NewMigrator(db), nil
+	case "sqlite":
+		return sqlite.NewMigrator(db), nil
+	case "boltdb":
+		return boltdb.NewMigrator(boltDB), nil
+	case "memory":
+		return memory.NewMigrator(), nil
+	default:
+		return nil, fmt.Errorf("unsupported DB_TYPE: %s", cfg.DBType)
+	}
+}
+
+// PrepareSchema 在 autoMigrate 为 true 时把 schema 迁移到最新版本并返回本次应用的步骤；
+// 否则只检查 schema，有待执行的迁移时返回错误。
+func PrepareSchema(ctx context.Context, m Migrator, autoMigrate bool) ([]schema.Migration, error) {
+	if autoMigrate {
+		return m.Up(ctx, 0)
+	}
+	status, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if pending := schema.Pending(status); len(pending) > 0 {
+		return nil, fmt.Errorf("database schema is out of date: %d pending migration(s) starting at version %d; run `epadmin schema up`", len(pending), pending[0].Version)
+	}
+	return nil, nil
+}
@@ -0,0 +1,239 @@
+package postgres
+
+import (
+	"context"
+	"easy-password-backend/internal/repository/schema"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// migrationLockID 是迁移使用的会话级 advisory lock 的键，任意但必须固定。
+const migrationLockID = 7_020_330_901
+
+// schemaMigration 是 schema_migrations 表中的一行。
+type schemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"type:varchar(255);not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// migrations 是 PostgreSQL 的全部迁移步骤。已发布的步骤不可修改，变更只能追加新步骤，
+// 并且新步骤应使用显式 SQL，而不是依赖会随 core 模型变化的 AutoMigrate。
+var migrations = []schema.Step[*gorm.DB]{
+	{
+		// 基线：与此前 AutoMigrate 创建的表结构一致。对已由 AutoMigrate 建表的数据库，
+		// 这一步只会补齐缺失的列和索引。
+		Version: 1,
+		Name:    "baseline",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AutoMigrate(baselineModels...)
+		},
+		Down: func(tx *gorm.DB) error {
+			for i := len(baselineModels) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropTable(baselineModels[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 2,
+		Name:    "backfill_user_status_role",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec(`UPDATE users SET status = 'active' WHERE status IS NULL OR status = ''`).Error; err != nil {
+				return err
+			}
+			return tx.Exec(`UPDATE users SET role = 'user' WHERE role IS NULL OR role = ''`).Error
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create_api_keys",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec(`CREATE TABLE api_keys (
+				id uuid PRIMARY KEY,
+				user_id uuid NOT NULL,
+				name varchar(255) NOT NULL,
+				secret_hash varchar(64) NOT NULL,
+				scopes jsonb NOT NULL,
+				item_ids jsonb,
+				categories jsonb,
+				expires_at timestamptz,
+				last_used_at timestamptz,
+				created_at timestamptz
+			)`).Error; err != nil {
+				return err
+			}
+			return tx.Exec(`CREATE INDEX idx_api_keys_user_id ON api_keys (user_id)`).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec(`DROP TABLE api_keys`).Error
+		},
+	},
+	{
+		Version: 4,
+		Name:    "add_pending_email_change",
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec(`ALTER TABLE users
+				ADD COLUMN pending_email varchar(255),
+				ADD COLUMN pending_email_code_hash varchar(64),
+				ADD COLUMN pending_email_expires_at timestamptz`).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec(`ALTER TABLE users
+				DROP COLUMN pending_email,
+				DROP COLUMN pending_email_code_hash,
+				DROP COLUMN pending_email_expires_at`).Error
+		},
+	},
+}
+
+// NewMigrator 返回 PostgreSQL 的 schema 迁移器。迁移期间持有一个会话级 advisory lock，
+// 多个实例同时启动时只有一个会执行迁移，其余的等待锁释放后发现已是最新版本。
+func NewMigrator(db *gorm.DB) *schema.Migrator[*gorm.DB] {
+	return schema.NewMigrator[*gorm.DB](&migrationDriver{db: db}, migrations)
+}
+
+type migrationDriver struct {
+	db *gorm.DB
+}
+
+// migrationConn 绑定在持有 advisory lock 的那条连接上。
+type migrationConn struct {
+	db *gorm.DB
+}
+
+func (d *migrationDriver) Lock(ctx context.Context, fn func(conn schema.Conn[*gorm.DB]) error) error {
+	// advisory lock 属于会话，加锁、迁移和解锁必须使用同一条连接。
+	return d.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockID).Error; err != nil {
+			return err
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockID)
+
+		if err := conn.Migrator().AutoMigrate(&schemaMigration{}); err != nil {
+			return err
+		}
+		return fn(&migrationConn{db: conn})
+	})
+}
+
+func (c *migrationConn) Applied(ctx context.Context) (map[int]time.Time, error) {
+	var rows []schemaMigration
+	if err := c.db.WithContext(ctx).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+	return applied, nil
+}
+
+func (c *migrationConn) Apply(ctx context.Context, version int, name string, up bool, fn func(tx *gorm.DB) error) error {
+	return c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if !up {
+			return tx.Delete(&schemaMigration{}, version).Error
+		}
+		return tx.Create(&schemaMigration{Version: version, Name: name, AppliedAt: time.Now().UTC()}).Error
+	})
+}
+
+// 以下是迁移 1 时的表结构快照，与 core 中的模型分开保存，
+// 以免之后修改模型改变基线迁移的含义。
+var baselineModels = []any{
+	&baselineUser{}, &baselineVaultItem{}, &baselineVerificationCode{},
+	&baselineAuditEvent{}, &baselineDevice{}, &baselineLoginApproval{},
+}
+
+type baselineUser struct {
+	ID                          uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Username                    string     `gorm:"type:varchar(255);uniqueIndex;not null"`
+	Email                       string     `gorm:"type:varchar(255);uniqueIndex;not null"`
+	AuthHash                    string     `gorm:"type:text;not null"`
+	MasterSalt                  []byte     `gorm:"type:bytea;not null"`
+	ResetPasswordToken          *string    `gorm:"type:varchar(255);uniqueIndex"`
+	ResetPasswordTokenExpiresAt *time.Time `gorm:"index"`
+	RequireDeviceApproval       bool       `gorm:"not null;default:false"`
+	SessionsRevokedAt           *time.Time
+	Status                      string `gorm:"type:varchar(20);not null;default:'active';index"`
+	Role                        string `gorm:"type:varchar(20);not null;default:'user'"`
+	CreatedAt                   time.Time
+	UpdatedAt                   time.Time
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineVaultItem struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID        uuid.UUID `gorm:"type:uuid;not null"`
+	EncryptedData []byte    `gorm:"type:jsonb;not null"`
+	Category      string    `gorm:"type:varchar(100);index"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (baselineVaultItem) TableName() string { return "vault_items" }
+
+type baselineVerificationCode struct {
+	Email     string    `gorm:"type:varchar(255);primary_key"`
+	Code      string    `gorm:"type:varchar(10);not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+func (baselineVerificationCode) TableName() string { return "verification_codes" }
+
+type baselineAuditEvent struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key"`
+	Sequence     int64     `gorm:"not null;uniqueIndex"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null;index"`
+	Type         string    `gorm:"type:varchar(100);not null;index"`
+	IPAddress    string    `gorm:"type:varchar(64)"`
+	UserAgent    string    `gorm:"type:text"`
+	Metadata     []byte    `gorm:"type:jsonb"`
+	CreatedAt    time.Time `gorm:"not null;index"`
+	PrevHash     string    `gorm:"type:varchar(64);not null"`
+	UserPrevHash string    `gorm:"type:varchar(64);not null"`
+	Hash         string    `gorm:"type:varchar(64);not null"`
+}
+
+func (baselineAuditEvent) TableName() string { return "audit_events" }
+
+type baselineDevice struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_devices_user_device"`
+	DeviceID    string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_devices_user_device"`
+	Name        string    `gorm:"type:varchar(255)"`
+	Type        string    `gorm:"type:varchar(50)"`
+	IPAddress   string    `gorm:"type:varchar(64)"`
+	FirstSeenAt time.Time `gorm:"not null"`
+	LastSeenAt  time.Time `gorm:"not null"`
+}
+
+func (baselineDevice) TableName() string { return "devices" }
+
+type baselineLoginApproval struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;index"`
+	DeviceID   string    `gorm:"type:varchar(255);not null"`
+	DeviceName string    `gorm:"type:varchar(255)"`
+	DeviceType string    `gorm:"type:varchar(50)"`
+	IPAddress  string    `gorm:"type:varchar(64)"`
+	CodeHash   string    `gorm:"type:varchar(64);not null"`
+	TokenHash  string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Attempts   int       `gorm:"not null;default:0"`
+	Status     string    `gorm:"type:varchar(20);not null"`
+	ExpiresAt  time.Time `gorm:"not null;index"`
+	CreatedAt  time.Time
+}
+
+func (baselineLoginApproval) TableName() string { return "login_approvals" }
@@ -3,8 +3,14 @@ package postgres
 import (
 	"context"
 	"easy-password-backend/internal/core"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -34,6 +40,68 @@ func (s *Storage) VerificationCode() core.VerificationCodeRepository {
 	return &verificationCodeRepository{db: s.db}
 }
 
+// Audit 返回一个在 PostgreSQL 数据库上操作的 AuditRepository。
+func (s *Storage) Audit() core.AuditRepository {
+	return &auditRepository{db: s.db}
+}
+
+// Device 返回一个在 PostgreSQL 数据库上操作的 DeviceRepository。
+func (s *Storage) Device() core.DeviceRepository {
+	return &deviceRepository{db: s.db}
+}
+
+// LoginApproval 返回一个在 PostgreSQL 数据库上操作的 LoginApprovalRepository。
+func (s *Storage) LoginApproval() core.LoginApprovalRepository {
+	return &loginApprovalRepository{db: s.db}
+}
+
+// APIKey 返回一个在 PostgreSQL 数据库上操作的 APIKeyRepository。
+func (s *Storage) APIKey() core.APIKeyRepository {
+	return &apiKeyRepository{db: s.db}
+}
+
+// Bulk 返回一个在 PostgreSQL 数据库上操作的 BulkRepository。
+func (s *Storage) Bulk() core.BulkRepository {
+	return &bulkRepository{db: s.db}
+}
+
+// statsModels 列出 Stats 统计行数的模型。
+var statsModels = []any{
+	&core.User{}, &core.VaultItem{}, &core.VerificationCode{},
+	&core.AuditEvent{}, &core.Device{}, &core.LoginApproval{}, &core.APIKey{},
+}
+
+// Stats 返回各表的行数和数据库的磁盘占用。
+func (s *Storage) Stats(ctx context.Context) (*core.StorageStats, error) {
+	db := s.db.WithContext(ctx)
+	stats := &core.StorageStats{Backend: "postgres", Records: make(map[string]int64)}
+	for _, model := range statsModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		var count int64
+		if err := db.Model(model).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		stats.Records[stmt.Schema.Table] = count
+	}
+	if err := db.Raw("SELECT pg_database_size(current_database())").Scan(&stats.SizeBytes).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Backup 对 PostgreSQL 不可用，应使用 pg_dump 等数据库自带的工具备份。
+func (s *Storage) Backup(ctx context.Context, w io.Writer) (int64, error) {
+	return 0, core.ErrBackupNotSupported
+}
+
+// RotateEncryptionKey 对 PostgreSQL 不可用，静态加密应由数据库或磁盘层提供。
+func (s *Storage) RotateEncryptionKey(ctx context.Context) error {
+	return core.ErrEncryptionNotEnabled
+}
+
 // --- 用户存储库实现 ---
 
 type userRepository struct {
@@ -41,7 +109,19 @@ type userRepository struct {
 }
 
 func (r *userRepository) Create(ctx context.Context, user *core.User) error {
-	return r.db.WithContext(ctx).Create(user).Error
+	return translateUniqueError(r.db.WithContext(ctx).Create(user).Error)
+}
+
+func (r *userRepository) FindByID(ctx context.Context, id uuid.UUID) (*core.User, error) {
+	var user core.User
+	err := r.db.WithContext(ctx).First(&user, "id = ?", id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, core.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
 }
 
 func (r *userRepository) FindByUsername(ctx context.Context, username string) (*core.User, error) {
@@ -80,8 +160,100 @@ func (r *userRepository) FindByResetPasswordToken(ctx context.Context, token str
 	return &user, nil
 }
 
+func (r *userRepository) List(ctx context.Context, query string, offset, limit int) ([]core.User, int64, error) {
+	// Session 使计数和查询各自使用独立的语句，互不影响。
+	db := r.db.WithContext(ctx).Model(&core.User{})
+	if query != "" {
+		pattern := "%" + escapeLike(query) + "%"
+		db = db.Where("username ILIKE ? OR email ILIKE ?", pattern, pattern)
+	}
+	db = db.Session(&gorm.Session{})
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []core.User
+	find := db.Order("created_at ASC").Offset(offset)
+	if limit > 0 {
+		find = find.Limit(limit)
+	}
+	err := find.Find(&users).Error
+	return users, total, err
+}
+
+// escapeLike 转义 LIKE 模式中的通配符。
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
 func (r *userRepository) Update(ctx context.Context, user *core.User) error {
-	return r.db.WithContext(ctx).Save(user).Error
+	// Save 在记录不存在时会插入新记录，这里只更新已存在的用户。
+	result := r.db.WithContext(ctx).Model(user).Select("*").Updates(user)
+	if result.Error != nil {
+		return translateUniqueError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return core.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) ClearExpiredResetTokens(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&core.User{}).
+		Where("reset_password_token IS NOT NULL AND reset_password_token_expires_at < ?", before).
+		Updates(map[string]any{"reset_password_token": nil, "reset_password_token_expires_at": nil})
+	return result.RowsAffected, result.Error
+}
+
+// uniqueViolation 是 PostgreSQL 唯一约束冲突的 SQLSTATE 代码。
+const uniqueViolation = "23505"
+
+// translateUniqueError 将 users 和 devices 表上的唯一约束冲突转换为 core.DuplicateEntryError。
+func translateUniqueError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
+		return err
+	}
+	switch {
+	case strings.Contains(pgErr.ConstraintName, "username"):
+		return &core.DuplicateEntryError{Field: "username"}
+	case strings.Contains(pgErr.ConstraintName, "email"):
+		return &core.DuplicateEntryError{Field: "email"}
+	case pgErr.ConstraintName == "idx_devices_user_device":
+		return &core.DuplicateEntryError{Field: "device_id"}
+	default:
+		return &core.DuplicateEntryError{Field: pgErr.ConstraintName}
+	}
+}
+
+func (r *userRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user core.User
+		if err := tx.First(&user, "id = ?", id).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return core.ErrUserNotFound
+			}
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&core.VaultItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("email = ?", user.Email).Delete(&core.VerificationCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&core.Device{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&core.LoginApproval{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&core.APIKey{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&core.User{}, "id = ?", id).Error
+	})
 }
 
 // --- 保险库存储库实现 ---
@@ -94,6 +266,14 @@ func (r *vaultRepository) Create(ctx context.Context, item *core.VaultItem) erro
 	return r.db.WithContext(ctx).Create(item).Error
 }
 
+func (r *vaultRepository) CreateMany(ctx context.Context, items []core.VaultItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	// CreateInBatches 在默认事务中执行所有批次，任一批次失败时全部回滚。
+	return r.db.WithContext(ctx).CreateInBatches(&items, 500).Error
+}
+
 func (r *vaultRepository) FindByID(ctx context.Context, id uuid.UUID) (*core.VaultItem, error) {
 	var item core.VaultItem
 	err := r.db.WithContext(ctx).First(&item, "id = ?", id).Error
@@ -112,12 +292,43 @@ func (r *vaultRepository) FindByUser(ctx context.Context, userID uuid.UUID) ([]c
 	return items, err
 }
 
+func (r *vaultRepository) CountByUser(ctx context.Context) (map[uuid.UUID]int64, error) {
+	var rows []struct {
+		UserID uuid.UUID
+		Count  int64
+	}
+	err := r.db.WithContext(ctx).Model(&core.VaultItem{}).
+		Select("user_id, COUNT(*) AS count").Group("user_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[uuid.UUID]int64, len(rows))
+	for _, row := range rows {
+		counts[row.UserID] = row.Count
+	}
+	return counts, nil
+}
+
 func (r *vaultRepository) Update(ctx context.Context, item *core.VaultItem) error {
-	return r.db.WithContext(ctx).Save(item).Error
+	result := r.db.WithContext(ctx).Model(item).Select("*").Updates(item)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return core.ErrVaultItemNotFound
+	}
+	return nil
 }
 
 func (r *vaultRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&core.VaultItem{}, id).Error
+	result := r.db.WithContext(ctx).Delete(&core.VaultItem{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return core.ErrVaultItemNotFound
+	}
+	return nil
 }
 
 // --- 验证码存储库实现 ---
@@ -149,4 +360,357 @@ func (r *verificationCodeRepository) Find(ctx context.Context, email string) (*c
 
 func (r *verificationCodeRepository) Delete(ctx context.Context, email string) error {
 	return r.db.WithContext(ctx).Where("email = ?", email).Delete(&core.VerificationCode{}).Error
-}
\ No newline at end of file
+}
+
+func (r *verificationCodeRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&core.VerificationCode{})
+	return result.RowsAffected, result.Error
+}
+
+// --- 审计事件存储库实现 ---
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+// auditChainLockKey 是串行化审计链写入所用的事务级咨询锁的键。
+const auditChainLockKey = 0x45504155444954 // "EPAUDIT"
+
+func (r *auditRepository) Create(ctx context.Context, event *core.AuditEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 同一时间只允许一个事务追加到链上，确保序号和前驱哈希不会交错。
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLockKey).Error; err != nil {
+			return err
+		}
+
+		id, err := uuid.NewV7()
+		if err != nil {
+			return err
+		}
+		event.ID = id
+		event.CreatedAt = event.CreatedAt.UTC().Truncate(time.Microsecond)
+
+		event.Sequence = 1
+		event.PrevHash = ""
+		var prev core.AuditEvent
+		err = tx.Order("sequence DESC").Take(&prev).Error
+		if err == nil {
+			event.Sequence = prev.Sequence + 1
+			event.PrevHash = prev.Hash
+		} else if err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		event.UserPrevHash = ""
+		var userPrev core.AuditEvent
+		err = tx.Where("user_id = ?", event.UserID).Order("sequence DESC").Take(&userPrev).Error
+		if err == nil {
+			event.UserPrevHash = userPrev.Hash
+		} else if err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		event.Hash = event.ComputeHash()
+		return tx.Create(event).Error
+	})
+}
+
+func (r *auditRepository) FindByUser(ctx context.Context, userID uuid.UUID, offset, limit int) ([]core.AuditEvent, int64, error) {
+	var total int64
+	query := r.db.WithContext(ctx).Model(&core.AuditEvent{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []core.AuditEvent
+	err := query.Order("sequence DESC").Offset(offset).Limit(limit).Find(&events).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+func (r *auditRepository) ForEach(ctx context.Context, fn func(event *core.AuditEvent) error) error {
+	const batchSize = 500
+	var last int64
+	for {
+		var batch []core.AuditEvent
+		err := r.db.WithContext(ctx).Where("sequence > ?", last).Order("sequence ASC").Limit(batchSize).Find(&batch).Error
+		if err != nil {
+			return err
+		}
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+		if len(batch) < batchSize {
+			return nil
+		}
+		last = batch[len(batch)-1].Sequence
+	}
+}
+
+// --- 设备存储库实现 ---
+
+type deviceRepository struct {
+	db *gorm.DB
+}
+
+func (r *deviceRepository) Create(ctx context.Context, device *core.Device) error {
+	return translateUniqueError(r.db.WithContext(ctx).Create(device).Error)
+}
+
+func (r *deviceRepository) FindByID(ctx context.Context, id uuid.UUID) (*core.Device, error) {
+	var device core.Device
+	err := r.db.WithContext(ctx).First(&device, "id = ?", id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, core.ErrDeviceNotFound
+		}
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (r *deviceRepository) FindByUserAndDeviceID(ctx context.Context, userID uuid.UUID, deviceID string) (*core.Device, error) {
+	var device core.Device
+	err := r.db.WithContext(ctx).Where("user_id = ? AND device_id = ?", userID, deviceID).Take(&device).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, core.ErrDeviceNotFound
+		}
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (r *deviceRepository) FindByUser(ctx context.Context, userID uuid.UUID) ([]core.Device, error) {
+	var devices []core.Device
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("last_seen_at DESC").Find(&devices).Error
+	return devices, err
+}
+
+func (r *deviceRepository) Update(ctx context.Context, device *core.Device) error {
+	result := r.db.WithContext(ctx).Model(device).Select("*").Updates(device)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return core.ErrDeviceNotFound
+	}
+	return nil
+}
+
+func (r *deviceRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Delete(&core.Device{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return core.ErrDeviceNotFound
+	}
+	return nil
+}
+
+// --- 待批准登录存储库实现 ---
+
+type loginApprovalRepository struct {
+	db *gorm.DB
+}
+
+func (r *loginApprovalRepository) Create(ctx context.Context, approval *core.LoginApproval) error {
+	if approval.ID == uuid.Nil {
+		approval.ID = uuid.New()
+	}
+	return r.db.WithContext(ctx).Create(approval).Error
+}
+
+func (r *loginApprovalRepository) FindByID(ctx context.Context, id uuid.UUID) (*core.LoginApproval, error) {
+	var approval core.LoginApproval
+	err := r.db.WithContext(ctx).First(&approval, "id = ?", id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, core.ErrLoginApprovalNotFound
+		}
+		return nil, err
+	}
+	return &approval, nil
+}
+
+func (r *loginApprovalRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*core.LoginApproval, error) {
+	var approval core.LoginApproval
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).Take(&approval).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, core.ErrLoginApprovalNotFound
+		}
+		return nil, err
+	}
+	return &approval, nil
+}
+
+func (r *loginApprovalRepository) Update(ctx context.Context, approval *core.LoginApproval) error {
+	result := r.db.WithContext(ctx).Model(approval).Select("*").Updates(approval)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return core.ErrLoginApprovalNotFound
+	}
+	return nil
+}
+
+func (r *loginApprovalRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&core.LoginApproval{}, "id = ?", id).Error
+}
+
+// --- API 密钥存储库实现 ---
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *core.APIKey) error {
+	if key.ID == uuid.Nil {
+		key.ID = uuid.New()
+	}
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *apiKeyRepository) FindByID(ctx context.Context, id uuid.UUID) (*core.APIKey, error) {
+	var key core.APIKey
+	err := r.db.WithContext(ctx).First(&key, "id = ?", id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, core.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) FindByUser(ctx context.Context, userID uuid.UUID) ([]core.APIKey, error) {
+	var keys []core.APIKey
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *apiKeyRepository) Update(ctx context.Context, key *core.APIKey) error {
+	result := r.db.WithContext(ctx).Model(key).Select("*").Updates(key)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return core.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (r *apiKeyRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Delete(&core.APIKey{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return core.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// --- 批量迁移存储库实现 ---
+
+type bulkRepository struct {
+	db *gorm.DB
+}
+
+func (r *bulkRepository) Count(ctx context.Context, kind core.RecordKind) (int64, error) {
+	model := core.NewRecord(kind)
+	if model == nil {
+		return 0, fmt.Errorf("unknown record kind %q", kind)
+	}
+	var n int64
+	err := r.db.WithContext(ctx).Model(model).Count(&n).Error
+	return n, err
+}
+
+func (r *bulkRepository) ForEach(ctx context.Context, kind core.RecordKind, fn func(record any) error) error {
+	db := r.db.WithContext(ctx)
+	switch kind {
+	case core.RecordUsers:
+		return forEachRecord[core.User](db, fn)
+	case core.RecordVaultItems:
+		return forEachRecord[core.VaultItem](db, fn)
+	case core.RecordVerificationCodes:
+		return forEachRecord[core.VerificationCode](db, fn)
+	case core.RecordDevices:
+		return forEachRecord[core.Device](db, fn)
+	case core.RecordLoginApprovals:
+		return forEachRecord[core.LoginApproval](db, fn)
+	case core.RecordAPIKeys:
+		return forEachRecord[core.APIKey](db, fn)
+	case core.RecordAuditEvents:
+		// 审计事件必须按序号而不是主键顺序返回。
+		return (&auditRepository{db: r.db}).ForEach(ctx, func(event *core.AuditEvent) error {
+			return fn(event)
+		})
+	default:
+		return fmt.Errorf("unknown record kind %q", kind)
+	}
+}
+
+// forEachRecord 按主键顺序分批读取 T 类型的全部记录。
+func forEachRecord[T any](db *gorm.DB, fn func(record any) error) error {
+	var batch []T
+	return db.FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			// FindInBatches 会复用 batch，因此传出每条记录的副本。
+			record := batch[i]
+			if err := fn(&record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+func (r *bulkRepository) Insert(ctx context.Context, kind core.RecordKind, records []any) error {
+	if len(records) == 0 {
+		return nil
+	}
+	db := r.db.WithContext(ctx)
+	switch kind {
+	case core.RecordUsers:
+		return insertRecords[core.User](db, records)
+	case core.RecordVaultItems:
+		return insertRecords[core.VaultItem](db, records)
+	case core.RecordVerificationCodes:
+		return insertRecords[core.VerificationCode](db, records)
+	case core.RecordDevices:
+		return insertRecords[core.Device](db, records)
+	case core.RecordLoginApprovals:
+		return insertRecords[core.LoginApproval](db, records)
+	case core.RecordAPIKeys:
+		return insertRecords[core.APIKey](db, records)
+	case core.RecordAuditEvents:
+		return insertRecords[core.AuditEvent](db, records)
+	default:
+		return fmt.Errorf("unknown record kind %q", kind)
+	}
+}
+
+// insertRecords 在一个事务中写入一批 *T 类型的记录。
+// gorm 只为零值的时间戳字段填充当前时间，因此源记录的时间戳会被保留。
+func insertRecords[T any](db *gorm.DB, records []any) error {
+	items := make([]T, len(records))
+	for i, record := range records {
+		item, ok := record.(*T)
+		if !ok {
+			return fmt.Errorf("unexpected record type %T", record)
+		}
+		items[i] = *item
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(items, 500).Error
+	})
+}
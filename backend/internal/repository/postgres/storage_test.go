@@ -0,0 +1,41 @@
+package postgres_test
+
+import (
+	"easy-password-backend/config"
+	"easy-password-backend/internal/repository"
+	"easy-password-backend/internal/repository/postgres"
+	"easy-password-backend/internal/repository/repotest"
+	"os"
+	"testing"
+)
+
+// scratchURLEnv 指定用于测试的 PostgreSQL 数据库。其中的所有表都会在用例之间被删除重建，
+// 不要指向存有数据的数据库。
+const scratchURLEnv = "EP_TEST_POSTGRES_URL"
+
+func TestStorageContract(t *testing.T) {
+	url := os.Getenv(scratchURLEnv)
+	if url == "" {
+		t.Skipf("%s is not set", scratchURLEnv)
+	}
+	repotest.Run(t, func(t *testing.T) repository.Storage {
+		db, err := repository.Connect(&config.Config{DatabaseURL: url})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			t.Fatalf("open database: %v", err)
+		}
+		t.Cleanup(func() { sqlDB.Close() })
+		// 回滚全部迁移再重新迁移，得到一个空的数据库。
+		migrator := postgres.NewMigrator(db)
+		if _, err := migrator.Down(t.Context(), 0); err != nil {
+			t.Fatalf("migrate down: %v", err)
+		}
+		if _, err := migrator.Up(t.Context(), 0); err != nil {
+			t.Fatalf("migrate up: %v", err)
+		}
+		return postgres.NewPostgresStorage(db)
+	})
+}
@@ -0,0 +1,154 @@
+package repotest
+
+import (
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/repository"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var apiKeyCases = []testCase{
+	{"api_key/create_and_find", testAPIKeyCreateAndFind},
+	{"api_key/find_missing", testAPIKeyFindMissing},
+	{"api_key/find_by_user", testAPIKeyFindByUser},
+	{"api_key/update", testAPIKeyUpdate},
+	{"api_key/update_missing", testAPIKeyUpdateMissing},
+	{"api_key/delete", testAPIKeyDelete},
+	{"api_key/user_delete_cascades", testAPIKeyUserDeleteCascades},
+}
+
+// newAPIKey 返回属于 userID 的一个只读 API 密钥。
+func newAPIKey(userID uuid.UUID, name string) *core.APIKey {
+	return &core.APIKey{
+		UserID:     userID,
+		Name:       name,
+		SecretHash: "hash-" + name,
+		Scopes:     []core.APIKeyScope{core.APIKeyScopeVaultRead},
+	}
+}
+
+func testAPIKeyCreateAndFind(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice")
+	expiresAt := time.Now().Add(24 * time.Hour)
+	key := newAPIKey(users[0].ID, "ci")
+	key.Scopes = append(key.Scopes, core.APIKeyScopeVaultWrite)
+	key.ItemIDs = []uuid.UUID{uuid.New(), uuid.New()}
+	key.Categories = []string{"servers"}
+	key.ExpiresAt = &expiresAt
+	mustNoError(t, "Create", s.APIKey().Create(ctx, key))
+	if key.ID == uuid.Nil {
+		t.Fatal("Create did not assign an id")
+	}
+
+	got, err := s.APIKey().FindByID(ctx, key.ID)
+	mustNoError(t, "FindByID", err)
+	if !slices.Equal(got.Scopes, key.Scopes) {
+		t.Errorf("scopes: got %v, want %v", got.Scopes, key.Scopes)
+	}
+	if !slices.Equal(got.ItemIDs, key.ItemIDs) {
+		t.Errorf("item ids: got %v, want %v", got.ItemIDs, key.ItemIDs)
+	}
+	if !slices.Equal(got.Categories, key.Categories) {
+		t.Errorf("categories: got %v, want %v", got.Categories, key.Categories)
+	}
+	if got.ExpiresAt == nil {
+		t.Errorf("expires_at: got nil, want %s", expiresAt)
+	} else {
+		expectTime(t, "expires_at", *got.ExpiresAt, expiresAt)
+	}
+	if got.LastUsedAt != nil {
+		t.Errorf("last_used_at: got %s, want nil", got.LastUsedAt)
+	}
+	expectEqual(t, "user id", got.UserID, key.UserID)
+	expectEqual(t, "name", got.Name, key.Name)
+	expectEqual(t, "secret hash", got.SecretHash, key.SecretHash)
+	expectEqual(t, "created_at set", got.CreatedAt.IsZero(), false)
+}
+
+func testAPIKeyFindMissing(t *testing.T, s repository.Storage) {
+	_, err := s.APIKey().FindByID(t.Context(), uuid.New())
+	expectError(t, "FindByID", err, core.ErrAPIKeyNotFound)
+}
+
+func testAPIKeyFindByUser(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice", "bob")
+	// 显式设置创建时间，确认结果按创建时间而不是按 ID 排序。
+	base := time.Now().Add(-time.Hour)
+	var want []uuid.UUID
+	for i, name := range []string{"first", "second", "third"} {
+		key := newAPIKey(users[0].ID, name)
+		key.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		mustNoError(t, "Create", s.APIKey().Create(ctx, key))
+		want = append(want, key.ID)
+	}
+	mustNoError(t, "Create", s.APIKey().Create(ctx, newAPIKey(users[1].ID, "other")))
+
+	keys, err := s.APIKey().FindByUser(ctx, users[0].ID)
+	mustNoError(t, "FindByUser", err)
+	got := make([]uuid.UUID, 0, len(keys))
+	for _, key := range keys {
+		got = append(got, key.ID)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("FindByUser: got %v, want %v", got, want)
+	}
+
+	keys, err = s.APIKey().FindByUser(ctx, uuid.New())
+	mustNoError(t, "FindByUser without keys", err)
+	expectEqual(t, "keys of unknown user", len(keys), 0)
+}
+
+func testAPIKeyUpdate(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice")
+	key := newAPIKey(users[0].ID, "ci")
+	mustNoError(t, "Create", s.APIKey().Create(ctx, key))
+	usedAt := time.Now()
+	key.LastUsedAt = &usedAt
+	mustNoError(t, "Update", s.APIKey().Update(ctx, key))
+	got, err := s.APIKey().FindByID(ctx, key.ID)
+	mustNoError(t, "FindByID", err)
+	if got.LastUsedAt == nil {
+		t.Fatal("last_used_at: got nil after Update")
+	}
+	expectTime(t, "last_used_at", *got.LastUsedAt, usedAt)
+	expectEqual(t, "name after Update", got.Name, key.Name)
+}
+
+func testAPIKeyUpdateMissing(t *testing.T, s repository.Storage) {
+	users := createUsers(t, s, "alice")
+	key := newAPIKey(users[0].ID, "ci")
+	key.ID = uuid.New()
+	expectError(t, "Update", s.APIKey().Update(t.Context(), key), core.ErrAPIKeyNotFound)
+}
+
+func testAPIKeyDelete(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice")
+	key := newAPIKey(users[0].ID, "ci")
+	mustNoError(t, "Create", s.APIKey().Create(ctx, key))
+	mustNoError(t, "Delete", s.APIKey().Delete(ctx, key.ID))
+	_, err := s.APIKey().FindByID(ctx, key.ID)
+	expectError(t, "FindByID after Delete", err, core.ErrAPIKeyNotFound)
+	expectError(t, "Delete of missing key", s.APIKey().Delete(ctx, key.ID), core.ErrAPIKeyNotFound)
+}
+
+func testAPIKeyUserDeleteCascades(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice", "bob")
+	alice, bob := users[0], users[1]
+	aliceKey, bobKey := newAPIKey(alice.ID, "alice-ci"), newAPIKey(bob.ID, "bob-ci")
+	for _, key := range []*core.APIKey{aliceKey, bobKey} {
+		mustNoError(t, "Create", s.APIKey().Create(ctx, key))
+	}
+	mustNoError(t, "User.Delete", s.User().Delete(ctx, alice.ID))
+	_, err := s.APIKey().FindByID(ctx, aliceKey.ID)
+	expectError(t, "FindByID of deleted user's key", err, core.ErrAPIKeyNotFound)
+	_, err = s.APIKey().FindByID(ctx, bobKey.ID)
+	mustNoError(t, "FindByID of other user's key", err)
+}
@@ -0,0 +1,78 @@
+package repotest
+
+import (
+	"bytes"
+	"easy-password-backend/internal/core"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// timeTolerance 是比较时间戳时允许的误差。PostgreSQL 只保存到微秒，
+// SQLite 和 BoltDB 保存到纳秒，因此不能要求完全相等。
+const timeTolerance = time.Millisecond
+
+// mustNoError 在 err 不为 nil 时终止用例。
+func mustNoError(t *testing.T, op string, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", op, err)
+	}
+}
+
+// expectError 确认 err 是 target。
+func expectError(t *testing.T, op string, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Errorf("%s: got error %v, want %v", op, err, target)
+	}
+}
+
+// expectDuplicate 确认 err 是 field 字段上的 core.DuplicateEntryError。
+func expectDuplicate(t *testing.T, op string, err error, field string) {
+	t.Helper()
+	var dup *core.DuplicateEntryError
+	if !errors.As(err, &dup) {
+		t.Errorf("%s: got error %v, want DuplicateEntryError on %s", op, err, field)
+		return
+	}
+	if dup.Field != field {
+		t.Errorf("%s: DuplicateEntryError on %q, want %q", op, dup.Field, field)
+	}
+}
+
+// expectEqual 确认 got 与 want 相等。
+func expectEqual[T comparable](t *testing.T, what string, got, want T) {
+	t.Helper()
+	if got != want {
+		t.Errorf("%s: got %v, want %v", what, got, want)
+	}
+}
+
+// expectTime 确认两个时间在误差范围内相等。
+func expectTime(t *testing.T, what string, got, want time.Time) {
+	t.Helper()
+	d := got.Sub(want)
+	if d < -timeTolerance || d > timeTolerance {
+		t.Errorf("%s: got %s, want %s", what, got, want)
+	}
+}
+
+// expectJSON 确认两个 JSON 文档语义相等。PostgreSQL 的 jsonb 会重新排列键并去掉空白。
+func expectJSON(t *testing.T, what string, got, want []byte) {
+	t.Helper()
+	var g, w any
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Errorf("%s: stored value is not valid JSON: %v", what, err)
+		return
+	}
+	if err := json.Unmarshal(want, &w); err != nil {
+		t.Fatalf("%s: expected value is not valid JSON: %v", what, err)
+	}
+	gb, _ := json.Marshal(g)
+	wb, _ := json.Marshal(w)
+	if !bytes.Equal(gb, wb) {
+		t.Errorf("%s: got %s, want %s", what, got, want)
+	}
+}
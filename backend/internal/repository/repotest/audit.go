@@ -0,0 +1,196 @@
+package repotest
+
+import (
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/repository"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var auditCases = []testCase{
+	{"audit/create_and_find", testAuditCreateAndFind},
+	{"audit/hash_chain", testAuditHashChain},
+	{"audit/find_by_user_pagination", testAuditFindByUserPagination},
+	{"audit/for_each_order", testAuditForEachOrder},
+	{"audit/for_each_stops", testAuditForEachStops},
+	{"audit/survives_user_delete", testAuditSurvivesUserDelete},
+}
+
+// errStop 用于确认 ForEach 在 fn 返回错误时停止遍历。
+var errStop = errors.New("stop")
+
+// newAuditEvent 返回 userID 的一个登录成功事件。
+func newAuditEvent(userID uuid.UUID) *core.AuditEvent {
+	return &core.AuditEvent{
+		UserID:    userID,
+		Type:      core.AuditEventLoginSuccess,
+		IPAddress: "192.0.2.1",
+		UserAgent: "repotest",
+		Metadata:  map[string]string{"device_id": "laptop"},
+		CreatedAt: time.Now(),
+	}
+}
+
+// createAuditEvents 依次为 userIDs 中的每个用户写入一个事件并返回这些事件。
+func createAuditEvents(t *testing.T, s repository.Storage, userIDs ...uuid.UUID) []*core.AuditEvent {
+	t.Helper()
+	events := make([]*core.AuditEvent, 0, len(userIDs))
+	for _, userID := range userIDs {
+		event := newAuditEvent(userID)
+		mustNoError(t, "Create", s.Audit().Create(t.Context(), event))
+		events = append(events, event)
+	}
+	return events
+}
+
+// auditEventIDs 返回事件的 ID 列表。
+func auditEventIDs(events []core.AuditEvent) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+// compareAuditEvent 比较存储往返后的审计事件。
+func compareAuditEvent(t *testing.T, what string, got, want *core.AuditEvent) {
+	t.Helper()
+	expectEqual(t, what+" id", got.ID, want.ID)
+	expectEqual(t, what+" sequence", got.Sequence, want.Sequence)
+	expectEqual(t, what+" user id", got.UserID, want.UserID)
+	expectEqual(t, what+" type", got.Type, want.Type)
+	expectEqual(t, what+" ip address", got.IPAddress, want.IPAddress)
+	expectEqual(t, what+" user agent", got.UserAgent, want.UserAgent)
+	if !maps.Equal(got.Metadata, want.Metadata) {
+		t.Errorf("%s metadata: got %v, want %v", what, got.Metadata, want.Metadata)
+	}
+	expectTime(t, what+" created_at", got.CreatedAt, want.CreatedAt)
+	expectEqual(t, what+" prev hash", got.PrevHash, want.PrevHash)
+	expectEqual(t, what+" user prev hash", got.UserPrevHash, want.UserPrevHash)
+	expectEqual(t, what+" hash", got.Hash, want.Hash)
+	// 读回的事件必须能重新算出相同的哈希，否则校验审计链时会误报篡改。
+	expectEqual(t, what+" recomputed hash", got.ComputeHash(), want.Hash)
+}
+
+func testAuditCreateAndFind(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice")
+	event := createAuditEvents(t, s, users[0].ID)[0]
+	if event.ID == uuid.Nil {
+		t.Fatal("Create did not assign an ID")
+	}
+	expectEqual(t, "sequence", event.Sequence, int64(1))
+	expectEqual(t, "hash", event.Hash, event.ComputeHash())
+
+	events, total, err := s.Audit().FindByUser(ctx, users[0].ID, 0, 10)
+	mustNoError(t, "FindByUser", err)
+	expectEqual(t, "total", total, int64(1))
+	if len(events) != 1 {
+		t.Fatalf("FindByUser: got %d events, want 1", len(events))
+	}
+	compareAuditEvent(t, "FindByUser", &events[0], event)
+
+	events, total, err = s.Audit().FindByUser(ctx, uuid.New(), 0, 10)
+	mustNoError(t, "FindByUser(unknown user)", err)
+	expectEqual(t, "total of unknown user", total, int64(0))
+	expectEqual(t, "events of unknown user", len(events), 0)
+}
+
+func testAuditHashChain(t *testing.T, s repository.Storage) {
+	users := createUsers(t, s, "alice", "bob")
+	alice, bob := users[0].ID, users[1].ID
+	events := createAuditEvents(t, s, alice, bob, alice)
+
+	for i, event := range events {
+		expectEqual(t, "sequence", event.Sequence, int64(i+1))
+	}
+	// 全局链依次相连，用户链跳过其他用户的事件。
+	expectEqual(t, "first prev hash", events[0].PrevHash, "")
+	expectEqual(t, "second prev hash", events[1].PrevHash, events[0].Hash)
+	expectEqual(t, "third prev hash", events[2].PrevHash, events[1].Hash)
+	expectEqual(t, "first user prev hash", events[0].UserPrevHash, "")
+	expectEqual(t, "second user prev hash", events[1].UserPrevHash, "")
+	expectEqual(t, "third user prev hash", events[2].UserPrevHash, events[0].Hash)
+}
+
+func testAuditFindByUserPagination(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice", "bob")
+	alice, bob := users[0].ID, users[1].ID
+	events := createAuditEvents(t, s, alice, bob, alice, alice, bob, alice)
+	// alice 的事件，最新的在前。
+	var want []uuid.UUID
+	for _, event := range slices.Backward(events) {
+		if event.UserID == alice {
+			want = append(want, event.ID)
+		}
+	}
+
+	for _, tc := range []struct {
+		offset, limit int
+		want          []uuid.UUID
+	}{
+		{0, 10, want},
+		{0, 2, want[:2]},
+		{2, 2, want[2:4]},
+		{3, 10, want[3:]},
+		{4, 10, nil},
+	} {
+		got, total, err := s.Audit().FindByUser(ctx, alice, tc.offset, tc.limit)
+		mustNoError(t, "FindByUser", err)
+		expectEqual(t, "total", total, int64(len(want)))
+		if ids := auditEventIDs(got); !slices.Equal(ids, tc.want) {
+			t.Errorf("FindByUser(offset=%d, limit=%d): got %v, want %v", tc.offset, tc.limit, ids, tc.want)
+		}
+	}
+}
+
+func testAuditForEachOrder(t *testing.T, s repository.Storage) {
+	users := createUsers(t, s, "alice", "bob")
+	want := createAuditEvents(t, s, users[0].ID, users[1].ID, users[0].ID)
+
+	var got []core.AuditEvent
+	mustNoError(t, "ForEach", s.Audit().ForEach(t.Context(), func(event *core.AuditEvent) error {
+		got = append(got, *event)
+		return nil
+	}))
+	if len(got) != len(want) {
+		t.Fatalf("ForEach: got %d events, want %d", len(got), len(want))
+	}
+	for i := range got {
+		compareAuditEvent(t, "ForEach", &got[i], want[i])
+	}
+}
+
+func testAuditForEachStops(t *testing.T, s repository.Storage) {
+	users := createUsers(t, s, "alice")
+	createAuditEvents(t, s, users[0].ID, users[0].ID, users[0].ID)
+
+	var seen int
+	err := s.Audit().ForEach(t.Context(), func(event *core.AuditEvent) error {
+		seen++
+		return errStop
+	})
+	expectError(t, "ForEach", err, errStop)
+	expectEqual(t, "events seen", seen, 1)
+}
+
+func testAuditSurvivesUserDelete(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice")
+	event := createAuditEvents(t, s, users[0].ID)[0]
+	mustNoError(t, "User.Delete", s.User().Delete(ctx, users[0].ID))
+
+	// 审计日志是只追加的，删除用户不会删除其事件。
+	events, total, err := s.Audit().FindByUser(ctx, users[0].ID, 0, 10)
+	mustNoError(t, "FindByUser", err)
+	expectEqual(t, "total", total, int64(1))
+	if ids := auditEventIDs(events); !slices.Equal(ids, []uuid.UUID{event.ID}) {
+		t.Errorf("FindByUser: got %v, want [%s]", ids, event.ID)
+	}
+}
@@ -0,0 +1,171 @@
+package repotest
+
+import (
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var deviceCases = []testCase{
+	{"device/create_and_find", testDeviceCreateAndFind},
+	{"device/find_missing", testDeviceFindMissing},
+	{"device/create_duplicate", testDeviceCreateDuplicate},
+	{"device/find_by_user", testDeviceFindByUser},
+	{"device/update", testDeviceUpdate},
+	{"device/delete", testDeviceDelete},
+	{"device/user_delete_cascades", testDeviceUserDeleteCascades},
+}
+
+// newDevice 返回属于 userID、客户端设备 ID 为 deviceID 的设备。
+func newDevice(userID uuid.UUID, deviceID string) *core.Device {
+	now := time.Now().UTC()
+	return &core.Device{
+		UserID:      userID,
+		DeviceID:    deviceID,
+		Name:        "device " + deviceID,
+		Type:        "desktop",
+		IPAddress:   "192.0.2.1",
+		FirstSeenAt: now,
+		LastSeenAt:  now,
+	}
+}
+
+// compareDevice 比较存储往返后的设备。
+func compareDevice(t *testing.T, what string, got, want *core.Device) {
+	t.Helper()
+	expectEqual(t, what+" id", got.ID, want.ID)
+	expectEqual(t, what+" user id", got.UserID, want.UserID)
+	expectEqual(t, what+" device id", got.DeviceID, want.DeviceID)
+	expectEqual(t, what+" name", got.Name, want.Name)
+	expectEqual(t, what+" type", got.Type, want.Type)
+	expectEqual(t, what+" ip address", got.IPAddress, want.IPAddress)
+	expectTime(t, what+" first_seen_at", got.FirstSeenAt, want.FirstSeenAt)
+	expectTime(t, what+" last_seen_at", got.LastSeenAt, want.LastSeenAt)
+}
+
+func testDeviceCreateAndFind(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice")
+	device := newDevice(users[0].ID, "laptop")
+	mustNoError(t, "Create", s.Device().Create(ctx, device))
+	if device.ID == uuid.Nil {
+		t.Fatal("Create did not assign an ID")
+	}
+
+	got, err := s.Device().FindByID(ctx, device.ID)
+	mustNoError(t, "FindByID", err)
+	compareDevice(t, "FindByID", got, device)
+	got, err = s.Device().FindByUserAndDeviceID(ctx, device.UserID, device.DeviceID)
+	mustNoError(t, "FindByUserAndDeviceID", err)
+	compareDevice(t, "FindByUserAndDeviceID", got, device)
+}
+
+func testDeviceFindMissing(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice", "bob")
+	mustNoError(t, "Create", s.Device().Create(ctx, newDevice(users[0].ID, "laptop")))
+
+	_, err := s.Device().FindByID(ctx, uuid.New())
+	expectError(t, "FindByID", err, core.ErrDeviceNotFound)
+	_, err = s.Device().FindByUserAndDeviceID(ctx, users[0].ID, "phone")
+	expectError(t, "FindByUserAndDeviceID(unknown device)", err, core.ErrDeviceNotFound)
+	// 设备 ID 由客户端选择，只在同一用户内唯一。
+	_, err = s.Device().FindByUserAndDeviceID(ctx, users[1].ID, "laptop")
+	expectError(t, "FindByUserAndDeviceID(other user)", err, core.ErrDeviceNotFound)
+}
+
+func testDeviceCreateDuplicate(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice", "bob")
+	mustNoError(t, "Create", s.Device().Create(ctx, newDevice(users[0].ID, "laptop")))
+	expectDuplicate(t, "Create with taken device id", s.Device().Create(ctx, newDevice(users[0].ID, "laptop")), "device_id")
+	// 其他用户可以使用同一个设备 ID。
+	mustNoError(t, "Create for other user", s.Device().Create(ctx, newDevice(users[1].ID, "laptop")))
+}
+
+func testDeviceFindByUser(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice", "bob")
+	want := make(map[uuid.UUID]*core.Device)
+	for _, deviceID := range []string{"phone", "laptop", "tablet"} {
+		device := newDevice(users[0].ID, deviceID)
+		mustNoError(t, "Create", s.Device().Create(ctx, device))
+		want[device.ID] = device
+	}
+	mustNoError(t, "Create", s.Device().Create(ctx, newDevice(users[1].ID, "other")))
+
+	got, err := s.Device().FindByUser(ctx, users[0].ID)
+	mustNoError(t, "FindByUser", err)
+	expectEqual(t, "FindByUser count", len(got), len(want))
+	for i := range got {
+		device, ok := want[got[i].ID]
+		if !ok {
+			t.Errorf("FindByUser returned device %s of another user", got[i].ID)
+			continue
+		}
+		compareDevice(t, "FindByUser", &got[i], device)
+	}
+
+	none, err := s.Device().FindByUser(ctx, uuid.New())
+	mustNoError(t, "FindByUser(unknown user)", err)
+	expectEqual(t, "devices of unknown user", len(none), 0)
+}
+
+func testDeviceUpdate(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice")
+	device := newDevice(users[0].ID, "laptop")
+	mustNoError(t, "Create", s.Device().Create(ctx, device))
+
+	device.Name = "renamed"
+	device.IPAddress = "198.51.100.7"
+	device.LastSeenAt = device.LastSeenAt.Add(time.Hour)
+	mustNoError(t, "Update", s.Device().Update(ctx, device))
+	got, err := s.Device().FindByID(ctx, device.ID)
+	mustNoError(t, "FindByID", err)
+	compareDevice(t, "FindByID after Update", got, device)
+
+	missing := newDevice(users[0].ID, "phone")
+	missing.ID = uuid.New()
+	expectError(t, "Update of missing device", s.Device().Update(ctx, missing), core.ErrDeviceNotFound)
+}
+
+func testDeviceDelete(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice")
+	keep, drop := newDevice(users[0].ID, "laptop"), newDevice(users[0].ID, "phone")
+	for _, device := range []*core.Device{keep, drop} {
+		mustNoError(t, "Create", s.Device().Create(ctx, device))
+	}
+	mustNoError(t, "Delete", s.Device().Delete(ctx, drop.ID))
+	_, err := s.Device().FindByID(ctx, drop.ID)
+	expectError(t, "FindByID after Delete", err, core.ErrDeviceNotFound)
+	_, err = s.Device().FindByUserAndDeviceID(ctx, drop.UserID, drop.DeviceID)
+	expectError(t, "FindByUserAndDeviceID after Delete", err, core.ErrDeviceNotFound)
+	_, err = s.Device().FindByID(ctx, keep.ID)
+	mustNoError(t, "FindByID of remaining device", err)
+
+	// 撤销后同一设备重新登录会登记一条 ID 不同的新记录。
+	again := newDevice(users[0].ID, "phone")
+	mustNoError(t, "Create after Delete", s.Device().Create(ctx, again))
+	if again.ID == drop.ID {
+		t.Error("Create after Delete reused the deleted device's ID")
+	}
+}
+
+func testDeviceUserDeleteCascades(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice", "bob")
+	aliceDevice, bobDevice := newDevice(users[0].ID, "laptop"), newDevice(users[1].ID, "laptop")
+	for _, device := range []*core.Device{aliceDevice, bobDevice} {
+		mustNoError(t, "Create", s.Device().Create(ctx, device))
+	}
+	mustNoError(t, "User.Delete", s.User().Delete(ctx, users[0].ID))
+	_, err := s.Device().FindByID(ctx, aliceDevice.ID)
+	expectError(t, "FindByID of deleted user's device", err, core.ErrDeviceNotFound)
+	_, err = s.Device().FindByID(ctx, bobDevice.ID)
+	mustNoError(t, "FindByID of other user's device", err)
+}
@@ -0,0 +1,127 @@
+package repotest
+
+import (
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var loginApprovalCases = []testCase{
+	{"login_approval/create_and_find", testLoginApprovalCreateAndFind},
+	{"login_approval/find_missing", testLoginApprovalFindMissing},
+	{"login_approval/update", testLoginApprovalUpdate},
+	{"login_approval/delete", testLoginApprovalDelete},
+	{"login_approval/user_delete_cascades", testLoginApprovalUserDeleteCascades},
+}
+
+// newLoginApproval 返回 userID 的一个待批准登录，token 用于区分批准链接。
+func newLoginApproval(userID uuid.UUID, token string) *core.LoginApproval {
+	return &core.LoginApproval{
+		UserID:     userID,
+		DeviceID:   "device-" + token,
+		DeviceName: "Laptop",
+		DeviceType: "desktop",
+		IPAddress:  "192.0.2.1",
+		CodeHash:   "code-" + token,
+		TokenHash:  "token-" + token,
+		Status:     core.LoginApprovalPending,
+		ExpiresAt:  time.Now().Add(15 * time.Minute),
+	}
+}
+
+// compareLoginApproval 比较存储往返后的待批准登录。
+func compareLoginApproval(t *testing.T, what string, got, want *core.LoginApproval) {
+	t.Helper()
+	expectEqual(t, what+" id", got.ID, want.ID)
+	expectEqual(t, what+" user id", got.UserID, want.UserID)
+	expectEqual(t, what+" device id", got.DeviceID, want.DeviceID)
+	expectEqual(t, what+" device name", got.DeviceName, want.DeviceName)
+	expectEqual(t, what+" device type", got.DeviceType, want.DeviceType)
+	expectEqual(t, what+" ip address", got.IPAddress, want.IPAddress)
+	expectEqual(t, what+" code hash", got.CodeHash, want.CodeHash)
+	expectEqual(t, what+" token hash", got.TokenHash, want.TokenHash)
+	expectEqual(t, what+" attempts", got.Attempts, want.Attempts)
+	expectEqual(t, what+" status", got.Status, want.Status)
+	expectTime(t, what+" expires_at", got.ExpiresAt, want.ExpiresAt)
+}
+
+func testLoginApprovalCreateAndFind(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice")
+	approval := newLoginApproval(users[0].ID, "a")
+	mustNoError(t, "Create", s.LoginApproval().Create(ctx, approval))
+	if approval.ID == uuid.Nil {
+		t.Fatal("Create did not assign an ID")
+	}
+
+	got, err := s.LoginApproval().FindByID(ctx, approval.ID)
+	mustNoError(t, "FindByID", err)
+	compareLoginApproval(t, "FindByID", got, approval)
+	got, err = s.LoginApproval().FindByTokenHash(ctx, approval.TokenHash)
+	mustNoError(t, "FindByTokenHash", err)
+	compareLoginApproval(t, "FindByTokenHash", got, approval)
+}
+
+func testLoginApprovalFindMissing(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice")
+	mustNoError(t, "Create", s.LoginApproval().Create(ctx, newLoginApproval(users[0].ID, "a")))
+
+	_, err := s.LoginApproval().FindByID(ctx, uuid.New())
+	expectError(t, "FindByID", err, core.ErrLoginApprovalNotFound)
+	_, err = s.LoginApproval().FindByTokenHash(ctx, "token-b")
+	expectError(t, "FindByTokenHash", err, core.ErrLoginApprovalNotFound)
+}
+
+func testLoginApprovalUpdate(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice")
+	approval := newLoginApproval(users[0].ID, "a")
+	mustNoError(t, "Create", s.LoginApproval().Create(ctx, approval))
+
+	approval.Attempts = 2
+	approval.Status = core.LoginApprovalApproved
+	mustNoError(t, "Update", s.LoginApproval().Update(ctx, approval))
+	got, err := s.LoginApproval().FindByID(ctx, approval.ID)
+	mustNoError(t, "FindByID", err)
+	compareLoginApproval(t, "FindByID after Update", got, approval)
+
+	missing := newLoginApproval(users[0].ID, "b")
+	missing.ID = uuid.New()
+	expectError(t, "Update of missing approval", s.LoginApproval().Update(ctx, missing), core.ErrLoginApprovalNotFound)
+}
+
+func testLoginApprovalDelete(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice")
+	keep, drop := newLoginApproval(users[0].ID, "a"), newLoginApproval(users[0].ID, "b")
+	for _, approval := range []*core.LoginApproval{keep, drop} {
+		mustNoError(t, "Create", s.LoginApproval().Create(ctx, approval))
+	}
+	mustNoError(t, "Delete", s.LoginApproval().Delete(ctx, drop.ID))
+	_, err := s.LoginApproval().FindByID(ctx, drop.ID)
+	expectError(t, "FindByID after Delete", err, core.ErrLoginApprovalNotFound)
+	_, err = s.LoginApproval().FindByTokenHash(ctx, drop.TokenHash)
+	expectError(t, "FindByTokenHash after Delete", err, core.ErrLoginApprovalNotFound)
+	_, err = s.LoginApproval().FindByID(ctx, keep.ID)
+	mustNoError(t, "FindByID of remaining approval", err)
+	// 批准完成后会删除记录，重复删除不应报错。
+	mustNoError(t, "Delete of missing approval", s.LoginApproval().Delete(ctx, drop.ID))
+}
+
+func testLoginApprovalUserDeleteCascades(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice", "bob")
+	aliceApproval, bobApproval := newLoginApproval(users[0].ID, "a"), newLoginApproval(users[1].ID, "b")
+	for _, approval := range []*core.LoginApproval{aliceApproval, bobApproval} {
+		mustNoError(t, "Create", s.LoginApproval().Create(ctx, approval))
+	}
+	mustNoError(t, "User.Delete", s.User().Delete(ctx, users[0].ID))
+	_, err := s.LoginApproval().FindByID(ctx, aliceApproval.ID)
+	expectError(t, "FindByID of deleted user's approval", err, core.ErrLoginApprovalNotFound)
+	_, err = s.LoginApproval().FindByID(ctx, bobApproval.ID)
+	mustNoError(t, "FindByID of other user's approval", err)
+}
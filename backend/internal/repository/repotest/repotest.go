@@ -0,0 +1,32 @@
+// Package repotest 是 repository.Storage 实现的一致性测试套件。
+//
+// 套件为每个存储库接口的方法约定了行为和错误语义，所有存储后端都必须通过同一组用例。
+// 各后端在自己的 _test.go 中调用 Run；每个用例都在 Factory 新建的空存储上运行，用例之间互不影响。
+package repotest
+
+import (
+	"easy-password-backend/internal/repository"
+	"slices"
+	"testing"
+)
+
+// Factory 返回一个空的、schema 已是最新版本的存储。存储需要释放时，Factory 用 t.Cleanup 注册释放函数。
+type Factory func(t *testing.T) repository.Storage
+
+// testCase 是套件中的一个用例。
+type testCase struct {
+	name string
+	run  func(t *testing.T, s repository.Storage)
+}
+
+// Run 将每个用例作为 t 的子测试运行，每个子测试使用 newStorage 新建的存储。
+func Run(t *testing.T, newStorage Factory) {
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.run(t, newStorage(t))
+		})
+	}
+}
+
+// cases 是套件的全部用例，按存储库分组。
+var cases = slices.Concat(userCases, vaultCases, verificationCodeCases, auditCases, deviceCases, loginApprovalCases, apiKeyCases)
@@ -0,0 +1,338 @@
+package repotest
+
+import (
+	"bytes"
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/repository"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var userCases = []testCase{
+	{"user/create_and_find", testUserCreateAndFind},
+	{"user/find_missing", testUserFindMissing},
+	{"user/create_duplicate", testUserCreateDuplicate},
+	{"user/update", testUserUpdate},
+	{"user/update_duplicate", testUserUpdateDuplicate},
+	{"user/update_missing", testUserUpdateMissing},
+	{"user/list", testUserList},
+	{"user/clear_expired_reset_tokens", testUserClearExpiredResetTokens},
+	{"user/delete", testUserDelete},
+	{"user/delete_missing", testUserDeleteMissing},
+}
+
+// newUser 返回一个可以直接写入的用户。
+func newUser(name string) *core.User {
+	return &core.User{
+		Username:   name,
+		Email:      name + "@example.com",
+		AuthHash:   "hash-" + name,
+		MasterSalt: []byte("salt-" + name),
+		Status:     core.UserStatusActive,
+		Role:       core.UserRoleUser,
+	}
+}
+
+// createUsers 依次创建指定名称的用户。
+func createUsers(t *testing.T, s repository.Storage, names ...string) []*core.User {
+	t.Helper()
+	users := make([]*core.User, 0, len(names))
+	for _, name := range names {
+		user := newUser(name)
+		mustNoError(t, "create user "+name, s.User().Create(t.Context(), user))
+		users = append(users, user)
+	}
+	return users
+}
+
+// compareUser 比较存储往返后的用户。
+func compareUser(t *testing.T, what string, got, want *core.User) {
+	t.Helper()
+	if !bytes.Equal(got.MasterSalt, want.MasterSalt) {
+		t.Errorf("%s: master salt %q, want %q", what, got.MasterSalt, want.MasterSalt)
+	}
+	expectEqual(t, what+" id", got.ID, want.ID)
+	expectEqual(t, what+" username", got.Username, want.Username)
+	expectEqual(t, what+" email", got.Email, want.Email)
+	expectEqual(t, what+" auth hash", got.AuthHash, want.AuthHash)
+	expectEqual(t, what+" status", got.AccountStatus(), want.AccountStatus())
+	expectEqual(t, what+" role", got.AccountRole(), want.AccountRole())
+	expectEqual(t, what+" require device approval", got.RequireDeviceApproval, want.RequireDeviceApproval)
+}
+
+func testUserCreateAndFind(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	user := newUser("alice")
+	mustNoError(t, "Create", s.User().Create(ctx, user))
+	if user.ID == uuid.Nil {
+		t.Fatal("Create did not assign an ID")
+	}
+	if user.CreatedAt.IsZero() {
+		t.Error("Create did not set CreatedAt")
+	}
+
+	lookups := []struct {
+		name string
+		find func() (*core.User, error)
+	}{
+		{"FindByID", func() (*core.User, error) { return s.User().FindByID(ctx, user.ID) }},
+		{"FindByUsername", func() (*core.User, error) { return s.User().FindByUsername(ctx, user.Username) }},
+		{"FindByEmail", func() (*core.User, error) { return s.User().FindByEmail(ctx, user.Email) }},
+	}
+	for _, lookup := range lookups {
+		got, err := lookup.find()
+		mustNoError(t, lookup.name, err)
+		compareUser(t, lookup.name, got, user)
+		expectTime(t, lookup.name+" created_at", got.CreatedAt, user.CreatedAt)
+	}
+}
+
+func testUserFindMissing(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	createUsers(t, s, "alice")
+	_, err := s.User().FindByID(ctx, uuid.New())
+	expectError(t, "FindByID", err, core.ErrUserNotFound)
+	_, err = s.User().FindByUsername(ctx, "nobody")
+	expectError(t, "FindByUsername", err, core.ErrUserNotFound)
+	_, err = s.User().FindByEmail(ctx, "nobody@example.com")
+	expectError(t, "FindByEmail", err, core.ErrUserNotFound)
+	_, err = s.User().FindByResetPasswordToken(ctx, "no-such-token")
+	expectError(t, "FindByResetPasswordToken", err, core.ErrUserNotFound)
+}
+
+func testUserCreateDuplicate(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	createUsers(t, s, "alice")
+
+	sameName := newUser("alice")
+	sameName.Email = "other@example.com"
+	expectDuplicate(t, "Create with taken username", s.User().Create(ctx, sameName), "username")
+	// 失败的写入不能留下任何数据，包括索引。
+	_, err := s.User().FindByEmail(ctx, "other@example.com")
+	expectError(t, "FindByEmail after failed Create", err, core.ErrUserNotFound)
+
+	sameEmail := newUser("bob")
+	sameEmail.Email = "alice@example.com"
+	expectDuplicate(t, "Create with taken email", s.User().Create(ctx, sameEmail), "email")
+	_, err = s.User().FindByUsername(ctx, "bob")
+	expectError(t, "FindByUsername after failed Create", err, core.ErrUserNotFound)
+}
+
+func testUserUpdate(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	user := createUsers(t, s, "alice")[0]
+
+	token := "reset-token"
+	expires := time.Now().Add(time.Hour).UTC()
+	revoked := time.Now().UTC()
+	user.Username = "alicia"
+	user.Email = "alicia@example.com"
+	user.AuthHash = "new-hash"
+	user.MasterSalt = []byte("new-salt")
+	user.ResetPasswordToken = &token
+	user.ResetPasswordTokenExpiresAt = &expires
+	user.RequireDeviceApproval = true
+	user.Status = core.UserStatusSuspended
+	user.Role = core.UserRoleAdmin
+	user.SessionsRevokedAt = &revoked
+	pendingEmail, pendingCodeHash := "alicia@example.org", "code-hash"
+	user.PendingEmail = &pendingEmail
+	user.PendingEmailCodeHash = &pendingCodeHash
+	user.PendingEmailExpiresAt = &expires
+	mustNoError(t, "Update", s.User().Update(ctx, user))
+
+	got, err := s.User().FindByID(ctx, user.ID)
+	mustNoError(t, "FindByID", err)
+	compareUser(t, "FindByID after Update", got, user)
+	if got.SessionsRevokedAt == nil {
+		t.Error("SessionsRevokedAt was not stored")
+	} else {
+		expectTime(t, "sessions_revoked_at", *got.SessionsRevokedAt, revoked)
+	}
+	if got.PendingEmail == nil || got.PendingEmailCodeHash == nil || got.PendingEmailExpiresAt == nil {
+		t.Error("pending email change was not stored")
+	} else {
+		expectEqual(t, "pending email", *got.PendingEmail, pendingEmail)
+		expectEqual(t, "pending email code hash", *got.PendingEmailCodeHash, pendingCodeHash)
+		expectTime(t, "pending_email_expires_at", *got.PendingEmailExpiresAt, expires)
+	}
+
+	// 用户名和邮箱的查找必须跟随修改。
+	_, err = s.User().FindByUsername(ctx, "alice")
+	expectError(t, "FindByUsername(old)", err, core.ErrUserNotFound)
+	_, err = s.User().FindByEmail(ctx, "alice@example.com")
+	expectError(t, "FindByEmail(old)", err, core.ErrUserNotFound)
+	_, err = s.User().FindByUsername(ctx, "alicia")
+	mustNoError(t, "FindByUsername(new)", err)
+	_, err = s.User().FindByEmail(ctx, "alicia@example.com")
+	mustNoError(t, "FindByEmail(new)", err)
+	byToken, err := s.User().FindByResetPasswordToken(ctx, token)
+	mustNoError(t, "FindByResetPasswordToken", err)
+	expectEqual(t, "FindByResetPasswordToken id", byToken.ID, user.ID)
+
+	// 旧的用户名和邮箱释放后可以被其他用户使用。
+	createUsers(t, s, "alice")
+}
+
+func testUserUpdateDuplicate(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice", "bob")
+	alice := users[0]
+
+	changed := *alice
+	changed.Username = "bob"
+	expectDuplicate(t, "Update to taken username", s.User().Update(ctx, &changed), "username")
+	changed = *alice
+	changed.Email = "bob@example.com"
+	expectDuplicate(t, "Update to taken email", s.User().Update(ctx, &changed), "email")
+
+	got, err := s.User().FindByID(ctx, alice.ID)
+	mustNoError(t, "FindByID", err)
+	compareUser(t, "user after failed Update", got, alice)
+	bob, err := s.User().FindByUsername(ctx, "bob")
+	mustNoError(t, "FindByUsername(bob)", err)
+	expectEqual(t, "owner of username bob", bob.ID, users[1].ID)
+}
+
+func testUserUpdateMissing(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	ghost := newUser("ghost")
+	ghost.ID = uuid.New()
+	expectError(t, "Update of missing user", s.User().Update(ctx, ghost), core.ErrUserNotFound)
+	// Update 不能顺带创建用户。
+	_, err := s.User().FindByID(ctx, ghost.ID)
+	expectError(t, "FindByID after failed Update", err, core.ErrUserNotFound)
+}
+
+func testUserList(t *testing.T, s repository.Storage) {
+	names := []string{"carol", "Alice_1", "bob", "alice%2"}
+	for _, name := range names {
+		createUsers(t, s, name)
+		// 保证注册时间严格递增，List 按注册时间排序。
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	checks := []struct {
+		query         string
+		offset, limit int
+		want          []string
+		total         int64
+	}{
+		{"", 0, 0, names, 4},
+		{"", 1, 2, []string{"Alice_1", "bob"}, 4},
+		{"", 3, 10, []string{"alice%2"}, 4},
+		{"", 10, 10, []string{}, 4},
+		{"ALICE", 0, 0, []string{"Alice_1", "alice%2"}, 2},
+		{"example.com", 0, 1, []string{"carol"}, 4},
+		// 通配符必须按字面匹配。
+		{"_", 0, 0, []string{"Alice_1"}, 1},
+		{"%", 0, 0, []string{"alice%2"}, 1},
+		{"nobody", 0, 0, []string{}, 0},
+	}
+	for _, check := range checks {
+		what := fmt.Sprintf("List(%q, %d, %d)", check.query, check.offset, check.limit)
+		users, total, err := s.User().List(t.Context(), check.query, check.offset, check.limit)
+		mustNoError(t, what, err)
+		got := make([]string, 0, len(users))
+		for _, user := range users {
+			got = append(got, user.Username)
+		}
+		expectEqual(t, what+" total", total, check.total)
+		if fmt.Sprint(got) != fmt.Sprint(check.want) {
+			t.Errorf("%s: got %v, want %v", what, got, check.want)
+		}
+	}
+}
+
+func testUserClearExpiredResetTokens(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "expired", "valid", "none")
+	now := time.Now()
+	for i, expires := range []time.Time{now.Add(-time.Hour), now.Add(time.Hour)} {
+		token := fmt.Sprintf("token-%d", i)
+		users[i].ResetPasswordToken = &token
+		users[i].ResetPasswordTokenExpiresAt = &expires
+		mustNoError(t, "Update", s.User().Update(ctx, users[i]))
+	}
+
+	n, err := s.User().ClearExpiredResetTokens(ctx, now)
+	mustNoError(t, "ClearExpiredResetTokens", err)
+	expectEqual(t, "cleared tokens", n, int64(1))
+	expired, err := s.User().FindByID(ctx, users[0].ID)
+	mustNoError(t, "FindByID", err)
+	if expired.ResetPasswordToken != nil || expired.ResetPasswordTokenExpiresAt != nil {
+		t.Error("expired reset token was not cleared")
+	}
+	_, err = s.User().FindByResetPasswordToken(ctx, "token-1")
+	mustNoError(t, "FindByResetPasswordToken(valid)", err)
+
+	n, err = s.User().ClearExpiredResetTokens(ctx, now)
+	mustNoError(t, "ClearExpiredResetTokens", err)
+	expectEqual(t, "cleared tokens on second run", n, int64(0))
+}
+
+func testUserDelete(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice", "bob")
+	alice, bob := users[0], users[1]
+
+	data := json.RawMessage(`{"ciphertext":"x"}`)
+	items := []core.VaultItem{
+		{UserID: alice.ID, EncryptedData: data},
+		{UserID: alice.ID, EncryptedData: data},
+		{UserID: bob.ID, EncryptedData: data},
+	}
+	mustNoError(t, "CreateMany", s.Vault().CreateMany(ctx, items))
+	for _, email := range []string{alice.Email, bob.Email} {
+		vc := &core.VerificationCode{Email: email, Code: "123456", ExpiresAt: time.Now().Add(time.Hour)}
+		mustNoError(t, "VerificationCode.Create", s.VerificationCode().Create(ctx, vc))
+	}
+	pendingEmail, pendingCodeHash := "alice@example.org", "code-hash"
+	pendingExpires := time.Now().Add(time.Hour)
+	alice.PendingEmail = &pendingEmail
+	alice.PendingEmailCodeHash = &pendingCodeHash
+	alice.PendingEmailExpiresAt = &pendingExpires
+	mustNoError(t, "Update", s.User().Update(ctx, alice))
+
+	mustNoError(t, "Delete", s.User().Delete(ctx, alice.ID))
+
+	_, err := s.User().FindByID(ctx, alice.ID)
+	expectError(t, "FindByID after Delete", err, core.ErrUserNotFound)
+	_, err = s.User().FindByUsername(ctx, alice.Username)
+	expectError(t, "FindByUsername after Delete", err, core.ErrUserNotFound)
+	_, err = s.User().FindByEmail(ctx, alice.Email)
+	expectError(t, "FindByEmail after Delete", err, core.ErrUserNotFound)
+	left, err := s.Vault().FindByUser(ctx, alice.ID)
+	mustNoError(t, "Vault.FindByUser", err)
+	expectEqual(t, "vault items left for deleted user", len(left), 0)
+	_, err = s.VerificationCode().Find(ctx, alice.Email)
+	expectError(t, "verification code of deleted user", err, core.ErrVerificationCodeNotFound)
+	// 待确认的邮箱更换随用户记录一起删除，存储中不再有任何记录带有新邮箱或验证码哈希。
+	err = s.Bulk().ForEach(ctx, core.RecordUsers, func(record any) error {
+		user := record.(*core.User)
+		if user.PendingEmail != nil && *user.PendingEmail == pendingEmail ||
+			user.PendingEmailCodeHash != nil && *user.PendingEmailCodeHash == pendingCodeHash {
+			t.Errorf("user %s still holds the deleted user's pending email change", user.ID)
+		}
+		return nil
+	})
+	mustNoError(t, "Bulk.ForEach(users)", err)
+
+	// 其他用户的数据不受影响。
+	bobItems, err := s.Vault().FindByUser(ctx, bob.ID)
+	mustNoError(t, "Vault.FindByUser(bob)", err)
+	expectEqual(t, "vault items of other user", len(bobItems), 1)
+	_, err = s.VerificationCode().Find(ctx, bob.Email)
+	mustNoError(t, "verification code of other user", err)
+
+	// 用户名和邮箱可以重新注册。
+	createUsers(t, s, "alice")
+}
+
+func testUserDeleteMissing(t *testing.T, s repository.Storage) {
+	expectError(t, "Delete of missing user", s.User().Delete(t.Context(), uuid.New()), core.ErrUserNotFound)
+}
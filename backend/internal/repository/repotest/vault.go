@@ -0,0 +1,175 @@
+package repotest
+
+import (
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/repository"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var vaultCases = []testCase{
+	{"vault/create_and_find", testVaultCreateAndFind},
+	{"vault/find_missing", testVaultFindMissing},
+	{"vault/create_many", testVaultCreateMany},
+	{"vault/find_by_user", testVaultFindByUser},
+	{"vault/count_by_user", testVaultCountByUser},
+	{"vault/update", testVaultUpdate},
+	{"vault/update_missing", testVaultUpdateMissing},
+	{"vault/delete", testVaultDelete},
+	{"vault/delete_missing", testVaultDeleteMissing},
+}
+
+// newItem 返回属于 userID 的一个项目，加密数据中带有 n 以便区分。
+func newItem(userID uuid.UUID, n int) *core.VaultItem {
+	now := time.Now().UTC()
+	return &core.VaultItem{
+		UserID:        userID,
+		EncryptedData: json.RawMessage(fmt.Sprintf(`{"iv": "iv-%d", "ciphertext": "ct-%d"}`, n, n)),
+		Category:      "login",
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// compareItem 比较存储往返后的项目。
+func compareItem(t *testing.T, what string, got, want *core.VaultItem) {
+	t.Helper()
+	expectEqual(t, what+" id", got.ID, want.ID)
+	expectEqual(t, what+" user id", got.UserID, want.UserID)
+	expectEqual(t, what+" category", got.Category, want.Category)
+	expectJSON(t, what+" encrypted data", got.EncryptedData, want.EncryptedData)
+	expectTime(t, what+" created_at", got.CreatedAt, want.CreatedAt)
+	expectTime(t, what+" updated_at", got.UpdatedAt, want.UpdatedAt)
+}
+
+func testVaultCreateAndFind(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice")
+	item := newItem(users[0].ID, 1)
+	mustNoError(t, "Create", s.Vault().Create(ctx, item))
+	if item.ID == uuid.Nil {
+		t.Fatal("Create did not assign an ID")
+	}
+	got, err := s.Vault().FindByID(ctx, item.ID)
+	mustNoError(t, "FindByID", err)
+	compareItem(t, "FindByID", got, item)
+}
+
+func testVaultFindMissing(t *testing.T, s repository.Storage) {
+	_, err := s.Vault().FindByID(t.Context(), uuid.New())
+	expectError(t, "FindByID", err, core.ErrVaultItemNotFound)
+}
+
+func testVaultCreateMany(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice")
+	items := make([]core.VaultItem, 3)
+	for i := range items {
+		items[i] = *newItem(users[0].ID, i)
+	}
+	mustNoError(t, "CreateMany", s.Vault().CreateMany(ctx, items))
+
+	seen := make(map[uuid.UUID]bool)
+	for i := range items {
+		if items[i].ID == uuid.Nil || seen[items[i].ID] {
+			t.Fatalf("CreateMany did not assign a distinct ID to item %d", i)
+		}
+		seen[items[i].ID] = true
+		got, err := s.Vault().FindByID(ctx, items[i].ID)
+		mustNoError(t, "FindByID", err)
+		compareItem(t, fmt.Sprintf("item %d", i), got, &items[i])
+	}
+
+	mustNoError(t, "CreateMany(nil)", s.Vault().CreateMany(ctx, nil))
+}
+
+func testVaultFindByUser(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice", "bob")
+	want := make(map[uuid.UUID]*core.VaultItem)
+	for i, owner := range []int{0, 0, 1} {
+		item := newItem(users[owner].ID, i)
+		mustNoError(t, "Create", s.Vault().Create(ctx, item))
+		if owner == 0 {
+			want[item.ID] = item
+		}
+	}
+
+	got, err := s.Vault().FindByUser(ctx, users[0].ID)
+	mustNoError(t, "FindByUser", err)
+	expectEqual(t, "FindByUser count", len(got), len(want))
+	for i := range got {
+		item, ok := want[got[i].ID]
+		if !ok {
+			t.Errorf("FindByUser returned item %s of another user", got[i].ID)
+			continue
+		}
+		compareItem(t, "FindByUser", &got[i], item)
+	}
+
+	none, err := s.Vault().FindByUser(ctx, uuid.New())
+	mustNoError(t, "FindByUser(unknown user)", err)
+	expectEqual(t, "items of unknown user", len(none), 0)
+}
+
+func testVaultCountByUser(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice", "bob", "carol")
+	for i, owner := range []int{0, 0, 0, 1} {
+		mustNoError(t, "Create", s.Vault().Create(ctx, newItem(users[owner].ID, i)))
+	}
+	counts, err := s.Vault().CountByUser(ctx)
+	mustNoError(t, "CountByUser", err)
+	expectEqual(t, "users with items", len(counts), 2)
+	expectEqual(t, "items of alice", counts[users[0].ID], int64(3))
+	expectEqual(t, "items of bob", counts[users[1].ID], int64(1))
+	expectEqual(t, "items of carol", counts[users[2].ID], int64(0))
+}
+
+func testVaultUpdate(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice")
+	item := newItem(users[0].ID, 1)
+	mustNoError(t, "Create", s.Vault().Create(ctx, item))
+
+	item.EncryptedData = json.RawMessage(`{"iv":"new","ciphertext":"changed"}`)
+	item.Category = "card"
+	item.UpdatedAt = item.UpdatedAt.Add(time.Minute)
+	mustNoError(t, "Update", s.Vault().Update(ctx, item))
+	got, err := s.Vault().FindByID(ctx, item.ID)
+	mustNoError(t, "FindByID", err)
+	compareItem(t, "FindByID after Update", got, item)
+}
+
+func testVaultUpdateMissing(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice")
+	item := newItem(users[0].ID, 1)
+	item.ID = uuid.New()
+	expectError(t, "Update of missing item", s.Vault().Update(ctx, item), core.ErrVaultItemNotFound)
+	// Update 不能顺带创建项目。
+	_, err := s.Vault().FindByID(ctx, item.ID)
+	expectError(t, "FindByID after failed Update", err, core.ErrVaultItemNotFound)
+}
+
+func testVaultDelete(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	users := createUsers(t, s, "alice")
+	keep, drop := newItem(users[0].ID, 1), newItem(users[0].ID, 2)
+	for _, item := range []*core.VaultItem{keep, drop} {
+		mustNoError(t, "Create", s.Vault().Create(ctx, item))
+	}
+	mustNoError(t, "Delete", s.Vault().Delete(ctx, drop.ID))
+	_, err := s.Vault().FindByID(ctx, drop.ID)
+	expectError(t, "FindByID after Delete", err, core.ErrVaultItemNotFound)
+	_, err = s.Vault().FindByID(ctx, keep.ID)
+	mustNoError(t, "FindByID of remaining item", err)
+}
+
+func testVaultDeleteMissing(t *testing.T, s repository.Storage) {
+	expectError(t, "Delete of missing item", s.Vault().Delete(t.Context(), uuid.New()), core.ErrVaultItemNotFound)
+}
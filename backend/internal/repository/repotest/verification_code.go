@@ -0,0 +1,78 @@
+package repotest
+
+import (
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/repository"
+	"testing"
+	"time"
+)
+
+var verificationCodeCases = []testCase{
+	{"verification_code/create_and_find", testVerificationCodeCreateAndFind},
+	{"verification_code/find_missing", testVerificationCodeFindMissing},
+	{"verification_code/create_replaces", testVerificationCodeCreateReplaces},
+	{"verification_code/delete", testVerificationCodeDelete},
+	{"verification_code/delete_expired", testVerificationCodeDeleteExpired},
+}
+
+func testVerificationCodeCreateAndFind(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	vc := &core.VerificationCode{Email: "alice@example.com", Code: "123456", ExpiresAt: time.Now().Add(10 * time.Minute)}
+	mustNoError(t, "Create", s.VerificationCode().Create(ctx, vc))
+	got, err := s.VerificationCode().Find(ctx, vc.Email)
+	mustNoError(t, "Find", err)
+	expectEqual(t, "email", got.Email, vc.Email)
+	expectEqual(t, "code", got.Code, vc.Code)
+	expectTime(t, "expires_at", got.ExpiresAt, vc.ExpiresAt)
+}
+
+func testVerificationCodeFindMissing(t *testing.T, s repository.Storage) {
+	_, err := s.VerificationCode().Find(t.Context(), "nobody@example.com")
+	expectError(t, "Find", err, core.ErrVerificationCodeNotFound)
+}
+
+func testVerificationCodeCreateReplaces(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	email := "alice@example.com"
+	older := &core.VerificationCode{Email: email, Code: "111111", ExpiresAt: time.Now().Add(time.Minute)}
+	newer := &core.VerificationCode{Email: email, Code: "222222", ExpiresAt: time.Now().Add(10 * time.Minute)}
+	for _, vc := range []*core.VerificationCode{older, newer} {
+		mustNoError(t, "Create", s.VerificationCode().Create(ctx, vc))
+	}
+	got, err := s.VerificationCode().Find(ctx, email)
+	mustNoError(t, "Find", err)
+	expectEqual(t, "code after second Create", got.Code, newer.Code)
+	expectTime(t, "expires_at after second Create", got.ExpiresAt, newer.ExpiresAt)
+}
+
+func testVerificationCodeDelete(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	vc := &core.VerificationCode{Email: "alice@example.com", Code: "123456", ExpiresAt: time.Now().Add(time.Minute)}
+	mustNoError(t, "Create", s.VerificationCode().Create(ctx, vc))
+	mustNoError(t, "Delete", s.VerificationCode().Delete(ctx, vc.Email))
+	_, err := s.VerificationCode().Find(ctx, vc.Email)
+	expectError(t, "Find after Delete", err, core.ErrVerificationCodeNotFound)
+	// 验证码在使用后删除，重复删除不是错误。
+	mustNoError(t, "Delete of missing code", s.VerificationCode().Delete(ctx, vc.Email))
+}
+
+func testVerificationCodeDeleteExpired(t *testing.T, s repository.Storage) {
+	ctx := t.Context()
+	now := time.Now()
+	codes := map[string]time.Time{
+		"expired1@example.com": now.Add(-time.Hour),
+		"expired2@example.com": now.Add(-time.Second),
+		"valid@example.com":    now.Add(time.Hour),
+	}
+	for email, expires := range codes {
+		vc := &core.VerificationCode{Email: email, Code: "123456", ExpiresAt: expires}
+		mustNoError(t, "Create", s.VerificationCode().Create(ctx, vc))
+	}
+	n, err := s.VerificationCode().DeleteExpired(ctx, now)
+	mustNoError(t, "DeleteExpired", err)
+	expectEqual(t, "deleted codes", n, int64(2))
+	_, err = s.VerificationCode().Find(ctx, "expired1@example.com")
+	expectError(t, "Find(expired)", err, core.ErrVerificationCodeNotFound)
+	_, err = s.VerificationCode().Find(ctx, "valid@example.com")
+	mustNoError(t, "Find(valid)", err)
+}
@@ -0,0 +1,191 @@
+// Package schema 实现与存储后端无关的编号 schema 迁移。
+//
+// 每个后端提供一组从 1 开始连续编号的迁移步骤，以及一个 Driver：
+// Driver 负责加锁、记录已应用的版本，并在同一个事务中执行步骤和更新版本记录。
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrSchemaTooNew 表示数据库已应用了当前程序不认识的迁移步骤，
+// 通常是用旧版本的程序打开了新版本迁移过的数据库。
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary supports")
+
+// Step 是一个编号的迁移步骤。Tx 是后端特定的事务句柄。
+type Step[Tx any] struct {
+	Version int
+	Name    string
+	Up      func(tx Tx) error
+	// Down 撤销 Up 的修改；为 nil 表示该步骤无需回滚操作（例如只回填数据）。
+	Down func(tx Tx) error
+}
+
+// Migration 描述一个迁移步骤及其在数据库中的状态。
+type Migration struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	// AppliedAt 为 nil 表示该步骤尚未应用。
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// Driver 是存储后端为迁移提供的入口。
+type Driver[Tx any] interface {
+	// Lock 获取迁移锁后调用 fn，保证同一时刻只有一个进程读取或修改 schema 版本。
+	Lock(ctx context.Context, fn func(conn Conn[Tx]) error) error
+}
+
+// Conn 是持有迁移锁期间可用的操作。
+type Conn[Tx any] interface {
+	// Applied 返回已应用的版本及其应用时间。
+	Applied(ctx context.Context) (map[int]time.Time, error)
+	// Apply 在一个事务中执行 fn，并在 up 为 true 时记录 version、为 false 时删除 version 的记录。
+	Apply(ctx context.Context, version int, name string, up bool, fn func(tx Tx) error) error
+}
+
+// Migrator 按版本号执行一组迁移步骤。
+type Migrator[Tx any] struct {
+	driver Driver[Tx]
+	steps  []Step[Tx]
+}
+
+// NewMigrator 创建一个 Migrator。steps 的版本号必须从 1 开始连续递增，否则会 panic，
+// 因为这属于编程错误而不是运行时状态。
+func NewMigrator[Tx any](driver Driver[Tx], steps []Step[Tx]) *Migrator[Tx] {
+	for i, step := range steps {
+		if step.Version != i+1 {
+			panic(fmt.Sprintf("schema: migration %q has version %d, want %d", step.Name, step.Version, i+1))
+		}
+		if step.Up == nil {
+			panic(fmt.Sprintf("schema: migration %d has no Up function", step.Version))
+		}
+	}
+	return &Migrator[Tx]{driver: driver, steps: steps}
+}
+
+// Latest 返回最新的版本号。
+func (m *Migrator[Tx]) Latest() int {
+	return len(m.steps)
+}
+
+// Status 返回所有已知步骤及其状态。
+func (m *Migrator[Tx]) Status(ctx context.Context) ([]Migration, error) {
+	var status []Migration
+	err := m.driver.Lock(ctx, func(conn Conn[Tx]) error {
+		applied, err := conn.Applied(ctx)
+		if err != nil {
+			return err
+		}
+		if err := m.checkKnown(applied); err != nil {
+			return err
+		}
+		status = m.status(applied)
+		return nil
+	})
+	return status, err
+}
+
+// Up 依次应用版本号不大于 target 的待执行步骤，target 为 0 时迁移到最新版本。
+// 返回本次应用的步骤；某一步失败时之前的步骤保持已应用状态。
+func (m *Migrator[Tx]) Up(ctx context.Context, target int) ([]Migration, error) {
+	if target == 0 {
+		target = m.Latest()
+	}
+	if target < 0 || target > m.Latest() {
+		return nil, fmt.Errorf("unknown schema version %d (latest is %d)", target, m.Latest())
+	}
+
+	var done []Migration
+	err := m.driver.Lock(ctx, func(conn Conn[Tx]) error {
+		applied, err := conn.Applied(ctx)
+		if err != nil {
+			return err
+		}
+		if err := m.checkKnown(applied); err != nil {
+			return err
+		}
+		for _, step := range m.steps[:target] {
+			if _, ok := applied[step.Version]; ok {
+				continue
+			}
+			if err := conn.Apply(ctx, step.Version, step.Name, true, step.Up); err != nil {
+				return fmt.Errorf("migration %d (%s) failed: %w", step.Version, step.Name, err)
+			}
+			now := time.Now().UTC()
+			done = append(done, Migration{Version: step.Version, Name: step.Name, AppliedAt: &now})
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down 按版本号从大到小回滚版本号大于 target 的已应用步骤，返回本次回滚的步骤。
+func (m *Migrator[Tx]) Down(ctx context.Context, target int) ([]Migration, error) {
+	if target < 0 || target > m.Latest() {
+		return nil, fmt.Errorf("unknown schema version %d (latest is %d)", target, m.Latest())
+	}
+
+	var done []Migration
+	err := m.driver.Lock(ctx, func(conn Conn[Tx]) error {
+		applied, err := conn.Applied(ctx)
+		if err != nil {
+			return err
+		}
+		if err := m.checkKnown(applied); err != nil {
+			return err
+		}
+		for i := len(m.steps) - 1; i >= target; i-- {
+			step := m.steps[i]
+			if _, ok := applied[step.Version]; !ok {
+				continue
+			}
+			down := step.Down
+			if down == nil {
+				down = func(Tx) error { return nil }
+			}
+			if err := conn.Apply(ctx, step.Version, step.Name, false, down); err != nil {
+				return fmt.Errorf("rollback of migration %d (%s) failed: %w", step.Version, step.Name, err)
+			}
+			done = append(done, Migration{Version: step.Version, Name: step.Name})
+		}
+		return nil
+	})
+	return done, err
+}
+
+// checkKnown 确认数据库中没有当前程序不认识的版本。
+func (m *Migrator[Tx]) checkKnown(applied map[int]time.Time) error {
+	for version := range applied {
+		if version < 1 || version > m.Latest() {
+			return fmt.Errorf("%w: found version %d, latest known is %d", ErrSchemaTooNew, version, m.Latest())
+		}
+	}
+	return nil
+}
+
+func (m *Migrator[Tx]) status(applied map[int]time.Time) []Migration {
+	status := make([]Migration, 0, len(m.steps))
+	for _, step := range m.steps {
+		migration := Migration{Version: step.Version, Name: step.Name}
+		if at, ok := applied[step.Version]; ok {
+			at := at
+			migration.AppliedAt = &at
+		}
+		status = append(status, migration)
+	}
+	return status
+}
+
+// Pending 返回 status 中尚未应用的步骤。
+func Pending(status []Migration) []Migration {
+	var pending []Migration
+	for _, migration := range status {
+		if migration.AppliedAt == nil {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
@@ -0,0 +1,219 @@
+package sqlite
+
+import (
+	"context"
+	"easy-password-backend/internal/repository/schema"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// schemaMigration 是 schema_migrations 表中的一行。
+type schemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// migrations 是 SQLite 的全部迁移步骤。已发布的步骤不可修改，变更只能追加新步骤。
+//
+// UUID 以文本存储；时间列必须声明为 DATETIME，驱动才会把它们解析回 time.Time。
+var migrations = []schema.Step[*gorm.DB]{
+	{
+		Version: 1,
+		Name:    "create_tables",
+		Up: execAll(
+			`CREATE TABLE users (
+				id TEXT PRIMARY KEY,
+				username TEXT NOT NULL,
+				email TEXT NOT NULL,
+				auth_hash TEXT NOT NULL,
+				master_salt BLOB NOT NULL,
+				reset_password_token TEXT,
+				reset_password_token_expires_at DATETIME,
+				require_device_approval BOOLEAN NOT NULL DEFAULT false,
+				status TEXT NOT NULL DEFAULT 'active',
+				role TEXT NOT NULL DEFAULT 'user',
+				sessions_revoked_at DATETIME,
+				created_at DATETIME,
+				updated_at DATETIME
+			)`,
+			`CREATE UNIQUE INDEX idx_users_username ON users (username)`,
+			`CREATE UNIQUE INDEX idx_users_email ON users (email)`,
+			`CREATE UNIQUE INDEX idx_users_reset_password_token ON users (reset_password_token)`,
+			`CREATE INDEX idx_users_status ON users (status)`,
+			`CREATE TABLE vault_items (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				encrypted_data TEXT NOT NULL,
+				category TEXT,
+				created_at DATETIME,
+				updated_at DATETIME
+			)`,
+			`CREATE INDEX idx_vault_items_user_id ON vault_items (user_id)`,
+			`CREATE INDEX idx_vault_items_category ON vault_items (category)`,
+			`CREATE TABLE verification_codes (
+				email TEXT PRIMARY KEY,
+				code TEXT NOT NULL,
+				expires_at DATETIME NOT NULL,
+				created_at DATETIME
+			)`,
+			`CREATE TABLE audit_events (
+				id TEXT PRIMARY KEY,
+				sequence INTEGER NOT NULL,
+				user_id TEXT NOT NULL,
+				type TEXT NOT NULL,
+				ip_address TEXT,
+				user_agent TEXT,
+				metadata TEXT,
+				created_at DATETIME NOT NULL,
+				prev_hash TEXT NOT NULL,
+				user_prev_hash TEXT NOT NULL,
+				hash TEXT NOT NULL
+			)`,
+			`CREATE UNIQUE INDEX idx_audit_events_sequence ON audit_events (sequence)`,
+			`CREATE INDEX idx_audit_events_user_id ON audit_events (user_id, sequence)`,
+			`CREATE INDEX idx_audit_events_type ON audit_events (type)`,
+			`CREATE TABLE devices (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				device_id TEXT NOT NULL,
+				name TEXT,
+				type TEXT,
+				ip_address TEXT,
+				first_seen_at DATETIME NOT NULL,
+				last_seen_at DATETIME NOT NULL
+			)`,
+			`CREATE UNIQUE INDEX idx_devices_user_device ON devices (user_id, device_id)`,
+			`CREATE TABLE login_approvals (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				device_id TEXT NOT NULL,
+				device_name TEXT,
+				device_type TEXT,
+				ip_address TEXT,
+				code_hash TEXT NOT NULL,
+				token_hash TEXT NOT NULL,
+				attempts INTEGER NOT NULL DEFAULT 0,
+				status TEXT NOT NULL,
+				expires_at DATETIME NOT NULL,
+				created_at DATETIME
+			)`,
+			`CREATE UNIQUE INDEX idx_login_approvals_token_hash ON login_approvals (token_hash)`,
+			`CREATE INDEX idx_login_approvals_user_id ON login_approvals (user_id)`,
+		),
+		Down: execAll(
+			`DROP TABLE login_approvals`,
+			`DROP TABLE devices`,
+			`DROP TABLE audit_events`,
+			`DROP TABLE verification_codes`,
+			`DROP TABLE vault_items`,
+			`DROP TABLE users`,
+		),
+	},
+	{
+		Version: 2,
+		Name:    "create_api_keys",
+		Up: execAll(
+			`CREATE TABLE api_keys (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				name TEXT NOT NULL,
+				secret_hash TEXT NOT NULL,
+				scopes TEXT NOT NULL,
+				item_ids TEXT,
+				categories TEXT,
+				expires_at DATETIME,
+				last_used_at DATETIME,
+				created_at DATETIME
+			)`,
+			`CREATE INDEX idx_api_keys_user_id ON api_keys (user_id)`,
+		),
+		Down: execAll(
+			`DROP TABLE api_keys`,
+		),
+	},
+	{
+		Version: 3,
+		Name:    "add_pending_email_change",
+		Up: execAll(
+			`ALTER TABLE users ADD COLUMN pending_email TEXT`,
+			`ALTER TABLE users ADD COLUMN pending_email_code_hash TEXT`,
+			`ALTER TABLE users ADD COLUMN pending_email_expires_at DATETIME`,
+		),
+		Down: execAll(
+			`ALTER TABLE users DROP COLUMN pending_email_expires_at`,
+			`ALTER TABLE users DROP COLUMN pending_email_code_hash`,
+			`ALTER TABLE users DROP COLUMN pending_email`,
+		),
+	},
+}
+
+// execAll 返回一个依次执行 statements 的迁移函数。
+func execAll(statements ...string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// NewMigrator 返回 SQLite 的 schema 迁移器。
+//
+// 每个步骤和它的版本记录在同一个 IMMEDIATE 事务中提交，该事务开始时即持有数据库的写锁，
+// 并在事务内再次确认版本状态，因此多个进程同时迁移时每个步骤只会执行一次。
+func NewMigrator(db *gorm.DB) *schema.Migrator[*gorm.DB] {
+	return schema.NewMigrator[*gorm.DB](&migrationDriver{db: db}, migrations)
+}
+
+type migrationDriver struct {
+	db *gorm.DB
+}
+
+func (d *migrationDriver) Lock(ctx context.Context, fn func(conn schema.Conn[*gorm.DB]) error) error {
+	err := d.db.WithContext(ctx).Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME NOT NULL
+	)`).Error
+	if err != nil {
+		return err
+	}
+	return fn(d)
+}
+
+func (d *migrationDriver) Applied(ctx context.Context) (map[int]time.Time, error) {
+	var rows []schemaMigration
+	if err := d.db.WithContext(ctx).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+	return applied, nil
+}
+
+func (d *migrationDriver) Apply(ctx context.Context, version int, name string, up bool, fn func(tx *gorm.DB) error) error {
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&schemaMigration{}).Where("version = ?", version).Count(&count).Error; err != nil {
+			return err
+		}
+		if (count > 0) == up {
+			return nil
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if !up {
+			return tx.Delete(&schemaMigration{}, "version = ?", version).Error
+		}
+		return tx.Create(&schemaMigration{Version: version, Name: name, AppliedAt: time.Now().UTC()}).Error
+	})
+}
@@ -0,0 +1,27 @@
+package sqlite_test
+
+import (
+	"easy-password-backend/internal/repository"
+	"easy-password-backend/internal/repository/repotest"
+	"easy-password-backend/internal/repository/sqlite"
+	"path/filepath"
+	"testing"
+)
+
+func TestStorageContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.Storage {
+		db, err := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatalf("open database: %v", err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			t.Fatalf("open database: %v", err)
+		}
+		t.Cleanup(func() { sqlDB.Close() })
+		if _, err := sqlite.NewMigrator(db).Up(t.Context(), 0); err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+		return sqlite.NewSQLiteStorage(db)
+	})
+}
@@ -1,11 +1,16 @@
 package repository
 
 import (
+	"context"
 	"easy-password-backend/config"
 	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/kms"
 	"easy-password-backend/internal/repository/boltdb"
+	"easy-password-backend/internal/repository/memory"
 	"easy-password-backend/internal/repository/postgres"
+	"easy-password-backend/internal/repository/sqlite"
 	"fmt"
+	"io"
 
 	"go.etcd.io/bbolt"
 	"gorm.io/gorm"
@@ -17,16 +22,39 @@ type Storage interface {
 	User() core.UserRepository
 	Vault() core.VaultRepository
 	VerificationCode() core.VerificationCodeRepository
+	Audit() core.AuditRepository
+	Device() core.DeviceRepository
+	LoginApproval() core.LoginApprovalRepository
+	APIKey() core.APIKeyRepository
+	// Bulk 返回用于跨存储后端迁移的原样读写存储库。
+	Bulk() core.BulkRepository
+	// Stats 返回存储后端的记录数量和占用空间。
+	Stats(ctx context.Context) (*core.StorageStats, error)
+	// Backup 将一致的数据库快照写入 w；不支持在线备份的后端返回 core.ErrBackupNotSupported。
+	Backup(ctx context.Context, w io.Writer) (int64, error)
+	// RotateEncryptionKey 轮换静态加密的数据密钥并在后台重新加密已有记录；
+	// 未启用静态加密的后端返回 core.ErrEncryptionNotEnabled。
+	RotateEncryptionKey(ctx context.Context) error
 }
 
 // NewStorage 根据提供的配置创建一个新的存储后端。
-// 它充当工厂并返回适当的实现（Postgres 或 BoltDB）。
-func NewStorage(cfg *config.Config, db *gorm.DB, boltDB *bbolt.DB) (Storage, error) {
+// 它充当工厂并返回适当的实现（Postgres、SQLite、BoltDB 或内存）。
+// db 是 PostgreSQL 或 SQLite 的连接，boltDB 仅用于 BoltDB；内存存储两者都不需要，
+// 每次调用都会返回一个新的空存储。keys 提供 BoltDB 静态加密的服务器密钥。
+func NewStorage(cfg *config.Config, keys kms.KeyProvider, db *gorm.DB, boltDB *bbolt.DB) (Storage, error) {
 	switch cfg.DBType {
 	case "postgres":
 		return postgres.NewPostgresStorage(db), nil
+	case "sqlite":
+		return sqlite.NewSQLiteStorage(db), nil
 	case "boltdb":
-		return boltdb.NewBoltDBStorage(boltDB), nil
+		enc, err := boltdb.OpenEncryption(context.Background(), boltDB, keys)
+		if err != nil {
+			return nil, err
+		}
+		return boltdb.NewEncryptedBoltDBStorage(boltDB, enc), nil
+	case "memory":
+		return memory.NewMemoryStorage(), nil
 	default:
 		return nil, fmt.Errorf("unsupported DB_TYPE: %s", cfg.DBType)
 	}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"easy-password-backend/internal/apierror"
+	"easy-password-backend/internal/audit"
+	"easy-password-backend/internal/core"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StorageOps 提供存储后端级别的统计、备份和密钥轮换操作，由 repository.Storage 实现。
+type StorageOps interface {
+	Stats(ctx context.Context) (*core.StorageStats, error)
+	Backup(ctx context.Context, w io.Writer) (int64, error)
+	RotateEncryptionKey(ctx context.Context) error
+}
+
+// AdminService 提供面向管理员的账户管理操作。
+type AdminService struct {
+	userRepo   core.UserRepository
+	vaultRepo  core.VaultRepository
+	deviceRepo core.DeviceRepository
+	storage    StorageOps
+	auditor    *audit.AuditService
+	startedAt  time.Time
+}
+
+// NewAdminService 创建一个新的 AdminService。
+func NewAdminService(userRepo core.UserRepository, vaultRepo core.VaultRepository, deviceRepo core.DeviceRepository, storage StorageOps, auditor *audit.AuditService) *AdminService {
+	return &AdminService{
+		userRepo:   userRepo,
+		vaultRepo:  vaultRepo,
+		deviceRepo: deviceRepo,
+		storage:    storage,
+		auditor:    auditor,
+		startedAt:  time.Now(),
+	}
+}
+
+// UserSummary 是管理员可见的用户元数据，不包含认证材料和保险库内容。
+type UserSummary struct {
+	User    *core.User
+	Items   int64
+	Devices int
+}
+
+// ListUsers 分页检索用户及其项目数量。query 非空时按用户名或邮箱过滤。
+func (s *AdminService) ListUsers(ctx context.Context, query string, page, pageSize int) ([]UserSummary, int64, error) {
+	users, total, err := s.userRepo.List(ctx, query, (page-1)*pageSize, pageSize)
+	if err != nil {
+		slog.Error("Failed to list users", "error", err)
+		return nil, 0, apierror.ErrInternalServer
+	}
+	counts, err := s.vaultRepo.CountByUser(ctx)
+	if err != nil {
+		slog.Error("Failed to count vault items", "error", err)
+		return nil, 0, apierror.ErrInternalServer
+	}
+
+	summaries := make([]UserSummary, len(users))
+	for i := range users {
+		summaries[i] = UserSummary{User: &users[i], Items: counts[users[i].ID]}
+	}
+	return summaries, total, nil
+}
+
+// GetUserSummary 检索单个用户的元数据、项目数量和设备数量。
+func (s *AdminService) GetUserSummary(ctx context.Context, userID uuid.UUID) (*UserSummary, error) {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.vaultRepo.CountByUser(ctx)
+	if err != nil {
+		slog.Error("Failed to count vault items", "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+	devices, err := s.deviceRepo.FindByUser(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to fetch devices", "user_id", userID, "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+	return &UserSummary{User: user, Items: counts[userID], Devices: len(devices)}, nil
+}
+
+// Health 描述服务器和存储后端的运行状况。
+type Health struct {
+	Status          string
+	StartedAt       time.Time
+	Uptime          time.Duration
+	DatabaseOK      bool
+	DatabaseLatency time.Duration
+}
+
+// Health 检查存储后端是否可用。存储不可用时 Status 为 "degraded"，而不是返回错误。
+func (s *AdminService) Health(ctx context.Context) *Health {
+	health := &Health{Status: "ok", StartedAt: s.startedAt, Uptime: time.Since(s.startedAt)}
+	start := time.Now()
+	if _, _, err := s.userRepo.List(ctx, "", 0, 1); err != nil {
+		slog.Error("Health check failed: storage unavailable", "error", err)
+		health.Status = "degraded"
+	} else {
+		health.DatabaseOK = true
+	}
+	health.DatabaseLatency = time.Since(start)
+	return health
+}
+
+// StorageStats 返回存储后端的记录数量和占用空间。
+func (s *AdminService) StorageStats(ctx context.Context) (*core.StorageStats, error) {
+	stats, err := s.storage.Stats(ctx)
+	if err != nil {
+		slog.Error("Failed to collect storage stats", "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+	return stats, nil
+}
+
+// GetUser 按 ID 检索用户。
+func (s *AdminService) GetUser(ctx context.Context, userID uuid.UUID) (*core.User, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if err == core.ErrUserNotFound {
+			return nil, apierror.ErrNotFound
+		}
+		slog.Error("Failed to find user", "user_id", userID, "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+	return user, nil
+}
+
+// SetUserStatus 修改账户状态，并在用户的审计链上记录变更前后的状态、操作者和原因。
+// 状态未发生变化时不做任何修改。
+func (s *AdminService) SetUserStatus(ctx context.Context, userID uuid.UUID, status core.UserStatus, actor, reason string) (*core.User, error) {
+	if !status.Valid() {
+		return nil, apierror.ErrInvalidUserStatus
+	}
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := user.AccountStatus()
+	if previous == status {
+		return user, nil
+	}
+	user.Status = status
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		slog.Error("Failed to update account status", "user_id", userID, "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+
+	slog.Info("Account status changed", "user_id", userID, "from", previous, "to", status, "actor", actor)
+	metadata := map[string]string{
+		"from":  string(previous),
+		"to":    string(status),
+		"actor": actor,
+	}
+	if reason != "" {
+		metadata["reason"] = reason
+	}
+	s.auditor.Record(ctx, userID, core.AuditEventAccountStatusChange, metadata)
+	return user, nil
+}
+
+// Backup 将存储后端的一致快照写入 w，并在管理员的审计链上记录这次下载。
+func (s *AdminService) Backup(ctx context.Context, adminID uuid.UUID, w io.Writer) error {
+	n, err := s.storage.Backup(ctx, w)
+	if err != nil {
+		if err == core.ErrBackupNotSupported {
+			return apierror.ErrBackupNotSupported
+		}
+		// 快照可能已部分写出，调用方无法再返回错误响应，这里只记录日志。
+		slog.Error("Failed to write backup snapshot", "error", err)
+		return apierror.ErrInternalServer
+	}
+
+	slog.Info("Backup snapshot downloaded", "admin_id", adminID, "bytes", n)
+	s.auditor.Record(ctx, adminID, core.AuditEventBackupDownload, map[string]string{
+		"bytes": fmt.Sprintf("%d", n),
+	})
+	return nil
+}
+
+// RotateEncryptionKey 轮换存储的静态加密数据密钥，已有记录在后台重新加密。
+// 轮换记录在管理员的审计链上。
+func (s *AdminService) RotateEncryptionKey(ctx context.Context, adminID uuid.UUID) (*core.EncryptionStatus, error) {
+	if err := s.storage.RotateEncryptionKey(ctx); err != nil {
+		if err == core.ErrEncryptionNotEnabled {
+			return nil, apierror.ErrEncryptionNotEnabled
+		}
+		slog.Error("Failed to rotate encryption key", "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+	stats, err := s.StorageStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Encryption key rotated", "admin_id", adminID, "active_key", stats.Encryption.ActiveKey)
+	s.auditor.Record(ctx, adminID, core.AuditEventEncryptionKeyRotate, map[string]string{
+		"active_key": fmt.Sprintf("%d", stats.Encryption.ActiveKey),
+	})
+	return stats.Encryption, nil
+}
+
+// SetUserRole 修改用户角色并记录审计事件。仅供运维工具使用，不通过 API 暴露。
+func (s *AdminService) SetUserRole(ctx context.Context, userID uuid.UUID, role core.UserRole, actor string) (*core.User, error) {
+	if !role.Valid() {
+		return nil, apierror.ErrInvalidRequest
+	}
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := user.AccountRole()
+	if previous == role {
+		return user, nil
+	}
+	user.Role = role
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		slog.Error("Failed to update user role", "user_id", userID, "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+
+	slog.Info("User role changed", "user_id", userID, "from", previous, "to", role, "actor", actor)
+	s.auditor.Record(ctx, userID, core.AuditEventRoleChange, map[string]string{
+		"from":  string(previous),
+		"to":    string(role),
+		"actor": actor,
+	})
+	return user, nil
+}
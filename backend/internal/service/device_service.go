@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"easy-password-backend/internal/apierror"
+	"easy-password-backend/internal/audit"
+	"easy-password-backend/internal/core"
+	"log/slog"
+
+	"github.com/google/uuid"
+)
+
+// DeviceService 提供与用户设备管理相关的服务。
+type DeviceService struct {
+	deviceRepo core.DeviceRepository
+	auditor    *audit.AuditService
+}
+
+// NewDeviceService 创建一个新的 DeviceService。
+func NewDeviceService(deviceRepo core.DeviceRepository, auditor *audit.AuditService) *DeviceService {
+	return &DeviceService{deviceRepo: deviceRepo, auditor: auditor}
+}
+
+// GetDevices 检索用户的所有已知设备。
+func (s *DeviceService) GetDevices(ctx context.Context, userID uuid.UUID) ([]core.Device, error) {
+	devices, err := s.deviceRepo.FindByUser(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to fetch devices", "user_id", userID, "error", err)
+		return nil, apierror.ErrInternalServer
+	}
+	return devices, nil
+}
+
+// RevokeDevice 删除用户的一个设备，绑定到该设备的令牌随即失效。
+func (s *DeviceService) RevokeDevice(ctx context.Context, id, userID uuid.UUID) error {
+	slog.Info("Revoking device", "device_id", id, "user_id", userID)
+	device, err := s.deviceRepo.FindByID(ctx, id)
+	if err != nil {
+		if err == core.ErrDeviceNotFound {
+			return apierror.ErrNotFound
+		}
+		slog.Error("Failed to find device", "device_id", id, "error", err)
+		return apierror.ErrInternalServer
+	}
+	// 不透露其他用户设备的存在。
+	if device.UserID != userID {
+		slog.Warn("User forbidden to revoke device", "device_id", id, "user_id", userID, "owner_id", device.UserID)
+		return apierror.ErrNotFound
+	}
+
+	if err := s.deviceRepo.Delete(ctx, id); err != nil {
+		slog.Error("Failed to delete device", "device_id", id, "error", err)
+		return apierror.ErrInternalServer
+	}
+	s.auditor.Record(ctx, userID, core.AuditEventDeviceRevoke, map[string]string{
+		"device_id":   device.ID.String(),
+		"device_name": device.Name,
+	})
+	slog.Info("Device revoked successfully", "device_id", id)
+	return nil
+}
@@ -3,8 +3,12 @@ package service
 import (
 	"context"
 	"easy-password-backend/internal/apierror"
+	"easy-password-backend/internal/audit"
 	"easy-password-backend/internal/core"
+	"easy-password-backend/pkg/vaultexport"
+	"io"
 	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,11 +17,13 @@ import (
 // VaultService 提供与保险库相关的服务。
 type VaultService struct {
 	vaultRepo core.VaultRepository
+	userRepo  core.UserRepository
+	auditor   *audit.AuditService
 }
 
 // NewVaultService 创建一个新的 VaultService。
-func NewVaultService(vaultRepo core.VaultRepository) *VaultService {
-	return &VaultService{vaultRepo: vaultRepo}
+func NewVaultService(vaultRepo core.VaultRepository, userRepo core.UserRepository, auditor *audit.AuditService) *VaultService {
+	return &VaultService{vaultRepo: vaultRepo, userRepo: userRepo, auditor: auditor}
 }
 
 // CreateVaultItem 为用户创建一个新的保险库项目。
@@ -32,6 +38,7 @@ func (s *VaultService) CreateVaultItem(ctx context.Context, item *core.VaultItem
 		return nil, apierror.ErrInternalServer
 	}
 	slog.Info("Vault item created successfully", "item_id", item.ID, "user_id", item.UserID)
+	s.auditor.Record(ctx, item.UserID, core.AuditEventVaultItemCreate, map[string]string{"item_id": item.ID.String()})
 	return item, nil
 }
 
@@ -96,6 +103,7 @@ func (s *VaultService) UpdateVaultItem(ctx context.Context, item *core.VaultItem
 	}
 
 	slog.Info("Vault item updated successfully", "item_id", item.ID)
+	s.auditor.Record(ctx, userID, core.AuditEventVaultItemUpdate, map[string]string{"item_id": item.ID.String()})
 	return item, nil
 }
 
@@ -114,5 +122,95 @@ func (s *VaultService) DeleteVaultItem(ctx context.Context, id, userID uuid.UUID
 		return err
 	}
 	slog.Info("Vault item deleted successfully", "item_id", id)
+	s.auditor.Record(ctx, userID, core.AuditEventVaultItemDelete, map[string]string{"item_id": id.String()})
 	return nil
-}
\ No newline at end of file
+}
+
+// ExportVault 将用户的全部保险库项目（仍为客户端加密状态）连同主盐和 KDF 参数
+// 以 vaultexport 格式流式写入 w。项目多于 vaultexport.MaxItems 时返回 apierror.ErrExportTooLarge，不写入任何内容。
+func (s *VaultService) ExportVault(ctx context.Context, userID uuid.UUID, w io.Writer) error {
+	slog.Info("Exporting vault", "user_id", userID)
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to find user for export", "user_id", userID, "error", err)
+		return apierror.ErrInternalServer
+	}
+	items, err := s.vaultRepo.FindByUser(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to fetch vault items for export", "user_id", userID, "error", err)
+		return apierror.ErrInternalServer
+	}
+	// 导入只接受 vaultexport.MaxItems 个项目，超出的导出文件无法再导入，因此在写出任何内容之前拒绝。
+	if len(items) > vaultexport.MaxItems {
+		slog.Warn("Vault export rejected: too many items", "user_id", userID, "count", len(items))
+		return apierror.ErrExportTooLarge.WithDetails(map[string]string{"max_items": strconv.Itoa(vaultexport.MaxItems)})
+	}
+
+	writer, err := vaultexport.NewWriter(w, time.Now(), vaultexport.DefaultKDF(string(user.MasterSalt)))
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		err := writer.WriteItem(vaultexport.Item{
+			ID:            item.ID,
+			Category:      item.Category,
+			EncryptedData: item.EncryptedData,
+			CreatedAt:     item.CreatedAt,
+			UpdatedAt:     item.UpdatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	s.auditor.Record(ctx, userID, core.AuditEventVaultExport, map[string]string{"count": strconv.Itoa(len(items))})
+	slog.Info("Vault exported successfully", "user_id", userID, "count", len(items))
+	return nil
+}
+
+// ImportVault 将一个已校验的导出文件中的项目在单个事务中导入到用户的保险库。
+// 项目使用导出时的主盐加密，因此导出文件的盐必须与用户当前的主盐一致。
+func (s *VaultService) ImportVault(ctx context.Context, userID uuid.UUID, env *vaultexport.Envelope) (int, error) {
+	slog.Info("Importing vault", "user_id", userID, "count", len(env.Items))
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to find user for import", "user_id", userID, "error", err)
+		return 0, apierror.ErrInternalServer
+	}
+	if env.KDF.Salt != string(user.MasterSalt) {
+		slog.Warn("Vault import rejected: salt mismatch", "user_id", userID)
+		return 0, apierror.ErrImportSaltMismatch
+	}
+
+	items := make([]core.VaultItem, 0, len(env.Items))
+	now := time.Now()
+	for _, item := range env.Items {
+		createdAt, updatedAt := item.CreatedAt, item.UpdatedAt
+		if createdAt.IsZero() {
+			createdAt = now
+		}
+		if updatedAt.IsZero() {
+			updatedAt = createdAt
+		}
+		// 导入的项目总是获得新的 ID，避免与现有项目冲突。
+		items = append(items, core.VaultItem{
+			UserID:        userID,
+			EncryptedData: item.EncryptedData,
+			Category:      item.Category,
+			CreatedAt:     createdAt,
+			UpdatedAt:     updatedAt,
+		})
+	}
+
+	if err := s.vaultRepo.CreateMany(ctx, items); err != nil {
+		slog.Error("Failed to import vault items", "user_id", userID, "error", err)
+		return 0, apierror.ErrInternalServer
+	}
+
+	s.auditor.Record(ctx, userID, core.AuditEventVaultImport, map[string]string{"count": strconv.Itoa(len(items))})
+	slog.Info("Vault imported successfully", "user_id", userID, "count", len(items))
+	return len(items), nil
+}
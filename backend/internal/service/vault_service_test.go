@@ -0,0 +1,266 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"easy-password-backend/internal/apierror"
+	"easy-password-backend/internal/audit"
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/repository/memory"
+	"easy-password-backend/pkg/vaultexport"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// vaultTestEnv 是内存存储上的 VaultService，owner 和 other 是两个已注册的用户。
+type vaultTestEnv struct {
+	vault   *VaultService
+	storage *memory.Storage
+	owner   *core.User
+	other   *core.User
+}
+
+func newVaultTestEnv(t *testing.T) *vaultTestEnv {
+	t.Helper()
+	storage := memory.NewMemoryStorage()
+	return &vaultTestEnv{
+		vault:   NewVaultService(storage.Vault(), storage.User(), audit.NewAuditService(storage.Audit())),
+		storage: storage,
+		owner:   createTestUser(t, storage, "alice"),
+		other:   createTestUser(t, storage, "bob"),
+	}
+}
+
+// createTestUser 直接在存储中创建一个用户。
+func createTestUser(t *testing.T, storage *memory.Storage, name string) *core.User {
+	t.Helper()
+	user := &core.User{Username: name, Email: name + "@example.com", AuthHash: "hash", MasterSalt: []byte("salt")}
+	if err := storage.User().Create(context.Background(), user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// createItem 通过服务为 userID 创建一个项目。
+func (e *vaultTestEnv) createItem(t *testing.T, userID uuid.UUID, category string) *core.VaultItem {
+	t.Helper()
+	item, err := e.vault.CreateVaultItem(context.Background(), &core.VaultItem{
+		UserID:        userID,
+		EncryptedData: json.RawMessage(`{"ciphertext":"original"}`),
+		Category:      category,
+	})
+	if err != nil {
+		t.Fatalf("create item: %v", err)
+	}
+	return item
+}
+
+// auditTypes 返回用户的审计事件类型，按时间倒序。
+func (e *vaultTestEnv) auditTypes(t *testing.T, userID uuid.UUID) []core.AuditEventType {
+	t.Helper()
+	events, _, err := e.storage.Audit().FindByUser(context.Background(), userID, 0, 100)
+	if err != nil {
+		t.Fatalf("find audit events: %v", err)
+	}
+	types := make([]core.AuditEventType, 0, len(events))
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func expectVaultError(t *testing.T, op string, err error, want *apierror.APIError) {
+	t.Helper()
+	switch {
+	case want == nil && err != nil:
+		t.Fatalf("%s: unexpected error: %v", op, err)
+	case want != nil && !errors.Is(err, want):
+		t.Fatalf("%s: error = %v, want %s", op, err, want.Code)
+	}
+}
+
+func TestVaultItemCreateAndList(t *testing.T) {
+	ctx := context.Background()
+	env := newVaultTestEnv(t)
+	first := env.createItem(t, env.owner.ID, "login")
+	second := env.createItem(t, env.owner.ID, "note")
+	env.createItem(t, env.other.ID, "login")
+
+	if first.ID == uuid.Nil || first.CreatedAt.IsZero() || !first.UpdatedAt.Equal(first.CreatedAt) {
+		t.Errorf("created item = %+v, want an ID and equal timestamps", first)
+	}
+	items, err := env.vault.GetVaultItems(ctx, env.owner.ID)
+	if err != nil {
+		t.Fatalf("get items: %v", err)
+	}
+	got := map[uuid.UUID]bool{}
+	for _, item := range items {
+		got[item.ID] = true
+	}
+	if len(items) != 2 || !got[first.ID] || !got[second.ID] {
+		t.Errorf("owner's items = %v, want only %s and %s", items, first.ID, second.ID)
+	}
+	if types := env.auditTypes(t, env.owner.ID); len(types) != 2 || types[0] != core.AuditEventVaultItemCreate {
+		t.Errorf("owner's audit events = %v, want two %s", types, core.AuditEventVaultItemCreate)
+	}
+}
+
+func TestVaultItemAccess(t *testing.T) {
+	tests := []struct {
+		name string
+		// target 返回被访问项目的 ID，以及发起请求的用户。
+		target func(env *vaultTestEnv, item *core.VaultItem) (uuid.UUID, uuid.UUID)
+		want   *apierror.APIError
+	}{
+		{
+			name:   "owner",
+			target: func(env *vaultTestEnv, item *core.VaultItem) (uuid.UUID, uuid.UUID) { return item.ID, env.owner.ID },
+		},
+		{
+			name:   "other user",
+			target: func(env *vaultTestEnv, item *core.VaultItem) (uuid.UUID, uuid.UUID) { return item.ID, env.other.ID },
+			want:   apierror.ErrForbidden,
+		},
+		{
+			name:   "missing item",
+			target: func(env *vaultTestEnv, item *core.VaultItem) (uuid.UUID, uuid.UUID) { return uuid.New(), env.owner.ID },
+			want:   apierror.ErrNotFound,
+		},
+	}
+	operations := []struct {
+		name string
+		run  func(env *vaultTestEnv, id, userID uuid.UUID) error
+		// check 在操作成功时确认存储中项目的状态。
+		check func(t *testing.T, env *vaultTestEnv, item *core.VaultItem)
+		audit core.AuditEventType
+	}{
+		{
+			name: "get",
+			run: func(env *vaultTestEnv, id, userID uuid.UUID) error {
+				_, err := env.vault.GetVaultItemByID(context.Background(), id, userID)
+				return err
+			},
+		},
+		{
+			name: "update",
+			run: func(env *vaultTestEnv, id, userID uuid.UUID) error {
+				_, err := env.vault.UpdateVaultItem(context.Background(), &core.VaultItem{
+					ID:            id,
+					UserID:        userID,
+					EncryptedData: json.RawMessage(`{"ciphertext":"updated"}`),
+				}, userID)
+				return err
+			},
+			check: func(t *testing.T, env *vaultTestEnv, item *core.VaultItem) {
+				stored, err := env.storage.Vault().FindByID(context.Background(), item.ID)
+				if err != nil {
+					t.Fatalf("find item: %v", err)
+				}
+				if string(stored.EncryptedData) != `{"ciphertext":"updated"}` {
+					t.Errorf("data after update = %s, want the new ciphertext", stored.EncryptedData)
+				}
+				// 未提供类别时保留原类别，创建时间不变。
+				if stored.Category != item.Category || stored.UserID != item.UserID || !stored.CreatedAt.Equal(item.CreatedAt) {
+					t.Errorf("item after update = %+v, want category, owner and created_at kept from %+v", stored, item)
+				}
+			},
+			audit: core.AuditEventVaultItemUpdate,
+		},
+		{
+			name: "delete",
+			run: func(env *vaultTestEnv, id, userID uuid.UUID) error {
+				return env.vault.DeleteVaultItem(context.Background(), id, userID)
+			},
+			check: func(t *testing.T, env *vaultTestEnv, item *core.VaultItem) {
+				if _, err := env.storage.Vault().FindByID(context.Background(), item.ID); !errors.Is(err, core.ErrVaultItemNotFound) {
+					t.Errorf("item after delete: error = %v, want it deleted", err)
+				}
+			},
+			audit: core.AuditEventVaultItemDelete,
+		},
+	}
+	for _, op := range operations {
+		for _, tt := range tests {
+			t.Run(op.name+"/"+tt.name, func(t *testing.T) {
+				env := newVaultTestEnv(t)
+				item := env.createItem(t, env.owner.ID, "login")
+				id, userID := tt.target(env, item)
+
+				err := op.run(env, id, userID)
+				expectVaultError(t, op.name, err, tt.want)
+				if tt.want == nil {
+					if op.check != nil {
+						op.check(t, env, item)
+					}
+					if op.audit != "" {
+						if types := env.auditTypes(t, env.owner.ID); types[0] != op.audit {
+							t.Errorf("latest audit event = %s, want %s", types[0], op.audit)
+						}
+					}
+					return
+				}
+				// 被拒绝的写操作不能修改项目。
+				stored, err := env.storage.Vault().FindByID(context.Background(), item.ID)
+				if err != nil {
+					t.Fatalf("item after rejected %s: %v", op.name, err)
+				}
+				if string(stored.EncryptedData) != string(item.EncryptedData) {
+					t.Errorf("item after rejected %s = %s, want it unchanged", op.name, stored.EncryptedData)
+				}
+			})
+		}
+	}
+}
+
+func TestVaultItemUpdateKeepsOwner(t *testing.T) {
+	env := newVaultTestEnv(t)
+	item := env.createItem(t, env.owner.ID, "login")
+	// 请求体中的 UserID 不能把项目转移给其他用户。
+	updated, err := env.vault.UpdateVaultItem(context.Background(), &core.VaultItem{
+		ID:            item.ID,
+		UserID:        env.other.ID,
+		EncryptedData: json.RawMessage(`{"ciphertext":"updated"}`),
+		Category:      "note",
+	}, env.owner.ID)
+	if err != nil {
+		t.Fatalf("update item: %v", err)
+	}
+	if updated.UserID != env.owner.ID || updated.Category != "note" {
+		t.Errorf("updated item = %+v, want owner kept and category changed", updated)
+	}
+}
+
+func TestVaultExportImport(t *testing.T) {
+	ctx := context.Background()
+	env := newVaultTestEnv(t)
+	env.createItem(t, env.owner.ID, "login")
+	env.createItem(t, env.owner.ID, "note")
+
+	var buf bytes.Buffer
+	if err := env.vault.ExportVault(ctx, env.owner.ID, &buf); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	export, err := vaultexport.Decode(&buf)
+	if err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+
+	// 导出文件使用所有者的主盐，只能导入到主盐相同的账户。
+	if _, err := env.vault.ImportVault(ctx, env.other.ID, &vaultexport.Envelope{KDF: vaultexport.DefaultKDF("other salt")}); !errors.Is(err, apierror.ErrImportSaltMismatch) {
+		t.Errorf("import with another salt: error = %v, want %s", err, apierror.ErrImportSaltMismatch.Code)
+	}
+	count, err := env.vault.ImportVault(ctx, env.other.ID, export)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	items, err := env.vault.GetVaultItems(ctx, env.other.ID)
+	if err != nil {
+		t.Fatalf("get items: %v", err)
+	}
+	if count != 2 || len(items) != 2 {
+		t.Errorf("imported %d items, other user has %d; want 2", count, len(items))
+	}
+}
@@ -0,0 +1,56 @@
+package transfer
+
+import (
+	"easy-password-backend/internal/core"
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// normalize 将记录转换为两个存储后端都能无损保存的规范形式：
+// 时间戳转为微秒精度的 UTC 时间（PostgreSQL 的精度），JSON 字段重新编码为紧凑且键有序的形式，
+// 空切片统一为 nil，未设置的账户状态和角色填入默认值。
+// 空 map 保持不变：审计事件的 Metadata 为空 map 和 nil 时编码不同（"{}" 与 "null"），哈希也不同。
+// 迁移写入的是规范形式，校验和也基于规范形式计算，因此两端的校验和可以直接比较。
+func normalize(record any) {
+	switch rec := record.(type) {
+	case *core.User:
+		rec.Status = rec.AccountStatus()
+		rec.Role = rec.AccountRole()
+	}
+	normalizeValue(reflect.ValueOf(record).Elem())
+}
+
+func normalizeValue(v reflect.Value) {
+	switch {
+	case v.Type() == timeType:
+		t := v.Interface().(time.Time)
+		v.Set(reflect.ValueOf(t.UTC().Truncate(time.Microsecond)))
+	case v.Type() == rawMessageType:
+		raw := v.Interface().(json.RawMessage)
+		var decoded any
+		if len(raw) > 0 && json.Unmarshal(raw, &decoded) == nil {
+			canonical, _ := json.Marshal(decoded)
+			v.Set(reflect.ValueOf(json.RawMessage(canonical)))
+		}
+	case v.Kind() == reflect.Pointer:
+		if !v.IsNil() {
+			normalizeValue(v.Elem())
+		}
+	case v.Kind() == reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				normalizeValue(v.Field(i))
+			}
+		}
+	case v.Kind() == reflect.Slice:
+		if !v.IsNil() && v.Len() == 0 {
+			v.Set(reflect.Zero(v.Type()))
+		}
+	}
+}
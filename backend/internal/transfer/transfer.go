@@ -0,0 +1,146 @@
+// Package transfer 在两个存储后端之间原样复制全部数据，并通过数量和校验和验证结果。
+package transfer
+
+import (
+	"context"
+	"crypto/sha256"
+	"easy-password-backend/internal/core"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// batchSize 是每个写入事务包含的记录数量。
+const batchSize = 500
+
+// KindResult 是一类记录的迁移结果。
+type KindResult struct {
+	Kind           core.RecordKind
+	SourceCount    int64
+	SourceChecksum string
+	TargetCount    int64
+	TargetChecksum string
+}
+
+// Verified 报告目标端的数量和校验和是否与源端一致。
+func (r *KindResult) Verified() bool {
+	return r.SourceCount == r.TargetCount && r.SourceChecksum == r.TargetChecksum
+}
+
+// Options 控制一次迁移。
+type Options struct {
+	// DryRun 为 true 时只读取源端并计算数量和校验和，不写入目标端。
+	DryRun bool
+	// Progress 在每类记录处理完成后被调用，可以为 nil。
+	Progress func(result *KindResult)
+}
+
+// Run 将 src 中的全部记录按 core.RecordKinds 的顺序写入 dst，保留 ID、序号、哈希和时间戳，
+// 然后重新读取 dst 校验每类记录的数量和校验和。目标端必须为空。
+func Run(ctx context.Context, src, dst core.BulkRepository, opts Options) ([]*KindResult, error) {
+	if !opts.DryRun {
+		for _, kind := range core.RecordKinds {
+			n, err := dst.Count(ctx, kind)
+			if err != nil {
+				return nil, fmt.Errorf("count target %s: %w", kind, err)
+			}
+			if n > 0 {
+				return nil, fmt.Errorf("target is not empty: %d %s", n, kind)
+			}
+		}
+	}
+
+	var results []*KindResult
+	for _, kind := range core.RecordKinds {
+		result := &KindResult{Kind: kind}
+		sum := newChecksum()
+
+		var batch []any
+		flush := func() error {
+			if opts.DryRun || len(batch) == 0 {
+				batch = batch[:0]
+				return nil
+			}
+			if err := dst.Insert(ctx, kind, batch); err != nil {
+				return fmt.Errorf("write %s: %w", kind, err)
+			}
+			batch = batch[:0]
+			return nil
+		}
+
+		err := src.ForEach(ctx, kind, func(record any) error {
+			normalize(record)
+			if err := sum.add(record); err != nil {
+				return err
+			}
+			result.SourceCount++
+			batch = append(batch, record)
+			if len(batch) >= batchSize {
+				return flush()
+			}
+			return nil
+		})
+		if err == nil {
+			err = flush()
+		}
+		if err != nil {
+			return results, fmt.Errorf("copy %s: %w", kind, err)
+		}
+		result.SourceChecksum = sum.String()
+
+		if !opts.DryRun {
+			result.TargetCount, result.TargetChecksum, err = Checksum(ctx, dst, kind)
+			if err != nil {
+				return results, fmt.Errorf("verify %s: %w", kind, err)
+			}
+		}
+		results = append(results, result)
+		if opts.Progress != nil {
+			opts.Progress(result)
+		}
+	}
+	return results, nil
+}
+
+// Checksum 计算一类记录的数量和与顺序无关的校验和。
+func Checksum(ctx context.Context, repo core.BulkRepository, kind core.RecordKind) (int64, string, error) {
+	var count int64
+	sum := newChecksum()
+	err := repo.ForEach(ctx, kind, func(record any) error {
+		normalize(record)
+		count++
+		return sum.add(record)
+	})
+	if err != nil {
+		return 0, "", err
+	}
+	return count, sum.String(), nil
+}
+
+// checksum 对每条记录的规范 JSON 编码取 SHA-256，作为 256 位大端序整数按模 2^256 相加，
+// 结果与遍历顺序无关，两个后端按不同顺序返回记录时也能比较。
+// 与异或不同，相加时重复的记录不会互相抵消。
+type checksum [sha256.Size]byte
+
+func newChecksum() *checksum {
+	return &checksum{}
+}
+
+func (c *checksum) add(record any) error {
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	h := sha256.Sum256(encoded)
+	carry := 0
+	for i := len(c) - 1; i >= 0; i-- {
+		sum := int(c[i]) + int(h[i]) + carry
+		c[i] = byte(sum)
+		carry = sum >> 8
+	}
+	return nil
+}
+
+func (c *checksum) String() string {
+	return hex.EncodeToString(c[:])
+}
@@ -0,0 +1,81 @@
+package transfer
+
+import (
+	"context"
+	"easy-password-backend/internal/core"
+	"easy-password-backend/internal/repository/memory"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func checksumOf(t *testing.T, records ...any) string {
+	t.Helper()
+	sum := newChecksum()
+	for _, record := range records {
+		if err := sum.add(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return sum.String()
+}
+
+func TestChecksum(t *testing.T) {
+	a := &core.VerificationCode{Email: "a@example.com", Code: "111111"}
+	b := &core.VerificationCode{Email: "b@example.com", Code: "222222"}
+
+	if checksumOf(t, a, b) != checksumOf(t, b, a) {
+		t.Fatal("checksum depends on the order of the records")
+	}
+	// 异或时两条相同的记录互相抵消，与没有记录无法区分。
+	if checksumOf(t, a, a) == checksumOf(t) {
+		t.Fatal("two identical records cancel out")
+	}
+	if checksumOf(t, a, a, b) == checksumOf(t, a, b, b) {
+		t.Fatal("checksum does not tell which record is duplicated")
+	}
+}
+
+// TestRunKeepsAuditHashes 检查迁移后审计事件的哈希仍与内容一致，包括 Metadata 为空 map 的事件。
+func TestRunKeepsAuditHashes(t *testing.T) {
+	ctx := context.Background()
+	src, dst := memory.NewMemoryStorage(), memory.NewMemoryStorage()
+	userID := uuid.New()
+	for _, metadata := range []map[string]string{{}, nil, {"device_id": "laptop"}} {
+		event := &core.AuditEvent{UserID: userID, Type: core.AuditEventLoginSuccess, Metadata: metadata, CreatedAt: time.Now()}
+		if err := src.Audit().Create(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := Run(ctx, src.Bulk(), dst.Bulk(), Options{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for _, result := range results {
+		if !result.Verified() {
+			t.Fatalf("%s not verified: %+v", result.Kind, result)
+		}
+	}
+
+	var events []*core.AuditEvent
+	err = dst.Audit().ForEach(ctx, func(event *core.AuditEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("copied %d events, want 3", len(events))
+	}
+	if events[0].Metadata == nil {
+		t.Fatal("empty metadata became nil")
+	}
+	for _, event := range events {
+		if event.ComputeHash() != event.Hash {
+			t.Fatalf("event %d: hash no longer matches its contents", event.Sequence)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Package apitypes 定义 /api/v1/auth 和 /api/v1/vault 接口的 JSON 请求和响应体。
+// api/v1 中的处理器和 pkg/client 中的 Go SDK 都使用这些类型，因此线上格式的变化在编译时就会同时反映到两端。
+//
+// binding 标签供服务器校验请求使用，客户端可以忽略。
+package apitypes
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginStatusPendingApproval 是 PendingLoginResponse 的 Status。
+const LoginStatusPendingApproval = "pending_approval"
+
+// ErrorResponse 是所有非 2xx 响应的响应体，包括未知路由的 404 和 405 响应。
+//
+// Code 是稳定的标识，如 "AUTH_INVALID_CREDENTIALS"；客户端应根据它判断错误和本地化，
+// 而不是根据 Error，后者是面向开发者的英文消息。Code 为 "REQUEST_INVALID" 时，
+// Details 把请求字段映射到未通过的校验规则（如 "email": "email" 或 "code": "len=6"）。
+// RequestID 与响应头 X-Request-ID 和服务器的请求日志一致。
+type ErrorResponse struct {
+	Error     string            `json:"error"`
+	Code      string            `json:"code"`
+	Details   map[string]string `json:"details,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+}
+
+// MessageResponse 由只确认操作完成的接口返回。
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// SendVerificationCodeRequest 请求通过邮件发送注册验证码。
+type SendVerificationCodeRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// RegisterRequest 创建账号。MasterKeyHash 和 MasterSalt 由客户端生成，服务器从不接触主密码。
+type RegisterRequest struct {
+	Username      string `json:"username" binding:"required,min=1"`
+	Email         string `json:"email" binding:"required,email"`
+	MasterKeyHash string `json:"master_key_hash" binding:"required"`
+	MasterSalt    string `json:"master_salt" binding:"required"`
+	Code          string `json:"code" binding:"required,len=6"`
+}
+
+// RegisterResponse 随 201 Created 返回。
+type RegisterResponse struct {
+	Message string    `json:"message"`
+	UserID  uuid.UUID `json:"user_id"`
+}
+
+// SaltRequest 按用户名或邮箱查询账号的主盐。
+type SaltRequest struct {
+	Identifier string `json:"identifier" binding:"required"`
+}
+
+// SaltResponse 携带十六进制编码的主盐。
+type SaltResponse struct {
+	MasterSalt string `json:"master_salt"`
+}
+
+// LoginRequest 用主密钥哈希登录。设备字段可选，没有 DeviceID 的登录视为来自新设备。
+type LoginRequest struct {
+	Identifier    string `json:"identifier" binding:"required"`
+	MasterKeyHash string `json:"master_key_hash" binding:"required"`
+	DeviceID      string `json:"device_id" binding:"omitempty,max=255"`
+	DeviceName    string `json:"device_name" binding:"omitempty,max=255"`
+	DeviceType    string `json:"device_type" binding:"omitempty,max=50"`
+}
+
+// LoginResponse 在登录成功时随 200 OK 返回，无论是直接成功，还是轮询审批结果时得知已被批准。
+//
+// DeviceID 是令牌绑定的设备：请求中的设备，或请求没有设备时服务器生成的新设备。
+// 之后登录时带上它，服务器才能识别该设备。
+type LoginResponse struct {
+	Username   string `json:"username"`
+	Token      string `json:"token"`
+	MasterSalt string `json:"master_salt"`
+	DeviceID   string `json:"device_id"`
+}
+
+// PendingLoginResponse 在新设备的登录等待审批时随 202 Accepted 返回。
+// 客户端轮询 GET /auth/login-approvals/{ApprovalID} 获取结果。
+type PendingLoginResponse struct {
+	Status     string    `json:"status"`
+	ApprovalID uuid.UUID `json:"approval_id"`
+}
+
+// ApproveLoginRequest 用邮件中的令牌批准待审批的登录。
+type ApproveLoginRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// VerifyLoginApprovalRequest 用邮件中的验证码批准待审批的登录。
+type VerifyLoginApprovalRequest struct {
+	Code string `json:"code" binding:"required,len=6"`
+}
+
+// RequestPasswordResetRequest 请求通过邮件发送密码重置链接。
+type RequestPasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest 设置新的主密钥哈希和盐。
+type ResetPasswordRequest struct {
+	Token            string `json:"token" binding:"required"`
+	NewMasterKeyHash string `json:"new_master_key_hash" binding:"required"`
+	NewMasterSalt    string `json:"new_master_salt" binding:"required"`
+}
+
+// APIKeyTokenRequest 用 API 密钥凭据换取访问令牌。
+type APIKeyTokenRequest struct {
+	ClientID     string `json:"client_id" binding:"required"`
+	ClientSecret string `json:"client_secret" binding:"required"`
+}
+
+// APIKeyTokenResponse 使用 OAuth 2.0 令牌响应（RFC 6749 第 5.1 节）的字段名。Scope 是以空格分隔的列表。
+type APIKeyTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+	MasterSalt  string `json:"master_salt"`
+}
+
+// VaultItem 是保险库接口返回的由客户端加密的条目。EncryptedData 是客户端生成的 JSON 字符串，
+// 即 base64(IV || AES-GCM 密文)；分类以明文存储。
+type VaultItem struct {
+	ID            uuid.UUID       `json:"ID"`
+	UserID        uuid.UUID       `json:"UserID"`
+	EncryptedData json.RawMessage `json:"EncryptedData"`
+	Category      string          `json:"Category"`
+	CreatedAt     time.Time       `json:"CreatedAt"`
+	UpdatedAt     time.Time       `json:"UpdatedAt"`
+}
+
+// CreateItemRequest 向保险库添加条目。
+type CreateItemRequest struct {
+	EncryptedData json.RawMessage `json:"encrypted_data" binding:"required"`
+	Category      string          `json:"category"`
+}
+
+// UpdateItemRequest 替换条目的加密数据。Category 为 nil 或空时保留当前分类。
+type UpdateItemRequest struct {
+	EncryptedData json.RawMessage `json:"encrypted_data" binding:"required"`
+	Category      *string         `json:"category"`
+}
+
+// ImportResponse 报告 POST /vault/import 添加的条目数。
+type ImportResponse struct {
+	Message  string `json:"message"`
+	Imported int    `json:"imported"`
+}
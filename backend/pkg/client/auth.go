@@ -0,0 +1,183 @@
+package client
+
+import (
+	"context"
+	"easy-password-backend/pkg/apitypes"
+	"easy-password-backend/pkg/vaultcrypto"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Device 向服务器的设备跟踪标识客户端。每次登录应使用相同的 ID：
+// 要求设备审批的账号在未知 ID 登录时都会通过邮件请求审批。
+type Device struct {
+	ID   string
+	Name string
+	// Type 是自由填写的类型，如 "cli" 或 "service"。
+	Type string
+}
+
+// PendingApproval 描述等待账号所有者通过邮件中的链接或验证码批准设备的登录。
+type PendingApproval struct {
+	ApprovalID uuid.UUID
+}
+
+// SendVerificationCode 请求服务器通过邮件发送注册验证码。
+func (c *Client) SendVerificationCode(ctx context.Context, email string) error {
+	_, err := c.do(ctx, http.MethodPost, "/api/v1/auth/send-verification-code", apitypes.SendVerificationCodeRequest{Email: email}, nil, false)
+	return err
+}
+
+// Register 以与扩展相同的方式创建账号：生成主盐，由主密码派生保险库密钥，只发送密钥哈希。
+// 返回新用户的 ID 和保险库密钥。
+func (c *Client) Register(ctx context.Context, username, email, masterPassword, code string) (uuid.UUID, vaultcrypto.Key, error) {
+	salt, err := vaultcrypto.GenerateSalt()
+	if err != nil {
+		return uuid.Nil, vaultcrypto.Key{}, err
+	}
+	key, err := vaultcrypto.DeriveKey(masterPassword, salt)
+	if err != nil {
+		return uuid.Nil, vaultcrypto.Key{}, err
+	}
+
+	var resp apitypes.RegisterResponse
+	_, err = c.do(ctx, http.MethodPost, "/api/v1/auth/register", apitypes.RegisterRequest{
+		Username:      username,
+		Email:         email,
+		MasterKeyHash: key.AuthHash(),
+		MasterSalt:    salt,
+		Code:          code,
+	}, &resp, false)
+	if err != nil {
+		return uuid.Nil, vaultcrypto.Key{}, err
+	}
+	return resp.UserID, key, nil
+}
+
+// Salt 返回账号的十六进制编码的主盐。
+func (c *Client) Salt(ctx context.Context, identifier string) (string, error) {
+	var resp apitypes.SaltResponse
+	if _, err := c.do(ctx, http.MethodPost, "/api/v1/auth/salt", apitypes.SaltRequest{Identifier: identifier}, &resp, false); err != nil {
+		return "", err
+	}
+	return resp.MasterSalt, nil
+}
+
+// LoginWithPassword 获取主盐，派生保险库密钥并用其哈希登录。返回的密钥用于解密保险库，见 Client.Vault。
+func (c *Client) LoginWithPassword(ctx context.Context, identifier, masterPassword string, device Device) (vaultcrypto.Key, error) {
+	salt, err := c.Salt(ctx, identifier)
+	if err != nil {
+		return vaultcrypto.Key{}, err
+	}
+	key, err := vaultcrypto.DeriveKey(masterPassword, salt)
+	if err != nil {
+		return vaultcrypto.Key{}, err
+	}
+	if err := c.Login(ctx, identifier, key.AuthHash(), device); err != nil {
+		return vaultcrypto.Key{}, err
+	}
+	return key, nil
+}
+
+// Login 用主密钥哈希（vaultcrypto.Key.AuthHash）登录。设备需要审批时，调用审批处理函数并轮询到登录被批准。
+// 此后令牌过期时，客户端用相同的凭据续期。
+func (c *Client) Login(ctx context.Context, identifier, masterKeyHash string, device Device) error {
+	renew := c.loginRenewal(identifier, masterKeyHash, device)
+	if err := renew(ctx); err != nil {
+		return err
+	}
+	c.setRenewal(renew)
+	return nil
+}
+
+func (c *Client) loginRenewal(identifier, masterKeyHash string, device Device) func(context.Context) error {
+	req := apitypes.LoginRequest{
+		Identifier:    identifier,
+		MasterKeyHash: masterKeyHash,
+		DeviceID:      device.ID,
+		DeviceName:    device.Name,
+		DeviceType:    device.Type,
+	}
+	return func(ctx context.Context) error {
+		return c.login(ctx, req)
+	}
+}
+
+func (c *Client) login(ctx context.Context, req apitypes.LoginRequest) error {
+	var raw json.RawMessage
+	status, err := c.do(ctx, http.MethodPost, "/api/v1/auth/login", req, &raw, false)
+	if err != nil {
+		return err
+	}
+	for status == http.StatusAccepted {
+		var pending apitypes.PendingLoginResponse
+		if err := json.Unmarshal(raw, &pending); err != nil {
+			return err
+		}
+		if c.onApproval != nil {
+			c.onApproval(PendingApproval{ApprovalID: pending.ApprovalID})
+		}
+		if status, err = c.pollApproval(ctx, pending.ApprovalID, &raw); err != nil {
+			return err
+		}
+	}
+
+	var resp apitypes.LoginResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return err
+	}
+	if resp.Token == "" {
+		return errors.New("client: server did not return a token")
+	}
+	c.acceptToken(resp.Token, tokenExpiry(resp.Token))
+	return nil
+}
+
+// pollApproval 等到审批不再处于待定状态，返回最终的状态码和响应体。
+func (c *Client) pollApproval(ctx context.Context, approvalID uuid.UUID, raw *json.RawMessage) (int, error) {
+	for {
+		if err := sleep(ctx, c.pollInterval); err != nil {
+			return 0, err
+		}
+		status, err := c.do(ctx, http.MethodGet, "/api/v1/auth/login-approvals/"+approvalID.String(), nil, raw, false)
+		if err != nil || status != http.StatusAccepted {
+			return status, err
+		}
+	}
+}
+
+// ExchangeAPIKey 用 API 密钥凭据换取限定范围的访问令牌。此后令牌过期时，客户端再次交换。
+// 响应还携带用于派生保险库密钥的主盐。
+func (c *Client) ExchangeAPIKey(ctx context.Context, clientID, clientSecret string) (*apitypes.APIKeyTokenResponse, error) {
+	resp, err := c.exchangeAPIKey(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	c.setRenewal(c.apiKeyRenewal(clientID, clientSecret))
+	return resp, nil
+}
+
+func (c *Client) apiKeyRenewal(clientID, clientSecret string) func(context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := c.exchangeAPIKey(ctx, clientID, clientSecret)
+		return err
+	}
+}
+
+func (c *Client) exchangeAPIKey(ctx context.Context, clientID, clientSecret string) (*apitypes.APIKeyTokenResponse, error) {
+	var resp apitypes.APIKeyTokenResponse
+	req := apitypes.APIKeyTokenRequest{ClientID: clientID, ClientSecret: clientSecret}
+	if _, err := c.do(ctx, http.MethodPost, "/api/v1/auth/api-keys/token", req, &resp, false); err != nil {
+		return nil, err
+	}
+	if resp.AccessToken == "" || !strings.EqualFold(resp.TokenType, "Bearer") {
+		return nil, errors.New("client: server did not return a bearer token")
+	}
+	c.acceptToken(resp.AccessToken, time.Now().Add(time.Duration(resp.ExpiresIn)*time.Second))
+	return &resp, nil
+}
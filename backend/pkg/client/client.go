@@ -0,0 +1,382 @@
+// Package client 是 EasyPassword /api/v1 认证和保险库接口的 Go SDK。
+//
+// 请求和响应体是 pkg/apitypes 中与服务器处理器共用的类型。密钥派生和条目加密使用 pkg/vaultcrypto，
+// 因此通过 SDK 写入的条目可以由浏览器扩展读取，反之亦然。主密码和保险库密钥不离开进程，
+// 服务器只收到密钥哈希和密文。
+//
+// 典型的会话：
+//
+//	c := client.New("https://vault.example.com")
+//	key, err := c.LoginWithPassword(ctx, "alice@example.com", password, client.Device{ID: deviceID, Name: "backup job"})
+//	...
+//	items, err := c.Vault(key).Items(ctx)
+//
+// 登录成功后，客户端自行续期访问令牌：在令牌即将过期前续期，服务器返回 401 时再续期一次。
+// 因服务器过载或无法连接而失败的请求按 RetryPolicy 以指数退避重试。
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultApprovalPollInterval 是轮询等待设备审批的登录的间隔。
+	DefaultApprovalPollInterval = 3 * time.Second
+
+	// refreshMargin 是令牌在过期前多久续期。
+	refreshMargin = 30 * time.Second
+)
+
+// RetryPolicy 控制如何重试失败的请求。
+//
+// 服务器无法连接或返回 502、503 时重试，返回 429 且带有 Retry-After 头时也重试。
+// 网络错误和 504 只对 GET、PUT 和 DELETE 重试，因为 POST 可能已经生效。
+type RetryPolicy struct {
+	// MaxAttempts 是包括第一次在内的总尝试次数。小于 2 时不重试。
+	MaxAttempts int
+	// MinBackoff 是第一次重试前的等待时间。之后每次翻倍，最多到 MaxBackoff，并加入随机抖动。
+	MinBackoff time.Duration
+	// MaxBackoff 是等待时间的上限。Retry-After 超过它时不再等待，直接返回错误。
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy 在没有给出 WithRetryPolicy 时使用。
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  250 * time.Millisecond,
+	MaxBackoff:  5 * time.Second,
+}
+
+// Client 调用 EasyPassword API，可以并发使用。
+type Client struct {
+	baseURL      string
+	httpClient   *http.Client
+	retry        RetryPolicy
+	pollInterval time.Duration
+	onApproval   func(PendingApproval)
+	onToken      func(token string)
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+	// renew 用最近一次登录或 API 密钥交换的凭据获取新令牌；客户端只有令牌时为 nil。
+	renew func(ctx context.Context) error
+	// renewMu 使续期串行执行，并发收到的多个 401 只触发一次登录。
+	renewMu sync.Mutex
+}
+
+// Option 配置 Client。
+type Option func(*Client)
+
+// WithHTTPClient 设置发送请求所用的 HTTP 客户端。
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithRetryPolicy 替换 DefaultRetryPolicy。
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) { c.retry = p }
+}
+
+// WithToken 让客户端使用之前获取的访问令牌。与 WithLogin 或 WithAPIKey 一起使用时客户端可以为它续期。
+func WithToken(token string) Option {
+	return func(c *Client) { c.setToken(token, tokenExpiry(token)) }
+}
+
+// WithLogin 让客户端在令牌过期时用这些凭据重新登录，但不会预先登录。
+func WithLogin(identifier, masterKeyHash string, device Device) Option {
+	return func(c *Client) { c.renew = c.loginRenewal(identifier, masterKeyHash, device) }
+}
+
+// WithAPIKey 让客户端在令牌过期时用这些 API 密钥凭据换取新令牌，但不会预先交换。
+func WithAPIKey(clientID, clientSecret string) Option {
+	return func(c *Client) { c.renew = c.apiKeyRenewal(clientID, clientSecret) }
+}
+
+// WithApprovalHandler 设置新设备的登录需要通过邮件审批时调用的函数。
+// Login 会持续轮询，直到登录被批准、审批过期或 context 结束。
+func WithApprovalHandler(fn func(PendingApproval)) Option {
+	return func(c *Client) { c.onApproval = fn }
+}
+
+// WithApprovalPollInterval 替换 DefaultApprovalPollInterval。
+func WithApprovalPollInterval(d time.Duration) Option {
+	return func(c *Client) { c.pollInterval = d }
+}
+
+// WithTokenHandler 设置每次得到新访问令牌时调用的函数，包括登录、API 密钥交换和自动续期之后，
+// 以便调用方保存令牌。
+func WithTokenHandler(fn func(token string)) Option {
+	return func(c *Client) { c.onToken = fn }
+}
+
+// New 返回访问 baseURL（如 "http://localhost:8081"）上服务器的客户端。
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		httpClient:   &http.Client{Timeout: 30 * time.Second},
+		retry:        DefaultRetryPolicy,
+		pollInterval: DefaultApprovalPollInterval,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Token 返回当前的访问令牌，首次登录前返回 ""。
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+func (c *Client) setToken(token string, expiresAt time.Time) {
+	c.mu.Lock()
+	c.token = token
+	c.expiresAt = expiresAt
+	c.mu.Unlock()
+}
+
+// acceptToken 保存服务器返回的令牌，并通知令牌处理函数。
+func (c *Client) acceptToken(token string, expiresAt time.Time) {
+	c.setToken(token, expiresAt)
+	if c.onToken != nil {
+		c.onToken(token)
+	}
+}
+
+// currentToken 返回用于认证请求的令牌，令牌即将过期时先续期。
+func (c *Client) currentToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	token, expiresAt, canRenew := c.token, c.expiresAt, c.renew != nil
+	c.mu.Unlock()
+
+	if canRenew && (token == "" || (!expiresAt.IsZero() && time.Until(expiresAt) < refreshMargin)) {
+		if err := c.renewToken(ctx, token); err != nil {
+			return "", err
+		}
+		return c.Token(), nil
+	}
+	if token == "" {
+		return "", ErrNotAuthenticated
+	}
+	return token, nil
+}
+
+func (c *Client) canRenew() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.renew != nil
+}
+
+// setRenewal 记录获取新令牌的方式。
+func (c *Client) setRenewal(renew func(ctx context.Context) error) {
+	c.mu.Lock()
+	c.renew = renew
+	c.mu.Unlock()
+}
+
+// renewToken 获取新令牌；如果在等待期间另一个 goroutine 已经替换了 stale，则不再获取。
+func (c *Client) renewToken(ctx context.Context, stale string) error {
+	c.renewMu.Lock()
+	defer c.renewMu.Unlock()
+	c.mu.Lock()
+	token, renew := c.token, c.renew
+	c.mu.Unlock()
+	if token != stale {
+		return nil
+	}
+	if renew == nil {
+		return ErrNotAuthenticated
+	}
+	return renew(ctx)
+}
+
+// do 发送 JSON 请求，并把 2xx 响应解码到 out，非 2xx 响应以 *Error 返回。
+// authenticated 为真时请求携带访问令牌，收到 401 时续期一次并重试。
+func (c *Client) do(ctx context.Context, method, path string, body, out any, authenticated bool) (int, error) {
+	var encoded []byte
+	if body != nil {
+		var err error
+		if encoded, err = json.Marshal(body); err != nil {
+			return 0, err
+		}
+	}
+
+	renewed := false
+	for {
+		token := ""
+		if authenticated {
+			var err error
+			if token, err = c.currentToken(ctx); err != nil {
+				return 0, err
+			}
+		}
+
+		status, err := c.send(ctx, method, path, encoded, token, out)
+		if authenticated && !renewed && status == http.StatusUnauthorized && c.canRenew() {
+			renewed = true
+			if err := c.renewToken(ctx, token); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		return status, err
+	}
+}
+
+// send 执行一个逻辑请求，按 RetryPolicy 重试。
+func (c *Client) send(ctx context.Context, method, path string, body []byte, token string, out any) (int, error) {
+	for attempt := 1; ; attempt++ {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("Accept", "application/json")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		wait, retry := c.retryDelay(method, resp, err, attempt)
+		if retry {
+			if resp != nil {
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+			if err := sleep(ctx, wait); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		return resp.StatusCode, c.readResponse(resp, method, path, out)
+	}
+}
+
+func (c *Client) readResponse(resp *http.Response, method, path string, out any) error {
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newError(resp, method, path)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return &Error{StatusCode: resp.StatusCode, Method: method, Path: path, Message: "invalid response body: " + err.Error()}
+	}
+	return nil
+}
+
+// retryDelay 决定一次尝试的结果是否需要重试，以及重试前等待多久。
+func (c *Client) retryDelay(method string, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if attempt >= c.retry.MaxAttempts {
+		return 0, false
+	}
+	idempotent := method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete
+	backoff := c.backoff(attempt)
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return 0, false
+		}
+		return backoff, idempotent
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+	case http.StatusGatewayTimeout:
+		if !idempotent {
+			return 0, false
+		}
+	case http.StatusTooManyRequests:
+		if resp.Header.Get("Retry-After") == "" {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+		if after > c.retry.MaxBackoff {
+			return 0, false
+		}
+		return after, true
+	}
+	return backoff, true
+}
+
+// backoff 返回第 attempt 次重试前加入抖动的等待时间。
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.retry.MinBackoff << (attempt - 1)
+	if d <= 0 || d > c.retry.MaxBackoff {
+		d = c.retry.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryAfter 解析以秒数或 HTTP 日期给出的 Retry-After 头。
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// tokenExpiry 读取 JWT 的 exp 声明但不验证令牌，验证由服务器完成。读不到过期时间时返回零值。
+func tokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"easy-password-backend/pkg/apitypes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrNotAuthenticated 表示调用需要令牌，而客户端既没有令牌，也没有可用于获取令牌的凭据。
+var ErrNotAuthenticated = errors.New("client: not logged in")
+
+// API 所用各类 HTTP 状态对应的哨兵错误。*Error 用 errors.Is 与其状态码对应的错误匹配：
+//
+//	if errors.Is(err, client.ErrNotFound) { ... }
+var (
+	ErrBadRequest      = errors.New("bad request")
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrForbidden       = errors.New("forbidden")
+	ErrNotFound        = errors.New("not found")
+	ErrConflict        = errors.New("conflict")
+	ErrGone            = errors.New("gone")
+	ErrLocked          = errors.New("locked")
+	ErrTooManyRequests = errors.New("too many requests")
+	ErrServer          = errors.New("server error")
+)
+
+var statusErrors = map[int]error{
+	http.StatusBadRequest:      ErrBadRequest,
+	http.StatusUnauthorized:    ErrUnauthorized,
+	http.StatusForbidden:       ErrForbidden,
+	http.StatusNotFound:        ErrNotFound,
+	http.StatusConflict:        ErrConflict,
+	http.StatusGone:            ErrGone,
+	http.StatusLocked:          ErrLocked,
+	http.StatusTooManyRequests: ErrTooManyRequests,
+}
+
+// Error 是服务器返回的非 2xx 响应。Message 是响应体的 "error" 字段，响应体中没有时为 HTTP 状态文本。
+// Code、Details 和 RequestID 同样复制自响应体，见 apitypes.ErrorResponse。
+// 区分状态码相同的错误时应比较 Code 而不是 Message：
+//
+//	var apiErr *client.Error
+//	if errors.As(err, &apiErr) && apiErr.Code == "AUTH_INVALID_CREDENTIALS" { ... }
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Details    map[string]string
+	RequestID  string
+	Method     string
+	Path       string
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("%s %s: %s (HTTP %d)", e.Method, e.Path, e.Message, e.StatusCode)
+	}
+	return fmt.Sprintf("%s %s: %s (HTTP %d, %s)", e.Method, e.Path, e.Message, e.StatusCode, e.Code)
+}
+
+// Is 报告 target 是否是 e 的状态码对应的哨兵错误。
+func (e *Error) Is(target error) bool {
+	if target == ErrServer {
+		return e.StatusCode >= 500
+	}
+	sentinel, ok := statusErrors[e.StatusCode]
+	return ok && sentinel == target
+}
+
+// newError 由非 2xx 响应构造 *Error。
+func newError(resp *http.Response, method, path string) *Error {
+	e := &Error{StatusCode: resp.StatusCode, Method: method, Path: path}
+	var body apitypes.ErrorResponse
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if json.Unmarshal(data, &body) == nil && body.Error != "" {
+		e.Message = body.Error
+		e.Code = body.Code
+		e.Details = body.Details
+		e.RequestID = body.RequestID
+	} else {
+		e.Message = http.StatusText(resp.StatusCode)
+	}
+	if e.RequestID == "" {
+		e.RequestID = resp.Header.Get("X-Request-ID")
+	}
+	return e
+}
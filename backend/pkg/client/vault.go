@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"easy-password-backend/pkg/apitypes"
+	"easy-password-backend/pkg/vaultcrypto"
+	"easy-password-backend/pkg/vaultexport"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Items 返回保险库中加密的条目。受限 API 密钥换得的令牌只能看到该密钥允许的条目。
+func (c *Client) Items(ctx context.Context) ([]apitypes.VaultItem, error) {
+	var items []apitypes.VaultItem
+	if _, err := c.do(ctx, http.MethodGet, "/api/v1/vault/items", nil, &items, true); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// CreateItem 保存一个加密的条目。
+func (c *Client) CreateItem(ctx context.Context, req apitypes.CreateItemRequest) (*apitypes.VaultItem, error) {
+	var item apitypes.VaultItem
+	if _, err := c.do(ctx, http.MethodPost, "/api/v1/vault/items", req, &item, true); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// UpdateItem 替换条目的加密数据。
+func (c *Client) UpdateItem(ctx context.Context, id uuid.UUID, req apitypes.UpdateItemRequest) (*apitypes.VaultItem, error) {
+	var item apitypes.VaultItem
+	if _, err := c.do(ctx, http.MethodPut, "/api/v1/vault/items/"+id.String(), req, &item, true); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// DeleteItem 删除条目。
+func (c *Client) DeleteItem(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, http.MethodDelete, "/api/v1/vault/items/"+id.String(), nil, nil, true)
+	return err
+}
+
+// ExportVault 下载整个保险库，条目保持加密。
+func (c *Client) ExportVault(ctx context.Context) (*vaultexport.Envelope, error) {
+	var env vaultexport.Envelope
+	if _, err := c.do(ctx, http.MethodGet, "/api/v1/vault/export", nil, &env, true); err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+// ImportVault 上传导出信封，返回添加的条目数。信封中的盐必须与账号的主盐相同。
+func (c *Client) ImportVault(ctx context.Context, env *vaultexport.Envelope) (int, error) {
+	var resp apitypes.ImportResponse
+	if _, err := c.do(ctx, http.MethodPost, "/api/v1/vault/import", env, &resp, true); err != nil {
+		return 0, err
+	}
+	return resp.Imported, nil
+}
+
+// ItemData 是保险库条目的明文，即扩展中的 DecryptedVaultItem，加密后成为 EncryptedData。
+type ItemData struct {
+	Name     string `json:"name"`
+	Account  string `json:"account"`
+	Website  string `json:"website,omitempty"`
+	Password string `json:"password,omitempty"`
+	Notes    string `json:"notes,omitempty"`
+}
+
+// Item 是解密后的保险库条目。分类不加密，与数据并列而不在数据之中。
+type Item struct {
+	ID       uuid.UUID `json:"id"`
+	Category string    `json:"category"`
+	ItemData
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Vault 以明文读写条目，在本地用保险库密钥加密和解密。
+type Vault struct {
+	client *Client
+	key    vaultcrypto.Key
+}
+
+// Vault 返回使用 key 的 Vault，key 由 LoginWithPassword 返回或用 vaultcrypto.DeriveKey 派生。
+func (c *Client) Vault(key vaultcrypto.Key) *Vault {
+	return &Vault{client: c, key: key}
+}
+
+// Decrypt 解密 Client.Items 返回的一个条目。
+func (v *Vault) Decrypt(item apitypes.VaultItem) (Item, error) {
+	decrypted := Item{
+		ID:        item.ID,
+		Category:  item.Category,
+		CreatedAt: item.CreatedAt,
+		UpdatedAt: item.UpdatedAt,
+	}
+	if err := v.key.DecryptItem(item.EncryptedData, &decrypted.ItemData); err != nil {
+		return Item{}, fmt.Errorf("item %s: %w", item.ID, err)
+	}
+	return decrypted, nil
+}
+
+// Items 返回解密后的全部条目。任何条目无法解密时都会失败；要跳过这样的条目，请使用 Client.Items 和 Decrypt。
+func (v *Vault) Items(ctx context.Context) ([]Item, error) {
+	raw, err := v.client.Items(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Item, len(raw))
+	for i, item := range raw {
+		if items[i], err = v.Decrypt(item); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// Create 加密 data 并保存为新条目。
+func (v *Vault) Create(ctx context.Context, data ItemData, category string) (Item, error) {
+	encrypted, err := v.key.EncryptItem(data)
+	if err != nil {
+		return Item{}, err
+	}
+	created, err := v.client.CreateItem(ctx, apitypes.CreateItemRequest{EncryptedData: encrypted, Category: category})
+	if err != nil {
+		return Item{}, err
+	}
+	return v.Decrypt(*created)
+}
+
+// Update 替换条目的数据。category 为 nil 或空时保留当前分类。
+func (v *Vault) Update(ctx context.Context, id uuid.UUID, data ItemData, category *string) (Item, error) {
+	encrypted, err := v.key.EncryptItem(data)
+	if err != nil {
+		return Item{}, err
+	}
+	updated, err := v.client.UpdateItem(ctx, id, apitypes.UpdateItemRequest{EncryptedData: encrypted, Category: category})
+	if err != nil {
+		return Item{}, err
+	}
+	return v.Decrypt(*updated)
+}
+
+// Delete 删除条目。
+func (v *Vault) Delete(ctx context.Context, id uuid.UUID) error {
+	return v.client.DeleteItem(ctx, id)
+}
@@ -0,0 +1,78 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Bitwarden 条目类型。
+const (
+	bitwardenLogin      = 1
+	bitwardenSecureNote = 2
+)
+
+type bitwardenExport struct {
+	Encrypted bool `json:"encrypted"`
+	Folders   []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"folders"`
+	Items []struct {
+		Type     int     `json:"type"`
+		Name     string  `json:"name"`
+		Notes    *string `json:"notes"`
+		FolderID *string `json:"folderId"`
+		Login    *struct {
+			Username *string `json:"username"`
+			Password *string `json:"password"`
+			URIs     []struct {
+				URI string `json:"uri"`
+			} `json:"uris"`
+		} `json:"login"`
+	} `json:"items"`
+}
+
+// ParseBitwarden 解析未加密的 Bitwarden JSON 导出文件。导入登录和安全笔记，文件夹成为分类。
+func ParseBitwarden(data []byte) (*Result, error) {
+	var export bitwardenExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("parse bitwarden export: %w", err)
+	}
+	if export.Encrypted {
+		return nil, errors.New("bitwarden export is encrypted; export it as unencrypted JSON")
+	}
+
+	folders := make(map[string]string, len(export.Folders))
+	for _, f := range export.Folders {
+		folders[f.ID] = f.Name
+	}
+
+	result := &Result{}
+	for _, src := range export.Items {
+		if src.Type != bitwardenLogin && src.Type != bitwardenSecureNote {
+			result.Skipped++
+			continue
+		}
+		item := Item{Name: src.Name, Notes: deref(src.Notes)}
+		if src.FolderID != nil {
+			item.Category = folders[*src.FolderID]
+		}
+		if src.Login != nil {
+			item.Account = deref(src.Login.Username)
+			item.Password = deref(src.Login.Password)
+			if len(src.Login.URIs) > 0 {
+				item.Website = src.Login.URIs[0].URI
+			}
+		}
+		result.Items = append(result.Items, normalize(item))
+	}
+	return result, nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
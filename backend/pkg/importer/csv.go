@@ -0,0 +1,61 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ParseCSV 解析 Chrome 或 Firefox 导出的密码 CSV 文件。按表头名称匹配列，因此两种布局都可以接受：
+//
+//	Chrome:  name,url,username,password,note
+//	Firefox: "url","username","password","httpRealm","formActionOrigin",...
+func ParseCSV(data []byte) (*Result, error) {
+	// Excel 和部分浏览器会在开头加上 UTF-8 字节顺序标记。
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse csv: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("csv export is empty")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["password"]; !ok {
+		return nil, errors.New("csv export has no password column")
+	}
+	field := func(record []string, names ...string) string {
+		for _, name := range names {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return record[i]
+			}
+		}
+		return ""
+	}
+
+	result := &Result{}
+	for _, record := range records[1:] {
+		item := Item{
+			Name:     field(record, "name", "title"),
+			Website:  field(record, "url", "origin"),
+			Account:  field(record, "username", "login"),
+			Password: field(record, "password"),
+			Notes:    field(record, "note", "notes"),
+		}
+		if item.Website == "" && item.Account == "" && item.Password == "" {
+			result.Skipped++
+			continue
+		}
+		result.Items = append(result.Items, normalize(item))
+	}
+	return result, nil
+}
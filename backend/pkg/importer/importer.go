@@ -0,0 +1,83 @@
+// Package importer 把其他密码管理器的导出文件转换为 EasyPassword 客户端使用的明文条目模型。
+// 得到的条目应在客户端加密（见 pkg/vaultcrypto）后再上传；本包不与服务器通信。
+package importer
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Format 标识一种支持的来源格式。
+type Format string
+
+const (
+	FormatBitwarden   Format = "bitwarden"
+	FormatOnePassword Format = "1pux"
+	FormatKeePass     Format = "keepass"
+	FormatCSV         Format = "csv"
+)
+
+// Formats 列出所有支持的来源格式。
+var Formats = []Format{FormatBitwarden, FormatOnePassword, FormatKeePass, FormatCSV}
+
+// Item 是保险库条目规范化后的明文形式。JSON 字段名与扩展加密的对象一致，
+// 因此 Item 可以直接传给 vaultcrypto.Key.EncryptItem。Category 由服务器以明文存储，因此不在 JSON 中。
+type Item struct {
+	Name     string `json:"name"`
+	Account  string `json:"account"`
+	Website  string `json:"website,omitempty"`
+	Password string `json:"password,omitempty"`
+	Notes    string `json:"notes,omitempty"`
+	Category string `json:"-"`
+}
+
+// Result 是解析导出文件的结果。
+type Result struct {
+	Items []Item
+	// Skipped 是条目模型中没有对应类型的来源条目数，如银行卡和身份信息。
+	Skipped int
+}
+
+// Parse 按给定格式解析 data。
+func Parse(format Format, data []byte) (*Result, error) {
+	switch format {
+	case FormatBitwarden:
+		return ParseBitwarden(data)
+	case FormatOnePassword:
+		return ParseOnePassword(data)
+	case FormatKeePass:
+		return ParseKeePass(data)
+	case FormatCSV:
+		return ParseCSV(data)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+// normalize 为没有名称的条目补上名称，并去掉首尾空白。
+func normalize(item Item) Item {
+	item.Name = strings.TrimSpace(item.Name)
+	item.Account = strings.TrimSpace(item.Account)
+	item.Website = strings.TrimSpace(item.Website)
+	item.Category = strings.TrimSpace(item.Category)
+	if item.Name == "" {
+		item.Name = hostOf(item.Website)
+	}
+	if item.Name == "" {
+		item.Name = item.Account
+	}
+	return item
+}
+
+// hostOf 返回 URL 的主机名，无法解析时原样返回输入。
+func hostOf(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	return u.Hostname()
+}
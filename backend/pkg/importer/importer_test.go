@@ -0,0 +1,104 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseFixtures(t *testing.T) {
+	tests := []struct {
+		file        string
+		format      Format
+		want        []Item
+		wantSkipped int
+	}{
+		{
+			// 卡片被跳过；文件夹名成为类别，安全笔记保留为只有备注的项目。
+			file:   "bitwarden.json",
+			format: FormatBitwarden,
+			want: []Item{
+				{Name: "GitHub", Account: "octocat", Website: "https://github.com/login", Password: "correct-horse-battery-staple", Notes: "Recovery codes are in the safe.", Category: "Work"},
+				{Name: "Wi-Fi", Notes: "SSID: home\nKey: hunter2"},
+			},
+			wantSkipped: 1,
+		},
+		{
+			// 已归档的项目和信用卡被跳过；保险库名成为类别。
+			file:   "sample.1pux",
+			format: FormatOnePassword,
+			want: []Item{
+				{Name: "GitHub", Account: "octocat", Website: "https://github.com", Password: "correct-horse-battery-staple", Notes: "Imported from 1Password", Category: "Private"},
+			},
+			wantSkipped: 2,
+		},
+		{
+			// 顶层组是数据库本身，其中的条目没有类别；回收站被跳过。
+			file:   "keepass.xml",
+			format: FormatKeePass,
+			want: []Item{
+				{Name: "Router", Account: "admin", Website: "http://192.168.1.1", Password: "s3cr3t-router"},
+				{Name: "Example Bank", Account: "jane.doe", Website: "https://bank.example.com", Password: "b4nk-p4ss", Notes: "PIN reminder: birthday", Category: "Banking"},
+			},
+			wantSkipped: 1,
+		},
+		{
+			// Chrome 的列顺序，密码中含有引号和逗号。
+			file:   "chrome.csv",
+			format: FormatCSV,
+			want: []Item{
+				{Name: "github.com", Account: "octocat", Website: "https://github.com/login", Password: "correct-horse-battery-staple"},
+				{Name: "accounts.google.com", Account: "jane.doe@gmail.com", Website: "https://accounts.google.com/signin", Password: `pa,ss"word`, Notes: "Work account"},
+			},
+		},
+		{
+			// Firefox 的导出没有名称列，名称取自网站的主机名。
+			file:   "firefox.csv",
+			format: FormatCSV,
+			want: []Item{
+				{Name: "github.com", Account: "octocat", Website: "https://github.com", Password: "correct-horse-battery-staple"},
+				{Name: "www.mozilla.org", Account: "jane", Website: "https://www.mozilla.org", Password: "m0zilla"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := Parse(tt.format, data)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !slices.Equal(result.Items, tt.want) {
+				t.Errorf("items:\ngot  %+v\nwant %+v", result.Items, tt.want)
+			}
+			if result.Skipped != tt.wantSkipped {
+				t.Errorf("skipped = %d, want %d", result.Skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		data   string
+	}{
+		{"unknown format", "lastpass", "name,url\n"},
+		{"bitwarden not json", FormatBitwarden, "name,url\n"},
+		{"bitwarden encrypted", FormatBitwarden, `{"encrypted": true, "items": []}`},
+		{"1pux not zip", FormatOnePassword, `{"accounts": []}`},
+		{"keepass not xml", FormatKeePass, `{"Root": {}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result, err := Parse(tt.format, []byte(tt.data)); err == nil {
+				t.Errorf("Parse succeeded with %+v", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+type keePassFile struct {
+	Root struct {
+		Groups []keePassGroup `xml:"Group"`
+	} `xml:"Root"`
+	Meta struct {
+		RecycleBinUUID string `xml:"RecycleBinUUID"`
+	} `xml:"Meta"`
+}
+
+type keePassGroup struct {
+	UUID    string         `xml:"UUID"`
+	Name    string         `xml:"Name"`
+	Entries []keePassEntry `xml:"Entry"`
+	Groups  []keePassGroup `xml:"Group"`
+}
+
+type keePassEntry struct {
+	Strings []struct {
+		Key   string `xml:"Key"`
+		Value string `xml:"Value"`
+	} `xml:"String"`
+}
+
+// ParseKeePass 解析 KeePass 2.x 的 XML 导出文件。条目所在组的名称成为其分类，回收站被跳过。
+func ParseKeePass(data []byte) (*Result, error) {
+	var file keePassFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse keepass xml: %w", err)
+	}
+
+	result := &Result{}
+	var walk func(group keePassGroup, category string)
+	walk = func(group keePassGroup, category string) {
+		if file.Meta.RecycleBinUUID != "" && group.UUID == file.Meta.RecycleBinUUID {
+			result.Skipped += countKeePassEntries(group)
+			return
+		}
+		for _, entry := range group.Entries {
+			item := Item{Category: category}
+			for _, s := range entry.Strings {
+				switch s.Key {
+				case "Title":
+					item.Name = s.Value
+				case "UserName":
+					item.Account = s.Value
+				case "Password":
+					item.Password = s.Value
+				case "URL":
+					item.Website = s.Value
+				case "Notes":
+					item.Notes = s.Value
+				}
+			}
+			result.Items = append(result.Items, normalize(item))
+		}
+		for _, child := range group.Groups {
+			walk(child, child.Name)
+		}
+	}
+	// 顶层组是数据库本身，因此其中的条目没有分类。
+	for _, root := range file.Root.Groups {
+		walk(root, "")
+	}
+	return result, nil
+}
+
+func countKeePassEntries(group keePassGroup) int {
+	n := len(group.Entries)
+	for _, child := range group.Groups {
+		n += countKeePassEntries(child)
+	}
+	return n
+}
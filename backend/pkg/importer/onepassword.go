@@ -0,0 +1,101 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// 可以映射到条目模型的 1Password 分类 UUID。
+const (
+	onePasswordLogin      = "001"
+	onePasswordSecureNote = "003"
+	onePasswordPassword   = "005"
+)
+
+type onePasswordExport struct {
+	Accounts []struct {
+		Vaults []struct {
+			Attrs struct {
+				Name string `json:"name"`
+			} `json:"attrs"`
+			Items []struct {
+				State        string `json:"state"`
+				CategoryUUID string `json:"categoryUuid"`
+				Overview     struct {
+					Title string `json:"title"`
+					URL   string `json:"url"`
+				} `json:"overview"`
+				Details struct {
+					LoginFields []struct {
+						Value       string `json:"value"`
+						Designation string `json:"designation"`
+					} `json:"loginFields"`
+					NotesPlain string `json:"notesPlain"`
+					Password   string `json:"password"`
+				} `json:"details"`
+			} `json:"items"`
+		} `json:"vaults"`
+	} `json:"accounts"`
+}
+
+// ParseOnePassword 解析 1Password 的 1PUX 导出文件（包含 export.data 的 zip 压缩包）。
+// 导入未归档的登录、密码和安全笔记，保险库名称成为分类。
+func ParseOnePassword(data []byte) (*Result, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("open 1pux archive: %w", err)
+	}
+	file, err := archive.Open("export.data")
+	if err != nil {
+		return nil, fmt.Errorf("1pux archive has no export.data: %w", err)
+	}
+	defer file.Close()
+	raw, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("read export.data: %w", err)
+	}
+
+	var export onePasswordExport
+	if err := json.Unmarshal(raw, &export); err != nil {
+		return nil, fmt.Errorf("parse export.data: %w", err)
+	}
+
+	result := &Result{}
+	for _, account := range export.Accounts {
+		for _, vault := range account.Vaults {
+			for _, src := range vault.Items {
+				if src.State == "archived" {
+					result.Skipped++
+					continue
+				}
+				switch src.CategoryUUID {
+				case onePasswordLogin, onePasswordSecureNote, onePasswordPassword:
+				default:
+					result.Skipped++
+					continue
+				}
+
+				item := Item{
+					Name:     src.Overview.Title,
+					Website:  src.Overview.URL,
+					Notes:    src.Details.NotesPlain,
+					Password: src.Details.Password,
+					Category: vault.Attrs.Name,
+				}
+				for _, field := range src.Details.LoginFields {
+					switch field.Designation {
+					case "username":
+						item.Account = field.Value
+					case "password":
+						item.Password = field.Value
+					}
+				}
+				result.Items = append(result.Items, normalize(item))
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,128 @@
+// Package vaultcrypto 是 extension/src/crypto/vault.ts 中客户端加密逻辑的 Go 移植。
+// 这里生成的密钥、认证哈希和密文与浏览器扩展及 Web 前端逐字节兼容。
+package vaultcrypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	// Iterations 是客户端使用的 PBKDF2 迭代次数。
+	Iterations = 100000
+	// KeySize 是 AES-256 密钥的字节数。
+	KeySize = 32
+	// SaltSize 是新生成的主盐的字节数。
+	SaltSize = 16
+	// NonceSize 是加在每个密文前面的 AES-GCM IV 的字节数。
+	NonceSize = 12
+)
+
+// ErrDecrypt 表示密文无法解密，通常是主密码或盐不正确。
+var ErrDecrypt = errors.New("decryption failed: invalid master password or corrupted data")
+
+// Key 是由主密码派生的保险库密钥。
+type Key [KeySize]byte
+
+// GenerateSalt 返回新的随机主盐，以十六进制编码。
+func GenerateSalt() (string, error) {
+	salt := make([]byte, SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(salt), nil
+}
+
+// DeriveKey 用 PBKDF2-SHA256 由主密码和十六进制编码的主盐派生保险库密钥。
+func DeriveKey(masterPassword, salt string) (Key, error) {
+	var key Key
+	saltBytes, err := hex.DecodeString(salt)
+	if err != nil {
+		return key, fmt.Errorf("invalid master salt: %w", err)
+	}
+	derived, err := pbkdf2.Key(sha256.New, masterPassword, saltBytes, Iterations, KeySize)
+	if err != nil {
+		return key, err
+	}
+	copy(key[:], derived)
+	return key, nil
+}
+
+// AuthHash 返回原始密钥的 SHA-256 十六进制值，即注册和登录时发送给服务器的 master_key_hash。
+func (k Key) AuthHash() string {
+	sum := sha256.Sum256(k[:])
+	return hex.EncodeToString(sum[:])
+}
+
+// Encrypt 用 AES-256-GCM 加密 plaintext，返回 base64(IV || ciphertext)。
+func (k Key) Encrypt(plaintext []byte) (string, error) {
+	gcm, err := k.gcm()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, NonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密由 Encrypt 或扩展生成的 base64(IV || ciphertext) 字符串。
+func (k Key) Decrypt(encoded string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(data) < NonceSize {
+		return nil, ErrDecrypt
+	}
+	gcm, err := k.gcm()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, data[:NonceSize], data[NonceSize:], nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// EncryptItem 把 v 编码为 JSON 并加密，以保险库 API 在 encrypted_data 中期望的 JSON 字符串值返回结果。
+func (k Key) EncryptItem(v any) (json.RawMessage, error) {
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := k.Encrypt(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(encoded)
+}
+
+// DecryptItem 是 EncryptItem 的逆操作，把解密得到的 JSON 解码到 v。
+func (k Key) DecryptItem(encryptedData json.RawMessage, v any) error {
+	var encoded string
+	if err := json.Unmarshal(encryptedData, &encoded); err != nil {
+		return fmt.Errorf("encrypted_data is not a string: %w", err)
+	}
+	plaintext, err := k.Decrypt(encoded)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(plaintext, v)
+}
+
+func (k Key) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
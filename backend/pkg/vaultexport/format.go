@@ -0,0 +1,236 @@
+// Package vaultexport 定义导出和导入 EasyPassword 保险库所用的 JSON 格式，与服务器实现无关。
+//
+// 条目保持客户端存储时的加密形式。信封同时携带由主密码重新派生保险库密钥所需的盐和 KDF 参数，
+// 因此导出文件是自包含的：
+//
+//	{
+//	  "format": "easypassword-vault",
+//	  "version": 1,
+//	  "exported_at": "2026-01-02T15:04:05Z",
+//	  "kdf": {"algorithm": "PBKDF2", "hash": "SHA-256", "iterations": 100000,
+//	          "key_length": 256, "salt": "<hex>", "cipher": "AES-GCM"},
+//	  "items": [{"id": "...", "category": "...", "encrypted_data": "<base64>",
+//	             "created_at": "...", "updated_at": "..."}],
+//	  "checksum": "sha256:<hex>"
+//	}
+//
+// checksum 是 encoding/json 编码的 items 数组的 SHA-256，损坏或被改动的导出文件在导入任何条目之前就会被拒绝。
+// 版本 1 的信封早于校验和，没有校验和时仍然接受。
+package vaultexport
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// FormatName 标识 EasyPassword 保险库导出文件。
+	FormatName = "easypassword-vault"
+	// FormatVersion 是信封结构的当前版本。版本 2 增加了校验和。
+	FormatVersion = 2
+
+	// MaxItems 是单个信封中允许的最大条目数。
+	MaxItems = 10000
+	// MaxCategoryLength 与 category 列的长度一致。
+	MaxCategoryLength = 100
+
+	checksumPrefix = "sha256:"
+)
+
+// ErrChecksumMismatch 表示条目与信封中的校验和不匹配。
+var ErrChecksumMismatch = errors.New("checksum does not match the items")
+
+// Envelope 是导出文件的顶层文档。
+type Envelope struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	KDF        KDF       `json:"kdf"`
+	Items      []Item    `json:"items"`
+	Checksum   string    `json:"checksum,omitempty"`
+}
+
+// KDF 描述如何由主密码派生保险库密钥。
+type KDF struct {
+	Algorithm  string `json:"algorithm"`
+	Hash       string `json:"hash"`
+	Iterations int    `json:"iterations"`
+	KeyLength  int    `json:"key_length"`
+	Salt       string `json:"salt"`
+	Cipher     string `json:"cipher"`
+}
+
+// Item 是一个由客户端加密的保险库条目。
+type Item struct {
+	ID            uuid.UUID       `json:"id"`
+	Category      string          `json:"category"`
+	EncryptedData json.RawMessage `json:"encrypted_data"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// DefaultKDF 返回 EasyPassword 客户端对给定盐使用的参数（PBKDF2-SHA256，100000 次迭代，AES-256-GCM）。
+func DefaultKDF(salt string) KDF {
+	return KDF{
+		Algorithm:  "PBKDF2",
+		Hash:       "SHA-256",
+		Iterations: 100000,
+		KeyLength:  256,
+		Salt:       salt,
+		Cipher:     "AES-GCM",
+	}
+}
+
+// ItemsChecksum 返回 items 的校验和，格式与信封中的 checksum 字段相同。
+func ItemsChecksum(items []Item) (string, error) {
+	if items == nil {
+		items = []Item{}
+	}
+	encoded, err := json.Marshal(items)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return checksumPrefix + hex.EncodeToString(sum[:]), nil
+}
+
+// Seal 为在内存中构建的信封设置校验和。
+func (e *Envelope) Seal() error {
+	checksum, err := ItemsChecksum(e.Items)
+	if err != nil {
+		return err
+	}
+	e.Checksum = checksum
+	return nil
+}
+
+// Validate 检查信封是受支持且格式正确的导出文件。
+func (e *Envelope) Validate() error {
+	if e.Format != FormatName {
+		return fmt.Errorf("unsupported format %q", e.Format)
+	}
+	if e.Version < 1 || e.Version > FormatVersion {
+		return fmt.Errorf("unsupported version %d", e.Version)
+	}
+	if e.KDF.Salt == "" {
+		return errors.New("kdf.salt is required")
+	}
+	if e.KDF.Iterations <= 0 {
+		return errors.New("kdf.iterations must be positive")
+	}
+	if len(e.Items) > MaxItems {
+		return fmt.Errorf("too many items: %d (max %d)", len(e.Items), MaxItems)
+	}
+	for i, item := range e.Items {
+		if len(item.EncryptedData) == 0 || string(item.EncryptedData) == "null" {
+			return fmt.Errorf("items[%d]: encrypted_data is required", i)
+		}
+		if !json.Valid(item.EncryptedData) {
+			return fmt.Errorf("items[%d]: encrypted_data is not valid JSON", i)
+		}
+		if len(item.Category) > MaxCategoryLength {
+			return fmt.Errorf("items[%d]: category exceeds %d characters", i, MaxCategoryLength)
+		}
+	}
+	return e.verifyChecksum()
+}
+
+// verifyChecksum 比较校验和与条目。只有版本 1 的信封可以省略校验和。
+func (e *Envelope) verifyChecksum() error {
+	if e.Checksum == "" {
+		if e.Version < 2 {
+			return nil
+		}
+		return errors.New("checksum is required")
+	}
+	want, err := ItemsChecksum(e.Items)
+	if err != nil {
+		return err
+	}
+	if e.Checksum != want {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+// Decode 读取并验证一个信封。
+func Decode(r io.Reader) (*Envelope, error) {
+	var env Envelope
+	if err := json.NewDecoder(r).Decode(&env); err != nil {
+		return nil, fmt.Errorf("decode export: %w", err)
+	}
+	if err := env.Validate(); err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+// Writer 逐个条目地流式写出信封，大型保险库不必作为整个文档保存在内存中。
+type Writer struct {
+	w     io.Writer
+	sum   hash.Hash
+	count int
+	err   error
+}
+
+// NewWriter 把信封头写入 w，并返回用于写入条目的 Writer。
+func NewWriter(w io.Writer, exportedAt time.Time, kdf KDF) (*Writer, error) {
+	header, err := json.Marshal(struct {
+		Format     string    `json:"format"`
+		Version    int       `json:"version"`
+		ExportedAt time.Time `json:"exported_at"`
+		KDF        KDF       `json:"kdf"`
+	}{FormatName, FormatVersion, exportedAt.UTC(), kdf})
+	if err != nil {
+		return nil, err
+	}
+	// 重新打开头部对象，开始 items 数组。
+	header = append(header[:len(header)-1], []byte(`,"items":[`)...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	sum := sha256.New()
+	sum.Write([]byte{'['})
+	return &Writer{w: w, sum: sum}, nil
+}
+
+// WriteItem 向 items 数组追加一个条目。
+func (w *Writer) WriteItem(item Item) error {
+	if w.err != nil {
+		return w.err
+	}
+	encoded, err := json.Marshal(item)
+	if err != nil {
+		w.err = err
+		return err
+	}
+	if w.count > 0 {
+		encoded = append([]byte{','}, encoded...)
+	}
+	if _, err := w.w.Write(encoded); err != nil {
+		w.err = err
+		return err
+	}
+	w.sum.Write(encoded)
+	w.count++
+	return nil
+}
+
+// Close 结束 items 数组并写入校验和。
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	w.sum.Write([]byte{']'})
+	checksum := checksumPrefix + hex.EncodeToString(w.sum.Sum(nil))
+	_, err := fmt.Fprintf(w.w, "],\"checksum\":%q}\n", checksum)
+	return err
+}
@@ -0,0 +1,213 @@
+package vaultexport
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func testItems() []Item {
+	created := time.Date(2026, 1, 2, 15, 4, 5, 123456789, time.FixedZone("CST", 8*60*60))
+	return []Item{
+		{ID: uuid.New(), Category: "work", EncryptedData: json.RawMessage(`"aXYxY2lwaGVydGV4dA=="`), CreatedAt: created, UpdatedAt: created.Add(time.Hour)},
+		{ID: uuid.New(), Category: "", EncryptedData: json.RawMessage(`{"iv":"abc","data":"<&>"}`), CreatedAt: created, UpdatedAt: created},
+	}
+}
+
+// sameJSON 报告 a 和 b 是否是相同的 JSON 值；编码时可能转义了 HTML 字符。
+func sameJSON(t *testing.T, a, b json.RawMessage) bool {
+	t.Helper()
+	var va, vb any
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Fatal(err)
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// export 用 Writer 写出 items，与服务器导出时的方式相同。
+func export(t *testing.T, items []Item) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, time.Now(), DefaultKDF("0011aabb"))
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	for _, item := range items {
+		if err := w.WriteItem(item); err != nil {
+			t.Fatalf("WriteItem: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		items []Item
+	}{
+		{"empty", nil},
+		{"items", testItems()},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			env, err := Decode(bytes.NewReader(export(t, tt.items)))
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if env.Format != FormatName || env.Version != FormatVersion || env.KDF != DefaultKDF("0011aabb") {
+				t.Errorf("envelope header = %+v", env)
+			}
+			if len(env.Items) != len(tt.items) {
+				t.Fatalf("decoded %d items, want %d", len(env.Items), len(tt.items))
+			}
+			for i, got := range env.Items {
+				want := tt.items[i]
+				if got.ID != want.ID || got.Category != want.Category || !got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) {
+					t.Errorf("items[%d] = %+v, want %+v", i, got, want)
+				}
+				if !sameJSON(t, got.EncryptedData, want.EncryptedData) {
+					t.Errorf("items[%d].encrypted_data = %s, want %s", i, got.EncryptedData, want.EncryptedData)
+				}
+			}
+
+			// 客户端把解码后的信封原样重新提交时，校验和仍然有效。
+			encoded, err := json.Marshal(env)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if _, err := Decode(bytes.NewReader(encoded)); err != nil {
+				t.Errorf("Decode of re-encoded envelope: %v", err)
+			}
+		})
+	}
+}
+
+func TestSealMatchesWriter(t *testing.T) {
+	items := testItems()
+	written, err := Decode(bytes.NewReader(export(t, items)))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	env := &Envelope{Format: FormatName, Version: FormatVersion, KDF: DefaultKDF("0011aabb"), Items: items}
+	if err := env.Seal(); err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if env.Checksum != written.Checksum {
+		t.Errorf("Seal checksum = %s, Writer checksum = %s", env.Checksum, written.Checksum)
+	}
+	if err := env.Validate(); err != nil {
+		t.Errorf("Validate of sealed envelope: %v", err)
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	// edit 修改一份有效的导出，返回修改后的 JSON。
+	edit := func(t *testing.T, change func(doc map[string]any)) []byte {
+		t.Helper()
+		var doc map[string]any
+		if err := json.Unmarshal(export(t, testItems()), &doc); err != nil {
+			t.Fatal(err)
+		}
+		change(doc)
+		data, err := json.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	item := func(doc map[string]any, i int) map[string]any {
+		return doc["items"].([]any)[i].(map[string]any)
+	}
+
+	tests := []struct {
+		name    string
+		change  func(doc map[string]any)
+		wantErr error
+		want    string
+	}{
+		{
+			name:    "edited item",
+			change:  func(doc map[string]any) { item(doc, 0)["category"] = "personal" },
+			wantErr: ErrChecksumMismatch,
+		},
+		{
+			name:    "removed item",
+			change:  func(doc map[string]any) { doc["items"] = doc["items"].([]any)[:1] },
+			wantErr: ErrChecksumMismatch,
+		},
+		{
+			name:    "wrong checksum",
+			change:  func(doc map[string]any) { doc["checksum"] = checksumPrefix + strings.Repeat("0", 64) },
+			wantErr: ErrChecksumMismatch,
+		},
+		{
+			name:   "missing checksum",
+			change: func(doc map[string]any) { delete(doc, "checksum") },
+			want:   "checksum is required",
+		},
+		{
+			name:   "version 0",
+			change: func(doc map[string]any) { doc["version"] = 0 },
+			want:   "unsupported version 0",
+		},
+		{
+			name:   "future version",
+			change: func(doc map[string]any) { doc["version"] = FormatVersion + 1 },
+			want:   "unsupported version",
+		},
+		{
+			name:   "other format",
+			change: func(doc map[string]any) { doc["format"] = "bitwarden" },
+			want:   "unsupported format",
+		},
+		{
+			name:   "missing salt",
+			change: func(doc map[string]any) { doc["kdf"].(map[string]any)["salt"] = "" },
+			want:   "kdf.salt is required",
+		},
+		{
+			name:   "missing encrypted data",
+			change: func(doc map[string]any) { delete(item(doc, 1), "encrypted_data") },
+			want:   "items[1]: encrypted_data is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(bytes.NewReader(edit(t, tt.change)))
+			switch {
+			case err == nil:
+				t.Fatal("Decode succeeded")
+			case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+				t.Errorf("Decode error = %v, want %v", err, tt.wantErr)
+			case tt.want != "" && !strings.Contains(err.Error(), tt.want):
+				t.Errorf("Decode error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeVersion1WithoutChecksum(t *testing.T) {
+	env := Envelope{Format: FormatName, Version: 1, KDF: DefaultKDF("0011aabb"), Items: testItems()}
+	data, err := json.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Decode of a version 1 export: %v", err)
+	}
+	if len(decoded.Items) != len(env.Items) {
+		t.Errorf("decoded %d items, want %d", len(decoded.Items), len(env.Items))
+	}
+}
@@ -3,6 +3,9 @@ import * as api from '../api/auth';
 import { deriveKey, generateSalt, hashKey } from '../crypto/vault';
 import { createChromeStorage } from './storage';
 
+// 设备 ID 与登录状态分开保存，退出登录后仍然保留。
+const DEVICE_ID_KEY = 'device_id';
+
 export const useAuthStore = defineStore('auth', {
   state: () => ({
     token: null as string | null,
@@ -37,10 +40,19 @@ export const useAuthStore = defineStore('auth', {
       const masterKeyHash = await hashKey(masterKey);
 
       // 步骤 3：使用标识符和主密钥哈希调用登录 API。
+      // 沿用服务器上一次返回的设备 ID，否则每次登录都会被当作一台新设备。
+      const storage = createChromeStorage();
+      const deviceId = await storage.getItem(DEVICE_ID_KEY);
       const loginResponse = await api.login({
         identifier,
         master_key_hash: masterKeyHash,
+        device_id: deviceId ?? undefined,
+        device_name: 'Browser extension',
+        device_type: 'extension',
       });
+      if (loginResponse.data.device_id) {
+        await storage.setItem(DEVICE_ID_KEY, loginResponse.data.device_id);
+      }
 
       // 步骤 4：在 store 中设置认证数据。
       // 注意：这里我们将标识符用作用户名。如果需要显示确切的用户名，
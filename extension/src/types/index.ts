@@ -9,6 +9,10 @@ export interface RegisterRequestPayload {
 export interface LoginRequestPayload {
   identifier: string;
   master_key_hash: string;
+  // 服务器在上一次登录时返回的设备 ID。首次登录时省略，由服务器生成。
+  device_id?: string;
+  device_name?: string;
+  device_type?: string;
 }
 
 export interface VaultItem {
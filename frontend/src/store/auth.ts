@@ -8,6 +8,8 @@ export const useAuthStore = defineStore('auth', {
     username: null as string | null,
     masterSalt: null as string | null,
     isAuthenticated: false,
+    // 服务器返回的设备 ID。退出登录时保留，供之后的登录沿用。
+    deviceId: null as string | null,
   }),
   actions: {
     async register(username: string, email: string, masterPassword: string, code: string): Promise<void> {
@@ -36,10 +38,17 @@ export const useAuthStore = defineStore('auth', {
       const masterKeyHash = await hashKey(masterKey);
 
       // 步骤 3：使用标识符和主密钥哈希调用登录 API。
+      // 沿用服务器上一次返回的设备 ID，否则每次登录都会被当作一台新设备。
       const loginResponse = await api.login({
         identifier,
         master_key_hash: masterKeyHash,
+        device_id: this.deviceId ?? undefined,
+        device_name: 'Web browser',
+        device_type: 'browser',
       });
+      if (loginResponse.data.device_id) {
+        this.deviceId = loginResponse.data.device_id;
+      }
 
       // 步骤 4：在 store 中设置认证数据。
       // 注意：这里我们将标识符用作用户名。如果需要显示确切的用户名，